@@ -0,0 +1,78 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/spf13/cobra"
+	"k8s.io/component-base/logs"
+	"k8s.io/klog"
+
+	"icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/emulator"
+)
+
+func main() {
+	logs.InitLogs()
+	defer logs.FlushLogs()
+
+	var address, stateFile string
+	config := emulator.Config{}
+
+	command := &cobra.Command{
+		Use: "bce-emulator",
+		Long: `bce-emulator serves the BLB, EIP, VPC, BCC and CCE APIs called by cce-cloud-controller-manager,
+backed by in-memory fake clients, for end-to-end tests without a cloud account.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if config.AccessKeyID != "" && config.SecretAccessKey == "" {
+				fmt.Fprintf(os.Stderr, "--secret-access-key must be set with --access-key-id\n")
+				os.Exit(1)
+			}
+			e := emulator.NewEmulator(config)
+			if stateFile != "" {
+				f, err := os.Open(stateFile)
+				if err != nil {
+					klog.Fatalf("open state file failed: %v", err)
+				}
+				err = e.LoadState(f)
+				f.Close()
+				if err != nil {
+					klog.Fatalf("load state file %s failed: %v", stateFile, err)
+				}
+			}
+			if config.AccessKeyID == "" {
+				klog.Warningf("--access-key-id is not set, signature of requests is not checked")
+			}
+			klog.Infof("bce-emulator listens on %s, state is dumped at %s", address, emulator.StatePath)
+			klog.Fatal(http.ListenAndServe(address, e))
+		},
+	}
+
+	flags := command.Flags()
+	flags.StringVar(&address, "address", ":8080", "The address the emulator listens on.")
+	flags.StringVar(&stateFile, "state-file", "", "The JSON file of resources the emulator starts with, in the format of its state dump, e.g. CCE cluster nodes and VPC route tables.")
+	flags.StringVar(&config.AccessKeyID, "access-key-id", "", "The AK requests must be signed with, signature is not checked if it is empty.")
+	flags.StringVar(&config.SecretAccessKey, "secret-access-key", "", "The SK requests must be signed with.")
+	flags.StringVar(&config.Token, "token", "", "The CCE plugin token requests must carry, not checked if it is empty.")
+
+	if err := command.Execute(); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+}
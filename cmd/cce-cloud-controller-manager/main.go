@@ -79,7 +79,7 @@ func main() {
 				os.Exit(1)
 			}
 
-			if err := app.Run(c.Complete(), wait.NeverStop); err != nil {
+			if err := app.Run(c.Complete(), s.CloudCredentialsSecret, wait.NeverStop); err != nil {
 				fmt.Fprintf(os.Stderr, "%v\n", err)
 				os.Exit(1)
 			}
@@ -37,6 +37,7 @@ import (
 	cloudprovider "k8s.io/cloud-provider"
 	cliflag "k8s.io/component-base/cli/flag"
 	"k8s.io/component-base/cli/globalflag"
+	_ "k8s.io/component-base/metrics/prometheus/workqueue" // for workqueue metric registration
 	"k8s.io/component-base/version"
 	"k8s.io/klog"
 	cloudcontrollerconfig "k8s.io/kubernetes/cmd/cloud-controller-manager/app/config"
@@ -45,6 +46,8 @@ import (
 	"k8s.io/kubernetes/pkg/util/configz"
 	utilflag "k8s.io/kubernetes/pkg/util/flag"
 	"k8s.io/kubernetes/pkg/version/verflag"
+
+	cloud_provider "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/cloud-provider"
 )
 
 const (
@@ -75,7 +78,7 @@ the cloud specific control loops shipped with Kubernetes.`,
 				os.Exit(1)
 			}
 
-			if err := Run(c.Complete(), wait.NeverStop); err != nil {
+			if err := Run(c.Complete(), "", wait.NeverStop); err != nil {
 				fmt.Fprintf(os.Stderr, "%v\n", err)
 				os.Exit(1)
 			}
@@ -112,10 +115,17 @@ the cloud specific control loops shipped with Kubernetes.`,
 }
 
 // Run runs the ExternalCMServer.  This should never exit.
-func Run(c *cloudcontrollerconfig.CompletedConfig, stopCh <-chan struct{}) error {
+// cloudCredentialsSecret overrides CredentialsSecret of the cloud config if it is not empty.
+func Run(c *cloudcontrollerconfig.CompletedConfig, cloudCredentialsSecret string, stopCh <-chan struct{}) error {
 	// To help debugging, immediately log version
 	klog.Infof("Version: %+v", version.Get())
 
+	if cloudCredentialsSecret != "" {
+		if err := cloud_provider.ValidateCredentialsSecret(cloudCredentialsSecret); err != nil {
+			return fmt.Errorf("--cloud-credentials-secret is invalid: %v", err)
+		}
+	}
+
 	cloud, err := cloudprovider.InitCloudProvider(c.ComponentConfig.KubeCloudShared.CloudProvider.Name, c.ComponentConfig.KubeCloudShared.CloudProvider.CloudConfigFile)
 	if err != nil {
 		klog.Fatalf("Cloud provider could not be initialized: %v", err)
@@ -123,6 +133,13 @@ func Run(c *cloudcontrollerconfig.CompletedConfig, stopCh <-chan struct{}) error
 	if cloud == nil {
 		klog.Fatalf("cloud provider is nil")
 	}
+	if cloudCredentialsSecret != "" {
+		baiducloud, ok := cloud.(*cloud_provider.Baiducloud)
+		if !ok {
+			klog.Fatalf("--cloud-credentials-secret is not supported by cloud provider %s", c.ComponentConfig.KubeCloudShared.CloudProvider.Name)
+		}
+		baiducloud.CredentialsSecret = cloudCredentialsSecret
+	}
 
 	if !cloud.HasClusterID() {
 		if c.ComponentConfig.KubeCloudShared.AllowUntaggedCloud {
@@ -279,5 +296,6 @@ func newControllerInitializers() map[string]initFunc {
 	controllers["cloud-node-lifecycle"] = startCloudNodeLifecycleController
 	controllers["service"] = startServiceController
 	controllers["route"] = startRouteController
+	controllers["orphan-gc"] = startOrphanGCController
 	return controllers
 }
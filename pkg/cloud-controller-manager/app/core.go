@@ -38,6 +38,7 @@ import (
 	kubefeatures "k8s.io/kubernetes/pkg/features"
 
 	cloudcontrollers "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/cloud"
+	gccontroller "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/gc"
 	routecontroller "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/route"
 	servicecontroller "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/service"
 )
@@ -141,6 +142,24 @@ func startRouteController(ctx *cloudcontrollerconfig.CompletedConfig, cloud clou
 	return nil, true, nil
 }
 
+func startOrphanGCController(ctx *cloudcontrollerconfig.CompletedConfig, cloud cloudprovider.Interface, stopCh <-chan struct{}) (http.Handler, bool, error) {
+	collector, ok := cloud.(gccontroller.Collector)
+	if !ok {
+		klog.Warning("cloud provider does not support collecting orphaned resources. Will not start orphan gc controller.")
+		return nil, false, nil
+	}
+
+	// Start the orphan gc controller
+	gcController := gccontroller.New(
+		collector,
+		ctx.ClientBuilder.ClientOrDie("orphan-gc-controller"),
+		ctx.SharedInformers.Core().V1().Services(),
+	)
+	go gcController.Run(stopCh)
+
+	return nil, true, nil
+}
+
 // processCIDRs is a helper function that works on a comma separated cidrs and returns
 // a list of typed cidrs
 // a flag if cidrs represents a dual stack
@@ -46,6 +46,8 @@ import (
 
 	// add the kubernetes feature gates
 	_ "k8s.io/kubernetes/pkg/features"
+
+	cloud_provider "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/cloud-provider"
 )
 
 const (
@@ -72,6 +74,9 @@ type CloudControllerManagerOptions struct {
 
 	// NodeStatusUpdateFrequency is the frequency at which the controller updates nodes' status
 	NodeStatusUpdateFrequency metav1.Duration
+
+	// CloudCredentialsSecret is the <namespace>/<name> of the Secret AK/SK are loaded from
+	CloudCredentialsSecret string
 }
 
 // NewCloudControllerManagerOptions creates a new ExternalCMServer with a default config.
@@ -142,6 +147,7 @@ func (o *CloudControllerManagerOptions) Flags(allControllers, disabledByDefaultC
 	fs.StringVar(&o.Master, "master", o.Master, "The address of the Kubernetes API server (overrides any value in kubeconfig).")
 	fs.StringVar(&o.Kubeconfig, "kubeconfig", o.Kubeconfig, "Path to kubeconfig file with authorization and master location information.")
 	fs.DurationVar(&o.NodeStatusUpdateFrequency.Duration, "node-status-update-frequency", o.NodeStatusUpdateFrequency.Duration, "Specifies how often the controller updates nodes' status.")
+	fs.StringVar(&o.CloudCredentialsSecret, "cloud-credentials-secret", o.CloudCredentialsSecret, "The <namespace>/<name> of the Secret to load and watch cloud AK/SK from, with keys AccessKeyID and SecretAccessKey, overriding those of the cloud config file.")
 
 	utilfeature.DefaultMutableFeatureGate.AddFlag(fss.FlagSet("generic"))
 
@@ -234,6 +240,11 @@ func (o *CloudControllerManagerOptions) Validate(allControllers, disabledByDefau
 	if len(o.KubeCloudShared.CloudProvider.Name) == 0 {
 		errors = append(errors, fmt.Errorf("--cloud-provider cannot be empty"))
 	}
+	if len(o.CloudCredentialsSecret) != 0 {
+		if err := cloud_provider.ValidateCredentialsSecret(o.CloudCredentialsSecret); err != nil {
+			errors = append(errors, fmt.Errorf("--cloud-credentials-secret is invalid: %v", err))
+		}
+	}
 
 	return utilerrors.NewAggregate(errors)
 }
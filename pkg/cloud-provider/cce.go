@@ -22,17 +22,18 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"sync"
 	"time"
 
 	v1 "k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
 	v1core "k8s.io/client-go/kubernetes/typed/core/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/workqueue"
@@ -40,11 +41,13 @@ import (
 	"k8s.io/klog"
 
 	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/bce"
-	bcesdk "icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/bce"
 	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/blb"
 	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/eip"
 	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/vpc"
+	bcc "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/temp-bcc"
+	blbext "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/temp-blb"
 	cce "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/temp-cce"
+	eipext "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/temp-eip"
 )
 
 // ProviderName is the name of this cloud provider.
@@ -73,6 +76,28 @@ type Baiducloud struct {
 	eventRecorder    record.EventRecorder
 	// services that need to be synced
 	svcQueue workqueue.RateLimitingInterface
+	// listers are set by SetInformers, API server is queried directly if nil
+	serviceLister   corelisters.ServiceLister
+	endpointsLister corelisters.EndpointsLister
+	nodeLister      corelisters.NodeLister
+	// endpointsListerSynced is not waited by service controller, endpoints are read from API server until it is true
+	endpointsListerSynced cache.InformerSynced
+	// blbBackendTypes records backend type of each BLB whose backends of the other type are removed, BLB id -> backend type
+	blbBackendTypes sync.Map
+	// instanceCache is set when cloud is created from config, CCE is queried directly if nil
+	instanceCache *instanceCache
+	// clientMiddleware rate limits and retries calls of clientSet, nil if clientSet is not wrapped
+	clientMiddleware *clientMiddleware
+	// credentials provides AK/SK and token of BCE API calls, nil if cloud is not created from config.
+	// It is replaced when AK/SK are loaded from Secret, read it by getCredentialsProvider.
+	credentials     CredentialsProvider
+	credentialsLock sync.RWMutex
+	// pendingCredentialsSecret is the credentials Secret not synced yet, which is used by the credentials watcher
+	// once synced
+	pendingCredentialsSecret *credentialsSecret
+	// clientsCredentials and tokenExpiryReported are only used by the credentials watcher after cloud is created
+	clientsCredentials  *Credentials
+	tokenExpiryReported string
 }
 
 // CloudConfig is the cloud config
@@ -88,6 +113,79 @@ type CloudConfig struct {
 	Endpoint        string `json:"Endpoint"`
 	NodeName        string `json:"NodeName"`
 	Debug           bool   `json:"Debug"`
+	// RsDrainGracePeriod is the default seconds to drain BLB backend servers before removing them, 0 means no draining
+	RsDrainGracePeriod int `json:"RsDrainGracePeriod"`
+	// InstanceCacheTTL is the seconds cluster instances are cached, default 30
+	InstanceCacheTTL int `json:"InstanceCacheTTL"`
+	// OrphanGC configures garbage collection of orphaned BLBs and EIPs
+	OrphanGC OrphanGCConfig `json:"OrphanGC"`
+	// CloudAPI configures timeout, rate limiting and retrying of BCE API calls
+	CloudAPI CloudAPIConfig `json:"CloudAPI"`
+	// Services overrides endpoint, proxy, scheme and timeout of BCE services by name, i.e. BLB, EIP, CCE, VPC and BCC
+	Services map[string]ServiceConfig `json:"Services"`
+	// Gateway configures CCE gateway, which BCE API calls except BLB ones are proxied through
+	Gateway GatewayConfig `json:"Gateway"`
+	// CredentialsSecret is the <namespace>/<name> of the Secret AK/SK are loaded from and watched, overriding
+	// AccessKeyID and SecretAccessKey, overridden by --cloud-credentials-secret
+	CredentialsSecret string `json:"CredentialsSecret"`
+}
+
+// OrphanGCConfig is the config of orphaned BLB and EIP garbage collection
+type OrphanGCConfig struct {
+	// Period is seconds between two collections, default 600
+	Period int `json:"Period"`
+	// GracePeriod is seconds a resource must stay orphaned before deleted, default 3600
+	GracePeriod int `json:"GracePeriod"`
+	// Delete enables deleting orphaned resources, otherwise they are only reported
+	Delete bool `json:"Delete"`
+	// DryRun reports the orphaned resources to delete without deleting them
+	DryRun bool `json:"DryRun"`
+}
+
+// CloudAPIConfig is the config of timeout, rate limiting and retrying of BCE API calls
+type CloudAPIConfig struct {
+	// Timeout is seconds of a request, default 30
+	Timeout int `json:"Timeout"`
+	// QPS and Burst are the default rate limit of each API, default 10 and 20
+	QPS   float32 `json:"QPS"`
+	Burst int     `json:"Burst"`
+	// RateLimits overrides QPS and Burst by API name like "BLB.DescribeLoadBalancers", or for all APIs of a client by
+	// client name like "BLB". Each API is still limited by its own token bucket.
+	RateLimits map[string]RateLimitConfig `json:"RateLimits"`
+	// MaxRetries is the max retries of a failed call, default 3, negative disables retrying
+	MaxRetries int `json:"MaxRetries"`
+	// RetryBaseDelay is milliseconds before the first retry, doubled for each retry with jitter, default 500
+	RetryBaseDelay int `json:"RetryBaseDelay"`
+	// RetryMaxDelay caps milliseconds between retries, default 10000
+	RetryMaxDelay int `json:"RetryMaxDelay"`
+}
+
+// RateLimitConfig is the token bucket rate limit of an API
+type RateLimitConfig struct {
+	QPS   float32 `json:"QPS"`
+	Burst int     `json:"Burst"`
+}
+
+// ServiceConfig is the config of the client of a BCE service
+type ServiceConfig struct {
+	// Endpoint is the host or host:port of service, default the endpoint of region, or Endpoint of cloud config for CCE
+	Endpoint string `json:"Endpoint"`
+	// ProxyHost and ProxyPort are the proxy requests are sent through, default CCE gateway unless it is disabled
+	ProxyHost string `json:"ProxyHost"`
+	ProxyPort int    `json:"ProxyPort"`
+	// Scheme is http or https, default http
+	Scheme string `json:"Scheme"`
+	// Timeout is seconds of a request, default CloudAPI.Timeout
+	Timeout int `json:"Timeout"`
+}
+
+// GatewayConfig is the config of CCE gateway
+type GatewayConfig struct {
+	// Host and Port of CCE gateway, default by region, or by env CCE_GATEWAY_HOST
+	Host string `json:"Host"`
+	Port int    `json:"Port"`
+	// Disabled sends requests to services directly with AK/SK, instead of through CCE gateway with CCE plugin token
+	Disabled bool `json:"Disabled"`
 }
 
 // CCMVersion is the version of CCM
@@ -112,15 +210,52 @@ func init() {
 		if cloudConfig.ClusterID == "" {
 			return nil, fmt.Errorf("Cloud config must have a ClusterID\n ")
 		}
-		if cloudConfig.Endpoint == "" {
-			return nil, fmt.Errorf("Cloud config must have a Endpoint\n ")
+		if cloudConfig.RsDrainGracePeriod < 0 || cloudConfig.RsDrainGracePeriod > maxRsDrainGracePeriod {
+			return nil, fmt.Errorf("Cloud config RsDrainGracePeriod must be in [0, %d]\n ", maxRsDrainGracePeriod)
+		}
+		if cloudConfig.InstanceCacheTTL < 0 || cloudConfig.InstanceCacheTTL > maxInstanceCacheTTL {
+			return nil, fmt.Errorf("Cloud config InstanceCacheTTL must be in [0, %d]\n ", maxInstanceCacheTTL)
+		}
+		if cloudConfig.CredentialsSecret != "" {
+			if err := ValidateCredentialsSecret(cloudConfig.CredentialsSecret); err != nil {
+				return nil, fmt.Errorf("Cloud config CredentialsSecret must be <namespace>/<name>\n ")
+			}
+		}
+		if cloudConfig.InstanceCacheTTL == 0 {
+			cloudConfig.InstanceCacheTTL = defaultInstanceCacheTTL
+		}
+		if err := completeCloudAPIConfig(&cloudConfig.CloudAPI); err != nil {
+			return nil, err
+		}
+		if err := completeServicesConfig(&cloudConfig); err != nil {
+			return nil, err
 		}
 
 		cloud.CloudConfig = cloudConfig
-		cloud.clientSet, err = newClientSet(&cloudConfig)
+		// AK/SK are reloaded from the config file if it is read from one
+		configFile := ""
+		if f, ok := configReader.(*os.File); ok {
+			configFile = f.Name()
+		}
+		credentials := newFileCredentialsProvider(configFile, defaultTokenDir, cloudConfig.AccessKeyID, cloudConfig.SecretAccessKey)
+		if _, err := credentials.Reload(); err != nil {
+			klog.Warningf("load credentials failed, will be reloaded later: %v", err)
+		}
+		cloud.credentials = credentials
+		cloud.clientsCredentials = credentials.Credentials()
+		setSecretValues(cloud.clientsCredentials)
+		if cloudConfig.Debug {
+			// debug dumps of SDK print credentials
+			redactSDKDebugOutput()
+		}
+		clientSet, err := cloud.newClientSetWithCredentials(cloud.clientsCredentials)
 		if err != nil {
 			return nil, err
 		}
+		cloud.clientMiddleware = newClientMiddleware(&cloud.CloudAPI)
+		cloud.clientSet = cloud.clientMiddleware.wrap(clientSet)
+		cloud.instanceCache = newInstanceCache(time.Duration(cloudConfig.InstanceCacheTTL)*time.Second, cloud.listClusterNodes)
+		RegisterMetrics()
 		return &cloud, nil
 	})
 }
@@ -139,6 +274,17 @@ func (bc *Baiducloud) Initialize(clientBuilder cloudprovider.ControllerClientBui
 	bc.eventBroadcaster.StartRecordingToSink(&v1core.EventSinkImpl{Interface: bc.kubeClient.CoreV1().Events("")})
 	bc.eventRecorder = bc.eventBroadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: "CCM"})
 	bc.svcQueue = workqueue.NewNamedRateLimitingQueue(workqueue.NewItemExponentialFailureRateLimiter(minRetryDelay, maxRetryDelay), "endpoints")
+	if bc.clientMiddleware != nil {
+		bc.clientMiddleware.stopOn(stop)
+	}
+	if bc.getCredentialsProvider() != nil {
+		if bc.CredentialsSecret != "" {
+			if err := bc.useCredentialsSecret(bc.CredentialsSecret, credentialsSecretSyncTimeout, stop); err != nil {
+				klog.Errorf("use credentials secret %s failed, keep AK/SK of cloud config until it is synced: %v", bc.CredentialsSecret, err)
+			}
+		}
+		bc.runCredentialsWatcher(stop)
+	}
 	bc.runServiceWorker()
 }
 
@@ -183,72 +329,30 @@ func (bc *Baiducloud) SetInformers(informerFactory informers.SharedInformerFacto
 		},
 	})
 
-	// endpoints
-	podInformer := informerFactory.Core().V1().Pods().Informer()
-	podInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
-		UpdateFunc: func(oldObj, newObj interface{}) {
-			pod := newObj.(*v1.Pod)
-
-			if len(pod.Status.PodIP) == 0 {
-				return
-			}
-
-			svcs, err := bc.kubeClient.CoreV1().Services(pod.Namespace).List(metav1.ListOptions{})
-			if err != nil {
-				klog.Errorf("podInformer failed to get service of pod %s/%s: %s", pod.Namespace, pod.Name, err)
-				return
-			}
-			for _, svc := range svcs.Items {
-				if svc.Spec.Selector == nil {
-					continue
-				}
-				if svc.Spec.ExternalTrafficPolicy != v1.ServiceExternalTrafficPolicyTypeLocal {
-					klog.Infof("Service %s/%s is not externalTrafficPolicy Local, skipping update rs", svc.Namespace, svc.Name)
-					continue
-				}
-				selector := labels.Set(svc.Spec.Selector).AsSelectorPreValidated()
-				if selector.Matches(labels.Set(pod.Labels)) {
-					key := fmt.Sprintf("%s/%s", svc.Namespace, svc.Name)
-					bc.svcQueue.AddRateLimited(key)
-				}
-			}
+	// endpoints of services whose backends follow endpoints, i.e. Local services and services using pod backends
+	endpointsInformer := informerFactory.Core().V1().Endpoints().Informer()
+	endpointsInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			bc.onEndpointsChanged(nil, obj.(*v1.Endpoints))
 		},
-		DeleteFunc: func(obj interface{}) {
-			pod := obj.(*v1.Pod)
-
-			if len(pod.Status.PodIP) == 0 {
-				return
-			}
-
-			svcs, err := bc.kubeClient.CoreV1().Services(pod.Namespace).List(metav1.ListOptions{})
-			if err != nil {
-				klog.Errorf("podInformer failed to get service of pod %s/%s: %s", pod.Namespace, pod.Name, err)
-				return
-			}
-			for _, svc := range svcs.Items {
-				if svc.Spec.Selector == nil {
-					continue
-				}
-				if svc.Spec.ExternalTrafficPolicy != v1.ServiceExternalTrafficPolicyTypeLocal {
-					klog.Infof("Service %s/%s is not externalTrafficPolicy Local, skipping update rs", svc.Namespace, svc.Name)
-					continue
-				}
-				selector := labels.Set(svc.Spec.Selector).AsSelectorPreValidated()
-				if selector.Matches(labels.Set(pod.Labels)) {
-					key := fmt.Sprintf("%s/%s", svc.Namespace, svc.Name)
-					bc.svcQueue.AddRateLimited(key)
-				}
-			}
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			bc.onEndpointsChanged(oldObj.(*v1.Endpoints), newObj.(*v1.Endpoints))
 		},
 	})
+
+	bc.serviceLister = informerFactory.Core().V1().Services().Lister()
+	bc.endpointsLister = informerFactory.Core().V1().Endpoints().Lister()
+	bc.endpointsListerSynced = endpointsInformer.HasSynced
+	bc.nodeLister = informerFactory.Core().V1().Nodes().Lister()
 }
 
 // ClientSet contains all the bce product client
 type ClientSet struct {
-	BLBClient blb.Interface
-	EIPClient eip.Interface
+	BLBClient blbext.Interface
+	EIPClient eipext.Interface
 	CCEClient cce.Interface
 	VPCClient vpc.Interface
+	BCCClient bcc.Interface
 }
 
 func newClientSet(config *CloudConfig) (*ClientSet, error) {
@@ -258,66 +362,41 @@ func newClientSet(config *CloudConfig) (*ClientSet, error) {
 
 	clientset := &ClientSet{}
 
-	// set cce-gateway proxy
-	proxyHost, proxyPort := getCCEGatewayHostAndPort(config.Region)
 	// BLBClient
-	lbClient := blb.NewBLBClient(&blb.Config{
-		Config: &bcesdk.Config{
-			Credentials: bcesdk.NewCredentials(config.AccessKeyID, config.SecretAccessKey),
-			Checksum:    true,
-			Timeout:     30 * time.Second,
-			Region:      config.Region,
-			Endpoint:    blb.Endpoint[config.Region], // notice!
-			UserAgent:   fmt.Sprintf("%s:%s", CCEUserAgent, config.ClusterID),
-		},
+	blbConfig := newBCEConfig(config, "BLB")
+	blbConfig.UserAgent = fmt.Sprintf("%s:%s", CCEUserAgent, config.ClusterID)
+	lbClient := blbext.NewClient(&blb.Config{
+		Config: blbConfig,
 	})
 	clientset.BLBClient = lbClient
 
 	// EIPClient
-	eipClient := eip.NewClient(&eip.Config{
-		Config: &bcesdk.Config{
-			Credentials: bcesdk.NewCredentials(config.AccessKeyID, config.SecretAccessKey),
-			Checksum:    true,
-			Timeout:     30 * time.Second,
-			Region:      config.Region,
-			Endpoint:    eip.Endpoint[config.Region],
-			ProxyHost:   proxyHost,
-			ProxyPort:   proxyPort,
-		},
+	eipClient := eipext.NewClient(&eip.Config{
+		Config: newBCEConfig(config, "EIP"),
 	})
 	clientset.EIPClient = eipClient
 
 	// CCEClient request Internal API
+	cceConfig := newBCEConfig(config, "CCE")
+	cceConfig.UserAgent = fmt.Sprintf("%s:%s", CCEUserAgent, config.ClusterID) // UserAgent
 	cceClient := cce.NewClient(&cce.Config{
-		Config: &bcesdk.Config{
-			Credentials: bcesdk.NewCredentials(config.AccessKeyID, config.SecretAccessKey),
-			Checksum:    true,
-			Timeout:     30 * time.Second,
-			Region:      config.Region,
-			Endpoint:    config.Endpoint,
-			UserAgent:   fmt.Sprintf("%s:%s", CCEUserAgent, config.ClusterID), // UserAgent
-			ProxyHost:   proxyHost,
-			ProxyPort:   proxyPort,
-		},
+		Config: cceConfig,
 	})
 	clientset.CCEClient = cceClient
 
 	// VPCClient
 	vpcClient := vpc.NewClient(&vpc.Config{
-		Config: &bcesdk.Config{
-			Credentials: bcesdk.NewCredentials(config.AccessKeyID, config.SecretAccessKey),
-			Checksum:    true,
-			Timeout:     30 * time.Second,
-			Region:      config.Region,
-			Endpoint:    vpc.Endpoint[config.Region],
-			ProxyHost:   proxyHost,
-			ProxyPort:   proxyPort,
-		},
+		Config: newBCEConfig(config, "VPC"),
 	})
 	clientset.VPCClient = vpcClient
 
+	// BCCClient
+	bccClient := bcc.NewClient(&bcc.Config{
+		Config: newBCEConfig(config, "BCC"),
+	})
+	clientset.BCCClient = bccClient
+
 	// Set Debug
-	config.Debug = true
 	if config.Debug == true {
 		klog.Info("cce-ingresss-controller set debug = true")
 	}
@@ -325,25 +404,11 @@ func newClientSet(config *CloudConfig) (*ClientSet, error) {
 	eipClient.SetDebug(config.Debug)
 	cceClient.SetDebug(config.Debug)
 	vpcClient.SetDebug(config.Debug)
+	bccClient.SetDebug(config.Debug)
 
 	return clientset, nil
 }
 
-func getCloudConfig(ctx context.Context) (*CloudConfig, error) {
-	ccBytes, err := ioutil.ReadFile("/etc/kubernetes/cloud.config")
-	if err != nil {
-		return nil, fmt.Errorf("read cloud config error: '%v'", err)
-	}
-
-	cc := new(CloudConfig)
-	err = json.Unmarshal(ccBytes, cc)
-	if err != nil {
-		return nil, fmt.Errorf("unmarshal cloud config error: '%v'", err)
-	}
-
-	return cc, nil
-}
-
 // NewCCEClient for internal cce service
 func NewCCEClient(accessKeyID, secretAccessKey, region, endpoint string) *cce.Client {
 	return cce.NewClient(&cce.Config{
@@ -371,27 +436,38 @@ func (bc *Baiducloud) serviceWorker() {
 }
 
 func (bc *Baiducloud) processNextService() bool {
-	ctx := context.WithValue(context.Background(), RequestID, GetRandom())
+	ctx := WithRequestID(context.Background())
 	key, quit := bc.svcQueue.Get()
 	if quit {
 		return false
 	}
 	defer bc.svcQueue.Done(key)
-	klog.Infof(Message(ctx, fmt.Sprintf("Pod changed, begin reconcile backend server for service %s", key)))
+	klog.Infof(Message(ctx, fmt.Sprintf("Endpoints changed, begin reconcile backend server for service %s", key)))
 
+	startTime := time.Now()
 	err := func() error {
 		namespace, name, err := cache.SplitMetaNamespaceKey(key.(string))
 		if err != nil {
 			runtime.HandleError(fmt.Errorf("Invalid resource key: %s", key))
 			return err
 		}
-		service, err := bc.kubeClient.CoreV1().Services(namespace).Get(name, metav1.GetOptions{})
+		service, err := bc.getService(namespace, name)
+		if errors.IsNotFound(err) {
+			klog.Infof(Message(ctx, fmt.Sprintf("service %s has been deleted, skip reconcile backend server", key)))
+			return nil
+		}
 		if err != nil {
 			return err
 		}
+		if usesHealthCheckNodePort(service) || (!isLocalTrafficService(service) && !isPodBackendService(service)) {
+			// backends follow the nodes known by service controller, only drained ones are removed at drain deadline
+			klog.Infof(Message(ctx, fmt.Sprintf("service %s uses all nodes as backends, remove drained backend server only", key)))
+			return bc.removeDrainedBackends(ctx, service)
+		}
 		nodes := make([]*v1.Node, 0)
 		return bc.reconcileBackendServers(ctx, bc.ClusterName, service, nodes)
 	}()
+	ObserveReconcile("endpoints", startTime, err)
 	if err == nil {
 		bc.svcQueue.Forget(key)
 		return true
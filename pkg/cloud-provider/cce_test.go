@@ -1,19 +1,28 @@
 package cloud_provider
 
 import (
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+
 	"icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/fake"
 )
 
 func NewFakeCloud(clusterID string) *Baiducloud {
+	blbClient := fake.NewBlbFakeClient()
+	bccClient := fake.NewBccFakeClient()
+	blbClient.BCC = bccClient
 	return &Baiducloud{
 		CloudConfig: CloudConfig{
 			ClusterID: clusterID,
 		},
 		clientSet: &ClientSet{
-			BLBClient: fake.NewBlbFakeClient(),
+			BLBClient: blbClient,
 			VPCClient: fake.NewVpcFakeClient(),
 			CCEClient: fake.NewCceFakeClient(),
 			EIPClient: fake.NewEipFakeClient(),
+			BCCClient: bccClient,
 		},
+		kubeClient:    k8sfake.NewSimpleClientset(),
+		eventRecorder: record.NewFakeRecorder(100),
 	}
 }
@@ -0,0 +1,162 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloud_provider
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/bce"
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/blb"
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/eip"
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/vpc"
+	bcc "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/temp-bcc"
+)
+
+const (
+	schemeHTTP  = "http"
+	schemeHTTPS = "https"
+)
+
+// regionEndpoints are the endpoints of services by region, CCE endpoint is Endpoint of cloud config
+var regionEndpoints = map[string]map[string]string{
+	"BLB": blb.Endpoint,
+	"EIP": eip.Endpoint,
+	"VPC": vpc.Endpoint,
+	"BCC": bcc.Endpoint,
+}
+
+// unproxiedServices are not proxied through CCE gateway by default
+var unproxiedServices = map[string]bool{
+	"BLB": true,
+}
+
+// completeServicesConfig validates Services and Gateway of config, and resolves the endpoint, proxy, scheme and timeout
+// of every service into Services. It must be called after CloudAPI is completed.
+func completeServicesConfig(config *CloudConfig) error {
+	for name, service := range config.Services {
+		if _, ok := cloudAPIClients[name]; !ok {
+			return fmt.Errorf("Cloud config Services has unknown service %s, service must be one of BLB, EIP, CCE, VPC and BCC\n ", name)
+		}
+		if service.Endpoint != "" {
+			if err := validateHostPort(service.Endpoint); err != nil {
+				return fmt.Errorf("Cloud config Services.%s.Endpoint is invalid: %v\n ", name, err)
+			}
+		}
+		if service.ProxyHost != "" {
+			if err := validateHostPort(service.ProxyHost); err != nil || strings.Contains(service.ProxyHost, ":") {
+				return fmt.Errorf("Cloud config Services.%s.ProxyHost must be a host without port\n ", name)
+			}
+		}
+		if service.ProxyPort < 0 || service.ProxyPort > 65535 || (service.ProxyPort != 0 && service.ProxyHost == "") {
+			return fmt.Errorf("Cloud config Services.%s.ProxyPort must be in [0, 65535], and be set with ProxyHost\n ", name)
+		}
+		if service.Scheme != "" && service.Scheme != schemeHTTP && service.Scheme != schemeHTTPS {
+			return fmt.Errorf("Cloud config Services.%s.Scheme must be %s or %s\n ", name, schemeHTTP, schemeHTTPS)
+		}
+		if service.Timeout < 0 || service.Timeout > maxCloudAPITimeout {
+			return fmt.Errorf("Cloud config Services.%s.Timeout must be in [0, %d]\n ", name, maxCloudAPITimeout)
+		}
+	}
+	if config.Gateway.Host != "" {
+		if err := validateHostPort(config.Gateway.Host); err != nil || strings.Contains(config.Gateway.Host, ":") {
+			return fmt.Errorf("Cloud config Gateway.Host must be a host without port\n ")
+		}
+	}
+	if config.Gateway.Port < 0 || config.Gateway.Port > 65535 {
+		return fmt.Errorf("Cloud config Gateway.Port must be in [0, 65535]\n ")
+	}
+
+	if config.Gateway.Disabled {
+		config.Gateway.Host, config.Gateway.Port = "", 0
+	} else if config.Gateway.Host == "" {
+		config.Gateway.Host, config.Gateway.Port = getCCEGatewayHostAndPort(config.Region)
+	}
+	services := make(map[string]ServiceConfig, len(cloudAPIClients))
+	for name := range cloudAPIClients {
+		service := config.Services[name]
+		if service.Endpoint == "" && name == "CCE" {
+			if config.Endpoint == "" {
+				return fmt.Errorf("Cloud config must have a Endpoint\n ")
+			}
+			service.Endpoint = config.Endpoint
+		}
+		if service.Endpoint == "" {
+			service.Endpoint = regionEndpoints[name][config.Region]
+			if service.Endpoint == "" {
+				return fmt.Errorf("Cloud config must have Services.%s.Endpoint, region %q has no default endpoint\n ", name, config.Region)
+			}
+		}
+		if service.ProxyHost == "" && !config.Gateway.Disabled && !unproxiedServices[name] {
+			service.ProxyHost, service.ProxyPort = config.Gateway.Host, config.Gateway.Port
+		}
+		if service.Scheme == "" {
+			service.Scheme = schemeHTTP
+		}
+		if service.Timeout == 0 {
+			service.Timeout = config.CloudAPI.Timeout
+		}
+		services[name] = service
+	}
+	config.Services = services
+	return nil
+}
+
+// validateHostPort validates hostPort is a host or host:port, without scheme or path
+func validateHostPort(hostPort string) error {
+	if strings.Contains(hostPort, "/") {
+		return fmt.Errorf("%q must be host or host:port, without scheme or path", hostPort)
+	}
+	host := hostPort
+	if strings.Contains(hostPort, ":") {
+		var port string
+		var err error
+		host, port, err = net.SplitHostPort(hostPort)
+		if err != nil {
+			return err
+		}
+		if p, err := strconv.Atoi(port); err != nil || p <= 0 || p > 65535 {
+			return fmt.Errorf("%q has invalid port", hostPort)
+		}
+	}
+	if host == "" {
+		return fmt.Errorf("%q has no host", hostPort)
+	}
+	return nil
+}
+
+// newBCEConfig returns the SDK config of service with its resolved endpoint, proxy, scheme and timeout
+func newBCEConfig(config *CloudConfig, service string) *bce.Config {
+	serviceConfig := config.Services[service]
+	timeout := time.Duration(serviceConfig.Timeout) * time.Second
+	if timeout == 0 {
+		timeout = defaultCloudAPITimeout * time.Second
+	}
+	return &bce.Config{
+		Credentials: bce.NewCredentials(config.AccessKeyID, config.SecretAccessKey),
+		Checksum:    true,
+		Timeout:     timeout,
+		Region:      config.Region,
+		Endpoint:    serviceConfig.Endpoint,
+		Protocol:    serviceConfig.Scheme,
+		ProxyHost:   serviceConfig.ProxyHost,
+		ProxyPort:   serviceConfig.ProxyPort,
+	}
+}
@@ -0,0 +1,80 @@
+package cloud_provider
+
+import (
+	"context"
+	"testing"
+)
+
+func newTestServicesConfig(services map[string]ServiceConfig, gateway GatewayConfig) *CloudConfig {
+	return &CloudConfig{
+		Region:   "sandbox",
+		Endpoint: "cce.sandbox.local:8693",
+		CloudAPI: CloudAPIConfig{Timeout: defaultCloudAPITimeout},
+		Services: services,
+		Gateway:  gateway,
+	}
+}
+
+func allServices(service ServiceConfig) map[string]ServiceConfig {
+	services := map[string]ServiceConfig{}
+	for _, name := range []string{"BLB", "EIP", "VPC", "BCC"} {
+		services[name] = service
+	}
+	return services
+}
+
+func TestCompleteServicesConfig(t *testing.T) {
+	// case1: defaults, CCE endpoint is Endpoint, services except BLB are proxied by gateway
+	config := newTestServicesConfig(allServices(ServiceConfig{Endpoint: "bce.sandbox.local"}), GatewayConfig{Host: "gateway.sandbox.local", Port: 8080})
+	if err := completeServicesConfig(config); err != nil {
+		t.Fatalf("completeServicesConfig err: %v", err)
+	}
+	if cceConfig := config.Services["CCE"]; cceConfig.Endpoint != "cce.sandbox.local:8693" || cceConfig.Scheme != schemeHTTP ||
+		cceConfig.Timeout != defaultCloudAPITimeout || cceConfig.ProxyHost != "gateway.sandbox.local" || cceConfig.ProxyPort != 8080 {
+		t.Errorf("completeServicesConfig err, want CCE defaults, get %+v", cceConfig)
+	}
+	if blbConfig := config.Services["BLB"]; blbConfig.ProxyHost != "" {
+		t.Errorf("completeServicesConfig err, want BLB not proxied, get %+v", blbConfig)
+	}
+
+	// case2: overrides
+	services := allServices(ServiceConfig{Endpoint: "bce.sandbox.local"})
+	services["EIP"] = ServiceConfig{Endpoint: "eip.sandbox.local:8443", Scheme: schemeHTTPS, Timeout: 5, ProxyHost: "proxy.local", ProxyPort: 3128}
+	config = newTestServicesConfig(services, GatewayConfig{Host: "gateway.sandbox.local"})
+	if err := completeServicesConfig(config); err != nil {
+		t.Fatalf("completeServicesConfig err: %v", err)
+	}
+	if eipConfig := config.Services["EIP"]; eipConfig != services["EIP"] {
+		t.Errorf("completeServicesConfig err, want %+v, get %+v", services["EIP"], eipConfig)
+	}
+
+	// case3: gateway disabled
+	config = newTestServicesConfig(services, GatewayConfig{Host: "gateway.sandbox.local", Disabled: true})
+	if err := completeServicesConfig(config); err != nil {
+		t.Fatalf("completeServicesConfig err: %v", err)
+	}
+	if config.Services["CCE"].ProxyHost != "" || config.Services["EIP"].ProxyHost != "proxy.local" || config.Gateway.Host != "" {
+		t.Errorf("completeServicesConfig err, want only explicit proxy, get %+v", config.Services)
+	}
+	cloud := &Baiducloud{CloudConfig: *config, credentials: newFileCredentialsProvider("", "", "ak", "sk")}
+	if option := cloud.getSignOption(context.Background()); option != nil {
+		t.Errorf("getSignOption err, want nil when gateway is disabled")
+	}
+
+	// case4: invalid configs
+	for i, config := range []*CloudConfig{
+		newTestServicesConfig(nil, GatewayConfig{}),
+		newTestServicesConfig(map[string]ServiceConfig{"SLB": {}}, GatewayConfig{}),
+		newTestServicesConfig(map[string]ServiceConfig{"BLB": {Endpoint: "http://blb.sandbox.local"}}, GatewayConfig{}),
+		newTestServicesConfig(map[string]ServiceConfig{"BLB": {Endpoint: "blb.sandbox.local:port"}}, GatewayConfig{}),
+		newTestServicesConfig(map[string]ServiceConfig{"BLB": {Scheme: "ftp"}}, GatewayConfig{}),
+		newTestServicesConfig(map[string]ServiceConfig{"BLB": {ProxyPort: 3128}}, GatewayConfig{}),
+		newTestServicesConfig(map[string]ServiceConfig{"BLB": {Timeout: maxCloudAPITimeout + 1}}, GatewayConfig{}),
+		newTestServicesConfig(allServices(ServiceConfig{Endpoint: "bce.sandbox.local"}), GatewayConfig{Host: "gateway:8080"}),
+		{Region: "sandbox", Services: allServices(ServiceConfig{Endpoint: "bce.sandbox.local"})},
+	} {
+		if err := completeServicesConfig(config); err == nil {
+			t.Errorf("case4-%d: completeServicesConfig err, want error for %+v", i+1, config.Services)
+		}
+	}
+}
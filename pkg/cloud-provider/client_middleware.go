@@ -0,0 +1,365 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloud_provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/flowcontrol"
+	"k8s.io/klog"
+
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/bce"
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/vpc"
+	bcc "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/temp-bcc"
+	blbext "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/temp-blb"
+	cce "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/temp-cce"
+	eipext "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/temp-eip"
+)
+
+const (
+	// defaultCloudAPITimeout is the default seconds of a BCE API request
+	defaultCloudAPITimeout = 30
+	maxCloudAPITimeout     = 300
+	// defaultCloudAPIQPS and defaultCloudAPIBurst are the default rate limit of each BCE API
+	defaultCloudAPIQPS   = 10
+	defaultCloudAPIBurst = 20
+	// defaultCloudAPIMaxRetries is the default max retries of a failed call, maxCloudAPIMaxRetries keeps a call from
+	// holding a worker too long
+	defaultCloudAPIMaxRetries = 3
+	maxCloudAPIMaxRetries     = 10
+	// defaultCloudAPIRetryBaseDelay and defaultCloudAPIRetryMaxDelay are the default milliseconds between retries
+	defaultCloudAPIRetryBaseDelay = 500
+	defaultCloudAPIRetryMaxDelay  = 10000
+)
+
+// cloudAPIClients are the interfaces of clients in ClientSet by client name, which API names are made of
+var cloudAPIClients = map[string]reflect.Type{
+	"BLB": reflect.TypeOf((*blbext.Interface)(nil)).Elem(),
+	"EIP": reflect.TypeOf((*eipext.Interface)(nil)).Elem(),
+	"CCE": reflect.TypeOf((*cce.Interface)(nil)).Elem(),
+	"VPC": reflect.TypeOf((*vpc.Interface)(nil)).Elem(),
+	"BCC": reflect.TypeOf((*bcc.Interface)(nil)).Elem(),
+}
+
+// throttlingErrorCodes are the BCE error codes of calls rejected by rate limiting, which are safe to retry
+var throttlingErrorCodes = map[string]bool{
+	"RequestLimitExceeded": true,
+	"TooManyRequests":      true,
+	"Throttling":           true,
+	"ThrottlingException":  true,
+}
+
+// serverErrorCodes are the BCE error codes of transient server errors
+var serverErrorCodes = map[string]bool{
+	"InternalError":      true,
+	"InternalException":  true,
+	"ServiceUnavailable": true,
+}
+
+// completeCloudAPIConfig validates config and sets defaults of unset fields
+func completeCloudAPIConfig(config *CloudAPIConfig) error {
+	if config.Timeout < 0 || config.Timeout > maxCloudAPITimeout {
+		return fmt.Errorf("Cloud config CloudAPI.Timeout must be in [0, %d]\n ", maxCloudAPITimeout)
+	}
+	if config.QPS < 0 || config.Burst < 0 {
+		return fmt.Errorf("Cloud config CloudAPI.QPS and CloudAPI.Burst must not be negative\n ")
+	}
+	for name, limit := range config.RateLimits {
+		if !isCloudAPIName(name) {
+			return fmt.Errorf("Cloud config CloudAPI.RateLimits has unknown API %s, API must be like BLB or BLB.DescribeLoadBalancers\n ", name)
+		}
+		if limit.QPS <= 0 || limit.Burst <= 0 {
+			return fmt.Errorf("Cloud config CloudAPI.RateLimits of %s must have positive QPS and Burst\n ", name)
+		}
+	}
+	if config.MaxRetries > maxCloudAPIMaxRetries {
+		return fmt.Errorf("Cloud config CloudAPI.MaxRetries must not be greater than %d\n ", maxCloudAPIMaxRetries)
+	}
+	if config.RetryBaseDelay < 0 || config.RetryMaxDelay < 0 {
+		return fmt.Errorf("Cloud config CloudAPI.RetryBaseDelay and CloudAPI.RetryMaxDelay must not be negative\n ")
+	}
+
+	if config.Timeout == 0 {
+		config.Timeout = defaultCloudAPITimeout
+	}
+	if config.QPS == 0 {
+		config.QPS = defaultCloudAPIQPS
+	}
+	if config.Burst == 0 {
+		config.Burst = defaultCloudAPIBurst
+	}
+	if config.MaxRetries == 0 {
+		config.MaxRetries = defaultCloudAPIMaxRetries
+	}
+	if config.MaxRetries < 0 {
+		config.MaxRetries = 0
+	}
+	if config.RetryBaseDelay == 0 {
+		config.RetryBaseDelay = defaultCloudAPIRetryBaseDelay
+	}
+	if config.RetryMaxDelay == 0 {
+		config.RetryMaxDelay = defaultCloudAPIRetryMaxDelay
+	}
+	if config.RetryMaxDelay < config.RetryBaseDelay {
+		return fmt.Errorf("Cloud config CloudAPI.RetryMaxDelay must not be less than CloudAPI.RetryBaseDelay\n ")
+	}
+	return nil
+}
+
+// isCloudAPIName checks whether name is a client name like "BLB", or an API name like "BLB.DescribeLoadBalancers"
+func isCloudAPIName(name string) bool {
+	parts := strings.SplitN(name, ".", 2)
+	client, ok := cloudAPIClients[parts[0]]
+	if !ok {
+		return false
+	}
+	if len(parts) == 1 {
+		return true
+	}
+	_, ok = client.MethodByName(parts[1])
+	return ok
+}
+
+// isReadAPI checks whether the API only reads resources, so that it is safe to retry on any transient error
+func isReadAPI(method string) bool {
+	return strings.HasPrefix(method, "Describe") || strings.HasPrefix(method, "List") || strings.HasPrefix(method, "Get")
+}
+
+// isRetryableError checks whether a failed call can be retried. Throttled calls are not done, so they are retried
+// for all APIs. Server errors and network errors may happen after the call is done, and mutating APIs generate a new
+// client token for every call, so they are only retried for read APIs.
+func isRetryableError(err error, read bool) bool {
+	if bceErr, ok := err.(*bce.Error); ok {
+		if bceErr.StatusCode == http.StatusTooManyRequests || throttlingErrorCodes[bceErr.Code] {
+			return true
+		}
+		if !read {
+			return false
+		}
+		return serverErrorCodes[bceErr.Code] ||
+			(bceErr.StatusCode >= http.StatusInternalServerError && bceErr.StatusCode != http.StatusNotImplemented)
+	}
+	if !read {
+		return false
+	}
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return true
+	}
+	// e.g. timeout, connection refused or reset
+	_, ok := err.(net.Error)
+	return ok
+}
+
+// cloudAPIResultCode returns the result code of a request for metrics, which is the BCE error code if there is one
+func cloudAPIResultCode(err error) string {
+	if err == nil {
+		return "Success"
+	}
+	if bceErr, ok := err.(*bce.Error); ok {
+		if bceErr.Code != "" {
+			return bceErr.Code
+		}
+		return strconv.Itoa(bceErr.StatusCode)
+	}
+	if errors.Is(err, context.Canceled) {
+		return "Canceled"
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return "DeadlineExceeded"
+	}
+	if netErr, ok := err.(net.Error); ok {
+		if netErr.Timeout() {
+			return "Timeout"
+		}
+		return "NetworkError"
+	}
+	return "ClientError"
+}
+
+// withRequestIDHeader returns a copy of option which sends request ID of ctx to BCE in RequestIDHeader
+func withRequestIDHeader(ctx context.Context, option *bce.SignOption) *bce.SignOption {
+	requestID := GetRequestID(ctx)
+	if requestID == "" {
+		return option
+	}
+	// nil option is signed the same as an empty one
+	withHeader := bce.SignOption{}
+	if option != nil {
+		withHeader = *option
+	}
+	headers := make(map[string]string, len(withHeader.Headers)+1)
+	for k, v := range withHeader.Headers {
+		headers[k] = v
+	}
+	headers[RequestIDHeader] = requestID
+	withHeader.Headers = headers
+	return &withHeader
+}
+
+// clientMiddleware rate limits and retries the calls of clients in ClientSet. Each API has its own token bucket,
+// and retryable failed calls are retried with jittered exponential backoff. Calls are aborted when their context is
+// done or when the middleware is stopped, e.g. on shutdown or leader loss. The wrapped clients can be swapped by
+// setClients while calls are in flight, e.g. when credentials are rotated.
+type clientMiddleware struct {
+	config *CloudAPIConfig
+	// base holds the *ClientSet calls are made with
+	base atomic.Value
+
+	// stopCtx is cancelled when the middleware is stopped
+	stopCtx context.Context
+	stop    context.CancelFunc
+
+	lock     sync.Mutex
+	limiters map[string]flowcontrol.RateLimiter
+}
+
+// newClientMiddleware creates middleware with completed config
+func newClientMiddleware(config *CloudAPIConfig) *clientMiddleware {
+	stopCtx, stop := context.WithCancel(context.Background())
+	return &clientMiddleware{
+		config:   config,
+		stopCtx:  stopCtx,
+		stop:     stop,
+		limiters: make(map[string]flowcontrol.RateLimiter),
+	}
+}
+
+// stopOn aborts in-flight and later calls when stopCh is closed
+func (m *clientMiddleware) stopOn(stopCh <-chan struct{}) {
+	go func() {
+		select {
+		case <-stopCh:
+			klog.Infof("cloud API calls are stopped")
+			m.stop()
+		case <-m.stopCtx.Done():
+		}
+	}()
+}
+
+// limiter returns the token bucket of API, configured by API name, then by client name, then by default
+func (m *clientMiddleware) limiter(client, method string) flowcontrol.RateLimiter {
+	api := client + "." + method
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if limiter, ok := m.limiters[api]; ok {
+		return limiter
+	}
+	qps, burst := m.config.QPS, m.config.Burst
+	if limit, ok := m.config.RateLimits[client]; ok {
+		qps, burst = limit.QPS, limit.Burst
+	}
+	if limit, ok := m.config.RateLimits[api]; ok {
+		qps, burst = limit.QPS, limit.Burst
+	}
+	limiter := flowcontrol.NewTokenBucketRateLimiter(qps, burst)
+	m.limiters[api] = limiter
+	return limiter
+}
+
+func (m *clientMiddleware) backoff() wait.Backoff {
+	return wait.Backoff{
+		Duration: time.Duration(m.config.RetryBaseDelay) * time.Millisecond,
+		Factor:   2,
+		Jitter:   0.5,
+		Steps:    m.config.MaxRetries,
+		Cap:      time.Duration(m.config.RetryMaxDelay) * time.Millisecond,
+	}
+}
+
+// call calls fn of API client.method with rate limiting and retrying, ctx passed to fn has a request ID and is done
+// when the middleware stops
+func (m *clientMiddleware) call(ctx context.Context, client, method string, fn func(ctx context.Context) error) error {
+	api := client + "." + method
+	// calls without a request ID started by controllers get their own, so that they can still be traced
+	ctx = WithRequestID(ctx)
+	if m.stopCtx.Err() != nil {
+		return fmt.Errorf("%s aborted: cloud API calls are stopped", api)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-m.stopCtx.Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	limiter := m.limiter(client, method)
+	backoff := m.backoff()
+	read := isReadAPI(method)
+	for retries := 0; ; retries++ {
+		if err := limiter.Wait(ctx); err != nil {
+			return fmt.Errorf("%s aborted while rate limited: %v", api, err)
+		}
+		startTime := time.Now()
+		err := fn(ctx)
+		cloudAPIRequestDuration.WithLabelValues(client, method).Observe(time.Since(startTime).Seconds())
+		cloudAPIRequests.WithLabelValues(client, method, cloudAPIResultCode(err)).Inc()
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil || retries >= m.config.MaxRetries || !isRetryableError(err, read) {
+			return err
+		}
+
+		delay := backoff.Step()
+		klog.Warningf(Message(ctx, fmt.Sprintf("%s failed, retry %d/%d after %v: %v", api, retries+1, m.config.MaxRetries, delay, err)))
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%s aborted while waiting to retry: %v, last error: %v", api, ctx.Err(), err)
+		case <-timer.C:
+		}
+		cloudAPIRetries.WithLabelValues(client, method).Inc()
+	}
+}
+
+// wrap returns ClientSet whose clients call through the middleware
+func (m *clientMiddleware) wrap(clientSet *ClientSet) *ClientSet {
+	m.setClients(clientSet)
+	return &ClientSet{
+		BLBClient: &blbClient{m: m},
+		EIPClient: &eipClient{m: m},
+		CCEClient: &cceClient{m: m},
+		VPCClient: &vpcClient{m: m},
+		BCCClient: &bccClient{m: m},
+	}
+}
+
+// setClients swaps the clients later calls are made with, calls in flight keep the clients they started with
+func (m *clientMiddleware) setClients(clientSet *ClientSet) {
+	m.base.Store(clientSet)
+}
+
+// clients returns the clients calls are made with
+func (m *clientMiddleware) clients() *ClientSet {
+	return m.base.Load().(*ClientSet)
+}
@@ -0,0 +1,457 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloud_provider
+
+import (
+	"context"
+
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/bce"
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/blb"
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/eip"
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/vpc"
+	bcc "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/temp-bcc"
+	blbext "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/temp-blb"
+	cce "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/temp-cce"
+	eipext "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/temp-eip"
+)
+
+// blbClient calls BLB APIs through clientMiddleware
+type blbClient struct {
+	m *clientMiddleware
+}
+
+func (c *blbClient) DescribeLoadBalancers(ctx context.Context, args *blb.DescribeLoadBalancersArgs, option *bce.SignOption) ([]blb.LoadBalancer, error) {
+	var result []blb.LoadBalancer
+	err := c.m.call(ctx, "BLB", "DescribeLoadBalancers", func(ctx context.Context) (err error) {
+		result, err = c.m.clients().BLBClient.DescribeLoadBalancers(ctx, args, withRequestIDHeader(ctx, option))
+		return err
+	})
+	return result, err
+}
+
+func (c *blbClient) DescribeLoadBalancersPage(ctx context.Context, args *blbext.DescribeLoadBalancersArgs, option *bce.SignOption) (*blbext.DescribeLoadBalancersResponse, error) {
+	var result *blbext.DescribeLoadBalancersResponse
+	err := c.m.call(ctx, "BLB", "DescribeLoadBalancersPage", func(ctx context.Context) (err error) {
+		result, err = c.m.clients().BLBClient.DescribeLoadBalancersPage(ctx, args, withRequestIDHeader(ctx, option))
+		return err
+	})
+	return result, err
+}
+
+func (c *blbClient) CreateLoadBalancer(ctx context.Context, args *blb.CreateLoadBalancerArgs, option *bce.SignOption) (*blb.CreateLoadBalancerResponse, error) {
+	var result *blb.CreateLoadBalancerResponse
+	err := c.m.call(ctx, "BLB", "CreateLoadBalancer", func(ctx context.Context) (err error) {
+		result, err = c.m.clients().BLBClient.CreateLoadBalancer(ctx, args, withRequestIDHeader(ctx, option))
+		return err
+	})
+	return result, err
+}
+
+func (c *blbClient) UpdateLoadBalancer(ctx context.Context, args *blb.UpdateLoadBalancerArgs, option *bce.SignOption) error {
+	return c.m.call(ctx, "BLB", "UpdateLoadBalancer", func(ctx context.Context) error {
+		return c.m.clients().BLBClient.UpdateLoadBalancer(ctx, args, withRequestIDHeader(ctx, option))
+	})
+}
+
+func (c *blbClient) DeleteLoadBalancer(ctx context.Context, args *blb.DeleteLoadBalancerArgs, option *bce.SignOption) error {
+	return c.m.call(ctx, "BLB", "DeleteLoadBalancer", func(ctx context.Context) error {
+		return c.m.clients().BLBClient.DeleteLoadBalancer(ctx, args, withRequestIDHeader(ctx, option))
+	})
+}
+
+func (c *blbClient) CreateTCPListener(ctx context.Context, args *blb.CreateTCPListenerArgs, option *bce.SignOption) error {
+	return c.m.call(ctx, "BLB", "CreateTCPListener", func(ctx context.Context) error {
+		return c.m.clients().BLBClient.CreateTCPListener(ctx, args, withRequestIDHeader(ctx, option))
+	})
+}
+
+func (c *blbClient) CreateUDPListener(ctx context.Context, args *blb.CreateUDPListenerArgs, option *bce.SignOption) error {
+	return c.m.call(ctx, "BLB", "CreateUDPListener", func(ctx context.Context) error {
+		return c.m.clients().BLBClient.CreateUDPListener(ctx, args, withRequestIDHeader(ctx, option))
+	})
+}
+
+func (c *blbClient) CreateHTTPListener(ctx context.Context, args *blb.CreateHTTPListenerArgs, option *bce.SignOption) error {
+	return c.m.call(ctx, "BLB", "CreateHTTPListener", func(ctx context.Context) error {
+		return c.m.clients().BLBClient.CreateHTTPListener(ctx, args, withRequestIDHeader(ctx, option))
+	})
+}
+
+func (c *blbClient) DescribeTCPListener(ctx context.Context, args *blb.DescribeTCPListenerArgs, option *bce.SignOption) ([]blb.TCPListener, error) {
+	var result []blb.TCPListener
+	err := c.m.call(ctx, "BLB", "DescribeTCPListener", func(ctx context.Context) (err error) {
+		result, err = c.m.clients().BLBClient.DescribeTCPListener(ctx, args, withRequestIDHeader(ctx, option))
+		return err
+	})
+	return result, err
+}
+
+func (c *blbClient) DescribeUDPListener(ctx context.Context, args *blb.DescribeUDPListenerArgs, option *bce.SignOption) ([]blb.UDPListener, error) {
+	var result []blb.UDPListener
+	err := c.m.call(ctx, "BLB", "DescribeUDPListener", func(ctx context.Context) (err error) {
+		result, err = c.m.clients().BLBClient.DescribeUDPListener(ctx, args, withRequestIDHeader(ctx, option))
+		return err
+	})
+	return result, err
+}
+
+func (c *blbClient) UpdateTCPListener(ctx context.Context, args *blb.UpdateTCPListenerArgs, option *bce.SignOption) error {
+	return c.m.call(ctx, "BLB", "UpdateTCPListener", func(ctx context.Context) error {
+		return c.m.clients().BLBClient.UpdateTCPListener(ctx, args, withRequestIDHeader(ctx, option))
+	})
+}
+
+func (c *blbClient) UpdateUDPListener(ctx context.Context, args *blb.UpdateUDPListenerArgs, option *bce.SignOption) error {
+	return c.m.call(ctx, "BLB", "UpdateUDPListener", func(ctx context.Context) error {
+		return c.m.clients().BLBClient.UpdateUDPListener(ctx, args, withRequestIDHeader(ctx, option))
+	})
+}
+
+func (c *blbClient) DeleteListeners(ctx context.Context, args *blb.DeleteListenersArgs, option *bce.SignOption) error {
+	return c.m.call(ctx, "BLB", "DeleteListeners", func(ctx context.Context) error {
+		return c.m.clients().BLBClient.DeleteListeners(ctx, args, withRequestIDHeader(ctx, option))
+	})
+}
+
+func (c *blbClient) DeleteListenersByType(ctx context.Context, args *blbext.DeleteListenersByTypeArgs, option *bce.SignOption) error {
+	return c.m.call(ctx, "BLB", "DeleteListenersByType", func(ctx context.Context) error {
+		return c.m.clients().BLBClient.DeleteListenersByType(ctx, args, withRequestIDHeader(ctx, option))
+	})
+}
+
+func (c *blbClient) DescribeListenerHealthChecks(ctx context.Context, args *blbext.DescribeListenerHealthChecksArgs, option *bce.SignOption) ([]blbext.ListenerHealthCheck, error) {
+	var result []blbext.ListenerHealthCheck
+	err := c.m.call(ctx, "BLB", "DescribeListenerHealthChecks", func(ctx context.Context) (err error) {
+		result, err = c.m.clients().BLBClient.DescribeListenerHealthChecks(ctx, args, withRequestIDHeader(ctx, option))
+		return err
+	})
+	return result, err
+}
+
+func (c *blbClient) UpdateListenerHealthCheck(ctx context.Context, args *blbext.UpdateListenerHealthCheckArgs, option *bce.SignOption) error {
+	return c.m.call(ctx, "BLB", "UpdateListenerHealthCheck", func(ctx context.Context) error {
+		return c.m.clients().BLBClient.UpdateListenerHealthCheck(ctx, args, withRequestIDHeader(ctx, option))
+	})
+}
+
+func (c *blbClient) DescribeHTTPListener(ctx context.Context, args *blbext.DescribeHTTPListenerArgs, option *bce.SignOption) ([]blb.HTTPListener, error) {
+	var result []blb.HTTPListener
+	err := c.m.call(ctx, "BLB", "DescribeHTTPListener", func(ctx context.Context) (err error) {
+		result, err = c.m.clients().BLBClient.DescribeHTTPListener(ctx, args, withRequestIDHeader(ctx, option))
+		return err
+	})
+	return result, err
+}
+
+func (c *blbClient) UpdateHTTPListener(ctx context.Context, args *blbext.UpdateHTTPListenerArgs, option *bce.SignOption) error {
+	return c.m.call(ctx, "BLB", "UpdateHTTPListener", func(ctx context.Context) error {
+		return c.m.clients().BLBClient.UpdateHTTPListener(ctx, args, withRequestIDHeader(ctx, option))
+	})
+}
+
+func (c *blbClient) CreateHTTPSListener(ctx context.Context, args *blbext.CreateHTTPSListenerArgs, option *bce.SignOption) error {
+	return c.m.call(ctx, "BLB", "CreateHTTPSListener", func(ctx context.Context) error {
+		return c.m.clients().BLBClient.CreateHTTPSListener(ctx, args, withRequestIDHeader(ctx, option))
+	})
+}
+
+func (c *blbClient) DescribeHTTPSListener(ctx context.Context, args *blbext.DescribeHTTPSListenerArgs, option *bce.SignOption) ([]blbext.HTTPSListener, error) {
+	var result []blbext.HTTPSListener
+	err := c.m.call(ctx, "BLB", "DescribeHTTPSListener", func(ctx context.Context) (err error) {
+		result, err = c.m.clients().BLBClient.DescribeHTTPSListener(ctx, args, withRequestIDHeader(ctx, option))
+		return err
+	})
+	return result, err
+}
+
+func (c *blbClient) UpdateHTTPSListener(ctx context.Context, args *blbext.UpdateHTTPSListenerArgs, option *bce.SignOption) error {
+	return c.m.call(ctx, "BLB", "UpdateHTTPSListener", func(ctx context.Context) error {
+		return c.m.clients().BLBClient.UpdateHTTPSListener(ctx, args, withRequestIDHeader(ctx, option))
+	})
+}
+
+func (c *blbClient) AddBackendServers(ctx context.Context, args *blb.AddBackendServersArgs, option *bce.SignOption) error {
+	return c.m.call(ctx, "BLB", "AddBackendServers", func(ctx context.Context) error {
+		return c.m.clients().BLBClient.AddBackendServers(ctx, args, withRequestIDHeader(ctx, option))
+	})
+}
+
+func (c *blbClient) DescribeBackendServers(ctx context.Context, args *blb.DescribeBackendServersArgs, option *bce.SignOption) ([]blb.BackendServer, error) {
+	var result []blb.BackendServer
+	err := c.m.call(ctx, "BLB", "DescribeBackendServers", func(ctx context.Context) (err error) {
+		result, err = c.m.clients().BLBClient.DescribeBackendServers(ctx, args, withRequestIDHeader(ctx, option))
+		return err
+	})
+	return result, err
+}
+
+func (c *blbClient) UpdateBackendServers(ctx context.Context, args *blb.UpdateBackendServersArgs, option *bce.SignOption) error {
+	return c.m.call(ctx, "BLB", "UpdateBackendServers", func(ctx context.Context) error {
+		return c.m.clients().BLBClient.UpdateBackendServers(ctx, args, withRequestIDHeader(ctx, option))
+	})
+}
+
+func (c *blbClient) RemoveBackendServers(ctx context.Context, args *blb.RemoveBackendServersArgs, option *bce.SignOption) error {
+	return c.m.call(ctx, "BLB", "RemoveBackendServers", func(ctx context.Context) error {
+		return c.m.clients().BLBClient.RemoveBackendServers(ctx, args, withRequestIDHeader(ctx, option))
+	})
+}
+
+func (c *blbClient) AddBackendIPs(ctx context.Context, args *blbext.AddBackendIPsArgs, option *bce.SignOption) error {
+	return c.m.call(ctx, "BLB", "AddBackendIPs", func(ctx context.Context) error {
+		return c.m.clients().BLBClient.AddBackendIPs(ctx, args, withRequestIDHeader(ctx, option))
+	})
+}
+
+func (c *blbClient) DescribeBackendIPs(ctx context.Context, args *blbext.DescribeBackendIPsArgs, option *bce.SignOption) ([]blbext.BackendIP, error) {
+	var result []blbext.BackendIP
+	err := c.m.call(ctx, "BLB", "DescribeBackendIPs", func(ctx context.Context) (err error) {
+		result, err = c.m.clients().BLBClient.DescribeBackendIPs(ctx, args, withRequestIDHeader(ctx, option))
+		return err
+	})
+	return result, err
+}
+
+func (c *blbClient) RemoveBackendIPs(ctx context.Context, args *blbext.RemoveBackendIPsArgs, option *bce.SignOption) error {
+	return c.m.call(ctx, "BLB", "RemoveBackendIPs", func(ctx context.Context) error {
+		return c.m.clients().BLBClient.RemoveBackendIPs(ctx, args, withRequestIDHeader(ctx, option))
+	})
+}
+
+func (c *blbClient) BindSecurityGroups(ctx context.Context, args *blbext.UpdateSecurityGroupsArgs, option *bce.SignOption) error {
+	return c.m.call(ctx, "BLB", "BindSecurityGroups", func(ctx context.Context) error {
+		return c.m.clients().BLBClient.BindSecurityGroups(ctx, args, withRequestIDHeader(ctx, option))
+	})
+}
+
+func (c *blbClient) UnbindSecurityGroups(ctx context.Context, args *blbext.UpdateSecurityGroupsArgs, option *bce.SignOption) error {
+	return c.m.call(ctx, "BLB", "UnbindSecurityGroups", func(ctx context.Context) error {
+		return c.m.clients().BLBClient.UnbindSecurityGroups(ctx, args, withRequestIDHeader(ctx, option))
+	})
+}
+
+func (c *blbClient) DescribeSecurityGroups(ctx context.Context, blbID string, option *bce.SignOption) ([]blbext.BlbSecurityGroup, error) {
+	var result []blbext.BlbSecurityGroup
+	err := c.m.call(ctx, "BLB", "DescribeSecurityGroups", func(ctx context.Context) (err error) {
+		result, err = c.m.clients().BLBClient.DescribeSecurityGroups(ctx, blbID, withRequestIDHeader(ctx, option))
+		return err
+	})
+	return result, err
+}
+
+// eipClient calls EIP APIs through clientMiddleware
+type eipClient struct {
+	m *clientMiddleware
+}
+
+func (c *eipClient) CreateEIP(ctx context.Context, args *eip.CreateEIPArgs, option *bce.SignOption) (string, error) {
+	var result string
+	err := c.m.call(ctx, "EIP", "CreateEIP", func(ctx context.Context) (err error) {
+		result, err = c.m.clients().EIPClient.CreateEIP(ctx, args, withRequestIDHeader(ctx, option))
+		return err
+	})
+	return result, err
+}
+
+func (c *eipClient) BindEIP(ctx context.Context, ip string, args *eip.BindEIPArgs, option *bce.SignOption) error {
+	return c.m.call(ctx, "EIP", "BindEIP", func(ctx context.Context) error {
+		return c.m.clients().EIPClient.BindEIP(ctx, ip, args, withRequestIDHeader(ctx, option))
+	})
+}
+
+func (c *eipClient) UnbindEIP(ctx context.Context, ip string, option *bce.SignOption) error {
+	return c.m.call(ctx, "EIP", "UnbindEIP", func(ctx context.Context) error {
+		return c.m.clients().EIPClient.UnbindEIP(ctx, ip, withRequestIDHeader(ctx, option))
+	})
+}
+
+func (c *eipClient) DeleteEIP(ctx context.Context, ip string, option *bce.SignOption) error {
+	return c.m.call(ctx, "EIP", "DeleteEIP", func(ctx context.Context) error {
+		return c.m.clients().EIPClient.DeleteEIP(ctx, ip, withRequestIDHeader(ctx, option))
+	})
+}
+
+func (c *eipClient) ResizeEIP(ctx context.Context, ip string, args *eip.ResizeEIPArgs, option *bce.SignOption) error {
+	return c.m.call(ctx, "EIP", "ResizeEIP", func(ctx context.Context) error {
+		return c.m.clients().EIPClient.ResizeEIP(ctx, ip, args, withRequestIDHeader(ctx, option))
+	})
+}
+
+func (c *eipClient) GetEIPs(ctx context.Context, args *eip.GetEIPsArgs, option *bce.SignOption) ([]*eip.EIP, error) {
+	var result []*eip.EIP
+	err := c.m.call(ctx, "EIP", "GetEIPs", func(ctx context.Context) (err error) {
+		result, err = c.m.clients().EIPClient.GetEIPs(ctx, args, withRequestIDHeader(ctx, option))
+		return err
+	})
+	return result, err
+}
+
+func (c *eipClient) ListEIPs(ctx context.Context, args *eipext.ListEIPsArgs, option *bce.SignOption) (*eipext.ListEIPsResponse, error) {
+	var result *eipext.ListEIPsResponse
+	err := c.m.call(ctx, "EIP", "ListEIPs", func(ctx context.Context) (err error) {
+		result, err = c.m.clients().EIPClient.ListEIPs(ctx, args, withRequestIDHeader(ctx, option))
+		return err
+	})
+	return result, err
+}
+
+// cceClient calls CCE APIs through clientMiddleware
+type cceClient struct {
+	m *clientMiddleware
+}
+
+func (c *cceClient) CreateCluster(ctx context.Context, args *cce.CreateClusterArgs) (*cce.CreateClusterResponse, error) {
+	var result *cce.CreateClusterResponse
+	err := c.m.call(ctx, "CCE", "CreateCluster", func(ctx context.Context) (err error) {
+		result, err = c.m.clients().CCEClient.CreateCluster(ctx, args)
+		return err
+	})
+	return result, err
+}
+
+func (c *cceClient) ListClusterNodes(ctx context.Context, clusterID string, option *bce.SignOption) (*cce.ListClusterNodesResponse, error) {
+	var result *cce.ListClusterNodesResponse
+	err := c.m.call(ctx, "CCE", "ListClusterNodes", func(ctx context.Context) (err error) {
+		result, err = c.m.clients().CCEClient.ListClusterNodes(ctx, clusterID, withRequestIDHeader(ctx, option))
+		return err
+	})
+	return result, err
+}
+
+func (c *cceClient) ListClusterNodesPage(ctx context.Context, args *cce.ListClusterNodesArgs, option *bce.SignOption) (*cce.ListClusterNodesResponse, error) {
+	var result *cce.ListClusterNodesResponse
+	err := c.m.call(ctx, "CCE", "ListClusterNodesPage", func(ctx context.Context) (err error) {
+		result, err = c.m.clients().CCEClient.ListClusterNodesPage(ctx, args, withRequestIDHeader(ctx, option))
+		return err
+	})
+	return result, err
+}
+
+// vpcClient calls VPC APIs through clientMiddleware
+type vpcClient struct {
+	m *clientMiddleware
+}
+
+func (c *vpcClient) CreateVPC(ctx context.Context, args *vpc.CreateVPCArgs, option *bce.SignOption) (string, error) {
+	var result string
+	err := c.m.call(ctx, "VPC", "CreateVPC", func(ctx context.Context) (err error) {
+		result, err = c.m.clients().VPCClient.CreateVPC(ctx, args, withRequestIDHeader(ctx, option))
+		return err
+	})
+	return result, err
+}
+
+func (c *vpcClient) ListVPC(ctx context.Context, args *vpc.ListVPCArgs, option *bce.SignOption) ([]*vpc.VPC, error) {
+	var result []*vpc.VPC
+	err := c.m.call(ctx, "VPC", "ListVPC", func(ctx context.Context) (err error) {
+		result, err = c.m.clients().VPCClient.ListVPC(ctx, args, withRequestIDHeader(ctx, option))
+		return err
+	})
+	return result, err
+}
+
+func (c *vpcClient) CreateSubnet(ctx context.Context, args *vpc.CreateSubnetArgs, option *bce.SignOption) (string, error) {
+	var result string
+	err := c.m.call(ctx, "VPC", "CreateSubnet", func(ctx context.Context) (err error) {
+		result, err = c.m.clients().VPCClient.CreateSubnet(ctx, args, withRequestIDHeader(ctx, option))
+		return err
+	})
+	return result, err
+}
+
+func (c *vpcClient) ListSubnet(ctx context.Context, args *vpc.ListSubnetArgs, option *bce.SignOption) ([]*vpc.Subnet, error) {
+	var result []*vpc.Subnet
+	err := c.m.call(ctx, "VPC", "ListSubnet", func(ctx context.Context) (err error) {
+		result, err = c.m.clients().VPCClient.ListSubnet(ctx, args, withRequestIDHeader(ctx, option))
+		return err
+	})
+	return result, err
+}
+
+func (c *vpcClient) DescribeSubnet(ctx context.Context, subnetID string, option *bce.SignOption) (*vpc.Subnet, error) {
+	var result *vpc.Subnet
+	err := c.m.call(ctx, "VPC", "DescribeSubnet", func(ctx context.Context) (err error) {
+		result, err = c.m.clients().VPCClient.DescribeSubnet(ctx, subnetID, withRequestIDHeader(ctx, option))
+		return err
+	})
+	return result, err
+}
+
+func (c *vpcClient) ListRouteTable(ctx context.Context, args *vpc.ListRouteArgs, option *bce.SignOption) ([]vpc.RouteRule, error) {
+	var result []vpc.RouteRule
+	err := c.m.call(ctx, "VPC", "ListRouteTable", func(ctx context.Context) (err error) {
+		result, err = c.m.clients().VPCClient.ListRouteTable(ctx, args, withRequestIDHeader(ctx, option))
+		return err
+	})
+	return result, err
+}
+
+func (c *vpcClient) DeleteRoute(ctx context.Context, routeID string, option *bce.SignOption) error {
+	return c.m.call(ctx, "VPC", "DeleteRoute", func(ctx context.Context) error {
+		return c.m.clients().VPCClient.DeleteRoute(ctx, routeID, withRequestIDHeader(ctx, option))
+	})
+}
+
+func (c *vpcClient) CreateRouteRule(ctx context.Context, args *vpc.CreateRouteRuleArgs, option *bce.SignOption) (string, error) {
+	var result string
+	err := c.m.call(ctx, "VPC", "CreateRouteRule", func(ctx context.Context) (err error) {
+		result, err = c.m.clients().VPCClient.CreateRouteRule(ctx, args, withRequestIDHeader(ctx, option))
+		return err
+	})
+	return result, err
+}
+
+// bccClient calls BCC APIs through clientMiddleware
+type bccClient struct {
+	m *clientMiddleware
+}
+
+func (c *bccClient) CreateSecurityGroup(ctx context.Context, args *bcc.CreateSecurityGroupArgs, option *bce.SignOption) (*bcc.CreateSecurityGroupResponse, error) {
+	var result *bcc.CreateSecurityGroupResponse
+	err := c.m.call(ctx, "BCC", "CreateSecurityGroup", func(ctx context.Context) (err error) {
+		result, err = c.m.clients().BCCClient.CreateSecurityGroup(ctx, args, withRequestIDHeader(ctx, option))
+		return err
+	})
+	return result, err
+}
+
+func (c *bccClient) ListSecurityGroups(ctx context.Context, args *bcc.ListSecurityGroupsArgs, option *bce.SignOption) ([]bcc.SecurityGroup, error) {
+	var result []bcc.SecurityGroup
+	err := c.m.call(ctx, "BCC", "ListSecurityGroups", func(ctx context.Context) (err error) {
+		result, err = c.m.clients().BCCClient.ListSecurityGroups(ctx, args, withRequestIDHeader(ctx, option))
+		return err
+	})
+	return result, err
+}
+
+func (c *bccClient) DeleteSecurityGroup(ctx context.Context, securityGroupID string, option *bce.SignOption) error {
+	return c.m.call(ctx, "BCC", "DeleteSecurityGroup", func(ctx context.Context) error {
+		return c.m.clients().BCCClient.DeleteSecurityGroup(ctx, securityGroupID, withRequestIDHeader(ctx, option))
+	})
+}
+
+func (c *bccClient) AuthorizeSecurityGroupRule(ctx context.Context, args *bcc.SecurityGroupRuleArgs, option *bce.SignOption) error {
+	return c.m.call(ctx, "BCC", "AuthorizeSecurityGroupRule", func(ctx context.Context) error {
+		return c.m.clients().BCCClient.AuthorizeSecurityGroupRule(ctx, args, withRequestIDHeader(ctx, option))
+	})
+}
+
+func (c *bccClient) RevokeSecurityGroupRule(ctx context.Context, args *bcc.SecurityGroupRuleArgs, option *bce.SignOption) error {
+	return c.m.call(ctx, "BCC", "RevokeSecurityGroupRule", func(ctx context.Context) error {
+		return c.m.clients().BCCClient.RevokeSecurityGroupRule(ctx, args, withRequestIDHeader(ctx, option))
+	})
+}
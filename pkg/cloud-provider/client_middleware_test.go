@@ -0,0 +1,317 @@
+package cloud_provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/bce"
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/blb"
+
+	"icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/fake"
+)
+
+func newTestClientMiddleware(t *testing.T, config CloudAPIConfig) *clientMiddleware {
+	if err := completeCloudAPIConfig(&config); err != nil {
+		t.Fatalf("completeCloudAPIConfig err: %v", err)
+	}
+	return newClientMiddleware(&config)
+}
+
+func TestCompleteCloudAPIConfig(t *testing.T) {
+	// case1: defaults
+	config := CloudAPIConfig{}
+	if err := completeCloudAPIConfig(&config); err != nil {
+		t.Fatalf("completeCloudAPIConfig err: %v", err)
+	}
+	if config.Timeout != defaultCloudAPITimeout || config.QPS != defaultCloudAPIQPS || config.Burst != defaultCloudAPIBurst ||
+		config.MaxRetries != defaultCloudAPIMaxRetries || config.RetryBaseDelay != defaultCloudAPIRetryBaseDelay ||
+		config.RetryMaxDelay != defaultCloudAPIRetryMaxDelay {
+		t.Errorf("completeCloudAPIConfig err, want defaults, get %+v", config)
+	}
+	// case2: negative MaxRetries disables retrying
+	config = CloudAPIConfig{MaxRetries: -1}
+	if err := completeCloudAPIConfig(&config); err != nil || config.MaxRetries != 0 {
+		t.Errorf("completeCloudAPIConfig err, want MaxRetries 0, get %d, %v", config.MaxRetries, err)
+	}
+	// case3: rate limits by client and API name
+	config = CloudAPIConfig{RateLimits: map[string]RateLimitConfig{
+		"BLB":                  {QPS: 5, Burst: 5},
+		"EIP.ListEIPs":         {QPS: 1, Burst: 1},
+		"CCE.ListClusterNodes": {QPS: 1, Burst: 1},
+	}}
+	if err := completeCloudAPIConfig(&config); err != nil {
+		t.Errorf("completeCloudAPIConfig err: %v", err)
+	}
+	// case4: invalid configs
+	for _, config := range []CloudAPIConfig{
+		{Timeout: maxCloudAPITimeout + 1},
+		{QPS: -1},
+		{MaxRetries: maxCloudAPIMaxRetries + 1},
+		{RetryBaseDelay: 1000, RetryMaxDelay: 100},
+		{RateLimits: map[string]RateLimitConfig{"SLB": {QPS: 1, Burst: 1}}},
+		{RateLimits: map[string]RateLimitConfig{"BLB.DescribeLoadBalancer": {QPS: 1, Burst: 1}}},
+		{RateLimits: map[string]RateLimitConfig{"BLB": {QPS: 1}}},
+	} {
+		if err := completeCloudAPIConfig(&config); err == nil {
+			t.Errorf("completeCloudAPIConfig err, want error for %+v", config)
+		}
+	}
+}
+
+// case1: read API retries server errors until success
+// case2: write API does not retry server errors
+// case3: write API retries throttling
+// case4: retrying stops at MaxRetries
+// case5: not retryable errors are returned at once
+func TestClientMiddlewareRetry(t *testing.T) {
+	ctx := context.Background()
+	m := newTestClientMiddleware(t, CloudAPIConfig{QPS: 1000, Burst: 1000, MaxRetries: 3, RetryBaseDelay: 1, RetryMaxDelay: 2})
+	serverErr := &bce.Error{StatusCode: http.StatusInternalServerError, Code: "InternalError"}
+	throttlingErr := &bce.Error{StatusCode: http.StatusTooManyRequests, Code: "RequestLimitExceeded"}
+
+	testCases := []struct {
+		method    string
+		errs      []error
+		wantCalls int
+		wantErr   bool
+	}{
+		{"DescribeLoadBalancers", []error{serverErr, serverErr, nil}, 3, false},
+		{"CreateLoadBalancer", []error{serverErr, nil}, 1, true},
+		{"CreateLoadBalancer", []error{throttlingErr, throttlingErr, nil}, 3, false},
+		{"DescribeLoadBalancers", []error{serverErr, serverErr, serverErr, serverErr, nil}, 4, true},
+		{"DescribeLoadBalancers", []error{fmt.Errorf("invalid args"), nil}, 1, true},
+	}
+	for i, tc := range testCases {
+		calls := 0
+		err := m.call(ctx, "BLB", tc.method, func(ctx context.Context) error {
+			err := tc.errs[calls]
+			calls++
+			return err
+		})
+		if calls != tc.wantCalls || (err != nil) != tc.wantErr {
+			t.Errorf("case%d: call err, want %d calls and error %v, get %d calls and %v", i+1, tc.wantCalls, tc.wantErr, calls, err)
+		}
+	}
+}
+
+// case1: cancelled context aborts waiting to retry
+// case2: stopped middleware aborts in-flight and later calls
+func TestClientMiddlewareCancel(t *testing.T) {
+	m := newTestClientMiddleware(t, CloudAPIConfig{QPS: 1000, Burst: 1000, RetryBaseDelay: 60000, RetryMaxDelay: 60000})
+	throttlingErr := &bce.Error{StatusCode: http.StatusTooManyRequests}
+
+	// case1
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := m.call(ctx, "EIP", "GetEIPs", func(ctx context.Context) error {
+		return throttlingErr
+	})
+	if err == nil || time.Since(start) > 10*time.Second {
+		t.Errorf("case1: call err, want aborted at once, get %v after %v", err, time.Since(start))
+	}
+
+	// case2
+	stopCh := make(chan struct{})
+	m.stopOn(stopCh)
+	started := make(chan struct{})
+	result := make(chan error)
+	go func() {
+		result <- m.call(context.Background(), "EIP", "GetEIPs", func(ctx context.Context) error {
+			close(started)
+			<-ctx.Done()
+			return ctx.Err()
+		})
+	}()
+	<-started
+	close(stopCh)
+	select {
+	case err := <-result:
+		if err == nil {
+			t.Errorf("case2: call err, want error after stop")
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("case2: call err, want aborted after stop")
+	}
+	err = m.call(context.Background(), "EIP", "GetEIPs", func(ctx context.Context) error {
+		return nil
+	})
+	if err == nil {
+		t.Errorf("case2: call err, want error after stop")
+	}
+}
+
+func TestClientMiddlewareRateLimit(t *testing.T) {
+	m := newTestClientMiddleware(t, CloudAPIConfig{
+		RateLimits: map[string]RateLimitConfig{
+			"BLB":                       {QPS: 1000, Burst: 1000},
+			"BLB.DescribeLoadBalancers": {QPS: 1, Burst: 1},
+		},
+	})
+	if qps := m.limiter("BLB", "DescribeLoadBalancers").QPS(); qps != 1 {
+		t.Errorf("limiter err, want qps 1 by API name, get %v", qps)
+	}
+	if qps := m.limiter("BLB", "CreateLoadBalancer").QPS(); qps != 1000 {
+		t.Errorf("limiter err, want qps 1000 by client name, get %v", qps)
+	}
+	if qps := m.limiter("EIP", "GetEIPs").QPS(); qps != defaultCloudAPIQPS {
+		t.Errorf("limiter err, want default qps, get %v", qps)
+	}
+	if m.limiter("BLB", "CreateLoadBalancer") == m.limiter("BLB", "DeleteLoadBalancer") {
+		t.Errorf("limiter err, want a token bucket for each API")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	noop := func(ctx context.Context) error { return nil }
+	if err := m.call(ctx, "BLB", "DescribeLoadBalancers", noop); err != nil {
+		t.Errorf("call err: %v", err)
+	}
+	if err := m.call(ctx, "BLB", "DescribeLoadBalancers", noop); err == nil {
+		t.Errorf("call err, want rate limited beyond context deadline")
+	}
+}
+
+func TestClientMiddlewareWrap(t *testing.T) {
+	ctx := context.Background()
+	cloud := NewFakeCloud("c-test")
+	m := newTestClientMiddleware(t, CloudAPIConfig{})
+	clientSet := m.wrap(cloud.clientSet)
+
+	resp, err := clientSet.BLBClient.CreateLoadBalancer(ctx, &blb.CreateLoadBalancerArgs{
+		Name: "test",
+	}, nil)
+	if err != nil {
+		t.Fatalf("CreateLoadBalancer err: %v", err)
+	}
+	lbs, err := clientSet.BLBClient.DescribeLoadBalancers(ctx, &blb.DescribeLoadBalancersArgs{
+		LoadBalancerId: resp.LoadBalancerId,
+	}, nil)
+	if err != nil || len(lbs) != 1 {
+		t.Errorf("DescribeLoadBalancers err, want 1 BLB, get %v, %v", lbs, err)
+	}
+	if _, ok := cloud.clientSet.BLBClient.(*fake.BlbFakeClient).LoadBalancerMap[resp.LoadBalancerId]; !ok {
+		t.Errorf("CreateLoadBalancer err, want BLB in fake client")
+	}
+}
+
+func TestCloudAPIResultCode(t *testing.T) {
+	testCases := []struct {
+		err  error
+		want string
+	}{
+		{nil, "Success"},
+		{&bce.Error{StatusCode: http.StatusTooManyRequests, Code: "RequestLimitExceeded"}, "RequestLimitExceeded"},
+		{&bce.Error{StatusCode: http.StatusBadGateway}, "502"},
+		{context.Canceled, "Canceled"},
+		{fmt.Errorf("invalid args"), "ClientError"},
+	}
+	for i, tc := range testCases {
+		if code := cloudAPIResultCode(tc.err); code != tc.want {
+			t.Errorf("case%d: cloudAPIResultCode err, want %s, get %s", i+1, tc.want, code)
+		}
+	}
+}
+
+func TestWithRequestIDHeader(t *testing.T) {
+	ctx := WithRequestID(context.Background())
+	requestID := GetRequestID(ctx)
+
+	// case1: nil option
+	option := withRequestIDHeader(ctx, nil)
+	if option == nil || option.Headers[RequestIDHeader] != requestID {
+		t.Errorf("withRequestIDHeader err, want header %s, get %+v", requestID, option)
+	}
+	// case2: headers of option are kept, and option is not changed
+	origin := &bce.SignOption{Headers: map[string]string{"Host": "blb.bj.baidubce.com"}}
+	option = withRequestIDHeader(ctx, origin)
+	if option.Headers[RequestIDHeader] != requestID || option.Headers["Host"] != "blb.bj.baidubce.com" {
+		t.Errorf("withRequestIDHeader err, want both headers, get %v", option.Headers)
+	}
+	if _, ok := origin.Headers[RequestIDHeader]; ok {
+		t.Errorf("withRequestIDHeader err, want option not changed, get %v", origin.Headers)
+	}
+	// case3: no request ID
+	if option := withRequestIDHeader(context.Background(), origin); option != origin {
+		t.Errorf("withRequestIDHeader err, want option itself, get %+v", option)
+	}
+
+	// case4: call keeps request ID of ctx
+	m := newTestClientMiddleware(t, CloudAPIConfig{})
+	err := m.call(ctx, "BLB", "DescribeLoadBalancers", func(ctx context.Context) error {
+		if got := GetRequestID(ctx); got != requestID {
+			return fmt.Errorf("want request ID %s, get %s", requestID, got)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Errorf("call err: %v", err)
+	}
+}
+
+// case1: throttled write API succeeds after retrying
+// case2: newly created BLB is found by waiting until it is visible
+// case3: partial failure of batch API applies part of args
+// case4: slow API is aborted by context deadline
+func TestClientMiddlewareFaults(t *testing.T) {
+	ctx := context.Background()
+	cloud := NewFakeCloud("c-test")
+	faults := fake.NewFaults()
+	cloud.clientSet.BLBClient.(*fake.BlbFakeClient).Faults = faults
+	m := newTestClientMiddleware(t, CloudAPIConfig{QPS: 1000, Burst: 1000, MaxRetries: 3, RetryBaseDelay: 1, RetryMaxDelay: 2})
+	clientSet := m.wrap(cloud.clientSet)
+	serverErr := &bce.Error{StatusCode: http.StatusInternalServerError, Code: "InternalError"}
+	throttlingErr := &bce.Error{StatusCode: http.StatusTooManyRequests, Code: "RequestLimitExceeded"}
+
+	// case1
+	faults.InjectErrors("CreateLoadBalancer", throttlingErr, throttlingErr)
+	faults.HideCreated(2)
+	resp, err := clientSet.BLBClient.CreateLoadBalancer(ctx, &blb.CreateLoadBalancerArgs{
+		Name: "test",
+	}, nil)
+	if err != nil || faults.Calls("CreateLoadBalancer") != 3 {
+		t.Fatalf("case1: CreateLoadBalancer err, want success after 3 calls, get %v after %d calls", err, faults.Calls("CreateLoadBalancer"))
+	}
+
+	// case2
+	err = waitForStatus(ctx, "case2", testWaitBackoff, time.Second, func(ctx context.Context) (bool, error) {
+		lbs, err := clientSet.BLBClient.DescribeLoadBalancers(ctx, &blb.DescribeLoadBalancersArgs{
+			LoadBalancerId: resp.LoadBalancerId,
+		}, nil)
+		return err == nil && len(lbs) == 1, nil
+	})
+	if err != nil || faults.Calls("DescribeLoadBalancers") != 3 {
+		t.Errorf("case2: DescribeLoadBalancers err, want BLB found after 3 calls, get %v after %d calls", err, faults.Calls("DescribeLoadBalancers"))
+	}
+
+	// case3
+	faults.InjectErrors("AddBackendServers", &fake.PartialFailure{Applied: 1, Err: serverErr})
+	err = clientSet.BLBClient.AddBackendServers(ctx, &blb.AddBackendServersArgs{
+		LoadBalancerId:    resp.LoadBalancerId,
+		BackendServerList: []blb.BackendServer{{InstanceId: "1"}, {InstanceId: "2"}},
+	}, nil)
+	if err == nil {
+		t.Errorf("case3: AddBackendServers err, want error of partial failure")
+	}
+	bs, err := clientSet.BLBClient.DescribeBackendServers(ctx, &blb.DescribeBackendServersArgs{
+		LoadBalancerId: resp.LoadBalancerId,
+	}, nil)
+	if err != nil || len(bs) != 1 {
+		t.Errorf("case3: DescribeBackendServers err, want 1 backend server, get %v, %v", bs, err)
+	}
+
+	// case4
+	faults.SetLatency("DescribeLoadBalancers", time.Minute)
+	timeoutCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = clientSet.BLBClient.DescribeLoadBalancers(timeoutCtx, &blb.DescribeLoadBalancersArgs{
+		LoadBalancerId: resp.LoadBalancerId,
+	}, nil)
+	if err == nil || time.Since(start) > 10*time.Second {
+		t.Errorf("case4: DescribeLoadBalancers err, want aborted by deadline, get %v after %v", err, time.Since(start))
+	}
+}
@@ -2,11 +2,21 @@ package cloud_provider
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
-	"github.com/virtual-kubelet/virtual-kubelet/log"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog"
+
 	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/bce"
 )
 
@@ -16,39 +26,236 @@ const (
 	RemoteHostHeaderKey = "cce-remote-host"
 )
 
-var (
-	tokenFilename     = "/var/run/secrets/cce/cce-plugin-token/token"
-	expiredAtFilename = "/var/run/secrets/cce/cce-plugin-token/expiredAt"
-	token             string
-	expiredAt         int64
+const (
+	// defaultTokenDir is where CCE plugin token is mounted, with the token in file tokenFile and its expiry unix time
+	// in file expiredAtFile
+	defaultTokenDir = "/var/run/secrets/cce/cce-plugin-token"
+	tokenFile       = "token"
+	expiredAtFile   = "expiredAt"
+	// credentialsReloadPeriod is how often credentials are reloaded
+	credentialsReloadPeriod = 10 * time.Second
+	// tokenExpiringThreshold is how long before its expiry a token is reported expiring
+	tokenExpiringThreshold = 10 * time.Minute
 )
 
-func (bc *Baiducloud) getSignOption(ctx context.Context) *bce.SignOption {
-	if token == "" || time.Now().Unix() >= expiredAt {
-		tokenBytes, err := ioutil.ReadFile(tokenFilename)
-		if err != nil {
-			log.G(ctx).WithError(err).Error("read token file failed")
-			return nil
-		}
-		expiredAtBytes, err := ioutil.ReadFile(expiredAtFilename)
+// Credentials are what BCE API calls are authenticated with
+type Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	// Token is the CCE plugin token carried by calls through CCE gateway, empty if it is not mounted
+	Token string
+	// ExpiredAt is when Token expires
+	ExpiredAt time.Time
+}
+
+// CredentialsProvider provides credentials of BCE API calls, which may be rotated while CCM is running
+type CredentialsProvider interface {
+	// Credentials returns the current credentials, which are shared and must not be modified
+	Credentials() *Credentials
+	// Reload loads credentials again and returns whether they are changed. Current credentials are kept on error.
+	Reload() (bool, error)
+}
+
+// fileCredentialsProvider provides AK/SK from cloud config file and token from token dir. Files are read by Reload,
+// and the credentials are swapped as a whole, so callers never see AK/SK or token half updated.
+type fileCredentialsProvider struct {
+	// configFile is the cloud config file, AK/SK are not reloaded if it is empty
+	configFile string
+	tokenDir   string
+
+	lock        sync.RWMutex
+	credentials *Credentials
+}
+
+// newFileCredentialsProvider creates provider with AK/SK from the loaded cloud config, Reload to load token
+func newFileCredentialsProvider(configFile, tokenDir, accessKeyID, secretAccessKey string) *fileCredentialsProvider {
+	return &fileCredentialsProvider{
+		configFile: configFile,
+		tokenDir:   tokenDir,
+		credentials: &Credentials{
+			AccessKeyID:     accessKeyID,
+			SecretAccessKey: secretAccessKey,
+		},
+	}
+}
+
+func (p *fileCredentialsProvider) Credentials() *Credentials {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return p.credentials
+}
+
+func (p *fileCredentialsProvider) Reload() (bool, error) {
+	credentials := *p.Credentials()
+	if p.configFile != "" {
+		config, err := readCloudConfig(p.configFile)
 		if err != nil {
-			log.G(ctx).WithError(err).Error("read expiredAt file failed")
-			return nil
+			return false, err
 		}
-		expiredAt, err = strconv.ParseInt(string(expiredAtBytes), 10, 64)
+		credentials.AccessKeyID = config.AccessKeyID
+		credentials.SecretAccessKey = config.SecretAccessKey
+	}
+	token, expiredAt, err := readToken(p.tokenDir)
+	if err != nil {
+		return false, err
+	}
+	credentials.Token = token
+	credentials.ExpiredAt = expiredAt
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if *p.credentials == credentials {
+		return false, nil
+	}
+	p.credentials = &credentials
+	return true, nil
+}
+
+// readCloudConfig reads cloud config from file
+func readCloudConfig(filename string) (*CloudConfig, error) {
+	ccBytes, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("read cloud config error: '%v'", err)
+	}
+	cc := new(CloudConfig)
+	err = json.Unmarshal(ccBytes, cc)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal cloud config error: '%v'", err)
+	}
+	return cc, nil
+}
+
+// readToken reads token and its expiry from dir, token is empty if it is not mounted
+func readToken(dir string) (string, time.Time, error) {
+	tokenBytes, err := ioutil.ReadFile(filepath.Join(dir, tokenFile))
+	if os.IsNotExist(err) {
+		return "", time.Time{}, nil
+	}
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("read token file failed: %v", err)
+	}
+	expiredAtBytes, err := ioutil.ReadFile(filepath.Join(dir, expiredAtFile))
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("read expiredAt file failed: %v", err)
+	}
+	expiredAt, err := strconv.ParseInt(strings.TrimSpace(string(expiredAtBytes)), 10, 64)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("parse expiredAt %q failed: %v", string(expiredAtBytes), err)
+	}
+	// token and expiredAt are read from two files, read token again in case they are rotated in between
+	tokenBytesAgain, err := ioutil.ReadFile(filepath.Join(dir, tokenFile))
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("read token file failed: %v", err)
+	}
+	if string(tokenBytes) != string(tokenBytesAgain) {
+		return "", time.Time{}, fmt.Errorf("token is rotated while reading")
+	}
+	return strings.TrimSpace(string(tokenBytes)), time.Unix(expiredAt, 0), nil
+}
+
+// getCredentialsProvider returns the current credentials provider, nil if cloud is not created from config
+func (bc *Baiducloud) getCredentialsProvider() CredentialsProvider {
+	bc.credentialsLock.RLock()
+	defer bc.credentialsLock.RUnlock()
+	return bc.credentials
+}
+
+// newClientSetWithCredentials creates clients with AK/SK of credentials
+func (bc *Baiducloud) newClientSetWithCredentials(credentials *Credentials) (*ClientSet, error) {
+	config := bc.CloudConfig
+	config.AccessKeyID = credentials.AccessKeyID
+	config.SecretAccessKey = credentials.SecretAccessKey
+	return newClientSet(&config)
+}
+
+// runCredentialsWatcher reloads credentials every credentialsReloadPeriod until stopCh is closed
+func (bc *Baiducloud) runCredentialsWatcher(stopCh <-chan struct{}) {
+	go wait.Until(bc.reloadCredentials, credentialsReloadPeriod, stopCh)
+}
+
+// reloadCredentials reloads credentials, swaps clients with new AK/SK into clientSet, and reports token expiry
+func (bc *Baiducloud) reloadCredentials() {
+	ctx := WithRequestID(context.Background())
+	bc.usePendingCredentialsSecret()
+	provider := bc.getCredentialsProvider()
+	changed, err := provider.Reload()
+	if err != nil {
+		credentialsReloads.WithLabelValues("error").Inc()
+		klog.Errorf(Message(ctx, fmt.Sprintf("reload credentials failed, keep current credentials: %v", err)))
+	} else if changed {
+		credentialsReloads.WithLabelValues("changed").Inc()
+	} else {
+		credentialsReloads.WithLabelValues("unchanged").Inc()
+	}
+
+	credentials := provider.Credentials()
+	if changed {
+		setSecretValues(credentials)
+	}
+	if bc.clientMiddleware != nil && bc.clientsCredentials != nil &&
+		(credentials.AccessKeyID != bc.clientsCredentials.AccessKeyID || credentials.SecretAccessKey != bc.clientsCredentials.SecretAccessKey) {
+		clientSet, err := bc.newClientSetWithCredentials(credentials)
 		if err != nil {
-			log.G(ctx).WithField("expiredAt", string(expiredAtBytes)).WithError(err).
-				Error("fail to parse expiredAt string")
-			return nil
+			klog.Errorf(Message(ctx, fmt.Sprintf("create clients with rotated AK/SK failed: %v", err)))
+		} else {
+			bc.clientMiddleware.setClients(clientSet)
+			bc.clientsCredentials = credentials
+			klog.Infof(Message(ctx, "clients are swapped with rotated AK/SK"))
 		}
-		token = string(tokenBytes)
+	}
+	bc.reportTokenExpiry(ctx, credentials)
+}
+
+// reportTokenExpiry exports expiry of token, and records an event on kube-system namespace once the token is expiring
+// and once it is expired
+func (bc *Baiducloud) reportTokenExpiry(ctx context.Context, credentials *Credentials) {
+	if credentials.Token == "" {
+		credentialsTokenExpiry.Set(0)
+		return
+	}
+	credentialsTokenExpiry.Set(float64(credentials.ExpiredAt.Unix()))
+
+	var reason, message string
+	remaining := credentials.ExpiredAt.Sub(time.Now())
+	if remaining <= 0 {
+		reason = "CredentialsTokenExpired"
+		message = fmt.Sprintf("CCE plugin token expired at %s, BCE API calls through CCE gateway will fail until it is rotated",
+			credentials.ExpiredAt.Format(time.RFC3339))
+	} else if remaining < tokenExpiringThreshold {
+		reason = "CredentialsTokenExpiring"
+		message = fmt.Sprintf("CCE plugin token expires at %s and is not rotated yet", credentials.ExpiredAt.Format(time.RFC3339))
+	}
+	reported := fmt.Sprintf("%s/%d", reason, credentials.ExpiredAt.Unix())
+	if reason == "" || reported == bc.tokenExpiryReported {
+		return
+	}
+	bc.tokenExpiryReported = reported
+	klog.Warningf(Message(ctx, message))
+	if bc.eventRecorder != nil {
+		Eventf(ctx, bc.eventRecorder, &v1.ObjectReference{
+			Kind: "Namespace",
+			Name: metav1.NamespaceSystem,
+		}, v1.EventTypeWarning, reason, "%s", message)
+	}
+}
+
+// getSignOption returns the option which sends requests through CCE gateway with token, nil if gateway is disabled or
+// there is no token
+func (bc *Baiducloud) getSignOption(ctx context.Context) *bce.SignOption {
+	provider := bc.getCredentialsProvider()
+	if provider == nil || bc.Gateway.Disabled {
+		return nil
+	}
+	token := provider.Credentials().Token
+	if token == "" {
+		return nil
 	}
 	return &bce.SignOption{
 		CustomSignFunc: func(ctx context.Context, req *bce.Request) {
 			req.Header.Set(TokenHeaderKey, token)
 			req.Header.Set(ClusterIDHeaderKey, bc.CloudConfig.ClusterID)
 			req.Header.Set(RemoteHostHeaderKey, req.Host)
-			req.Host, _ = getCCEGatewayHostAndPort(bc.CloudConfig.Region)
+			req.Host = bc.CloudConfig.Gateway.Host
 		},
 	}
 }
@@ -0,0 +1,173 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloud_provider
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/informers"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog"
+)
+
+const (
+	// SecretAccessKeyIDKey and SecretSecretAccessKeyKey are the keys of AK/SK in the credentials Secret
+	SecretAccessKeyIDKey     = "AccessKeyID"
+	SecretSecretAccessKeyKey = "SecretAccessKey"
+)
+
+// credentialsSecretSyncTimeout bounds waiting for the credentials Secret at startup, e.g. if CCM is not allowed to
+// list and watch Secrets, so that CCM starts with AK/SK of cloud config instead of hanging. The Secret is still used
+// by the credentials watcher once it is synced.
+const credentialsSecretSyncTimeout = 30 * time.Second
+
+// credentialsSecret is the Secret AK/SK are loaded from, watched by an informer
+type credentialsSecret struct {
+	namespace string
+	name      string
+	lister    corelisters.SecretLister
+	synced    cache.InformerSynced
+}
+
+// ValidateCredentialsSecret checks that key of the credentials Secret is <namespace>/<name>
+func ValidateCredentialsSecret(key string) error {
+	if namespace, name, err := cache.SplitMetaNamespaceKey(key); err != nil || namespace == "" || name == "" {
+		return fmt.Errorf("credentials secret must be <namespace>/<name>, got %q", key)
+	}
+	return nil
+}
+
+// secretCredentialsProvider provides AK/SK from a Secret merged over the credentials of base, e.g. token from file.
+// The Secret is watched by an informer, and read from its cache by Reload.
+type secretCredentialsProvider struct {
+	base      CredentialsProvider
+	namespace string
+	name      string
+	lister    corelisters.SecretLister
+
+	lock        sync.RWMutex
+	credentials *Credentials
+}
+
+func newSecretCredentialsProvider(base CredentialsProvider, namespace, name string, lister corelisters.SecretLister) *secretCredentialsProvider {
+	return &secretCredentialsProvider{
+		base:        base,
+		namespace:   namespace,
+		name:        name,
+		lister:      lister,
+		credentials: base.Credentials(),
+	}
+}
+
+func (p *secretCredentialsProvider) Credentials() *Credentials {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return p.credentials
+}
+
+func (p *secretCredentialsProvider) Reload() (bool, error) {
+	if _, err := p.base.Reload(); err != nil {
+		return false, err
+	}
+	credentials := *p.base.Credentials()
+	secret, err := p.lister.Secrets(p.namespace).Get(p.name)
+	if err != nil {
+		return false, fmt.Errorf("get credentials secret %s/%s failed: %v", p.namespace, p.name, err)
+	}
+	accessKeyID := string(secret.Data[SecretAccessKeyIDKey])
+	secretAccessKey := string(secret.Data[SecretSecretAccessKeyKey])
+	if accessKeyID == "" || secretAccessKey == "" {
+		return false, fmt.Errorf("credentials secret %s/%s must have %s and %s", p.namespace, p.name,
+			SecretAccessKeyIDKey, SecretSecretAccessKeyKey)
+	}
+	credentials.AccessKeyID = accessKeyID
+	credentials.SecretAccessKey = secretAccessKey
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if *p.credentials == credentials {
+		return false, nil
+	}
+	p.credentials = &credentials
+	return true, nil
+}
+
+// useCredentialsSecret loads AK/SK from Secret key <namespace>/<name> over the current credentials, and watches the
+// Secret until stopCh is closed. If the Secret is not synced within timeout, the current credentials are kept, and
+// the credentials watcher switches to the Secret once it is synced.
+func (bc *Baiducloud) useCredentialsSecret(key string, timeout time.Duration, stopCh <-chan struct{}) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return err
+	}
+	// only the credentials Secret is watched
+	informerFactory := informers.NewSharedInformerFactoryWithOptions(bc.kubeClient, 0, informers.WithNamespace(namespace),
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.FieldSelector = fields.OneTermEqualSelector("metadata.name", name).String()
+		}))
+	secretInformer := informerFactory.Core().V1().Secrets()
+	secret := &credentialsSecret{
+		namespace: namespace,
+		name:      name,
+		lister:    secretInformer.Lister(),
+		synced:    secretInformer.Informer().HasSynced,
+	}
+	informerFactory.Start(stopCh)
+
+	bc.credentialsLock.Lock()
+	bc.pendingCredentialsSecret = secret
+	bc.credentialsLock.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	go func() {
+		select {
+		case <-stopCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	if !cache.WaitForCacheSync(ctx.Done(), secret.synced) {
+		// the informer retries listing, e.g. until RBAC allows to list Secrets
+		return fmt.Errorf("wait for credentials secret %s/%s synced failed: %v", namespace, name, ctx.Err())
+	}
+	bc.reloadCredentials()
+	return nil
+}
+
+// usePendingCredentialsSecret loads AK/SK from the pending credentials Secret once it is synced
+func (bc *Baiducloud) usePendingCredentialsSecret() {
+	bc.credentialsLock.Lock()
+	defer bc.credentialsLock.Unlock()
+	secret := bc.pendingCredentialsSecret
+	if secret == nil {
+		return
+	}
+	if !secret.synced() {
+		klog.Warningf("credentials secret %s/%s is not synced yet, keep current AK/SK", secret.namespace, secret.name)
+		return
+	}
+	bc.credentials = newSecretCredentialsProvider(bc.credentials, secret.namespace, secret.name, secret.lister)
+	bc.pendingCredentialsSecret = nil
+	klog.Infof("AK/SK are loaded from secret %s/%s", secret.namespace, secret.name)
+}
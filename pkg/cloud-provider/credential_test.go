@@ -0,0 +1,289 @@
+package cloud_provider
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+)
+
+func writeCredentialFiles(t *testing.T, configFile, tokenDir, accessKeyID, token string, expiredAt time.Time) {
+	config := fmt.Sprintf(`{"ClusterId": "c-test", "AccessKeyID": %q, "SecretAccessKey": "sk"}`, accessKeyID)
+	if err := ioutil.WriteFile(configFile, []byte(config), 0600); err != nil {
+		t.Fatalf("write config file err: %v", err)
+	}
+	if token == "" {
+		return
+	}
+	if err := ioutil.WriteFile(filepath.Join(tokenDir, tokenFile), []byte(token), 0600); err != nil {
+		t.Fatalf("write token file err: %v", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(tokenDir, expiredAtFile), []byte(fmt.Sprintf("%d\n", expiredAt.Unix())), 0600); err != nil {
+		t.Fatalf("write expiredAt file err: %v", err)
+	}
+}
+
+func newTestCredentialsDir(t *testing.T) (string, string, func()) {
+	dir, err := ioutil.TempDir("", "credentials")
+	if err != nil {
+		t.Fatalf("create temp dir err: %v", err)
+	}
+	tokenDir := filepath.Join(dir, "token")
+	if err := os.Mkdir(tokenDir, 0700); err != nil {
+		t.Fatalf("create token dir err: %v", err)
+	}
+	return filepath.Join(dir, "cloud.config"), tokenDir, func() { os.RemoveAll(dir) }
+}
+
+func TestFileCredentialsProvider(t *testing.T) {
+	configFile, tokenDir, cleanup := newTestCredentialsDir(t)
+	defer cleanup()
+	expiredAt := time.Unix(time.Now().Add(time.Hour).Unix(), 0)
+
+	// case1: no token mounted
+	writeCredentialFiles(t, configFile, tokenDir, "ak-1", "", expiredAt)
+	p := newFileCredentialsProvider(configFile, tokenDir, "", "")
+	changed, err := p.Reload()
+	if err != nil || !changed || p.Credentials().AccessKeyID != "ak-1" || p.Credentials().Token != "" {
+		t.Errorf("Reload err, want ak-1 without token, get %+v, %v, %v", p.Credentials(), changed, err)
+	}
+	// case2: token mounted
+	writeCredentialFiles(t, configFile, tokenDir, "ak-1", "token-1", expiredAt)
+	changed, err = p.Reload()
+	if err != nil || !changed || p.Credentials().Token != "token-1" || !p.Credentials().ExpiredAt.Equal(expiredAt) {
+		t.Errorf("Reload err, want token-1, get %+v, %v, %v", p.Credentials(), changed, err)
+	}
+	// case3: nothing changed
+	if changed, err = p.Reload(); err != nil || changed {
+		t.Errorf("Reload err, want unchanged, get %v, %v", changed, err)
+	}
+	// case4: AK/SK rotated
+	old := p.Credentials()
+	writeCredentialFiles(t, configFile, tokenDir, "ak-2", "token-1", expiredAt)
+	changed, err = p.Reload()
+	if err != nil || !changed || p.Credentials().AccessKeyID != "ak-2" || old.AccessKeyID != "ak-1" {
+		t.Errorf("Reload err, want ak-2 and old credentials unchanged, get %+v, %+v, %v", p.Credentials(), old, err)
+	}
+	// case5: invalid expiredAt keeps current credentials
+	if err := ioutil.WriteFile(filepath.Join(tokenDir, expiredAtFile), []byte("invalid"), 0600); err != nil {
+		t.Fatalf("write expiredAt file err: %v", err)
+	}
+	if _, err = p.Reload(); err == nil || p.Credentials().Token != "token-1" {
+		t.Errorf("Reload err, want error and token-1 kept, get %+v, %v", p.Credentials(), err)
+	}
+}
+
+func TestSecretCredentialsProvider(t *testing.T) {
+	configFile, tokenDir, cleanup := newTestCredentialsDir(t)
+	defer cleanup()
+	writeCredentialFiles(t, configFile, tokenDir, "ak-file", "token-1", time.Now().Add(time.Hour))
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	base := newFileCredentialsProvider(configFile, tokenDir, "", "")
+	p := newSecretCredentialsProvider(base, "kube-system", "cce-credentials", corelisters.NewSecretLister(indexer))
+
+	// case1: secret not found keeps current credentials
+	if _, err := p.Reload(); err == nil {
+		t.Errorf("Reload err, want secret not found")
+	}
+	// case2: AK/SK of secret are merged over token of base
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: "cce-credentials"},
+		Data: map[string][]byte{
+			SecretAccessKeyIDKey:     []byte("ak-secret"),
+			SecretSecretAccessKeyKey: []byte("sk-secret"),
+		},
+	}
+	indexer.Add(secret)
+	changed, err := p.Reload()
+	credentials := p.Credentials()
+	if err != nil || !changed || credentials.AccessKeyID != "ak-secret" || credentials.SecretAccessKey != "sk-secret" || credentials.Token != "token-1" {
+		t.Errorf("Reload err, want ak-secret with token-1, get %+v, %v, %v", credentials, changed, err)
+	}
+	// case3: secret without SK is rejected
+	invalid := secret.DeepCopy()
+	delete(invalid.Data, SecretSecretAccessKeyKey)
+	indexer.Update(invalid)
+	if _, err := p.Reload(); err == nil || p.Credentials().SecretAccessKey != "sk-secret" {
+		t.Errorf("Reload err, want error and sk-secret kept, get %+v, %v", p.Credentials(), err)
+	}
+}
+
+func TestReloadCredentials(t *testing.T) {
+	configFile, tokenDir, cleanup := newTestCredentialsDir(t)
+	defer cleanup()
+	writeCredentialFiles(t, configFile, tokenDir, "ak-1", "token-1", time.Now().Add(time.Hour))
+
+	cloud := NewFakeCloud("c-test")
+	recorder := record.NewFakeRecorder(10)
+	cloud.eventRecorder = recorder
+	cloud.clientMiddleware = newTestClientMiddleware(t, CloudAPIConfig{})
+	cloud.clientSet = cloud.clientMiddleware.wrap(cloud.clientSet)
+	provider := newFileCredentialsProvider(configFile, tokenDir, "", "")
+	if _, err := provider.Reload(); err != nil {
+		t.Fatalf("Reload err: %v", err)
+	}
+	cloud.credentials = provider
+	cloud.clientsCredentials = provider.Credentials()
+	fakeClients := cloud.clientMiddleware.clients()
+
+	// case1: sign option carries token
+	if option := cloud.getSignOption(context.Background()); option == nil {
+		t.Errorf("getSignOption err, want option with token")
+	}
+
+	// case2: clients are swapped when AK/SK are rotated
+	cloud.reloadCredentials()
+	if cloud.clientMiddleware.clients() != fakeClients {
+		t.Errorf("reloadCredentials err, want clients kept when AK/SK are not changed")
+	}
+	writeCredentialFiles(t, configFile, tokenDir, "ak-2", "token-1", time.Now().Add(time.Hour))
+	cloud.reloadCredentials()
+	if cloud.clientMiddleware.clients() == fakeClients || cloud.clientsCredentials.AccessKeyID != "ak-2" {
+		t.Errorf("reloadCredentials err, want clients swapped with ak-2")
+	}
+
+	// case3: expired token is reported once
+	writeCredentialFiles(t, configFile, tokenDir, "ak-2", "token-1", time.Now().Add(-time.Minute))
+	cloud.reloadCredentials()
+	cloud.reloadCredentials()
+	if len(recorder.Events) != 1 {
+		t.Fatalf("reloadCredentials err, want 1 event, get %d", len(recorder.Events))
+	}
+	if event := <-recorder.Events; !strings.Contains(event, "CredentialsTokenExpired") {
+		t.Errorf("reloadCredentials err, want CredentialsTokenExpired event, get %s", event)
+	}
+}
+
+// run with -race to check credentials are reloaded and read concurrently without races
+func TestCredentialsConcurrentReload(t *testing.T) {
+	configFile, tokenDir, cleanup := newTestCredentialsDir(t)
+	defer cleanup()
+	writeCredentialFiles(t, configFile, tokenDir, "ak-1", "token-1", time.Now().Add(time.Hour))
+
+	cloud := NewFakeCloud("c-test")
+	cloud.clientMiddleware = newTestClientMiddleware(t, CloudAPIConfig{})
+	cloud.clientSet = cloud.clientMiddleware.wrap(cloud.clientSet)
+	provider := newFileCredentialsProvider(configFile, tokenDir, "", "")
+	cloud.credentials = provider
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				cloud.getSignOption(context.Background())
+				_ = cloud.clientMiddleware.clients()
+			}
+		}()
+	}
+	for j := 0; j < 50; j++ {
+		if _, err := provider.Reload(); err != nil {
+			t.Errorf("Reload err: %v", err)
+		}
+		cloud.clientMiddleware.setClients(cloud.clientMiddleware.clients())
+	}
+	wg.Wait()
+}
+
+// case1: credentials are kept if the Secret is not synced within timeout, e.g. without RBAC to list Secrets
+// case2: credentials watcher loads AK/SK from the Secret once it is synced
+// case3: AK/SK are loaded from the synced Secret
+func TestUseCredentialsSecret(t *testing.T) {
+	configFile, tokenDir, cleanup := newTestCredentialsDir(t)
+	defer cleanup()
+	writeCredentialFiles(t, configFile, tokenDir, "ak-file", "token-1", time.Now().Add(time.Hour))
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+
+	// case1
+	cloud := NewFakeCloud("c-test")
+	kubeClient := k8sfake.NewSimpleClientset()
+	var denied int32 = 1
+	kubeClient.PrependReactor("list", "secrets", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if atomic.LoadInt32(&denied) == 0 {
+			return false, nil, nil
+		}
+		return true, nil, errors.NewForbidden(v1.Resource("secrets"), "", fmt.Errorf("RBAC denied"))
+	})
+	cloud.kubeClient = kubeClient
+	provider := newFileCredentialsProvider(configFile, tokenDir, "", "")
+	if _, err := provider.Reload(); err != nil {
+		t.Fatalf("Reload err: %v", err)
+	}
+	cloud.credentials = provider
+	startTime := time.Now()
+	if err := cloud.useCredentialsSecret("kube-system/creds", 100*time.Millisecond, stopCh); err == nil {
+		t.Errorf("useCredentialsSecret err, want error without RBAC")
+	}
+	if time.Since(startTime) > 10*time.Second {
+		t.Errorf("useCredentialsSecret err, want return after timeout, get %v", time.Since(startTime))
+	}
+	if cloud.getCredentialsProvider() != provider {
+		t.Errorf("useCredentialsSecret err, want file credentials kept")
+	}
+
+	// case2
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "creds", Namespace: "kube-system"},
+		Data: map[string][]byte{
+			SecretAccessKeyIDKey:     []byte("ak-secret"),
+			SecretSecretAccessKeyKey: []byte("sk-secret"),
+		},
+	}
+	if _, err := kubeClient.CoreV1().Secrets("kube-system").Create(secret); err != nil {
+		t.Fatalf("create secret err: %v", err)
+	}
+	atomic.StoreInt32(&denied, 0)
+	err := wait.PollImmediate(100*time.Millisecond, 10*time.Second, func() (bool, error) {
+		cloud.reloadCredentials()
+		return cloud.getCredentialsProvider().Credentials().AccessKeyID == "ak-secret", nil
+	})
+	if err != nil {
+		t.Errorf("reloadCredentials err, want ak-secret after secret synced, get %s", cloud.getCredentialsProvider().Credentials().AccessKeyID)
+	}
+
+	// case3
+	cloud = NewFakeCloud("c-test")
+	cloud.credentials = provider
+	_, err = cloud.kubeClient.CoreV1().Secrets("kube-system").Create(secret)
+	if err != nil {
+		t.Fatalf("create secret err: %v", err)
+	}
+	if err := cloud.useCredentialsSecret("kube-system/creds", 10*time.Second, stopCh); err != nil {
+		t.Fatalf("useCredentialsSecret err: %v", err)
+	}
+	if ak := cloud.getCredentialsProvider().Credentials().AccessKeyID; ak != "ak-secret" {
+		t.Errorf("useCredentialsSecret err, want ak-secret, get %s", ak)
+	}
+}
+
+func TestValidateCredentialsSecret(t *testing.T) {
+	for _, key := range []string{"kube-system/creds"} {
+		if err := ValidateCredentialsSecret(key); err != nil {
+			t.Errorf("ValidateCredentialsSecret %q err: %v", key, err)
+		}
+	}
+	for _, key := range []string{"creds", "/creds", "kube-system/", "a/b/c"} {
+		if err := ValidateCredentialsSecret(key); err == nil {
+			t.Errorf("ValidateCredentialsSecret %q err, want error", key)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloud_provider
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/klog"
+
+	cce "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/temp-cce"
+)
+
+const (
+	// defaultInstanceCacheTTL is the default seconds cluster instances are cached
+	defaultInstanceCacheTTL = 30
+	// maxInstanceCacheTTL keeps new nodes from waiting too long for their instances
+	maxInstanceCacheTTL = 600
+	// minInstanceCacheRefreshInterval limits the listings forced by lookup misses, e.g. of deleted nodes
+	minInstanceCacheRefreshInterval = 5 * time.Second
+)
+
+const (
+	instanceCacheIndexID   = "id"
+	instanceCacheIndexName = "name"
+	instanceCacheIndexList = "list"
+)
+
+// instanceCache caches the instances of cluster listed from CCE, indexed by instance id, and by hostname and IP which
+// node name can be. Instances are listed again when cache expires, or when a lookup misses, at most once per
+// minInstanceCacheRefreshInterval. Cached instances are shared by callers, so they are read-only.
+type instanceCache struct {
+	ttl  time.Duration
+	list func(ctx context.Context) ([]*cce.Node, error)
+	now  func() time.Time
+
+	// lock is held during listing, so that concurrent callers wait for one listing instead of making their own
+	lock      sync.Mutex
+	refreshed time.Time
+	instances []*cce.Node
+	byID      map[string]*cce.Node
+	byName    map[string]*cce.Node
+}
+
+func newInstanceCache(ttl time.Duration, list func(ctx context.Context) ([]*cce.Node, error)) *instanceCache {
+	return &instanceCache{
+		ttl:  ttl,
+		list: list,
+		now:  time.Now,
+	}
+}
+
+func (c *instanceCache) expiredLocked() bool {
+	return c.byID == nil || c.now().Sub(c.refreshed) >= c.ttl
+}
+
+func (c *instanceCache) refreshLocked(ctx context.Context) error {
+	instances, err := c.list(ctx)
+	if err != nil {
+		instanceCacheRefreshes.WithLabelValues("error").Inc()
+		return err
+	}
+	instanceCacheRefreshes.WithLabelValues("success").Inc()
+	byID := make(map[string]*cce.Node, len(instances))
+	byName := make(map[string]*cce.Node, 2*len(instances))
+	for _, ins := range instances {
+		byID[ins.InstanceID] = ins
+		if ins.Hostname != "" {
+			byName[ins.Hostname] = ins
+		}
+	}
+	// hostname takes precedence over IP, since node name is the hostname unless it is empty
+	for _, ins := range instances {
+		if _, ok := byName[ins.IP]; ins.IP != "" && !ok {
+			byName[ins.IP] = ins
+		}
+	}
+	c.instances = instances
+	c.byID = byID
+	c.byName = byName
+	c.refreshed = c.now()
+	instanceCacheSize.Set(float64(len(instances)))
+	klog.V(4).Infof(Message(ctx, fmt.Sprintf("instance cache refreshed with %d instances", len(instances))))
+	return nil
+}
+
+// listInstances returns all instances of cluster
+func (c *instanceCache) listInstances(ctx context.Context) ([]*cce.Node, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if !c.expiredLocked() {
+		instanceCacheLookups.WithLabelValues(instanceCacheIndexList, "hit").Inc()
+		return append([]*cce.Node{}, c.instances...), nil
+	}
+	instanceCacheLookups.WithLabelValues(instanceCacheIndexList, "miss").Inc()
+	err := c.refreshLocked(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return append([]*cce.Node{}, c.instances...), nil
+}
+
+// getByID returns the instance with instance id, nil if not found
+func (c *instanceCache) getByID(ctx context.Context, instanceID string) (*cce.Node, error) {
+	return c.get(ctx, instanceCacheIndexID, instanceID, func() map[string]*cce.Node { return c.byID })
+}
+
+// getByName returns the instance with hostname or IP, nil if not found
+func (c *instanceCache) getByName(ctx context.Context, name string) (*cce.Node, error) {
+	return c.get(ctx, instanceCacheIndexName, name, func() map[string]*cce.Node { return c.byName })
+}
+
+func (c *instanceCache) get(ctx context.Context, index string, key string, indexer func() map[string]*cce.Node) (*cce.Node, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	expired := c.expiredLocked()
+	if !expired {
+		if ins, ok := indexer()[key]; ok {
+			instanceCacheLookups.WithLabelValues(index, "hit").Inc()
+			return ins, nil
+		}
+	}
+	instanceCacheLookups.WithLabelValues(index, "miss").Inc()
+	if !expired && c.now().Sub(c.refreshed) < minInstanceCacheRefreshInterval {
+		return nil, nil
+	}
+	err := c.refreshLocked(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return indexer()[key], nil
+}
+
+// listClusterNodes lists instances of cluster from CCE
+func (bc *Baiducloud) listClusterNodes(ctx context.Context) ([]*cce.Node, error) {
+	instanceResponse, err := bc.clientSet.CCEClient.ListClusterNodes(ctx, bc.ClusterID, bc.getSignOption(ctx))
+	if err != nil {
+		return nil, err
+	}
+	return instanceResponse.Nodes, nil
+}
+
+// listClusterInstances returns instances of cluster from cache if it is set, otherwise from CCE. The result is read-only.
+func (bc *Baiducloud) listClusterInstances(ctx context.Context) ([]*cce.Node, error) {
+	if bc.instanceCache != nil {
+		return bc.instanceCache.listInstances(ctx)
+	}
+	return bc.listClusterNodes(ctx)
+}
+
+// getClusterInstanceByID returns instance of cluster with instance id, nil if not found. The result is read-only.
+func (bc *Baiducloud) getClusterInstanceByID(ctx context.Context, instanceID string) (*cce.Node, error) {
+	if bc.instanceCache != nil {
+		return bc.instanceCache.getByID(ctx, instanceID)
+	}
+	instances, err := bc.listClusterNodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, ins := range instances {
+		if ins.InstanceID == instanceID {
+			return ins, nil
+		}
+	}
+	return nil, nil
+}
+
+// getClusterInstanceByName returns instance of cluster with hostname or IP, nil if not found. The result is read-only.
+func (bc *Baiducloud) getClusterInstanceByName(ctx context.Context, name string) (*cce.Node, error) {
+	if bc.instanceCache != nil {
+		return bc.instanceCache.getByName(ctx, name)
+	}
+	instances, err := bc.listClusterNodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, ins := range instances {
+		// nodeName can be a ip or a hostname
+		if ins.Hostname == name || ins.IP == name {
+			return ins, nil
+		}
+	}
+	return nil, nil
+}
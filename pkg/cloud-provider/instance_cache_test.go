@@ -0,0 +1,106 @@
+package cloud_provider
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	cce "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/temp-cce"
+)
+
+// case1: lookups by id, hostname, IP and listing share one listing
+// case2: miss right after listing does not list again
+// case3: miss lists again after minInstanceCacheRefreshInterval
+// case4: expired cache lists again
+// case5: failed listing is returned and retried by the next lookup
+func TestInstanceCache(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	listed := 0
+	var listErr error
+	instances := []*cce.Node{
+		{InstanceID: "i-1", Hostname: "host-1", IP: "10.0.0.1"},
+	}
+	cache := newInstanceCache(30*time.Second, func(ctx context.Context) ([]*cce.Node, error) {
+		listed++
+		if listErr != nil {
+			return nil, listErr
+		}
+		return append([]*cce.Node{}, instances...), nil
+	})
+	cache.now = func() time.Time {
+		return now
+	}
+
+	// case1
+	for _, get := range []func() (*cce.Node, error){
+		func() (*cce.Node, error) { return cache.getByID(ctx, "i-1") },
+		func() (*cce.Node, error) { return cache.getByName(ctx, "host-1") },
+		func() (*cce.Node, error) { return cache.getByName(ctx, "10.0.0.1") },
+	} {
+		ins, err := get()
+		if err != nil || ins == nil || ins.InstanceID != "i-1" {
+			t.Errorf("instanceCache get err, want i-1, get %v, %v", ins, err)
+		}
+	}
+	if all, err := cache.listInstances(ctx); err != nil || len(all) != 1 {
+		t.Errorf("instanceCache listInstances err, want 1 instance, get %v, %v", all, err)
+	}
+	if listed != 1 {
+		t.Errorf("instanceCache err, want listed once, get %d", listed)
+	}
+
+	// case2
+	instances = append(instances, &cce.Node{InstanceID: "i-2", Hostname: "host-2", IP: "10.0.0.2"})
+	if ins, err := cache.getByName(ctx, "host-2"); err != nil || ins != nil || listed != 1 {
+		t.Errorf("instanceCache getByName err, want nil without listing, get %v, %v, listed %d", ins, err, listed)
+	}
+
+	// case3
+	now = now.Add(minInstanceCacheRefreshInterval)
+	if ins, err := cache.getByName(ctx, "host-2"); err != nil || ins == nil || ins.InstanceID != "i-2" || listed != 2 {
+		t.Errorf("instanceCache getByName err, want i-2 after listing, get %v, %v, listed %d", ins, err, listed)
+	}
+
+	// case4
+	now = now.Add(30 * time.Second)
+	if all, err := cache.listInstances(ctx); err != nil || len(all) != 2 || listed != 3 {
+		t.Errorf("instanceCache listInstances err, want 2 instances after listing, get %v, %v, listed %d", all, err, listed)
+	}
+
+	// case5
+	now = now.Add(30 * time.Second)
+	listErr = fmt.Errorf("list failed")
+	if _, err := cache.getByID(ctx, "i-1"); err == nil {
+		t.Errorf("instanceCache getByID err, want list error")
+	}
+	listErr = nil
+	if ins, err := cache.getByID(ctx, "i-1"); err != nil || ins == nil || listed != 5 {
+		t.Errorf("instanceCache getByID err, want i-1 after retry, get %v, %v, listed %d", ins, err, listed)
+	}
+}
+
+func TestGetInstanceByNodeNameWithCache(t *testing.T) {
+	ctx := context.Background()
+	cloud, nodesResq, err := newCluster()
+	if err != nil {
+		t.Fatalf("create cluster error, %v", err)
+	}
+	cloud.instanceCache = newInstanceCache(time.Minute, cloud.listClusterNodes)
+
+	for _, name := range []string{nodesResq.Nodes[0].Hostname, nodesResq.Nodes[0].IP} {
+		node, err := cloud.getInstanceByNodeName(ctx, types.NodeName(name))
+		if err != nil || node.InstanceID != nodesResq.Nodes[0].InstanceID {
+			t.Errorf("getInstanceByNodeName err, want %s, get %v, %v", nodesResq.Nodes[0].InstanceID, node, err)
+		}
+	}
+	if _, err := cloud.getInstanceByProviderID(ctx, nodesResq.Nodes[0].InstanceID); err != nil {
+		t.Errorf("getInstanceByProviderID err, %v", err)
+	}
+	if _, err := cloud.getInstanceByNodeName(ctx, "not-exist"); err == nil {
+		t.Errorf("getInstanceByNodeName err, want InstanceNotFound")
+	}
+}
@@ -72,23 +72,17 @@ func (bc *Baiducloud) NodeAddressesByProviderID(ctx context.Context, providerID
 		return nil, fmt.Errorf("parse ProviderID failed: %v", providerID)
 	}
 	instanceID := splitted[1]
-	var addresses []v1.NodeAddress
-	instanceResponse, err := bc.clientSet.CCEClient.ListClusterNodes(ctx, bc.ClusterID, bc.getSignOption(ctx))
+	instance, err := bc.getClusterInstanceByID(ctx, instanceID)
 	if err != nil {
 		return nil, err
 	}
-	instances := instanceResponse.Nodes
-	if len(instances) == 0 {
-		return addresses, nil
+	if instance == nil {
+		return nil, fmt.Errorf("NodeAddressesByProviderID faill, not found target providerID: %v", providerID)
 	}
-	for _, instance := range instances {
-		if instance.InstanceID == instanceID {
-			addresses = append(addresses, v1.NodeAddress{Type: v1.NodeHostName, Address: instance.IP})
-			addresses = append(addresses, v1.NodeAddress{Type: v1.NodeInternalIP, Address: instance.IP})
-			return addresses, nil
-		}
-	}
-	return nil, fmt.Errorf("NodeAddressesByProviderID faill, not found target providerID: %v", providerID)
+	return []v1.NodeAddress{
+		{Type: v1.NodeHostName, Address: instance.IP},
+		{Type: v1.NodeInternalIP, Address: instance.IP},
+	}, nil
 }
 
 // InstanceID returns the cloud provider ID of the node with the specified NodeName.
@@ -169,18 +163,14 @@ func (bc *Baiducloud) getInstanceByNodeName(ctx context.Context, name types.Node
 	if len(nameStr) == 0 {
 		return vm, fmt.Errorf("Node name: %s is nil\n ", nameStr)
 	}
-	instanceResponse, err := bc.clientSet.CCEClient.ListClusterNodes(ctx, bc.ClusterID, bc.getSignOption(ctx))
+	vm, err = bc.getClusterInstanceByName(ctx, nameStr)
 	if err != nil {
-		return vm, err
+		return nil, err
 	}
-	ins := instanceResponse.Nodes
-	for _, i := range ins {
-		// nodeName can be a ip or a hostname
-		if i.Hostname == nameStr || i.IP == nameStr {
-			return i, nil
-		}
+	if vm == nil {
+		return nil, cloudprovider.InstanceNotFound
 	}
-	return vm, cloudprovider.InstanceNotFound
+	return vm, nil
 }
 
 // Returns the instance with the providerID
@@ -194,19 +184,12 @@ func (bc *Baiducloud) getInstanceByProviderID(ctx context.Context, providerID st
 		return nil, fmt.Errorf("parse ProviderID failed: %v", providerID)
 	}
 	instanceID := splitted[1]
-	instanceResponse, err := bc.clientSet.CCEClient.ListClusterNodes(ctx, bc.ClusterID, bc.getSignOption(ctx))
+	ins, err := bc.getClusterInstanceByID(ctx, instanceID)
 	if err != nil {
 		return nil, err
 	}
-	ins := instanceResponse.Nodes
-	if len(ins) == 0 {
+	if ins == nil {
 		return nil, cloudprovider.InstanceNotFound
 	}
-	for _, i := range ins {
-		if i.InstanceID == instanceID {
-			return i, nil
-		}
-	}
-
-	return nil, cloudprovider.InstanceNotFound
+	return ins, nil
 }
@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/vpc"
+	"icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/fake"
 	cce "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/temp-cce"
 	"k8s.io/apimachinery/pkg/types"
 )
@@ -187,3 +188,25 @@ func TestNodeAddressesByProviderID(t *testing.T) {
 	}
 
 }
+
+func TestListClusterNodesPaging(t *testing.T) {
+	ctx := context.Background()
+	cloud, nodesResq, err := newCluster()
+	if err != nil {
+		t.Fatalf("create cluster error, %v", err)
+	}
+	cloud.clientSet.CCEClient.(*fake.CceFakeClient).MaxKeys = 1
+
+	instances, err := cloud.listClusterInstances(ctx)
+	if err != nil {
+		t.Fatalf("listClusterInstances err, %v", err)
+	}
+	if len(instances) != len(nodesResq.Nodes) {
+		t.Errorf("listClusterInstances err, want %d instances from all pages, get %d", len(nodesResq.Nodes), len(instances))
+	}
+	for _, node := range nodesResq.Nodes {
+		if _, err := cloud.getInstanceByProviderID(ctx, node.InstanceID); err != nil {
+			t.Errorf("getInstanceByProviderID %s err, %v", node.InstanceID, err)
+		}
+	}
+}
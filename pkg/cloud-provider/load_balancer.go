@@ -37,7 +37,7 @@ func (bc *Baiducloud) LoadBalancer() (cloudprovider.LoadBalancer, bool) {
 // Implementations must treat the *v1.Service parameter as read-only and not modify it.
 // Parameter 'clusterName' is the name of the cluster as presented to kube-controller-manager
 func (bc *Baiducloud) GetLoadBalancer(ctx context.Context, clusterName string, service *v1.Service) (status *v1.LoadBalancerStatus, exists bool, err error) {
-	ctx = context.WithValue(ctx, RequestID, GetRandom())
+	ctx = WithRequestID(ctx)
 	// workaround to support old version, can be removed if not support old version
 	lb, exist, err := bc.getServiceAssociatedBLB(ctx, clusterName, service)
 	if err != nil {
@@ -53,7 +53,7 @@ func (bc *Baiducloud) GetLoadBalancer(ctx context.Context, clusterName string, s
 	} else {
 		ip = lb.PublicIp // EIP
 	}
-	klog.V(3).Infof("[%v %v] GetLoadBalancer ip: %s", service.Namespace, service.Name, ip)
+	klog.V(3).Infof(Message(ctx, fmt.Sprintf("[%v %v] GetLoadBalancer ip: %s", service.Namespace, service.Name, ip)))
 
 	return &v1.LoadBalancerStatus{Ingress: []v1.LoadBalancerIngress{{IP: ip}}}, true, nil
 }
@@ -76,13 +76,17 @@ func (bc *Baiducloud) GetLoadBalancerName(ctx context.Context, clusterName strin
 // parameters as read-only and not modify them.
 // Parameter 'clusterName' is the name of the cluster as presented to kube-controller-manager
 func (bc *Baiducloud) EnsureLoadBalancer(ctx context.Context, clusterName string, service *v1.Service, nodes []*v1.Node) (*v1.LoadBalancerStatus, error) {
-	ctx = context.WithValue(ctx, RequestID, GetRandom())
+	ctx = WithRequestID(ctx)
 	serviceKey := fmt.Sprintf("%s/%s", service.Namespace, service.Name)
 	klog.Infof(Message(ctx, fmt.Sprintf("EnsureLoadBalancer for service %s", serviceKey)))
 	err := bc.validateService(service)
 	if err != nil {
 		return nil, err
 	}
+	if isSharedBLBService(service) {
+		sharedBLBLock.Lock()
+		defer sharedBLBLock.Unlock()
+	}
 
 	// ensure BLB
 	lb, err := bc.ensureBLB(ctx, clusterName, service)
@@ -95,6 +99,11 @@ func (bc *Baiducloud) EnsureLoadBalancer(ctx context.Context, clusterName string
 		return nil, err
 	}
 
+	err = bc.reconcileSecurityGroup(ctx, service, lb)
+	if err != nil {
+		return nil, err
+	}
+
 	err = bc.reconcileBackendServers(ctx, clusterName, service, nodes)
 	if err != nil {
 		return nil, err
@@ -124,7 +133,7 @@ func (bc *Baiducloud) EnsureLoadBalancer(ctx context.Context, clusterName string
 // parameters as read-only and not modify them.
 // Parameter 'clusterName' is the name of the cluster as presented to kube-controller-manager
 func (bc *Baiducloud) UpdateLoadBalancer(ctx context.Context, clusterName string, service *v1.Service, nodes []*v1.Node) error {
-	ctx = context.WithValue(ctx, RequestID, GetRandom())
+	ctx = WithRequestID(ctx)
 	startTime := time.Now()
 	serviceKey := fmt.Sprintf("%s/%s", service.Namespace, service.Name)
 	defer func() {
@@ -148,7 +157,11 @@ func (bc *Baiducloud) UpdateLoadBalancer(ctx context.Context, clusterName string
 // Implementations must treat the *v1.Service parameter as read-only and not modify it.
 // Parameter 'clusterName' is the name of the cluster as presented to kube-controller-manager
 func (bc *Baiducloud) EnsureLoadBalancerDeleted(ctx context.Context, clusterName string, service *v1.Service) error {
-	ctx = context.WithValue(ctx, RequestID, GetRandom())
+	ctx = WithRequestID(ctx)
+	if isSharedBLBService(service) {
+		sharedBLBLock.Lock()
+		defer sharedBLBLock.Unlock()
+	}
 	lb, exist, err := bc.getServiceAssociatedBLB(ctx, clusterName, service)
 	if err != nil {
 		return err
@@ -158,6 +171,13 @@ func (bc *Baiducloud) EnsureLoadBalancerDeleted(ctx context.Context, clusterName
 		klog.Info(Message(ctx, msg))
 	}
 
+	if exist && isSharedBLBService(service) {
+		last, err := bc.ensureSharedBLBServiceDeleted(ctx, service, lb)
+		if err != nil || !last {
+			return err
+		}
+	}
+
 	if internalIP, ok := service.Annotations[ServiceAnnotationLoadBalancerInternalVpc]; !ok || internalIP != "true" {
 		err = bc.ensureEipDeleted(ctx, service, lb)
 		if err != nil {
@@ -165,11 +185,22 @@ func (bc *Baiducloud) EnsureLoadBalancerDeleted(ctx context.Context, clusterName
 		}
 	}
 
+	err = bc.ensureSecurityGroupDeleted(ctx, service, lb)
+	if err != nil {
+		return err
+	}
+
 	if reserveLB, ok := service.Annotations[ServiceAnnotationLoadBalancerReserveLB]; !ok || reserveLB != "true" {
 		err = bc.ensureBLBDeleted(ctx, lb)
 		if err != nil {
 			return err
 		}
+	} else if exist {
+		// reserved BLB should not be collected as orphan
+		err = bc.releaseBLB(ctx, lb)
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
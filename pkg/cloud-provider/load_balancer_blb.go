@@ -27,6 +27,10 @@ func (bc *Baiducloud) ensureBLB(ctx context.Context, clusterName string, service
 		return lb, nil
 	}
 
+	if sharedID := service.Annotations[ServiceAnnotationLoadBalancerSharedID]; sharedID != "" {
+		return nil, fmt.Errorf("shared BLB %s for service %s not exist", sharedID, serviceKey)
+	}
+
 	klog.Info(Message(ctx, fmt.Sprintf("BLB for service %s not exist, need create one", serviceKey)))
 	lbId, err := bc.createBLB(ctx, service)
 	if err != nil || lbId == "" {
@@ -35,10 +39,21 @@ func (bc *Baiducloud) ensureBLB(ctx context.Context, clusterName string, service
 		return nil, fmt.Errorf(msg)
 	}
 
-	time.Sleep(6 * time.Second)
-	lb, exist, err = bc.getBLBByID(ctx, lbId)
-	if err != nil || !exist {
-		msg := fmt.Sprintf("check blb %s for service %s exist failed, exist is %v, err is %v", lbId, serviceKey, exist, err)
+	err = waitForStatus(ctx, fmt.Sprintf("blb %s to be available", lbId), defaultWaitBackoff, defaultWaitTimeout, func(ctx context.Context) (bool, error) {
+		// newly created BLB may not be visible at once, getBLBByID returns error in that case
+		lb, exist, err = bc.getBLBByID(ctx, lbId)
+		if err != nil || !exist {
+			klog.Infof(Message(ctx, fmt.Sprintf("blb %s for service %s not found yet, wait...: %v", lbId, serviceKey, err)))
+			return false, nil
+		}
+		if lb.Status != blbStatusAvailable {
+			klog.Infof(Message(ctx, fmt.Sprintf("blb %s for service %s status is %s, not available, wait...", lbId, serviceKey, lb.Status)))
+			return false, nil
+		}
+		return true, nil
+	})
+	if err != nil {
+		msg := fmt.Sprintf("check blb %s for service %s exist failed: %v", lbId, serviceKey, err)
 		klog.Error(Message(ctx, msg))
 		return nil, fmt.Errorf(msg)
 	}
@@ -72,7 +87,7 @@ func (bc *Baiducloud) createBLB(ctx context.Context, service *v1.Service) (strin
 		Name:        blbName,
 		VpcID:       vpcID,
 		SubnetID:    subnetID,
-		Desc:        "auto generated by cce:" + bc.ClusterID,
+		Desc:        getBlbDesc(bc.ClusterID, service),
 		AllocateVIP: allocateVip,
 	}
 	klog.Infof(Message(ctx, fmt.Sprintf("create blb for service %s args: %v", serviceKey, args)))
@@ -94,6 +109,11 @@ func (bc *Baiducloud) getServiceAssociatedBLB(ctx context.Context, clusterName s
 	ID := result.LoadBalancerID
 	autoAddID := result.CceAutoAddLoadBalancerID
 	existID := result.LoadBalancerExistID
+	if result.LoadBalancerSharedID != "" {
+		// shared BLB is never created by service, so other ways are not tried
+		klog.Infof(Message(ctx, fmt.Sprintf("shared BLB ID %s is set in annotation", result.LoadBalancerSharedID)))
+		return bc.getBLBByID(ctx, result.LoadBalancerSharedID)
+	}
 	if ID != "" {
 		klog.Infof(Message(ctx, fmt.Sprintf("BLB ID %s is set in annotation", existID)))
 		lb, exist, err := bc.getBLBByID(ctx, ID)
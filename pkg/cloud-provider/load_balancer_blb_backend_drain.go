@@ -0,0 +1,252 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloud_provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/blb"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog"
+)
+
+// maxRsDrainGracePeriod is the max seconds to drain backend servers
+const maxRsDrainGracePeriod = 3600
+
+// getRsDrainGracePeriod returns drain grace period of service, annotation takes precedence over cloud config
+func (bc *Baiducloud) getRsDrainGracePeriod(anno *ServiceAnnotation) time.Duration {
+	if anno.LoadBalancerRsDrainGracePeriod != nil {
+		return time.Duration(*anno.LoadBalancerRsDrainGracePeriod) * time.Second
+	}
+	return time.Duration(bc.RsDrainGracePeriod) * time.Second
+}
+
+// drainBackendServers sets weight of backend servers to delete to 0, and returns the ones whose grace period is over.
+// Removal deadlines are recorded in service annotation, so draining survives CCM restarts.
+// Backend servers are removed on the first reconcile after deadline, which is scheduled at the earliest deadline.
+func (bc *Baiducloud) drainBackendServers(ctx context.Context, service *v1.Service, lb *blb.LoadBalancer,
+	existingBackends, rsToDel []blb.BackendServer, gracePeriod time.Duration) ([]blb.BackendServer, error) {
+
+	serviceKey := fmt.Sprintf("%s/%s", service.Namespace, service.Name)
+	draining, err := getDrainingBackends(service)
+	if err != nil {
+		klog.Warningf(Message(ctx, fmt.Sprintf("service %s has invalid draining rs, ignore it: %v", serviceKey, err)))
+		draining = nil
+	}
+	existingWeight := make(map[string]int, len(existingBackends))
+	for _, rs := range existingBackends {
+		existingWeight[rs.InstanceId] = rs.Weight
+	}
+
+	now := time.Now()
+	stillDraining := make(map[string]time.Time)
+	var rsToRemove, rsToZero []blb.BackendServer
+	for _, rs := range rsToDel {
+		deadline, ok := draining[rs.InstanceId]
+		if !ok {
+			deadline = now.Add(gracePeriod)
+		}
+		// backend servers not in BLB any more, e.g. instance deleted, need no draining
+		weight, exist := existingWeight[rs.InstanceId]
+		if !exist || !now.Before(deadline) {
+			rsToRemove = append(rsToRemove, rs)
+			continue
+		}
+		stillDraining[rs.InstanceId] = deadline
+		if weight != 0 {
+			rsToZero = append(rsToZero, blb.BackendServer{InstanceId: rs.InstanceId, Weight: 0})
+		}
+	}
+
+	if len(rsToZero) > 0 {
+		klog.Infof(Message(ctx, fmt.Sprintf("drain nodes %v in BLB %s for service %s", rsToZero, lb.BlbId, serviceKey)))
+		args := blb.UpdateBackendServersArgs{
+			LoadBalancerId:    lb.BlbId,
+			BackendServerList: rsToZero,
+		}
+		err = bc.clientSet.BLBClient.UpdateBackendServers(ctx, &args, bc.getSignOption(ctx))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !isSameDraining(draining, stillDraining) {
+		err = bc.patchDrainingBackends(ctx, service, stillDraining)
+		if err != nil {
+			return nil, err
+		}
+	}
+	bc.enqueueDrainDeadline(ctx, service, stillDraining, now)
+	return rsToRemove, nil
+}
+
+// removeDrainedBackends removes the draining backend servers whose deadline is over, without reconciling other
+// backend servers. It is used when service is requeued at drain deadline, and backend servers follow the nodes known
+// only by service controller.
+// Backend servers whose weight is restored are not draining any more, they are left to the next reconcile.
+func (bc *Baiducloud) removeDrainedBackends(ctx context.Context, service *v1.Service) error {
+	serviceKey := fmt.Sprintf("%s/%s", service.Namespace, service.Name)
+	draining, err := getDrainingBackends(service)
+	if err != nil {
+		klog.Warningf(Message(ctx, fmt.Sprintf("service %s has invalid draining rs, ignore it: %v", serviceKey, err)))
+		return nil
+	}
+	if len(draining) == 0 {
+		return nil
+	}
+	lb, exist, err := bc.getServiceAssociatedBLB(ctx, bc.ClusterName, service)
+	if err != nil {
+		return err
+	}
+	if !exist {
+		return nil
+	}
+	existingBackends, err := bc.getAllBackendServer(ctx, lb)
+	if err != nil {
+		return err
+	}
+	existingWeight := make(map[string]int, len(existingBackends))
+	for _, rs := range existingBackends {
+		existingWeight[rs.InstanceId] = rs.Weight
+	}
+
+	now := time.Now()
+	stillDraining := make(map[string]time.Time)
+	var delList []string
+	for insID, deadline := range draining {
+		weight, exist := existingWeight[insID]
+		if !exist {
+			continue
+		}
+		if now.Before(deadline) {
+			stillDraining[insID] = deadline
+			continue
+		}
+		if weight == 0 {
+			delList = append(delList, insID)
+		}
+	}
+
+	if len(delList) > 0 {
+		klog.Infof(Message(ctx, fmt.Sprintf("remove drained nodes %v from BLB %s for service %s", delList, lb.BlbId, serviceKey)))
+		args := blb.RemoveBackendServersArgs{
+			LoadBalancerId:    lb.BlbId,
+			BackendServerList: delList,
+		}
+		err = bc.clientSet.BLBClient.RemoveBackendServers(ctx, &args, bc.getSignOption(ctx))
+		if err != nil {
+			return err
+		}
+	}
+	if !isSameDraining(draining, stillDraining) {
+		err = bc.patchDrainingBackends(ctx, service, stillDraining)
+		if err != nil {
+			return err
+		}
+	}
+	bc.enqueueDrainDeadline(ctx, service, stillDraining, now)
+	return nil
+}
+
+// enqueueDrainDeadline requeues service at the earliest removal deadline of draining backend servers, as nothing else
+// reconciles service if neither service nor nodes change
+func (bc *Baiducloud) enqueueDrainDeadline(ctx context.Context, service *v1.Service, draining map[string]time.Time, now time.Time) {
+	if bc.svcQueue == nil || len(draining) == 0 {
+		return
+	}
+	var earliest time.Time
+	for _, deadline := range draining {
+		if earliest.IsZero() || deadline.Before(earliest) {
+			earliest = deadline
+		}
+	}
+	key, err := cache.MetaNamespaceKeyFunc(service)
+	if err != nil {
+		klog.Errorf(Message(ctx, fmt.Sprintf("get key of service %s/%s failed: %v", service.Namespace, service.Name, err)))
+		return
+	}
+	klog.V(3).Infof(Message(ctx, fmt.Sprintf("requeue service %s to remove drained rs after %v", key, earliest.Sub(now))))
+	bc.svcQueue.AddAfter(key, earliest.Sub(now))
+}
+
+// getDrainingBackends returns draining backend servers and their removal deadline recorded in service annotation
+func getDrainingBackends(service *v1.Service) (map[string]time.Time, error) {
+	value, ok := service.Annotations[ServiceAnnotationLoadBalancerDrainingRs]
+	if !ok || value == "" {
+		return nil, nil
+	}
+	raw := make(map[string]string)
+	err := json.Unmarshal([]byte(value), &raw)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]time.Time, len(raw))
+	for insID, deadline := range raw {
+		t, err := time.Parse(time.RFC3339, deadline)
+		if err != nil {
+			return nil, err
+		}
+		result[insID] = t
+	}
+	return result, nil
+}
+
+// patchDrainingBackends records draining backend servers in service annotation, the annotation is removed if nothing is draining
+func (bc *Baiducloud) patchDrainingBackends(ctx context.Context, service *v1.Service, draining map[string]time.Time) error {
+	var value interface{}
+	if len(draining) > 0 {
+		raw := make(map[string]string, len(draining))
+		for insID, deadline := range draining {
+			raw[insID] = deadline.UTC().Format(time.RFC3339)
+		}
+		b, err := json.Marshal(raw)
+		if err != nil {
+			return err
+		}
+		value = string(b)
+	}
+	data, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{
+				ServiceAnnotationLoadBalancerDrainingRs: value,
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	klog.Infof(Message(ctx, fmt.Sprintf("patch draining rs of service %s/%s: %s", service.Namespace, service.Name, data)))
+	_, err = bc.kubeClient.CoreV1().Services(service.Namespace).Patch(service.Name, types.StrategicMergePatchType, data)
+	return err
+}
+
+func isSameDraining(a, b map[string]time.Time) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for insID, deadline := range a {
+		if d, ok := b[insID]; !ok || !d.Equal(deadline) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,175 @@
+package cloud_provider
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/blb"
+	api "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// case1: node leaves, its weight is set to 0 and it is recorded as draining
+// case2: reconcile again before deadline, nothing changes
+// case3: CCM restarts and deadline is over, node is removed and record is cleared
+// case4: grace period is 0, node is removed at once
+func TestReconcileBackendServersDrain(t *testing.T) {
+	cloud, nodesRes, blbRes, err := beforeTestBackend()
+	if err != nil {
+		t.Errorf("beforeTestBackend err, err: %v", err)
+	}
+	ctx := context.Background()
+	svc := buildService()
+	svc.SetAnnotations(map[string]string{ServiceAnnotationLoadBalancerRsDrainGracePeriod: "60"})
+	_, err = cloud.kubeClient.CoreV1().Services(svc.Namespace).Create(svc)
+	if err != nil {
+		t.Errorf("create service err, err: %v", err)
+	}
+	ins0 := nodesRes.Nodes[0].InstanceID
+	ins1 := nodesRes.Nodes[1].InstanceID
+	nodes := []*api.Node{
+		&api.Node{
+			Spec: api.NodeSpec{
+				ProviderID: "test//" + ins0,
+			},
+		},
+		&api.Node{
+			Spec: api.NodeSpec{
+				ProviderID: "test//" + ins1,
+			},
+		},
+	}
+	err = cloud.reconcileBackendServers(ctx, cloud.ClusterName, svc, nodes)
+	if err != nil {
+		t.Errorf("reconcileBackendServers err, err: %v", err)
+	}
+
+	// case1
+	err = cloud.reconcileBackendServers(ctx, cloud.ClusterName, svc, nodes[:1])
+	if err != nil {
+		t.Errorf("reconcileBackendServers err, err: %v", err)
+	}
+	checkBackendWeight(t, cloud, blbRes.LoadBalancerId, map[string]int{ins0: defaultBLBRSWeight, ins1: 0})
+	svc, err = cloud.kubeClient.CoreV1().Services(svc.Namespace).Get(svc.Name, meta_v1.GetOptions{})
+	if err != nil {
+		t.Errorf("get service err, err: %v", err)
+	}
+	draining, err := getDrainingBackends(svc)
+	if err != nil {
+		t.Errorf("getDrainingBackends err, err: %v", err)
+	}
+	if _, ok := draining[ins1]; !ok || len(draining) != 1 {
+		t.Errorf("getDrainingBackends err, want %s get %v", ins1, draining)
+	}
+
+	// case2
+	err = cloud.reconcileBackendServers(ctx, cloud.ClusterName, svc, nodes[:1])
+	if err != nil {
+		t.Errorf("reconcileBackendServers err, err: %v", err)
+	}
+	checkBackendWeight(t, cloud, blbRes.LoadBalancerId, map[string]int{ins0: defaultBLBRSWeight, ins1: 0})
+
+	// case3
+	b, _ := json.Marshal(map[string]string{ins1: time.Now().Add(-time.Second).UTC().Format(time.RFC3339)})
+	svc.Annotations[ServiceAnnotationLoadBalancerDrainingRs] = string(b)
+	err = cloud.reconcileBackendServers(ctx, cloud.ClusterName, svc, nodes[:1])
+	if err != nil {
+		t.Errorf("reconcileBackendServers err, err: %v", err)
+	}
+	checkBackendWeight(t, cloud, blbRes.LoadBalancerId, map[string]int{ins0: defaultBLBRSWeight})
+	svc, err = cloud.kubeClient.CoreV1().Services(svc.Namespace).Get(svc.Name, meta_v1.GetOptions{})
+	if err != nil {
+		t.Errorf("get service err, err: %v", err)
+	}
+	if _, ok := svc.Annotations[ServiceAnnotationLoadBalancerDrainingRs]; ok {
+		t.Errorf("draining rs should be cleared, get %v", svc.Annotations)
+	}
+
+	// case4
+	svc.Annotations[ServiceAnnotationLoadBalancerRsDrainGracePeriod] = "0"
+	err = cloud.reconcileBackendServers(ctx, cloud.ClusterName, svc, nodes)
+	if err != nil {
+		t.Errorf("reconcileBackendServers err, err: %v", err)
+	}
+	err = cloud.reconcileBackendServers(ctx, cloud.ClusterName, svc, nodes[1:])
+	if err != nil {
+		t.Errorf("reconcileBackendServers err, err: %v", err)
+	}
+	checkBackendWeight(t, cloud, blbRes.LoadBalancerId, map[string]int{ins1: defaultBLBRSWeight})
+}
+
+// case1: node leaves, service is requeued at drain deadline
+// case2: requeued service removes the drained node and clears the record
+func TestRemoveDrainedBackendsAtDeadline(t *testing.T) {
+	cloud, nodesRes, blbRes, err := beforeTestBackend()
+	if err != nil {
+		t.Errorf("beforeTestBackend err, err: %v", err)
+	}
+	cloud.svcQueue = workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "endpoints")
+	ctx := context.Background()
+	svc := buildService()
+	svc.SetAnnotations(map[string]string{ServiceAnnotationLoadBalancerRsDrainGracePeriod: "1"})
+	_, err = cloud.kubeClient.CoreV1().Services(svc.Namespace).Create(svc)
+	if err != nil {
+		t.Errorf("create service err, err: %v", err)
+	}
+	ins0 := nodesRes.Nodes[0].InstanceID
+	ins1 := nodesRes.Nodes[1].InstanceID
+	nodes := []*api.Node{
+		{Spec: api.NodeSpec{ProviderID: "test//" + ins0}},
+		{Spec: api.NodeSpec{ProviderID: "test//" + ins1}},
+	}
+	err = cloud.reconcileBackendServers(ctx, cloud.ClusterName, svc, nodes)
+	if err != nil {
+		t.Errorf("reconcileBackendServers err, err: %v", err)
+	}
+
+	// case1
+	err = cloud.reconcileBackendServers(ctx, cloud.ClusterName, svc, nodes[:1])
+	if err != nil {
+		t.Errorf("reconcileBackendServers err, err: %v", err)
+	}
+	checkBackendWeight(t, cloud, blbRes.LoadBalancerId, map[string]int{ins0: defaultBLBRSWeight, ins1: 0})
+	if n := cloud.svcQueue.Len(); n != 0 {
+		t.Errorf("svcQueue err, want service requeued after deadline, get %d keys before deadline", n)
+	}
+
+	// case2
+	done := make(chan struct{})
+	go func() {
+		cloud.processNextService()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("svcQueue err, want service requeued at drain deadline")
+	}
+	checkBackendWeight(t, cloud, blbRes.LoadBalancerId, map[string]int{ins0: defaultBLBRSWeight})
+	svc, err = cloud.kubeClient.CoreV1().Services(svc.Namespace).Get(svc.Name, meta_v1.GetOptions{})
+	if err != nil {
+		t.Errorf("get service err, err: %v", err)
+	}
+	if _, ok := svc.Annotations[ServiceAnnotationLoadBalancerDrainingRs]; ok {
+		t.Errorf("draining rs should be cleared, get %v", svc.Annotations)
+	}
+	cloud.svcQueue.ShutDown()
+}
+
+func TestDrainBackendServersRestoreWeight(t *testing.T) {
+	existing := []blb.BackendServer{
+		{InstanceId: "1", Weight: 0},
+		{InstanceId: "2", Weight: 100},
+	}
+	candidates := []blb.BackendServer{
+		{InstanceId: "1"},
+		{InstanceId: "2"},
+	}
+	rsToUpdate := getBackendsToUpdate(candidates, existing, nil)
+	if len(rsToUpdate) != 1 || rsToUpdate[0].InstanceId != "1" || rsToUpdate[0].Weight != defaultBLBRSWeight {
+		t.Errorf("getBackendsToUpdate err, want 1 with weight %d get %v", defaultBLBRSWeight, rsToUpdate)
+	}
+}
@@ -0,0 +1,290 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloud_provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/blb"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/klog"
+
+	blbext "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/temp-blb"
+)
+
+const (
+	// backendTypeNodePort registers nodes as backends, traffic goes to NodePort and then kube-proxy
+	backendTypeNodePort = "nodeport"
+	// backendTypePod registers ready pod IPs as backends, traffic goes to targetPort of pods directly
+	backendTypePod = "pod"
+)
+
+// isPodBackendService checks whether ready pods of service are registered as BLB backends directly
+func isPodBackendService(service *v1.Service) bool {
+	return service.Annotations[ServiceAnnotationLoadBalancerBackendType] == backendTypePod
+}
+
+// getServiceEndpoints returns endpoints of service, nil if not exist
+func (bc *Baiducloud) getServiceEndpoints(service *v1.Service) (*v1.Endpoints, error) {
+	ep, err := bc.getEndpoints(service.Namespace, service.Name)
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ep, nil
+}
+
+// getPodBackendIPs returns the sorted IPs of ready endpoints in all subsets
+func getPodBackendIPs(ep *v1.Endpoints) []string {
+	if ep == nil {
+		return nil
+	}
+	ipSet := make(map[string]bool)
+	for _, subset := range ep.Subsets {
+		for _, addr := range subset.Addresses {
+			ipSet[addr.IP] = true
+		}
+	}
+	ips := make([]string, 0, len(ipSet))
+	for ip := range ipSet {
+		ips = append(ips, ip)
+	}
+	sort.Strings(ips)
+	return ips
+}
+
+// getPodBackendPorts returns the sorted "name/protocol/port" of endpoints in all subsets
+func getPodBackendPorts(ep *v1.Endpoints) []string {
+	if ep == nil {
+		return nil
+	}
+	portSet := make(map[string]bool)
+	for _, subset := range ep.Subsets {
+		for _, port := range subset.Ports {
+			portSet[fmt.Sprintf("%s/%s/%d", port.Name, port.Protocol, port.Port)] = true
+		}
+	}
+	ports := make([]string, 0, len(portSet))
+	for port := range portSet {
+		ports = append(ports, port)
+	}
+	sort.Strings(ports)
+	return ports
+}
+
+// resolveTargetPort returns the port of pods which servicePort targets. Named targetPort is resolved by endpoints,
+// and must be the same number on all pods, since a listener has only one backend port.
+func resolveTargetPort(servicePort v1.ServicePort, ep *v1.Endpoints) (int, error) {
+	if servicePort.TargetPort.Type == intstr.Int {
+		if servicePort.TargetPort.IntVal == 0 {
+			return int(servicePort.Port), nil
+		}
+		return servicePort.TargetPort.IntValue(), nil
+	}
+	ports := make(map[int32]bool)
+	if ep != nil {
+		for _, subset := range ep.Subsets {
+			for _, port := range subset.Ports {
+				if port.Name == servicePort.Name && port.Protocol == servicePort.Protocol {
+					ports[port.Port] = true
+				}
+			}
+		}
+	}
+	if len(ports) != 1 {
+		return 0, fmt.Errorf("named targetPort %s of port %d/%s must resolve to exactly one port by endpoints, get %d",
+			servicePort.TargetPort.StrVal, servicePort.Port, servicePort.Protocol, len(ports))
+	}
+	for port := range ports {
+		return int(port), nil
+	}
+	return 0, nil
+}
+
+// setPodBackendPorts sets backend port of expected listeners to targetPort of pods
+func (bc *Baiducloud) setPodBackendPorts(ctx context.Context, service *v1.Service, expected map[listenerKey]PortListener) error {
+	ep, err := bc.getServiceEndpoints(service)
+	if err != nil {
+		return err
+	}
+	for _, servicePort := range service.Spec.Ports {
+		key := newListenerKey(string(servicePort.Protocol), int(servicePort.Port))
+		pl, ok := expected[key]
+		if !ok {
+			continue
+		}
+		port, err := resolveTargetPort(servicePort, ep)
+		if err != nil {
+			msg := fmt.Sprintf("resolve backend port of service %s/%s failed: %v", service.Namespace, service.Name, err)
+			if bc.eventRecorder != nil {
+				Eventf(ctx, bc.eventRecorder, service, v1.EventTypeWarning, "InvalidPodBackendPort", msg)
+			}
+			return fmt.Errorf(msg)
+		}
+		pl.NodePort = int32(port)
+		expected[key] = pl
+	}
+	klog.V(4).Infof(Message(ctx, fmt.Sprintf("service %s/%s uses pod backends, listeners: %v", service.Namespace, service.Name, expected)))
+	return nil
+}
+
+// isBackendTypeSettled checks whether backends of the other type than backendType are removed from BLB.
+// It is kept in memory, so that backends of the other type are only looked up when backend type of service changes,
+// or once after CCM starts.
+func (bc *Baiducloud) isBackendTypeSettled(lb *blb.LoadBalancer, backendType string) bool {
+	value, ok := bc.blbBackendTypes.Load(lb.BlbId)
+	return ok && value.(string) == backendType
+}
+
+// reconcilePodBackends registers ready pod IPs of service as BLB backends, node backends left by nodeport mode are
+// removed after pod IPs are registered
+func (bc *Baiducloud) reconcilePodBackends(ctx context.Context, service *v1.Service, lb *blb.LoadBalancer) error {
+	serviceKey := fmt.Sprintf("%s/%s", service.Namespace, service.Name)
+	anno, err := ExtractServiceAnnotation(service)
+	if err != nil {
+		return fmt.Errorf("failed to ExtractServiceAnnotation %s, err: %v", service.Name, err)
+	}
+
+	ep, err := bc.getServiceEndpoints(service)
+	if err != nil {
+		return err
+	}
+	var candidateBackends []blb.BackendServer
+	for _, ip := range getPodBackendIPs(ep) {
+		candidateBackends = append(candidateBackends, blb.BackendServer{
+			InstanceId: ip,
+			Weight:     defaultBLBRSWeight,
+		})
+	}
+	existing, err := bc.clientSet.BLBClient.DescribeBackendIPs(ctx, &blbext.DescribeBackendIPsArgs{
+		LoadBalancerId: lb.BlbId,
+	}, bc.getSignOption(ctx))
+	if err != nil {
+		return err
+	}
+	var existingBackends []blb.BackendServer
+	for _, b := range existing {
+		existingBackends = append(existingBackends, blb.BackendServer{
+			InstanceId: b.IP,
+			Weight:     b.Weight,
+		})
+	}
+
+	var rsToAdd, rsToDel []blb.BackendServer
+	if len(candidateBackends) == 0 {
+		// pods may be all not ready for a while, e.g. in a rollout, so backends are kept unless asked for draining
+		if anno.LoadBalancerZeroEndpointsPolicy != zeroEndpointsPolicyDrain {
+			msg := fmt.Sprintf("service %s has no ready pods, keep the last known backend IPs", serviceKey)
+			Eventf(ctx, bc.eventRecorder, service, v1.EventTypeNormal, "ZeroEndpoints", msg)
+			klog.Infof(Message(ctx, msg))
+			return nil
+		}
+		msg := fmt.Sprintf("service %s has no ready pods, remove all backend IPs", serviceKey)
+		Eventf(ctx, bc.eventRecorder, service, v1.EventTypeWarning, "ZeroEndpoints", msg)
+		klog.Infof(Message(ctx, msg))
+		rsToDel = existingBackends
+	} else {
+		targetRsNum := blbMaxRSNum
+		if anno.LoadBalancerRsMaxNum > 0 {
+			targetRsNum = anno.LoadBalancerRsMaxNum
+		}
+		if len(candidateBackends) < targetRsNum {
+			targetRsNum = len(candidateBackends)
+		}
+		rsToAdd, rsToDel, err = mergeBackend(candidateBackends, existingBackends, targetRsNum, nil)
+		if err != nil {
+			return err
+		}
+	}
+	klog.Infof(Message(ctx, fmt.Sprintf("find pods %v to add to BLB %s for service %s", rsToAdd, lb.BlbId, serviceKey)))
+	klog.Infof(Message(ctx, fmt.Sprintf("find pods %v to del from BLB %s for service %s", rsToDel, lb.BlbId, serviceKey)))
+
+	if len(rsToAdd) > 0 {
+		var addList []blbext.BackendIP
+		for _, rs := range rsToAdd {
+			addList = append(addList, blbext.BackendIP{
+				IP:     rs.InstanceId,
+				Weight: rs.Weight,
+			})
+		}
+		err = bc.clientSet.BLBClient.AddBackendIPs(ctx, &blbext.AddBackendIPsArgs{
+			LoadBalancerId: lb.BlbId,
+			BackendIPList:  addList,
+		}, bc.getSignOption(ctx))
+		if err != nil {
+			return err
+		}
+	}
+	if len(rsToDel) > 0 {
+		var delList []string
+		for _, rs := range rsToDel {
+			delList = append(delList, rs.InstanceId)
+		}
+		err = bc.clientSet.BLBClient.RemoveBackendIPs(ctx, &blbext.RemoveBackendIPsArgs{
+			LoadBalancerId: lb.BlbId,
+			BackendIPList:  delList,
+		}, bc.getSignOption(ctx))
+		if err != nil {
+			return err
+		}
+	}
+
+	// node backends are kept until pods are ready, so that switching from nodeport mode does not break traffic
+	if len(candidateBackends) > 0 && !bc.isBackendTypeSettled(lb, backendTypePod) {
+		err = bc.deleteAllBackendServers(ctx, lb)
+		if err != nil {
+			return err
+		}
+		bc.blbBackendTypes.Store(lb.BlbId, backendTypePod)
+	}
+	return nil
+}
+
+// deleteAllBackendIPs removes pod backends left by pod mode
+func (bc *Baiducloud) deleteAllBackendIPs(ctx context.Context, lb *blb.LoadBalancer) error {
+	existing, err := bc.clientSet.BLBClient.DescribeBackendIPs(ctx, &blbext.DescribeBackendIPsArgs{
+		LoadBalancerId: lb.BlbId,
+	}, bc.getSignOption(ctx))
+	if err != nil {
+		return err
+	}
+	if len(existing) == 0 {
+		return nil
+	}
+	var delList []string
+	for _, b := range existing {
+		delList = append(delList, b.IP)
+	}
+	klog.Infof(Message(ctx, fmt.Sprintf("remove pod backends %v from BLB %s", delList, lb.BlbId)))
+	return bc.clientSet.BLBClient.RemoveBackendIPs(ctx, &blbext.RemoveBackendIPsArgs{
+		LoadBalancerId: lb.BlbId,
+		BackendIPList:  delList,
+	}, bc.getSignOption(ctx))
+}
+
+// podBackendsChanged checks whether ready pods or ports of endpoints change
+func podBackendsChanged(old, cur *v1.Endpoints) bool {
+	return strings.Join(getPodBackendIPs(old), ",") != strings.Join(getPodBackendIPs(cur), ",") ||
+		strings.Join(getPodBackendPorts(old), ",") != strings.Join(getPodBackendPorts(cur), ",")
+}
@@ -0,0 +1,301 @@
+package cloud_provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/blb"
+	api "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/fake"
+)
+
+func buildPodBackendEndpoints(ips ...string) *api.Endpoints {
+	ep := &api.Endpoints{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+		},
+	}
+	// every pod in its own subset, as endpoints controller does for pods with different port numbers
+	for _, ip := range ips {
+		ep.Subsets = append(ep.Subsets, api.EndpointSubset{
+			Addresses: []api.EndpointAddress{{IP: ip}},
+			Ports:     []api.EndpointPort{{Name: "http", Port: 8080, Protocol: "TCP"}},
+		})
+	}
+	return ep
+}
+
+func backendIPs(cloud *Baiducloud, blbID string) []string {
+	blbClient := cloud.clientSet.BLBClient.(*fake.BlbFakeClient)
+	var ips []string
+	for _, b := range blbClient.BackendIPMap[blbID] {
+		ips = append(ips, b.IP)
+	}
+	sort.Strings(ips)
+	return ips
+}
+
+// case1: listener backend port is targetPort of pods, resolved by endpoints for named targetPort
+// case2: ready pods in all subsets are registered as backends, node backends are removed
+// case3: pod backends follow endpoints
+// case4: pod backends are kept when no pods are ready, and removed if zero-endpoints-policy is drain
+// case5: switching back to nodeport removes pod backends
+func TestReconcilePodBackends(t *testing.T) {
+	cloud, nodesRes, resp, err := beforeTestBackend()
+	if err != nil {
+		t.Fatalf("beforeTestBackend err, err: %v", err)
+	}
+	ctx := context.Background()
+	lb := &blb.LoadBalancer{BlbId: resp.LoadBalancerId}
+	svc := &api.Service{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+			Annotations: map[string]string{
+				ServiceAnnotationCceAutoAddLoadBalancerID: resp.LoadBalancerId,
+				ServiceAnnotationLoadBalancerBackendType:  backendTypePod,
+			},
+		},
+		Spec: api.ServiceSpec{
+			Type: api.ServiceTypeLoadBalancer,
+			Ports: []api.ServicePort{
+				{
+					Name:       "http",
+					Port:       80,
+					Protocol:   "TCP",
+					NodePort:   30080,
+					TargetPort: intstr.FromString("http"),
+				},
+				{
+					Name:       "dns",
+					Port:       53,
+					Protocol:   "UDP",
+					NodePort:   30053,
+					TargetPort: intstr.FromInt(5353),
+				},
+			},
+		},
+	}
+	nodes := []*api.Node{
+		{
+			Spec: api.NodeSpec{
+				ProviderID: "test//" + nodesRes.Nodes[0].InstanceID,
+			},
+		},
+	}
+	if err := cloud.reconcileBackendServers(ctx, cloud.ClusterName, buildService(), nodes); err != nil {
+		t.Fatalf("reconcileBackendServers err, err: %v", err)
+	}
+
+	// case1
+	if err := cloud.reconcileListeners(ctx, cloud.ClusterName, svc); err == nil {
+		t.Errorf("reconcileListeners err, want error for named targetPort without endpoints")
+	}
+	_, err = cloud.kubeClient.CoreV1().Endpoints(api.NamespaceDefault).Create(buildPodBackendEndpoints("172.16.0.1", "172.16.0.2"))
+	if err != nil {
+		t.Fatalf("create endpoints err: %v", err)
+	}
+	if err := cloud.reconcileListeners(ctx, cloud.ClusterName, svc); err != nil {
+		t.Fatalf("reconcileListeners err, err: %v", err)
+	}
+	all, err := cloud.getAllListeners(ctx, lb)
+	if err != nil {
+		t.Fatalf("getAllListeners err, err: %v", err)
+	}
+	for _, l := range all {
+		if l.Port == 80 && l.NodePort != 8080 || l.Port == 53 && l.NodePort != 5353 {
+			t.Errorf("reconcileListeners err, want backend port of pods, get %v", l)
+		}
+	}
+
+	// case2
+	if err := cloud.reconcileBackendServers(ctx, cloud.ClusterName, svc, nodes); err != nil {
+		t.Fatalf("reconcileBackendServers err, err: %v", err)
+	}
+	if ips := backendIPs(cloud, resp.LoadBalancerId); len(ips) != 2 || ips[0] != "172.16.0.1" || ips[1] != "172.16.0.2" {
+		t.Errorf("reconcileBackendServers err, want pod backends [172.16.0.1 172.16.0.2], get %v", ips)
+	}
+	if rs, _ := cloud.getAllBackendServer(ctx, lb); len(rs) != 0 {
+		t.Errorf("reconcileBackendServers err, want node backends removed, get %v", rs)
+	}
+
+	// case3
+	_, err = cloud.kubeClient.CoreV1().Endpoints(api.NamespaceDefault).Update(buildPodBackendEndpoints("172.16.0.2", "172.16.0.3"))
+	if err != nil {
+		t.Fatalf("update endpoints err: %v", err)
+	}
+	if err := cloud.reconcileBackendServers(ctx, cloud.ClusterName, svc, nodes); err != nil {
+		t.Fatalf("reconcileBackendServers err, err: %v", err)
+	}
+	if ips := backendIPs(cloud, resp.LoadBalancerId); len(ips) != 2 || ips[0] != "172.16.0.2" || ips[1] != "172.16.0.3" {
+		t.Errorf("reconcileBackendServers err, want pod backends [172.16.0.2 172.16.0.3], get %v", ips)
+	}
+
+	// case4
+	_, err = cloud.kubeClient.CoreV1().Endpoints(api.NamespaceDefault).Update(buildPodBackendEndpoints())
+	if err != nil {
+		t.Fatalf("update endpoints err: %v", err)
+	}
+	if err := cloud.reconcileBackendServers(ctx, cloud.ClusterName, svc, nodes); err != nil {
+		t.Fatalf("reconcileBackendServers err, err: %v", err)
+	}
+	if ips := backendIPs(cloud, resp.LoadBalancerId); len(ips) != 2 {
+		t.Errorf("case4: reconcileBackendServers err, want pod backends kept without ready pods, get %v", ips)
+	}
+	svc.Annotations[ServiceAnnotationLoadBalancerZeroEndpointsPolicy] = zeroEndpointsPolicyDrain
+	if err := cloud.reconcileBackendServers(ctx, cloud.ClusterName, svc, nodes); err != nil {
+		t.Fatalf("reconcileBackendServers err, err: %v", err)
+	}
+	if ips := backendIPs(cloud, resp.LoadBalancerId); len(ips) != 0 {
+		t.Errorf("case4: reconcileBackendServers err, want pod backends removed by drain policy, get %v", ips)
+	}
+	delete(svc.Annotations, ServiceAnnotationLoadBalancerZeroEndpointsPolicy)
+	_, err = cloud.kubeClient.CoreV1().Endpoints(api.NamespaceDefault).Update(buildPodBackendEndpoints("172.16.0.2", "172.16.0.3"))
+	if err != nil {
+		t.Fatalf("update endpoints err: %v", err)
+	}
+	if err := cloud.reconcileBackendServers(ctx, cloud.ClusterName, svc, nodes); err != nil {
+		t.Fatalf("reconcileBackendServers err, err: %v", err)
+	}
+
+	// case5
+	delete(svc.Annotations, ServiceAnnotationLoadBalancerBackendType)
+	if err := cloud.reconcileBackendServers(ctx, cloud.ClusterName, svc, nodes); err != nil {
+		t.Fatalf("reconcileBackendServers err, err: %v", err)
+	}
+	if ips := backendIPs(cloud, resp.LoadBalancerId); len(ips) != 0 {
+		t.Errorf("reconcileBackendServers err, want pod backends removed, get %v", ips)
+	}
+	if rs, _ := cloud.getAllBackendServer(ctx, lb); len(rs) != 1 {
+		t.Errorf("reconcileBackendServers err, want node backends, get %v", rs)
+	}
+}
+
+func TestResolveTargetPort(t *testing.T) {
+	ep := buildPodBackendEndpoints("172.16.0.1")
+	// pods with different port numbers for the same name
+	mixed := buildPodBackendEndpoints("172.16.0.1", "172.16.0.2")
+	mixed.Subsets[1].Ports[0].Port = 8081
+	named := api.ServicePort{Name: "http", Port: 80, Protocol: "TCP", TargetPort: intstr.FromString("http")}
+	namedUDP := api.ServicePort{Name: "http", Port: 80, Protocol: "UDP", TargetPort: intstr.FromString("http")}
+	cases := []struct {
+		port    api.ServicePort
+		ep      *api.Endpoints
+		want    int
+		wantErr bool
+	}{
+		{api.ServicePort{Port: 80, Protocol: "TCP"}, nil, 80, false},
+		{api.ServicePort{Port: 80, Protocol: "TCP", TargetPort: intstr.FromInt(8000)}, nil, 8000, false},
+		{named, ep, 8080, false},
+		{named, nil, 0, true},
+		{namedUDP, ep, 0, true},
+		{named, mixed, 0, true},
+	}
+	for i, c := range cases {
+		got, err := resolveTargetPort(c.port, c.ep)
+		if (err != nil) != c.wantErr || got != c.want {
+			t.Errorf("case %d resolveTargetPort err, want %d(err %v), get %d(%v)", i, c.want, c.wantErr, got, err)
+		}
+	}
+}
+
+func TestValidatePodBackendService(t *testing.T) {
+	cloud := NewFakeCloud("c-pod")
+	svc := buildService()
+	svc.Spec.Ports = []api.ServicePort{{Port: 80, Protocol: "TCP"}}
+	svc.SetAnnotations(map[string]string{
+		ServiceAnnotationLoadBalancerBackendType: backendTypePod,
+	})
+	if err := cloud.validateService(svc); err != nil {
+		t.Errorf("validateService err: %v", err)
+	}
+	svc.Annotations[ServiceAnnotationLoadBalancerSharedGroup] = "web"
+	if err := cloud.validateService(svc); err == nil {
+		t.Errorf("validateService err, want error for pod backends on shared blb")
+	}
+}
+
+// case1: node backends are kept if pod IPs fail to be registered
+// case2: node backends are kept until pods are ready
+// case3: pod backends are looked up only once by nodeport mode
+func TestSwitchPodBackendsKeepNodes(t *testing.T) {
+	cloud, nodesRes, resp, err := beforeTestBackend()
+	if err != nil {
+		t.Fatalf("beforeTestBackend err, err: %v", err)
+	}
+	ctx := context.Background()
+	lb := &blb.LoadBalancer{BlbId: resp.LoadBalancerId}
+	faults := fake.NewFaults()
+	cloud.clientSet.BLBClient.(*fake.BlbFakeClient).Faults = faults
+	svc := buildService()
+	nodes := []*api.Node{
+		{
+			Spec: api.NodeSpec{
+				ProviderID: "test//" + nodesRes.Nodes[0].InstanceID,
+			},
+		},
+	}
+	if err := cloud.reconcileBackendServers(ctx, cloud.ClusterName, svc, nodes); err != nil {
+		t.Fatalf("reconcileBackendServers err, err: %v", err)
+	}
+
+	// case1
+	svc.Annotations = map[string]string{
+		ServiceAnnotationCceAutoAddLoadBalancerID: resp.LoadBalancerId,
+		ServiceAnnotationLoadBalancerBackendType:  backendTypePod,
+	}
+	_, err = cloud.kubeClient.CoreV1().Endpoints(api.NamespaceDefault).Create(buildPodBackendEndpoints("172.16.0.1"))
+	if err != nil {
+		t.Fatalf("create endpoints err: %v", err)
+	}
+	faults.InjectErrors("AddBackendIPs", fmt.Errorf("server error"))
+	if err := cloud.reconcileBackendServers(ctx, cloud.ClusterName, svc, nodes); err == nil {
+		t.Errorf("reconcileBackendServers err, want error of AddBackendIPs")
+	}
+	if rs, _ := cloud.getAllBackendServer(ctx, lb); len(rs) != 1 {
+		t.Errorf("reconcileBackendServers err, want node backends kept, get %v", rs)
+	}
+
+	// case2
+	_, err = cloud.kubeClient.CoreV1().Endpoints(api.NamespaceDefault).Update(buildPodBackendEndpoints())
+	if err != nil {
+		t.Fatalf("update endpoints err: %v", err)
+	}
+	if err := cloud.reconcileBackendServers(ctx, cloud.ClusterName, svc, nodes); err != nil {
+		t.Fatalf("reconcileBackendServers err, err: %v", err)
+	}
+	if rs, _ := cloud.getAllBackendServer(ctx, lb); len(rs) != 1 {
+		t.Errorf("reconcileBackendServers err, want node backends kept without ready pods, get %v", rs)
+	}
+	_, err = cloud.kubeClient.CoreV1().Endpoints(api.NamespaceDefault).Update(buildPodBackendEndpoints("172.16.0.1"))
+	if err != nil {
+		t.Fatalf("update endpoints err: %v", err)
+	}
+	if err := cloud.reconcileBackendServers(ctx, cloud.ClusterName, svc, nodes); err != nil {
+		t.Fatalf("reconcileBackendServers err, err: %v", err)
+	}
+	if rs, _ := cloud.getAllBackendServer(ctx, lb); len(rs) != 0 {
+		t.Errorf("reconcileBackendServers err, want node backends removed after pods registered, get %v", rs)
+	}
+
+	// case3
+	delete(svc.Annotations, ServiceAnnotationLoadBalancerBackendType)
+	calls := faults.Calls("DescribeBackendIPs")
+	for i := 0; i < 3; i++ {
+		if err := cloud.reconcileBackendServers(ctx, cloud.ClusterName, svc, nodes); err != nil {
+			t.Fatalf("reconcileBackendServers err, err: %v", err)
+		}
+	}
+	if n := faults.Calls("DescribeBackendIPs") - calls; n != 1 {
+		t.Errorf("reconcileBackendServers err, want pod backends looked up once, get %d", n)
+	}
+	if ips := backendIPs(cloud, resp.LoadBalancerId); len(ips) != 0 {
+		t.Errorf("reconcileBackendServers err, want pod backends removed, get %v", ips)
+	}
+}
@@ -19,19 +19,37 @@ package cloud_provider
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	v1 "k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/klog"
 
 	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/blb"
+
+	cce "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/temp-cce"
 )
 
 const blbMaxRSNum int = 50
 const defaultBLBRSWeight int = 100
 
+const (
+	// rsWeightPolicyDefault weights backend servers by node annotation/label, or defaultBLBRSWeight
+	rsWeightPolicyDefault = "default"
+	// rsWeightPolicyCPU weights backend servers by node annotation/label, or cpu cores of instance
+	rsWeightPolicyCPU = "cpu"
+)
+
+const (
+	// zeroEndpointsPolicyKeep keeps the last known backend servers of Local service without endpoints
+	zeroEndpointsPolicyKeep = "keep"
+	// zeroEndpointsPolicyDrain drains and removes all backend servers of Local service without endpoints
+	zeroEndpointsPolicyDrain = "drain"
+)
+
 func (bc *Baiducloud) reconcileBackendServers(ctx context.Context, clusterName string, service *v1.Service, nodes []*v1.Node) error {
 	startTime := time.Now()
 	serviceKey := fmt.Sprintf("%s/%s", service.Namespace, service.Name)
@@ -46,41 +64,67 @@ func (bc *Baiducloud) reconcileBackendServers(ctx context.Context, clusterName s
 		return fmt.Errorf("failed to reconcileBackendServers: lb not exist")
 	}
 
-	if service.Spec.ExternalTrafficPolicy == v1.ServiceExternalTrafficPolicyTypeLocal {
+	if isPodBackendService(service) {
+		return bc.reconcilePodBackends(ctx, service, lb)
+	}
+
+	// extract annotation
+	anno, err := ExtractServiceAnnotation(service)
+	if err != nil {
+		return fmt.Errorf("failed to ExtractServiceAnnotation %s, err: %v", service.Name, err)
+	}
+
+	// all backend servers are removed only when Local service has no endpoints and asks for draining
+	removeAll := false
+	if usesHealthCheckNodePort(service) {
+		// nodes without local endpoints fail the health check on healthCheckNodePort, and get no traffic from BLB
+		klog.Infof(Message(ctx, fmt.Sprintf("service %s uses healthCheckNodePort %d, register all nodes", serviceKey, service.Spec.HealthCheckNodePort)))
+	} else if service.Spec.ExternalTrafficPolicy == v1.ServiceExternalTrafficPolicyTypeLocal {
 		nodes, err = bc.getServiceAssociatedNodes(ctx, service)
 		if err != nil {
 			return err
 		}
 		if len(nodes) == 0 {
-			klog.Infof(Message(ctx, fmt.Sprintf("service %s has no nodes to add to lb, maybe has no pod, do nothing", serviceKey)))
-			return nil
+			if anno.LoadBalancerZeroEndpointsPolicy != zeroEndpointsPolicyDrain {
+				msg := fmt.Sprintf("service %s has no endpoints on nodes, keep the last known backend servers", serviceKey)
+				Eventf(ctx, bc.eventRecorder, service, v1.EventTypeNormal, "ZeroEndpoints", msg)
+				klog.Infof(Message(ctx, msg))
+				return nil
+			}
+			msg := fmt.Sprintf("service %s has no endpoints on nodes, drain all backend servers", serviceKey)
+			Eventf(ctx, bc.eventRecorder, service, v1.EventTypeWarning, "ZeroEndpoints", msg)
+			klog.Infof(Message(ctx, msg))
+			removeAll = true
 		}
 		klog.Infof(Message(ctx, fmt.Sprintf("externalTrafficPolicy of service %s is Local, nodes is %+v", serviceKey, nodes)))
 	}
-
-	// extract annotation
-	anno, err := ExtractServiceAnnotation(service)
-	if err != nil {
-		return fmt.Errorf("failed to ExtractServiceAnnotation %s, err: %v", service.Name, err)
-	}
 	// default rs num of a blb is 50
 	targetRsNum := blbMaxRSNum
 	if anno.LoadBalancerRsMaxNum > 0 {
 		targetRsNum = anno.LoadBalancerRsMaxNum
 	}
+	// instances are only needed when weighted by cpu or rs num is capped
+	var instances map[string]*cce.Node
+	if anno.LoadBalancerRsWeightPolicy == rsWeightPolicyCPU || len(nodes) > targetRsNum {
+		instances, err = bc.getClusterInstances(ctx)
+		if err != nil {
+			return err
+		}
+	}
 	// turn kube nodes list to backend list
 	var candidateBackends []blb.BackendServer
 	for _, node := range nodes {
 		splitted := strings.Split(node.Spec.ProviderID, "//")
 		if len(splitted) != 2 {
 			msg := fmt.Sprintf("node %s has no spec.providerId", node.Name)
-			bc.eventRecorder.Eventf(node, v1.EventTypeNormal, "Node has no providerID", msg)
+			Eventf(ctx, bc.eventRecorder, node, v1.EventTypeNormal, "Node has no providerID", msg)
 			klog.Warningf(Message(ctx, msg))
 			continue
 		}
 		name := splitted[1]
 		candidateBackends = append(candidateBackends, blb.BackendServer{
 			InstanceId: name,
+			Weight:     bc.getBackendWeight(ctx, node, instances[name], anno.LoadBalancerRsWeightPolicy),
 		})
 	}
 	if len(candidateBackends) < targetRsNum {
@@ -93,12 +137,27 @@ func (bc *Baiducloud) reconcileBackendServers(ctx context.Context, clusterName s
 		return err
 	}
 
-	rsToAdd, rsToDel, err := mergeBackend(candidateBackends, existingBackends, targetRsNum)
-	if err != nil {
-		return err
+	// zones only matter when rs num is capped
+	zones := make(map[string]string, len(instances))
+	for id, ins := range instances {
+		zones[id] = ins.AvailableZone
 	}
+
+	var rsToAdd, rsToDel []blb.BackendServer
+	if removeAll {
+		for _, rs := range existingBackends {
+			rsToDel = append(rsToDel, blb.BackendServer{InstanceId: rs.InstanceId})
+		}
+	} else {
+		rsToAdd, rsToDel, err = mergeBackend(candidateBackends, existingBackends, targetRsNum, zones)
+		if err != nil {
+			return err
+		}
+	}
+	rsToUpdate := getBackendsToUpdate(candidateBackends, existingBackends, rsToDel)
 	klog.Infof(Message(ctx, fmt.Sprintf("find nodes %v to add to BLB %s for service %s", rsToAdd, lb.BlbId, serviceKey)))
 	klog.Infof(Message(ctx, fmt.Sprintf("find nodes %v to del from BLB %s for service %s", rsToDel, lb.BlbId, serviceKey)))
+	klog.Infof(Message(ctx, fmt.Sprintf("find nodes %v to update weight in BLB %s for service %s", rsToUpdate, lb.BlbId, serviceKey)))
 
 	if len(rsToAdd) > 0 {
 		args := blb.AddBackendServersArgs{
@@ -111,6 +170,23 @@ func (bc *Baiducloud) reconcileBackendServers(ctx context.Context, clusterName s
 		}
 	}
 
+	if len(rsToUpdate) > 0 {
+		args := blb.UpdateBackendServersArgs{
+			LoadBalancerId:    lb.BlbId,
+			BackendServerList: rsToUpdate,
+		}
+		err = bc.clientSet.BLBClient.UpdateBackendServers(ctx, &args, bc.getSignOption(ctx))
+		if err != nil {
+			return err
+		}
+	}
+
+	// drain rs before removing them
+	rsToDel, err = bc.drainBackendServers(ctx, service, lb, existingBackends, rsToDel, bc.getRsDrainGracePeriod(anno))
+	if err != nil {
+		return err
+	}
+
 	if len(rsToDel) > 0 {
 		var delList []string
 		for _, rs := range rsToDel {
@@ -126,39 +202,53 @@ func (bc *Baiducloud) reconcileBackendServers(ctx context.Context, clusterName s
 		}
 	}
 
+	// pod backends left by pod mode are removed after nodes are registered
+	if !bc.isBackendTypeSettled(lb, backendTypeNodePort) {
+		err = bc.deleteAllBackendIPs(ctx, lb)
+		if err != nil {
+			return err
+		}
+		bc.blbBackendTypes.Store(lb.BlbId, backendTypeNodePort)
+	}
 	return nil
 }
 
+// getServiceAssociatedNodes returns the nodes running ready or not ready endpoints of service in all subsets,
+// endpoints without node name are ignored
 func (bc *Baiducloud) getServiceAssociatedNodes(ctx context.Context, service *v1.Service) ([]*v1.Node, error) {
-	ep, err := bc.kubeClient.CoreV1().Endpoints(service.Namespace).Get(service.Name, metav1.GetOptions{})
+	ep, err := bc.getEndpoints(service.Namespace, service.Name)
+	if errors.IsNotFound(err) {
+		klog.Infof(Message(ctx, fmt.Sprintf("Endpoints %s/%s not found", service.Namespace, service.Name)))
+		return nil, nil
+	}
 	if err != nil {
 		return nil, err
 	}
-	if len(ep.Subsets) == 0 {
-		klog.Infof(Message(ctx, fmt.Sprintf("Endpoints %s/%s has no subsets", ep.Namespace, ep.Name)))
+	nodeNames := getEndpointsNodeNames(ep)
+	if len(nodeNames) == 0 {
+		klog.Infof(Message(ctx, fmt.Sprintf("Endpoints %s/%s has no endpoints on nodes", ep.Namespace, ep.Name)))
 		return nil, nil
 	}
-	nodeMap := make(map[string]string, 0)
-	for _, addr := range ep.Subsets[0].Addresses {
-		nodeMap[*addr.NodeName] = *addr.NodeName
+	nodeMap := make(map[string]bool, len(nodeNames))
+	for _, name := range nodeNames {
+		nodeMap[name] = true
 	}
 
-	for _, addr := range ep.Subsets[0].NotReadyAddresses {
-		nodeMap[*addr.NodeName] = *addr.NodeName
-	}
-
-	all_nodes, err := bc.kubeClient.CoreV1().Nodes().List(metav1.ListOptions{})
+	allNodes, err := bc.listNodes()
 	if err != nil {
 		return nil, err
 	}
 	result := make([]*v1.Node, 0)
-	for _, node := range all_nodes.Items {
-		if _, exist := nodeMap[node.Name]; exist {
+	for _, node := range allNodes {
+		if nodeMap[node.Name] {
 			n := node.DeepCopy()
 			klog.Infof(Message(ctx, fmt.Sprintf("Node is %s", n.Name)))
 			result = append(result, n)
 		}
 	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
 
 	return result, nil
 }
@@ -175,10 +265,16 @@ rsToAdd: []  rsToDel: ["5"]
 case 3:
 candidateBackends: ["1", "2", "3", "4"] existingBackends: ["4", "5"] targetBackendsNum: 3
 rsToAdd: ["1", "2"]  rsToDel: ["5"]
+
+case 4:
+candidateBackends: ["a1", "a2", "b1", "c1"] zones: {a1: A, a2: A, b1: B, c1: C} existingBackends: ["a1", "a2"] targetBackendsNum: 3
+rsToAdd: ["b1", "c1"]  rsToDel: ["a2"]
 */
 // candidateBackends contains all ready kubernetes nodes
 // existingBackends is real rss(nodes) bound to BLB
-func mergeBackend(candidateBackends, existingBackends []blb.BackendServer, targetBackendsNum int) (
+// zones maps instance id to its available zone, the selected backends are spread evenly across zones,
+// and existing backends are kept whenever possible. The result is deterministic.
+func mergeBackend(candidateBackends, existingBackends []blb.BackendServer, targetBackendsNum int, zones map[string]string) (
 	[]blb.BackendServer, []blb.BackendServer, error) {
 
 	if targetBackendsNum > len(candidateBackends) || targetBackendsNum <= 0 {
@@ -193,7 +289,7 @@ func mergeBackend(candidateBackends, existingBackends []blb.BackendServer, targe
 	// turn candidateBackends to map
 	candidateBackendsMap := make(map[string]int)
 	for _, backend := range candidateBackends {
-		candidateBackendsMap[backend.InstanceId] = 0
+		candidateBackendsMap[backend.InstanceId] = backend.Weight
 	}
 
 	// find rs to delete
@@ -209,35 +305,176 @@ func mergeBackend(candidateBackends, existingBackends []blb.BackendServer, targe
 		}
 	}
 
-	// then, if number of rs in BLB still > targetBackendsNum, random choose rs in blb to delete
-	numToDel := len(existingBackendsMap) - targetBackendsNum
-	for insID := range existingBackendsMap {
-		if numToDel > 0 {
-			rsToDel = append(rsToDel, blb.BackendServer{InstanceId: insID})
-			delete(existingBackendsMap, insID)
-			numToDel = numToDel - 1
+	// group candidates by zone, existing rs first and then sorted by id
+	zoneBackends := make(map[string][]string)
+	for insID := range candidateBackendsMap {
+		zone := zones[insID]
+		zoneBackends[zone] = append(zoneBackends[zone], insID)
+	}
+	zoneNames := make([]string, 0, len(zoneBackends))
+	zoneExisting := make(map[string]int)
+	for zone, insIDs := range zoneBackends {
+		sort.Slice(insIDs, func(i, j int) bool {
+			_, iExist := existingBackendsMap[insIDs[i]]
+			_, jExist := existingBackendsMap[insIDs[j]]
+			if iExist != jExist {
+				return iExist
+			}
+			return insIDs[i] < insIDs[j]
+		})
+		for _, insID := range insIDs {
+			if _, exist := existingBackendsMap[insID]; exist {
+				zoneExisting[zone]++
+			}
 		}
+		zoneNames = append(zoneNames, zone)
 	}
+	// zones holding more existing rs get the remainder slots to minimise churn
+	sort.Slice(zoneNames, func(i, j int) bool {
+		if zoneExisting[zoneNames[i]] != zoneExisting[zoneNames[j]] {
+			return zoneExisting[zoneNames[i]] > zoneExisting[zoneNames[j]]
+		}
+		return zoneNames[i] < zoneNames[j]
+	})
 
-	// find rs to add
-	if len(existingBackendsMap) < targetBackendsNum {
-		numToAdd := targetBackendsNum - len(existingBackendsMap)
-		for insID := range candidateBackendsMap {
-			if numToAdd == 0 {
+	// assign quota to zones round by round, so that quotas differ by at most one unless a zone runs out of nodes
+	quota := make(map[string]int, len(zoneNames))
+	remaining := targetBackendsNum
+	for remaining > 0 {
+		for _, zone := range zoneNames {
+			if remaining == 0 {
 				break
 			}
-			if _, exist := existingBackendsMap[insID]; !exist {
-				rsToAdd = append(rsToAdd, blb.BackendServer{
-					InstanceId: insID,
-					Weight:     defaultBLBRSWeight,
-				})
-				numToAdd = numToAdd - 1
+			if quota[zone] < len(zoneBackends[zone]) {
+				quota[zone]++
+				remaining--
 			}
 		}
 	}
+
+	selected := make(map[string]bool, targetBackendsNum)
+	for _, zone := range zoneNames {
+		for _, insID := range zoneBackends[zone][:quota[zone]] {
+			selected[insID] = true
+		}
+	}
+
+	// existing rs not selected should be deleted, selected rs not existing should be added
+	var keepDel []string
+	for insID := range existingBackendsMap {
+		if !selected[insID] {
+			keepDel = append(keepDel, insID)
+		}
+	}
+	for _, insID := range keepDel {
+		rsToDel = append(rsToDel, blb.BackendServer{InstanceId: insID})
+	}
+	sort.Slice(rsToDel, func(i, j int) bool {
+		return rsToDel[i].InstanceId < rsToDel[j].InstanceId
+	})
+	var addList []string
+	for insID := range selected {
+		if _, exist := existingBackendsMap[insID]; !exist {
+			addList = append(addList, insID)
+		}
+	}
+	sort.Strings(addList)
+	for _, insID := range addList {
+		weight := candidateBackendsMap[insID]
+		if weight == 0 {
+			weight = defaultBLBRSWeight
+		}
+		rsToAdd = append(rsToAdd, blb.BackendServer{
+			InstanceId: insID,
+			Weight:     weight,
+		})
+	}
 	return rsToAdd, rsToDel, nil
 }
 
+// getBackendsToUpdate returns existing backend servers which are kept in BLB but whose weight changed
+func getBackendsToUpdate(candidateBackends, existingBackends, rsToDel []blb.BackendServer) []blb.BackendServer {
+	deleted := make(map[string]bool, len(rsToDel))
+	for _, rs := range rsToDel {
+		deleted[rs.InstanceId] = true
+	}
+	expectedWeight := make(map[string]int, len(candidateBackends))
+	for _, rs := range candidateBackends {
+		weight := rs.Weight
+		if weight == 0 {
+			weight = defaultBLBRSWeight
+		}
+		expectedWeight[rs.InstanceId] = weight
+	}
+	var rsToUpdate []blb.BackendServer
+	for _, rs := range existingBackends {
+		weight, ok := expectedWeight[rs.InstanceId]
+		if !ok || deleted[rs.InstanceId] || weight == rs.Weight {
+			continue
+		}
+		rsToUpdate = append(rsToUpdate, blb.BackendServer{
+			InstanceId: rs.InstanceId,
+			Weight:     weight,
+		})
+	}
+	sort.Slice(rsToUpdate, func(i, j int) bool {
+		return rsToUpdate[i].InstanceId < rsToUpdate[j].InstanceId
+	})
+	return rsToUpdate
+}
+
+// getBackendWeight returns weight of node as backend server, node annotation/label takes precedence over weight policy
+func (bc *Baiducloud) getBackendWeight(ctx context.Context, node *v1.Node, instance *cce.Node, policy string) int {
+	weight, err := getNodeRsWeight(node)
+	if err != nil {
+		msg := fmt.Sprintf("node %s has invalid rs weight, use default weight: %v", node.Name, err)
+		Eventf(ctx, bc.eventRecorder, node, v1.EventTypeWarning, "InvalidRsWeight", msg)
+		klog.Warningf(Message(ctx, msg))
+	} else if weight > 0 {
+		return weight
+	}
+	if policy == rsWeightPolicyCPU && instance != nil && instance.CPU > 0 {
+		if instance.CPU > defaultBLBRSWeight {
+			return defaultBLBRSWeight
+		}
+		return instance.CPU
+	}
+	return defaultBLBRSWeight
+}
+
+// getNodeRsWeight returns the weight set by node annotation or label, 0 if not set
+func getNodeRsWeight(node *v1.Node) (int, error) {
+	// annotation takes precedence over label
+	rsWeight, ok := node.Annotations[NodeAnnotationBLBRsWeight]
+	if !ok {
+		rsWeight, ok = node.Labels[NodeAnnotationBLBRsWeight]
+	}
+	if !ok {
+		return 0, nil
+	}
+	weight, err := strconv.Atoi(rsWeight)
+	if err != nil {
+		return 0, fmt.Errorf("NodeAnnotationBLBRsWeight must be int")
+	}
+	if weight < 1 || weight > defaultBLBRSWeight {
+		return 0, fmt.Errorf("NodeAnnotationBLBRsWeight must be in [1, %d]", defaultBLBRSWeight)
+	}
+	return weight, nil
+}
+
+// getClusterInstances returns all instances in cluster, keyed by instance id
+func (bc *Baiducloud) getClusterInstances(ctx context.Context) (map[string]*cce.Node, error) {
+	nodes, err := bc.listClusterInstances(ctx)
+	if err != nil {
+		return nil, err
+	}
+	instances := make(map[string]*cce.Node, len(nodes))
+	for _, ins := range nodes {
+		instances[ins.InstanceID] = ins
+	}
+	return instances, nil
+}
+
 func (bc *Baiducloud) getAllBackendServer(ctx context.Context, lb *blb.LoadBalancer) ([]blb.BackendServer, error) {
 	args := blb.DescribeBackendServersArgs{
 		LoadBalancerId: lb.BlbId,
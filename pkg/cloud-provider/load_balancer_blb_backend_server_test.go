@@ -2,12 +2,14 @@ package cloud_provider
 
 import (
 	"context"
+	"reflect"
 	"testing"
 
 	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/bce"
 	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/blb"
 	cce "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/temp-cce"
 	api "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 func beforeTestBackend() (*Baiducloud, *cce.ListClusterNodesResponse, *blb.CreateLoadBalancerResponse, error) {
@@ -131,13 +133,13 @@ func arraysHelp(des []blb.BackendServer, ss ...string) bool {
 
 //  case 1:
 // 	candidateBackends: ["1", "2", "3"] existingBackends: ["4", "5"] targetBackendsNum: 1
-// 	rsToAdd: ["1"] or ["2"] or ["3"]  rsToDel: ["4", "5"]
+// 	rsToAdd: ["1"]  rsToDel: ["4", "5"]
 // 	case 2:
 // 	candidateBackends: ["1", "2", "3", "4"] existingBackends: ["4", "5"] targetBackendsNum: 1
 // 	rsToAdd: []  rsToDel: ["5"]
 // 	case 3:
 // 	candidateBackends: ["1", "2", "3", "4"] existingBackends: ["4", "5"] targetBackendsNum: 3
-// 	rsToAdd: ["1", "2"] or ["1", "3"] or ["3", "2"] rsToDel: ["5"]
+// 	rsToAdd: ["1", "2"] rsToDel: ["5"]
 func TestMergeBackend(t *testing.T) {
 	// case1
 	candidateBackends := []blb.BackendServer{
@@ -159,14 +161,12 @@ func TestMergeBackend(t *testing.T) {
 			InstanceId: "5",
 		},
 	}
-	rsToAdd, rsToDel, err := mergeBackend(candidateBackends, existingBackends, 1)
+	rsToAdd, rsToDel, err := mergeBackend(candidateBackends, existingBackends, 1, nil)
 	if err != nil {
 		t.Errorf("mergeBackend err, err: %v", err)
 	}
-	if !arraysHelp(rsToAdd, "1") && !arraysHelp(rsToAdd, "2") && !arraysHelp(rsToAdd, "3") ||
-		!arraysHelp(rsToDel, "4") || !arraysHelp(rsToDel, "5") {
-		t.Errorf("mergeBackend err, want 1 or 2 or 3| 4 ,5 get %v | %v, %v", rsToAdd[0].InstanceId,
-			rsToDel[0].InstanceId, rsToDel[1].InstanceId)
+	if !reflect.DeepEqual(backendIDs(rsToAdd), []string{"1"}) || !reflect.DeepEqual(backendIDs(rsToDel), []string{"4", "5"}) {
+		t.Errorf("mergeBackend err, want 1 | 4, 5 get %v | %v", backendIDs(rsToAdd), backendIDs(rsToDel))
 	}
 	// case2
 	candidateBackends = []blb.BackendServer{
@@ -191,7 +191,7 @@ func TestMergeBackend(t *testing.T) {
 			InstanceId: "5",
 		},
 	}
-	rsToAdd, rsToDel, err = mergeBackend(candidateBackends, existingBackends, 1)
+	rsToAdd, rsToDel, err = mergeBackend(candidateBackends, existingBackends, 1, nil)
 	if err != nil {
 		t.Errorf("mergeBackend err, err: %v", err)
 	}
@@ -222,14 +222,84 @@ func TestMergeBackend(t *testing.T) {
 			InstanceId: "5",
 		},
 	}
-	rsToAdd, rsToDel, err = mergeBackend(candidateBackends, existingBackends, 3)
+	rsToAdd, rsToDel, err = mergeBackend(candidateBackends, existingBackends, 3, nil)
 	if err != nil {
 		t.Errorf("mergeBackend err, err: %v", err)
 	}
-	if !arraysHelp(rsToAdd, "1", "2") && !arraysHelp(rsToAdd, "2", "3") &&
-		!arraysHelp(rsToAdd, "1", "3") || !arraysHelp(rsToDel, "5") {
-		t.Errorf("mergeBackend err, want 1，2 or 2，3 or 1，3| 5 get %v, %v | %v", rsToAdd[0].InstanceId,
-			rsToAdd[1].InstanceId, rsToDel[0].InstanceId)
+	if !reflect.DeepEqual(backendIDs(rsToAdd), []string{"1", "2"}) || !reflect.DeepEqual(backendIDs(rsToDel), []string{"5"}) {
+		t.Errorf("mergeBackend err, want 1, 2 | 5 get %v | %v", backendIDs(rsToAdd), backendIDs(rsToDel))
+	}
+}
+
+func backendIDs(bs []blb.BackendServer) []string {
+	ids := make([]string, 0, len(bs))
+	for _, b := range bs {
+		ids = append(ids, b.InstanceId)
+	}
+	return ids
+}
+
+// case 1: no existing rs, one rs per zone
+// case 2: existing rs all in zone a, keep one of them and spread the rest
+// case 3: merge again with the result, nothing changes
+// case 4: zone b runs out of nodes, the remaining quota goes to other zones
+func TestMergeBackendZoneBalanced(t *testing.T) {
+	zones := map[string]string{
+		"a1": "zoneA",
+		"a2": "zoneA",
+		"a3": "zoneA",
+		"b1": "zoneB",
+		"c1": "zoneC",
+		"c2": "zoneC",
+	}
+	var candidateBackends []blb.BackendServer
+	for _, id := range []string{"c2", "a3", "b1", "a1", "c1", "a2"} {
+		candidateBackends = append(candidateBackends, blb.BackendServer{InstanceId: id})
+	}
+
+	// case1
+	rsToAdd, rsToDel, err := mergeBackend(candidateBackends, nil, 3, zones)
+	if err != nil {
+		t.Errorf("mergeBackend err, err: %v", err)
+	}
+	if !reflect.DeepEqual(backendIDs(rsToAdd), []string{"a1", "b1", "c1"}) || len(rsToDel) != 0 {
+		t.Errorf("mergeBackend err, want a1, b1, c1 | nil get %v | %v", backendIDs(rsToAdd), backendIDs(rsToDel))
+	}
+
+	// case2
+	existingBackends := []blb.BackendServer{
+		{InstanceId: "a3"},
+		{InstanceId: "a2"},
+	}
+	rsToAdd, rsToDel, err = mergeBackend(candidateBackends, existingBackends, 3, zones)
+	if err != nil {
+		t.Errorf("mergeBackend err, err: %v", err)
+	}
+	if !reflect.DeepEqual(backendIDs(rsToAdd), []string{"b1", "c1"}) || !reflect.DeepEqual(backendIDs(rsToDel), []string{"a3"}) {
+		t.Errorf("mergeBackend err, want b1, c1 | a3 get %v | %v", backendIDs(rsToAdd), backendIDs(rsToDel))
+	}
+
+	// case3
+	existingBackends = []blb.BackendServer{
+		{InstanceId: "a2"},
+		{InstanceId: "b1"},
+		{InstanceId: "c1"},
+	}
+	rsToAdd, rsToDel, err = mergeBackend(candidateBackends, existingBackends, 3, zones)
+	if err != nil {
+		t.Errorf("mergeBackend err, err: %v", err)
+	}
+	if len(rsToAdd) != 0 || len(rsToDel) != 0 {
+		t.Errorf("mergeBackend err, want nil | nil get %v | %v", backendIDs(rsToAdd), backendIDs(rsToDel))
+	}
+
+	// case4
+	rsToAdd, rsToDel, err = mergeBackend(candidateBackends, existingBackends, 5, zones)
+	if err != nil {
+		t.Errorf("mergeBackend err, err: %v", err)
+	}
+	if !reflect.DeepEqual(backendIDs(rsToAdd), []string{"a1", "c2"}) || len(rsToDel) != 0 {
+		t.Errorf("mergeBackend err, want a1, c2 | nil get %v | %v", backendIDs(rsToAdd), backendIDs(rsToDel))
 	}
 }
 
@@ -277,3 +347,177 @@ func TestReconcileBackendServers(t *testing.T) {
 		t.Errorf("reconcileBackendServers err, err: %v", err)
 	}
 }
+
+// case1: weight from node annotation, or default weight
+// case2: weight from node label, or cpu cores of instance
+// case3: invalid weight falls back to default weight
+func TestReconcileBackendServersWeight(t *testing.T) {
+	cloud, nodesRes, blbRes, err := beforeTestBackend()
+	if err != nil {
+		t.Errorf("beforeTestBackend err, err: %v", err)
+	}
+	ctx := context.Background()
+	svc := buildService()
+	nodesRes.Nodes[1].CPU = 8
+	// case1
+	nodes := []*api.Node{
+		&api.Node{
+			ObjectMeta: meta_v1.ObjectMeta{
+				Name:        "node0",
+				Annotations: map[string]string{NodeAnnotationBLBRsWeight: "20"},
+			},
+			Spec: api.NodeSpec{
+				ProviderID: "test//" + nodesRes.Nodes[0].InstanceID,
+			},
+		},
+		&api.Node{
+			ObjectMeta: meta_v1.ObjectMeta{
+				Name: "node1",
+			},
+			Spec: api.NodeSpec{
+				ProviderID: "test//" + nodesRes.Nodes[1].InstanceID,
+			},
+		},
+	}
+	err = cloud.reconcileBackendServers(ctx, cloud.ClusterName, svc, nodes)
+	if err != nil {
+		t.Errorf("reconcileBackendServers err, err: %v", err)
+	}
+	expected := map[string]int{
+		nodesRes.Nodes[0].InstanceID: 20,
+		nodesRes.Nodes[1].InstanceID: defaultBLBRSWeight,
+	}
+	checkBackendWeight(t, cloud, blbRes.LoadBalancerId, expected)
+
+	// case2
+	nodes[0].Annotations = nil
+	nodes[0].Labels = map[string]string{NodeAnnotationBLBRsWeight: "30"}
+	svc.SetAnnotations(map[string]string{ServiceAnnotationLoadBalancerRsWeightPolicy: rsWeightPolicyCPU})
+	err = cloud.reconcileBackendServers(ctx, cloud.ClusterName, svc, nodes)
+	if err != nil {
+		t.Errorf("reconcileBackendServers err, err: %v", err)
+	}
+	expected = map[string]int{
+		nodesRes.Nodes[0].InstanceID: 30,
+		nodesRes.Nodes[1].InstanceID: 8,
+	}
+	checkBackendWeight(t, cloud, blbRes.LoadBalancerId, expected)
+
+	// case3
+	nodes[0].Labels = map[string]string{NodeAnnotationBLBRsWeight: "abc"}
+	svc.SetAnnotations(nil)
+	err = cloud.reconcileBackendServers(ctx, cloud.ClusterName, svc, nodes)
+	if err != nil {
+		t.Errorf("reconcileBackendServers err, err: %v", err)
+	}
+	expected = map[string]int{
+		nodesRes.Nodes[0].InstanceID: defaultBLBRSWeight,
+		nodesRes.Nodes[1].InstanceID: defaultBLBRSWeight,
+	}
+	checkBackendWeight(t, cloud, blbRes.LoadBalancerId, expected)
+}
+
+func checkBackendWeight(t *testing.T, cloud *Baiducloud, blbID string, expected map[string]int) {
+	bs, err := cloud.getAllBackendServer(context.Background(), &blb.LoadBalancer{BlbId: blbID})
+	if err != nil {
+		t.Errorf("getAllBackendServer err, err: %v", err)
+	}
+	if len(bs) != len(expected) {
+		t.Errorf("getAllBackendServer err, want %v get %v", expected, bs)
+	}
+	for _, rs := range bs {
+		if expected[rs.InstanceId] != rs.Weight {
+			t.Errorf("backend %s weight err, want %d get %d", rs.InstanceId, expected[rs.InstanceId], rs.Weight)
+		}
+	}
+}
+
+// case1: nodes of endpoints in all subsets are used, endpoints without node name are ignored
+// case2: Local service without endpoints keeps the last known backends by default
+// case3: Local service without endpoints drains all backends with policy drain
+// case4: Local service using healthCheckNodePort registers all nodes regardless of endpoints
+func TestReconcileBackendServersLocal(t *testing.T) {
+	cloud, nodesRes, blbRes, err := beforeTestBackend()
+	if err != nil {
+		t.Fatalf("beforeTestBackend err, err: %v", err)
+	}
+	ctx := context.Background()
+	svc := buildService()
+	svc.Spec.ExternalTrafficPolicy = api.ServiceExternalTrafficPolicyTypeLocal
+	for i, name := range []string{"node0", "node1"} {
+		_, err := cloud.kubeClient.CoreV1().Nodes().Create(&api.Node{
+			ObjectMeta: meta_v1.ObjectMeta{Name: name},
+			Spec: api.NodeSpec{
+				ProviderID: "test//" + nodesRes.Nodes[i].InstanceID,
+			},
+		})
+		if err != nil {
+			t.Fatalf("create node err: %v", err)
+		}
+	}
+	node0, node1 := "node0", "node1"
+	ep := &api.Endpoints{
+		ObjectMeta: meta_v1.ObjectMeta{Name: svc.Name, Namespace: svc.Namespace},
+		Subsets: []api.EndpointSubset{
+			{
+				Addresses: []api.EndpointAddress{{IP: "172.16.0.1", NodeName: &node0}, {IP: "172.16.0.2"}},
+				Ports:     []api.EndpointPort{{Name: "http", Port: 8080}},
+			},
+			{
+				NotReadyAddresses: []api.EndpointAddress{{IP: "172.16.0.3", NodeName: &node1}},
+				Ports:             []api.EndpointPort{{Name: "http", Port: 8081}},
+			},
+		},
+	}
+
+	// case1
+	ep, err = cloud.kubeClient.CoreV1().Endpoints(svc.Namespace).Create(ep)
+	if err != nil {
+		t.Fatalf("create endpoints err: %v", err)
+	}
+	err = cloud.reconcileBackendServers(ctx, cloud.ClusterName, svc, nil)
+	if err != nil {
+		t.Fatalf("reconcileBackendServers err, err: %v", err)
+	}
+	expected := map[string]int{
+		nodesRes.Nodes[0].InstanceID: defaultBLBRSWeight,
+		nodesRes.Nodes[1].InstanceID: defaultBLBRSWeight,
+	}
+	checkBackendWeight(t, cloud, blbRes.LoadBalancerId, expected)
+
+	// case2
+	ep.Subsets = nil
+	if _, err := cloud.kubeClient.CoreV1().Endpoints(svc.Namespace).Update(ep); err != nil {
+		t.Fatalf("update endpoints err: %v", err)
+	}
+	err = cloud.reconcileBackendServers(ctx, cloud.ClusterName, svc, nil)
+	if err != nil {
+		t.Fatalf("reconcileBackendServers err, err: %v", err)
+	}
+	checkBackendWeight(t, cloud, blbRes.LoadBalancerId, expected)
+
+	// case3
+	svc.SetAnnotations(map[string]string{ServiceAnnotationLoadBalancerZeroEndpointsPolicy: zeroEndpointsPolicyDrain})
+	err = cloud.reconcileBackendServers(ctx, cloud.ClusterName, svc, nil)
+	if err != nil {
+		t.Fatalf("reconcileBackendServers err, err: %v", err)
+	}
+	checkBackendWeight(t, cloud, blbRes.LoadBalancerId, map[string]int{})
+
+	// case4
+	svc.SetAnnotations(map[string]string{ServiceAnnotationLoadBalancerHealthCheckNodePort: "true"})
+	svc.Spec.HealthCheckNodePort = 32000
+	nodeList, err := cloud.kubeClient.CoreV1().Nodes().List(meta_v1.ListOptions{})
+	if err != nil {
+		t.Fatalf("list nodes err: %v", err)
+	}
+	var nodes []*api.Node
+	for i := range nodeList.Items {
+		nodes = append(nodes, &nodeList.Items[i])
+	}
+	err = cloud.reconcileBackendServers(ctx, cloud.ClusterName, svc, nodes)
+	if err != nil {
+		t.Fatalf("reconcileBackendServers err, err: %v", err)
+	}
+	checkBackendWeight(t, cloud, blbRes.LoadBalancerId, expected)
+}
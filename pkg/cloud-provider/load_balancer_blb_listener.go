@@ -19,18 +19,231 @@ package cloud_provider
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/blb"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/klog"
+
+	blbext "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/temp-blb"
+)
+
+const (
+	// defaultBLBServerTimeout is the default backend keepalive timeout of HTTP/HTTPS listeners
+	defaultBLBServerTimeout = 30
+	// defaultBLBKeepSessionDuration is the default session keeping duration of HTTP/HTTPS listeners
+	defaultBLBKeepSessionDuration = 3600
+	// blbKeepSessionTypeInsert means BLB inserts cookie to keep session
+	blbKeepSessionTypeInsert = "insert"
+
+	// default health check config of TCP/UDP listeners, same as BLB's
+	defaultBLBHealthCheckTimeoutInSecond = 3
+	defaultBLBHealthCheckInterval        = 3
+	defaultBLBUnhealthyThreshold         = 3
+	defaultBLBHealthyThreshold           = 3
+	defaultBLBHealthCheckString          = "HealthCheck"
+
+	// kube-proxy serves healthCheckNodePort of Local service, which returns 200 only if node has local endpoints
+	blbHealthCheckTypeHTTP        = "HTTP"
+	blbHealthCheckNormalStatus2xx = "http_2xx"
+	healthCheckNodePortURI        = "/healthz"
+
+	// BLB scheduling algorithms
+	blbSchedulerRoundRobin      = "RoundRobin"
+	blbSchedulerLeastConnection = "LeastConnection"
+	blbSchedulerHash            = "Hash"
+	defaultBLBScheduler         = blbSchedulerRoundRobin
 )
 
+// blbSupportedSchedulers is the scheduling algorithms supported by each listener protocol
+var blbSupportedSchedulers = map[string][]string{
+	"TCP":   {blbSchedulerRoundRobin, blbSchedulerLeastConnection, blbSchedulerHash},
+	"UDP":   {blbSchedulerRoundRobin, blbSchedulerHash},
+	"HTTP":  {blbSchedulerRoundRobin, blbSchedulerLeastConnection},
+	"HTTPS": {blbSchedulerRoundRobin, blbSchedulerLeastConnection},
+}
+
 // PortListener describe listener port
 type PortListener struct {
-	Port     int
+	Port      int
+	Protocol  string
+	NodePort  int32
+	Scheduler string
+
+	// TCP/UDP only
+	HealthCheckTimeoutInSecond int
+	HealthCheckInterval        int
+	UnhealthyThreshold         int
+	HealthyThreshold           int
+	// UDP only
+	HealthCheckString string
+
+	// health check target other than the backend port, e.g. healthCheckNodePort of Local service
+	HealthCheckType string
+	HealthCheckPort int
+	HealthCheckURI  string
+
+	// HTTP/HTTPS only
+	KeepSession         bool
+	KeepSessionDuration int
+	ServerTimeout       int
+	// HTTP only, the HTTPS listener port which requests are redirected to
+	RedirectPort int
+	// HTTPS only, certificate ids joined by ","
+	CertIDs string
+}
+
+// listenerKey identifies a listener on BLB. HTTP/HTTPS listeners use TCP ports, so a port can have one of
+// TCP/HTTP/HTTPS listener and one UDP listener at the same time.
+type listenerKey struct {
+	// Protocol is the transport protocol, "TCP" or "UDP"
 	Protocol string
-	NodePort int32
+	Port     int
+}
+
+func (k listenerKey) String() string {
+	return fmt.Sprintf("%d/%s", k.Port, k.Protocol)
+}
+
+// newListenerKey returns key of listener with protocol TCP/UDP/HTTP/HTTPS
+func newListenerKey(protocol string, port int) listenerKey {
+	if protocol != "UDP" {
+		protocol = "TCP"
+	}
+	return listenerKey{Protocol: protocol, Port: port}
+}
+
+// key returns the key of listener
+func (pl PortListener) key() listenerKey {
+	return newListenerKey(pl.Protocol, pl.Port)
+}
+
+// joinCertIDs joins certificate ids in order, so that listeners with the same certificates are equal
+func joinCertIDs(certIDs []string) string {
+	sorted := append([]string{}, certIDs...)
+	sort.Strings(sorted)
+	return strings.Join(sorted, ",")
+}
+
+// withoutHealthCheckTarget returns listener without health check target, which is updated by another API
+func (pl PortListener) withoutHealthCheckTarget() PortListener {
+	pl.HealthCheckType = ""
+	pl.HealthCheckPort = 0
+	pl.HealthCheckURI = ""
+	return pl
+}
+
+// getExpectedListeners builds the listeners declared by service, HTTP/HTTPS listeners are declared by annotation on TCP ports
+func getExpectedListeners(service *v1.Service) (map[listenerKey]PortListener, error) {
+	serviceAnnotation, err := ExtractServiceAnnotation(service)
+	if err != nil {
+		return nil, err
+	}
+	expected := make(map[listenerKey]PortListener)
+	for _, servicePort := range service.Spec.Ports {
+		pl := PortListener{
+			Port:     int(servicePort.Port),
+			Protocol: string(servicePort.Protocol),
+			NodePort: servicePort.NodePort,
+		}
+		if protocol, ok := serviceAnnotation.LoadBalancerListenerProtocol[pl.Port]; ok && pl.Protocol == "TCP" {
+			pl.Protocol = protocol
+			pl.ServerTimeout = defaultBLBServerTimeout
+			if serviceAnnotation.LoadBalancerServerTimeout != 0 {
+				pl.ServerTimeout = serviceAnnotation.LoadBalancerServerTimeout
+			}
+			if serviceAnnotation.LoadBalancerKeepSession {
+				pl.KeepSession = true
+				pl.KeepSessionDuration = defaultBLBKeepSessionDuration
+				if serviceAnnotation.LoadBalancerKeepSessionDuration != 0 {
+					pl.KeepSessionDuration = serviceAnnotation.LoadBalancerKeepSessionDuration
+				}
+			}
+			switch protocol {
+			case "HTTP":
+				pl.RedirectPort = serviceAnnotation.LoadBalancerRedirectPort[pl.Port]
+			case "HTTPS":
+				pl.CertIDs = joinCertIDs(serviceAnnotation.LoadBalancerCertIDs)
+			}
+		} else {
+			setHealthCheck(&pl, serviceAnnotation)
+		}
+		if usesHealthCheckNodePort(service) {
+			pl.HealthCheckType = blbHealthCheckTypeHTTP
+			pl.HealthCheckPort = int(service.Spec.HealthCheckNodePort)
+			pl.HealthCheckURI = healthCheckNodePortURI
+		}
+		scheduler, err := getExpectedScheduler(service, serviceAnnotation, pl)
+		if err != nil {
+			return nil, err
+		}
+		pl.Scheduler = scheduler
+		expected[pl.key()] = pl
+	}
+	return expected, nil
+}
+
+// getExpectedScheduler returns scheduler of listener, per port annotation takes precedence over service annotation,
+// services with ClientIP session affinity use Hash by default if protocol supports it
+func getExpectedScheduler(service *v1.Service, serviceAnnotation *ServiceAnnotation, pl PortListener) (string, error) {
+	scheduler := defaultBLBScheduler
+	if service.Spec.SessionAffinity == v1.ServiceAffinityClientIP && isSchedulerSupported(pl.Protocol, blbSchedulerHash) {
+		scheduler = blbSchedulerHash
+	}
+	if serviceAnnotation.LoadBalancerScheduler != "" {
+		scheduler = serviceAnnotation.LoadBalancerScheduler
+	}
+	if portScheduler, ok := serviceAnnotation.LoadBalancerPortScheduler[pl.Port]; ok {
+		scheduler = portScheduler
+	}
+	if !isSchedulerSupported(pl.Protocol, scheduler) {
+		return "", fmt.Errorf("scheduler %s is not supported by %s listener %d, support %v", scheduler, pl.Protocol, pl.Port, blbSupportedSchedulers[pl.Protocol])
+	}
+	return scheduler, nil
+}
+
+func isSchedulerSupported(protocol string, scheduler string) bool {
+	for _, s := range blbSupportedSchedulers[protocol] {
+		if s == scheduler {
+			return true
+		}
+	}
+	return false
+}
+
+func listenerScheduler(pl PortListener) string {
+	if pl.Scheduler == "" {
+		return defaultBLBScheduler
+	}
+	return pl.Scheduler
+}
+
+// setHealthCheck sets health check config of TCP/UDP listener from annotation, with BLB's default value
+func setHealthCheck(pl *PortListener, serviceAnnotation *ServiceAnnotation) {
+	pl.HealthCheckTimeoutInSecond = defaultBLBHealthCheckTimeoutInSecond
+	if serviceAnnotation.LoadBalancerHealthCheckTimeoutInSecond != 0 {
+		pl.HealthCheckTimeoutInSecond = serviceAnnotation.LoadBalancerHealthCheckTimeoutInSecond
+	}
+	pl.HealthCheckInterval = defaultBLBHealthCheckInterval
+	if serviceAnnotation.LoadBalancerHealthCheckInterval != 0 {
+		pl.HealthCheckInterval = serviceAnnotation.LoadBalancerHealthCheckInterval
+	}
+	pl.UnhealthyThreshold = defaultBLBUnhealthyThreshold
+	if serviceAnnotation.LoadBalancerUnhealthyThreshold != 0 {
+		pl.UnhealthyThreshold = serviceAnnotation.LoadBalancerUnhealthyThreshold
+	}
+	pl.HealthyThreshold = defaultBLBHealthyThreshold
+	if serviceAnnotation.LoadBalancerHealthyThreshold != 0 {
+		pl.HealthyThreshold = serviceAnnotation.LoadBalancerHealthyThreshold
+	}
+	if pl.Protocol == "UDP" {
+		pl.HealthCheckString = defaultBLBHealthCheckString
+		if serviceAnnotation.LoadBalancerHealthCheckString != "" {
+			pl.HealthCheckString = serviceAnnotation.LoadBalancerHealthCheckString
+		}
+	}
 }
 
 func (bc *Baiducloud) reconcileListeners(ctx context.Context, clusterName string, service *v1.Service) error {
@@ -40,12 +253,14 @@ func (bc *Baiducloud) reconcileListeners(ctx context.Context, clusterName string
 		klog.V(4).Infof(Message(ctx, fmt.Sprintf("Finished reconcileListeners for service %q (%v)", serviceKey, time.Since(startTime))))
 	}()
 	// add expected ports
-	expected := make(map[int]PortListener)
-	for _, servicePort := range service.Spec.Ports {
-		expected[int(servicePort.Port)] = PortListener{
-			Port:     int(servicePort.Port),
-			Protocol: string(servicePort.Protocol),
-			NodePort: servicePort.NodePort,
+	expected, err := getExpectedListeners(service)
+	if err != nil {
+		return err
+	}
+	if isPodBackendService(service) {
+		err = bc.setPodBackendPorts(ctx, service, expected)
+		if err != nil {
+			return err
 		}
 	}
 
@@ -57,6 +272,17 @@ func (bc *Baiducloud) reconcileListeners(ctx context.Context, clusterName string
 		return fmt.Errorf("failed to reconcileListeners: lb not exist")
 	}
 
+	// listeners of other services sharing the BLB are left alone
+	peers, err := bc.getSharedBLBPeers(ctx, service, lb)
+	if err != nil {
+		return err
+	}
+	owners := getSharedListenerOwners(peers)
+	err = bc.checkSharedBLBConflict(ctx, service, expected, owners, peers)
+	if err != nil {
+		return err
+	}
+
 	// delete or update unexpected ports
 	all, err := bc.getAllListeners(ctx, lb)
 	if err != nil {
@@ -64,21 +290,35 @@ func (bc *Baiducloud) reconcileListeners(ctx context.Context, clusterName string
 	}
 	var deleteList []PortListener
 	for _, l := range all {
-		port, ok := expected[l.Port]
+		port, ok := expected[l.key()]
+		if _, shared := owners[l.key()]; !ok && shared {
+			continue
+		}
 		if !ok {
 			// delete listener port
 			// add to deleteList
 			deleteList = append(deleteList, l)
+		} else if l.Protocol != port.Protocol {
+			// listener protocol can not be updated, delete it and create a new one
+			deleteList = append(deleteList, l)
 		} else {
 			if l != port {
 				// update listener port
 				klog.Infof(Message(ctx, fmt.Sprintf("reconcileListeners for service %s: update listener with new config: %v", serviceKey, port)))
-				err := bc.updateListener(ctx, lb, port)
-				if err != nil {
-					return err
+				if l.withoutHealthCheckTarget() != port.withoutHealthCheckTarget() {
+					err := bc.updateListener(ctx, lb, port)
+					if err != nil {
+						return err
+					}
+				}
+				if l.HealthCheckType != port.HealthCheckType || l.HealthCheckPort != port.HealthCheckPort || l.HealthCheckURI != port.HealthCheckURI {
+					err := bc.updateListenerHealthCheck(ctx, lb, port)
+					if err != nil {
+						return err
+					}
 				}
 			}
-			delete(expected, l.Port)
+			delete(expected, l.key())
 		}
 	}
 	// delete listener
@@ -91,12 +331,32 @@ func (bc *Baiducloud) reconcileListeners(ctx context.Context, clusterName string
 	}
 
 	// create expected listener
-	klog.Infof(Message(ctx, fmt.Sprintf("reconcileListeners for service %s: create expected listener: %v", serviceKey, expected)))
+	// HTTPS listeners are created first, because HTTP listeners may redirect to them
+	var createList []PortListener
 	for _, pl := range expected {
+		createList = append(createList, pl)
+	}
+	sort.Slice(createList, func(i, j int) bool {
+		if (createList[i].Protocol == "HTTPS") != (createList[j].Protocol == "HTTPS") {
+			return createList[i].Protocol == "HTTPS"
+		}
+		if createList[i].Port != createList[j].Port {
+			return createList[i].Port < createList[j].Port
+		}
+		return createList[i].key().Protocol < createList[j].key().Protocol
+	})
+	klog.Infof(Message(ctx, fmt.Sprintf("reconcileListeners for service %s: create expected listener: %v", serviceKey, createList)))
+	for _, pl := range createList {
 		err := bc.createListener(ctx, lb, pl)
 		if err != nil {
 			return err
 		}
+		if pl.HealthCheckPort != 0 {
+			err = bc.updateListenerHealthCheck(ctx, lb, pl)
+			if err != nil {
+				return err
+			}
+		}
 	}
 
 	return nil
@@ -106,11 +366,18 @@ func (bc *Baiducloud) createListener(ctx context.Context, lb *blb.LoadBalancer,
 	switch pl.Protocol {
 	case "UDP":
 		args := blb.CreateUDPListenerArgs{
-			LoadBalancerId:    lb.BlbId,
-			ListenerPort:      pl.Port,
-			BackendPort:       int(pl.NodePort),
-			Scheduler:         "RoundRobin",
-			HealthCheckString: "HealthCheck",
+			LoadBalancerId:             lb.BlbId,
+			ListenerPort:               pl.Port,
+			BackendPort:                int(pl.NodePort),
+			Scheduler:                  listenerScheduler(pl),
+			HealthCheckTimeoutInSecond: pl.HealthCheckTimeoutInSecond,
+			HealthCheckInterval:        pl.HealthCheckInterval,
+			UnhealthyThreshold:         pl.UnhealthyThreshold,
+			HealthyThreshold:           pl.HealthyThreshold,
+			HealthCheckString:          pl.HealthCheckString,
+		}
+		if args.HealthCheckString == "" {
+			args.HealthCheckString = defaultBLBHealthCheckString
 		}
 		err := bc.clientSet.BLBClient.CreateUDPListener(ctx, &args, bc.getSignOption(ctx))
 		if err != nil {
@@ -119,20 +386,63 @@ func (bc *Baiducloud) createListener(ctx context.Context, lb *blb.LoadBalancer,
 		return nil
 	case "TCP":
 		args := blb.CreateTCPListenerArgs{
+			LoadBalancerId:             lb.BlbId,
+			ListenerPort:               pl.Port,
+			BackendPort:                int(pl.NodePort),
+			Scheduler:                  listenerScheduler(pl),
+			HealthCheckTimeoutInSecond: pl.HealthCheckTimeoutInSecond,
+			HealthCheckInterval:        pl.HealthCheckInterval,
+			UnhealthyThreshold:         pl.UnhealthyThreshold,
+			HealthyThreshold:           pl.HealthyThreshold,
+		}
+		err := bc.clientSet.BLBClient.CreateTCPListener(ctx, &args, bc.getSignOption(ctx))
+		if err != nil {
+			return err
+		}
+		return nil
+	case "HTTP":
+		args := blb.CreateHTTPListenerArgs{
 			LoadBalancerId: lb.BlbId,
 			ListenerPort:   pl.Port,
 			BackendPort:    int(pl.NodePort),
-			Scheduler:      "RoundRobin",
+			Scheduler:      listenerScheduler(pl),
+			XForwardFor:    true,
+			ServerTimeout:  pl.ServerTimeout,
+			RedirectPort:   pl.RedirectPort,
 		}
-		err := bc.clientSet.BLBClient.CreateTCPListener(ctx, &args, bc.getSignOption(ctx))
+		if pl.KeepSession {
+			args.KeepSession = true
+			args.KeepSessionType = blbKeepSessionTypeInsert
+			args.KeepSessionDuration = pl.KeepSessionDuration
+		}
+		err := bc.clientSet.BLBClient.CreateHTTPListener(ctx, &args, bc.getSignOption(ctx))
 		if err != nil {
 			return err
 		}
 		return nil
-	case "HTTP":
-		// TODO
 	case "HTTPS":
-		// TODO
+		if pl.CertIDs == "" {
+			return fmt.Errorf("CreateListener HTTPS listener %d need annotation %s", pl.Port, ServiceAnnotationLoadBalancerCertID)
+		}
+		args := blbext.CreateHTTPSListenerArgs{
+			LoadBalancerId: lb.BlbId,
+			ListenerPort:   pl.Port,
+			BackendPort:    int(pl.NodePort),
+			Scheduler:      listenerScheduler(pl),
+			XForwardFor:    true,
+			ServerTimeout:  pl.ServerTimeout,
+			CertIds:        strings.Split(pl.CertIDs, ","),
+		}
+		if pl.KeepSession {
+			args.KeepSession = true
+			args.KeepSessionType = blbKeepSessionTypeInsert
+			args.KeepSessionDuration = pl.KeepSessionDuration
+		}
+		err := bc.clientSet.BLBClient.CreateHTTPSListener(ctx, &args, bc.getSignOption(ctx))
+		if err != nil {
+			return err
+		}
+		return nil
 	}
 	return fmt.Errorf("CreateListener protocol not match: %s", pl.Protocol)
 }
@@ -141,11 +451,18 @@ func (bc *Baiducloud) updateListener(ctx context.Context, lb *blb.LoadBalancer,
 	switch pl.Protocol {
 	case "UDP":
 		args := blb.UpdateUDPListenerArgs{
-			LoadBalancerId:    lb.BlbId,
-			ListenerPort:      pl.Port,
-			BackendPort:       int(pl.NodePort),
-			Scheduler:         "RoundRobin",
-			HealthCheckString: "HealthCheck",
+			LoadBalancerId:             lb.BlbId,
+			ListenerPort:               pl.Port,
+			BackendPort:                int(pl.NodePort),
+			Scheduler:                  listenerScheduler(pl),
+			HealthCheckTimeoutInSecond: pl.HealthCheckTimeoutInSecond,
+			HealthCheckInterval:        pl.HealthCheckInterval,
+			UnhealthyThreshold:         pl.UnhealthyThreshold,
+			HealthyThreshold:           pl.HealthyThreshold,
+			HealthCheckString:          pl.HealthCheckString,
+		}
+		if args.HealthCheckString == "" {
+			args.HealthCheckString = defaultBLBHealthCheckString
 		}
 		err := bc.clientSet.BLBClient.UpdateUDPListener(ctx, &args, bc.getSignOption(ctx))
 		if err != nil {
@@ -154,20 +471,63 @@ func (bc *Baiducloud) updateListener(ctx context.Context, lb *blb.LoadBalancer,
 		return nil
 	case "TCP":
 		args := blb.UpdateTCPListenerArgs{
+			LoadBalancerId:             lb.BlbId,
+			ListenerPort:               pl.Port,
+			BackendPort:                int(pl.NodePort),
+			Scheduler:                  listenerScheduler(pl),
+			HealthCheckTimeoutInSecond: pl.HealthCheckTimeoutInSecond,
+			HealthCheckInterval:        pl.HealthCheckInterval,
+			UnhealthyThreshold:         pl.UnhealthyThreshold,
+			HealthyThreshold:           pl.HealthyThreshold,
+		}
+		err := bc.clientSet.BLBClient.UpdateTCPListener(ctx, &args, bc.getSignOption(ctx))
+		if err != nil {
+			return err
+		}
+		return nil
+	case "HTTP":
+		args := blbext.UpdateHTTPListenerArgs{
 			LoadBalancerId: lb.BlbId,
 			ListenerPort:   pl.Port,
 			BackendPort:    int(pl.NodePort),
-			Scheduler:      "RoundRobin",
+			Scheduler:      listenerScheduler(pl),
+			XForwardFor:    true,
+			ServerTimeout:  pl.ServerTimeout,
+			RedirectPort:   pl.RedirectPort,
 		}
-		err := bc.clientSet.BLBClient.UpdateTCPListener(ctx, &args, bc.getSignOption(ctx))
+		if pl.KeepSession {
+			args.KeepSession = true
+			args.KeepSessionType = blbKeepSessionTypeInsert
+			args.KeepSessionDuration = pl.KeepSessionDuration
+		}
+		err := bc.clientSet.BLBClient.UpdateHTTPListener(ctx, &args, bc.getSignOption(ctx))
 		if err != nil {
 			return err
 		}
 		return nil
-	case "HTTP":
-		// TODO
 	case "HTTPS":
-		// TODO
+		if pl.CertIDs == "" {
+			return fmt.Errorf("updateListener HTTPS listener %d need annotation %s", pl.Port, ServiceAnnotationLoadBalancerCertID)
+		}
+		args := blbext.UpdateHTTPSListenerArgs{
+			LoadBalancerId: lb.BlbId,
+			ListenerPort:   pl.Port,
+			BackendPort:    int(pl.NodePort),
+			Scheduler:      listenerScheduler(pl),
+			XForwardFor:    true,
+			ServerTimeout:  pl.ServerTimeout,
+			CertIds:        strings.Split(pl.CertIDs, ","),
+		}
+		if pl.KeepSession {
+			args.KeepSession = true
+			args.KeepSessionType = blbKeepSessionTypeInsert
+			args.KeepSessionDuration = pl.KeepSessionDuration
+		}
+		err := bc.clientSet.BLBClient.UpdateHTTPSListener(ctx, &args, bc.getSignOption(ctx))
+		if err != nil {
+			return err
+		}
+		return nil
 	}
 	return fmt.Errorf("updateListener protocol not match: %s", pl.Protocol)
 }
@@ -185,9 +545,14 @@ func (bc *Baiducloud) getAllListeners(ctx context.Context, lb *blb.LoadBalancer)
 	}
 	for _, listener := range tcpListeners {
 		allListeners = append(allListeners, PortListener{
-			Port:     listener.ListenerPort,
-			Protocol: "TCP",
-			NodePort: int32(listener.BackendPort),
+			Port:                       listener.ListenerPort,
+			Protocol:                   "TCP",
+			NodePort:                   int32(listener.BackendPort),
+			Scheduler:                  listener.Scheduler,
+			HealthCheckTimeoutInSecond: listener.HealthCheckTimeoutInSecond,
+			HealthCheckInterval:        listener.HealthCheckInterval,
+			UnhealthyThreshold:         listener.UnhealthyThreshold,
+			HealthyThreshold:           listener.HealthyThreshold,
 		})
 	}
 
@@ -201,26 +566,150 @@ func (bc *Baiducloud) getAllListeners(ctx context.Context, lb *blb.LoadBalancer)
 	}
 	for _, listener := range udpListeners {
 		allListeners = append(allListeners, PortListener{
-			Port:     listener.ListenerPort,
-			Protocol: "UDP",
-			NodePort: int32(listener.BackendPort),
+			Port:                       listener.ListenerPort,
+			Protocol:                   "UDP",
+			NodePort:                   int32(listener.BackendPort),
+			Scheduler:                  listener.Scheduler,
+			HealthCheckTimeoutInSecond: listener.HealthCheckTimeoutInSecond,
+			HealthCheckInterval:        listener.HealthCheckInterval,
+			UnhealthyThreshold:         listener.UnhealthyThreshold,
+			HealthyThreshold:           listener.HealthyThreshold,
+			HealthCheckString:          listener.HealthCheckString,
 		})
 	}
 
-	// TODO: add HTTP,HTTPS
+	// add HTTPlisteners
+	describeHTTPListenerArgs := blbext.DescribeHTTPListenerArgs{
+		LoadBalancerId: lb.BlbId,
+	}
+	httpListeners, err := bc.clientSet.BLBClient.DescribeHTTPListener(ctx, &describeHTTPListenerArgs, bc.getSignOption(ctx))
+	if err != nil {
+		return nil, err
+	}
+	for _, listener := range httpListeners {
+		pl := PortListener{
+			Port:          listener.ListenerPort,
+			Protocol:      "HTTP",
+			NodePort:      int32(listener.BackendPort),
+			Scheduler:     listener.Scheduler,
+			ServerTimeout: listener.ServerTimeout,
+			RedirectPort:  listener.RedirectPort,
+		}
+		if listener.KeepSession {
+			pl.KeepSession = true
+			pl.KeepSessionDuration = listener.KeepSessionDuration
+		}
+		allListeners = append(allListeners, pl)
+	}
+
+	// add HTTPSlisteners
+	describeHTTPSListenerArgs := blbext.DescribeHTTPSListenerArgs{
+		LoadBalancerId: lb.BlbId,
+	}
+	httpsListeners, err := bc.clientSet.BLBClient.DescribeHTTPSListener(ctx, &describeHTTPSListenerArgs, bc.getSignOption(ctx))
+	if err != nil {
+		return nil, err
+	}
+	for _, listener := range httpsListeners {
+		pl := PortListener{
+			Port:          listener.ListenerPort,
+			Protocol:      "HTTPS",
+			NodePort:      int32(listener.BackendPort),
+			Scheduler:     listener.Scheduler,
+			ServerTimeout: listener.ServerTimeout,
+			CertIDs:       joinCertIDs(listener.CertIds),
+		}
+		if listener.KeepSession {
+			pl.KeepSession = true
+			pl.KeepSessionDuration = listener.KeepSessionDuration
+		}
+		allListeners = append(allListeners, pl)
+	}
+
+	err = bc.setHealthCheckTargets(ctx, lb, allListeners)
+	if err != nil {
+		return nil, err
+	}
 	return allListeners, nil
 }
 
+// setHealthCheckTargets sets health check target of listeners which are not checked on the backend port
+func (bc *Baiducloud) setHealthCheckTargets(ctx context.Context, lb *blb.LoadBalancer, listeners []PortListener) error {
+	protocols := make(map[string]bool)
+	for _, l := range listeners {
+		protocols[l.Protocol] = true
+	}
+	targets := make(map[listenerKey]blbext.ListenerHealthCheck)
+	for _, protocol := range []string{"TCP", "UDP", "HTTP", "HTTPS"} {
+		if !protocols[protocol] {
+			continue
+		}
+		args := blbext.DescribeListenerHealthChecksArgs{
+			LoadBalancerId: lb.BlbId,
+			Type:           protocol,
+		}
+		healthChecks, err := bc.clientSet.BLBClient.DescribeListenerHealthChecks(ctx, &args, bc.getSignOption(ctx))
+		if err != nil {
+			return err
+		}
+		for _, hc := range healthChecks {
+			if hc.HealthCheckPort != 0 {
+				targets[newListenerKey(protocol, hc.ListenerPort)] = hc
+			}
+		}
+	}
+	for i := range listeners {
+		hc, ok := targets[listeners[i].key()]
+		if !ok {
+			continue
+		}
+		listeners[i].HealthCheckType = hc.HealthCheckType
+		listeners[i].HealthCheckPort = hc.HealthCheckPort
+		listeners[i].HealthCheckURI = hc.HealthCheckURI
+	}
+	return nil
+}
+
+// updateListenerHealthCheck updates health check target of listener, listener without target is checked on the backend port
+func (bc *Baiducloud) updateListenerHealthCheck(ctx context.Context, lb *blb.LoadBalancer, pl PortListener) error {
+	args := blbext.UpdateListenerHealthCheckArgs{
+		LoadBalancerId:  lb.BlbId,
+		ListenerPort:    pl.Port,
+		Type:            pl.Protocol,
+		HealthCheckType: pl.HealthCheckType,
+		HealthCheckPort: pl.HealthCheckPort,
+		HealthCheckURI:  pl.HealthCheckURI,
+	}
+	if pl.HealthCheckPort == 0 {
+		// restore the default health check of protocol
+		args.HealthCheckType = pl.Protocol
+		if pl.Protocol == "HTTPS" {
+			args.HealthCheckType = blbHealthCheckTypeHTTP
+		}
+	}
+	if args.HealthCheckType == blbHealthCheckTypeHTTP && args.HealthCheckPort != 0 {
+		args.HealthCheckNormalStatus = blbHealthCheckNormalStatus2xx
+	}
+	klog.Infof(Message(ctx, fmt.Sprintf("update health check of %s listener %d of blb %s: %s %d %s",
+		pl.Protocol, pl.Port, lb.BlbId, args.HealthCheckType, args.HealthCheckPort, args.HealthCheckURI)))
+	return bc.clientSet.BLBClient.UpdateListenerHealthCheck(ctx, &args, bc.getSignOption(ctx))
+}
+
+// deleteListener deletes listeners by port and protocol, so that only the unwanted one of TCP and UDP listeners on
+// the same port is deleted
 func (bc *Baiducloud) deleteListener(ctx context.Context, lb *blb.LoadBalancer, pl []PortListener) error {
-	var portList []int
+	var portTypeList []blbext.ListenerPortType
 	for _, l := range pl {
-		portList = append(portList, l.Port)
+		portTypeList = append(portTypeList, blbext.ListenerPortType{
+			Port: l.Port,
+			Type: l.Protocol,
+		})
 	}
-	args := blb.DeleteListenersArgs{
+	args := blbext.DeleteListenersByTypeArgs{
 		LoadBalancerId: lb.BlbId,
-		PortList:       portList,
+		PortTypeList:   portTypeList,
 	}
-	err := bc.clientSet.BLBClient.DeleteListeners(ctx, &args, bc.getSignOption(ctx))
+	err := bc.clientSet.BLBClient.DeleteListenersByType(ctx, &args, bc.getSignOption(ctx))
 	if err != nil {
 		return err
 	}
@@ -8,6 +8,9 @@ import (
 	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/blb"
 	api "k8s.io/api/core/v1"
 	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/fake"
+	blbext "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/temp-blb"
 )
 
 func beforeTestListener() (*Baiducloud, *blb.CreateLoadBalancerResponse, error) {
@@ -43,6 +46,31 @@ func beforeTestListener() (*Baiducloud, *blb.CreateLoadBalancerResponse, error)
 	if err != nil {
 		return nil, nil, err
 	}
+	// create httplistener
+	argsHttp := blb.CreateHTTPListenerArgs{
+		LoadBalancerId: resp.LoadBalancerId,
+		ListenerPort:   15,
+		BackendPort:    16,
+		Scheduler:      "RoundRobin",
+		ServerTimeout:  defaultBLBServerTimeout,
+	}
+	err = cloud.clientSet.BLBClient.CreateHTTPListener(ctx, &argsHttp, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	// create httpslistener
+	argsHttps := blbext.CreateHTTPSListenerArgs{
+		LoadBalancerId: resp.LoadBalancerId,
+		ListenerPort:   17,
+		BackendPort:    18,
+		Scheduler:      "RoundRobin",
+		ServerTimeout:  defaultBLBServerTimeout,
+		CertIds:        []string{"cert-test"},
+	}
+	err = cloud.clientSet.BLBClient.CreateHTTPSListener(ctx, &argsHttps, nil)
+	if err != nil {
+		return nil, nil, err
+	}
 	return cloud, resp, nil
 }
 
@@ -59,8 +87,9 @@ func TestGetAllListeners(t *testing.T) {
 	if err != nil {
 		t.Errorf("getAllListeners err, err: %v", err)
 	}
-	if len(pl) != 2 || pl[0].Protocol != "TCP" ||
-		pl[1].Protocol != "UDP" {
+	if len(pl) != 4 || pl[0].Protocol != "TCP" ||
+		pl[1].Protocol != "UDP" || pl[2].Protocol != "HTTP" ||
+		pl[3].Protocol != "HTTPS" || pl[3].CertIDs != "cert-test" {
 		t.Errorf("getAllListeners err, get pl: %v", pl)
 	}
 	case2 := &blb.LoadBalancer{
@@ -92,6 +121,22 @@ func TestCreateListener(t *testing.T) {
 			Protocol: "UDP",
 			NodePort: 11,
 		},
+		{
+			Port:          80,
+			Protocol:      "HTTP",
+			NodePort:      11,
+			ServerTimeout: 60,
+			RedirectPort:  443,
+		},
+		{
+			Port:                443,
+			Protocol:            "HTTPS",
+			NodePort:            11,
+			ServerTimeout:       60,
+			KeepSession:         true,
+			KeepSessionDuration: 100,
+			CertIDs:             "cert-a,cert-b",
+		},
 	}
 	for _, pl := range pls {
 		err = cloud.createListener(ctx, lb, pl)
@@ -105,11 +150,6 @@ func TestCreateListener(t *testing.T) {
 			Protocol: "HTTPS",
 			NodePort: 11,
 		},
-		{
-			Port:     12,
-			Protocol: "HTTP",
-			NodePort: 11,
-		},
 		{
 			Port:     12,
 			Protocol: "test",
@@ -174,12 +214,40 @@ func TestUpdateListener(t *testing.T) {
 	}
 	pls = []PortListener{
 		{
-			Port:     12,
-			Protocol: "HTTP",
-			NodePort: 11,
+			Port:          15,
+			Protocol:      "HTTP",
+			NodePort:      11,
+			ServerTimeout: 60,
 		},
 		{
-			Port:     12,
+			Port:          17,
+			Protocol:      "HTTPS",
+			NodePort:      11,
+			ServerTimeout: 60,
+			CertIDs:       "cert-new",
+		},
+	}
+	for _, pl := range pls {
+		err = cloud.updateListener(ctx, lb, pl)
+		if err != nil {
+			t.Errorf("updateListener err, err: %v", err)
+		}
+	}
+	all, err := cloud.getAllListeners(ctx, lb)
+	if err != nil {
+		t.Errorf("getAllListeners err, err: %v", err)
+	}
+	for _, l := range all {
+		if l.Port == 17 && l.CertIDs != "cert-new" {
+			t.Errorf("updateListener err, HTTPS listener not updated: %v", l)
+		}
+		if l.Port == 15 && l.ServerTimeout != 60 {
+			t.Errorf("updateListener err, HTTP listener not updated: %v", l)
+		}
+	}
+	pls = []PortListener{
+		{
+			Port:     17,
 			Protocol: "HTTPS",
 			NodePort: 11,
 		},
@@ -288,3 +356,520 @@ func TestReconcileListeners(t *testing.T) {
 	}
 	// to complate...
 }
+
+func TestReconcileHTTPListeners(t *testing.T) {
+	cloud, resp, err := beforeTestListener()
+	if err != nil {
+		t.Errorf("beforeTestListener err, err: %v", err)
+	}
+	ctx := context.Background()
+	svc := &api.Service{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+			Annotations: map[string]string{
+				ServiceAnnotationCceAutoAddLoadBalancerID:     resp.LoadBalancerId,
+				ServiceAnnotationLoadBalancerListenerProtocol: "80:HTTP,443:HTTPS",
+				ServiceAnnotationLoadBalancerCertID:           "cert-a",
+				ServiceAnnotationLoadBalancerRedirectPort:     "80:443",
+				ServiceAnnotationLoadBalancerKeepSession:      "true",
+			},
+		},
+		Spec: api.ServiceSpec{
+			Ports: []api.ServicePort{
+				{
+					Name:     "http",
+					Port:     80,
+					Protocol: "TCP",
+					NodePort: 30080,
+				},
+				{
+					Name:     "https",
+					Port:     443,
+					Protocol: "TCP",
+					NodePort: 30443,
+				},
+			},
+		},
+	}
+	err = cloud.reconcileListeners(ctx, cloud.ClusterName, svc)
+	if err != nil {
+		t.Errorf("reconcileListeners err, err %v", err)
+	}
+	lb := &blb.LoadBalancer{
+		BlbId: resp.LoadBalancerId,
+	}
+	all, err := cloud.getAllListeners(ctx, lb)
+	if err != nil {
+		t.Errorf("getAllListeners err, err: %v", err)
+	}
+	expected, err := getExpectedListeners(svc)
+	if err != nil {
+		t.Errorf("getExpectedListeners err, err: %v", err)
+	}
+	if len(all) != len(expected) {
+		t.Errorf("reconcileListeners err, expected %v but get %v", expected, all)
+	}
+	for _, l := range all {
+		if expected[l.key()] != l {
+			t.Errorf("reconcileListeners err, expected %v but get %v", expected[l.key()], l)
+		}
+	}
+
+	// change protocol of port 80 from HTTP to TCP
+	delete(svc.Annotations, ServiceAnnotationLoadBalancerRedirectPort)
+	svc.Annotations[ServiceAnnotationLoadBalancerListenerProtocol] = "443:HTTPS"
+	svc.Annotations[ServiceAnnotationLoadBalancerCertID] = "cert-b"
+	err = cloud.reconcileListeners(ctx, cloud.ClusterName, svc)
+	if err != nil {
+		t.Errorf("reconcileListeners err, err %v", err)
+	}
+	all, err = cloud.getAllListeners(ctx, lb)
+	if err != nil {
+		t.Errorf("getAllListeners err, err: %v", err)
+	}
+	if len(all) != 2 {
+		t.Errorf("reconcileListeners err, get %v", all)
+	}
+	for _, l := range all {
+		if l.Port == 80 && l.Protocol != "TCP" {
+			t.Errorf("reconcileListeners err, port 80 should be TCP but get %v", l)
+		}
+		if l.Port == 443 && l.CertIDs != "cert-b" {
+			t.Errorf("reconcileListeners err, port 443 should use cert-b but get %v", l)
+		}
+	}
+}
+
+func TestListenerCertIDsOrder(t *testing.T) {
+	cloud, resp, err := beforeTestListener()
+	if err != nil {
+		t.Errorf("beforeTestListener err, err: %v", err)
+	}
+	ctx := context.Background()
+	args := blbext.CreateHTTPSListenerArgs{
+		LoadBalancerId: resp.LoadBalancerId,
+		ListenerPort:   443,
+		BackendPort:    30443,
+		Scheduler:      "RoundRobin",
+		ServerTimeout:  defaultBLBServerTimeout,
+		CertIds:        []string{"cert-b", "cert-a"},
+	}
+	err = cloud.clientSet.BLBClient.CreateHTTPSListener(ctx, &args, nil)
+	if err != nil {
+		t.Errorf("CreateHTTPSListener err, err: %v", err)
+	}
+	svc := &api.Service{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+			Annotations: map[string]string{
+				ServiceAnnotationLoadBalancerListenerProtocol: "443:HTTPS",
+				ServiceAnnotationLoadBalancerCertID:           "cert-a,cert-b",
+			},
+		},
+		Spec: api.ServiceSpec{
+			Ports: []api.ServicePort{
+				{
+					Name:     "https",
+					Port:     443,
+					Protocol: "TCP",
+					NodePort: 30443,
+				},
+			},
+		},
+	}
+	expected, err := getExpectedListeners(svc)
+	if err != nil {
+		t.Errorf("getExpectedListeners err, err: %v", err)
+	}
+	all, err := cloud.getAllListeners(ctx, &blb.LoadBalancer{BlbId: resp.LoadBalancerId})
+	if err != nil {
+		t.Errorf("getAllListeners err, err: %v", err)
+	}
+	for _, l := range all {
+		if l.Port == 443 && l.CertIDs != expected[l.key()].CertIDs {
+			t.Errorf("getAllListeners err, want cert ids %s, get %s", expected[l.key()].CertIDs, l.CertIDs)
+		}
+	}
+}
+
+func TestReconcileListenersHealthCheck(t *testing.T) {
+	cloud, resp, err := beforeTestListener()
+	if err != nil {
+		t.Errorf("beforeTestListener err, err: %v", err)
+	}
+	ctx := context.Background()
+	svc := &api.Service{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+			Annotations: map[string]string{
+				ServiceAnnotationCceAutoAddLoadBalancerID: resp.LoadBalancerId,
+			},
+		},
+		Spec: api.ServiceSpec{
+			Ports: []api.ServicePort{
+				{
+					Name:     "tcp",
+					Port:     11,
+					Protocol: "TCP",
+					NodePort: 30011,
+				},
+				{
+					Name:     "udp",
+					Port:     13,
+					Protocol: "UDP",
+					NodePort: 30013,
+				},
+			},
+		},
+	}
+	lb := &blb.LoadBalancer{
+		BlbId: resp.LoadBalancerId,
+	}
+	// default health check
+	err = cloud.reconcileListeners(ctx, cloud.ClusterName, svc)
+	if err != nil {
+		t.Errorf("reconcileListeners err, err %v", err)
+	}
+	all, err := cloud.getAllListeners(ctx, lb)
+	if err != nil {
+		t.Errorf("getAllListeners err, err: %v", err)
+	}
+	for _, l := range all {
+		if l.HealthCheckTimeoutInSecond != defaultBLBHealthCheckTimeoutInSecond ||
+			l.HealthCheckInterval != defaultBLBHealthCheckInterval ||
+			l.UnhealthyThreshold != defaultBLBUnhealthyThreshold ||
+			l.HealthyThreshold != defaultBLBHealthyThreshold {
+			t.Errorf("reconcileListeners err, listener should use default health check: %v", l)
+		}
+		if l.Protocol == "UDP" && l.HealthCheckString != defaultBLBHealthCheckString {
+			t.Errorf("reconcileListeners err, UDP listener should use default health check string: %v", l)
+		}
+	}
+
+	// annotation changed
+	svc.Annotations[ServiceAnnotationLoadBalancerHealthCheckTimeoutInSecond] = "10"
+	svc.Annotations[ServiceAnnotationLoadBalancerHealthCheckInterval] = "5"
+	svc.Annotations[ServiceAnnotationLoadBalancerUnhealthyThreshold] = "2"
+	svc.Annotations[ServiceAnnotationLoadBalancerHealthyThreshold] = "4"
+	svc.Annotations[ServiceAnnotationLoadBalancerHealthCheckString] = "ping"
+	err = cloud.reconcileListeners(ctx, cloud.ClusterName, svc)
+	if err != nil {
+		t.Errorf("reconcileListeners err, err %v", err)
+	}
+	all, err = cloud.getAllListeners(ctx, lb)
+	if err != nil {
+		t.Errorf("getAllListeners err, err: %v", err)
+	}
+	if len(all) != 2 {
+		t.Errorf("reconcileListeners err, get %v", all)
+	}
+	for _, l := range all {
+		if l.HealthCheckTimeoutInSecond != 10 || l.HealthCheckInterval != 5 ||
+			l.UnhealthyThreshold != 2 || l.HealthyThreshold != 4 {
+			t.Errorf("reconcileListeners err, listener health check not updated: %v", l)
+		}
+		if l.Protocol == "UDP" && l.HealthCheckString != "ping" {
+			t.Errorf("reconcileListeners err, UDP listener health check string not updated: %v", l)
+		}
+	}
+}
+
+func TestGetExpectedListenersScheduler(t *testing.T) {
+	svc := &api.Service{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:        "foo",
+			Namespace:   api.NamespaceDefault,
+			Annotations: map[string]string{},
+		},
+		Spec: api.ServiceSpec{
+			Ports: []api.ServicePort{
+				{
+					Name:     "tcp",
+					Port:     80,
+					Protocol: "TCP",
+					NodePort: 30080,
+				},
+				{
+					Name:     "udp",
+					Port:     53,
+					Protocol: "UDP",
+					NodePort: 30053,
+				},
+			},
+		},
+	}
+	// default
+	expected, err := getExpectedListeners(svc)
+	if err != nil {
+		t.Errorf("getExpectedListeners err, err: %v", err)
+	}
+	if expected[listenerKey{"TCP", 80}].Scheduler != "RoundRobin" || expected[listenerKey{"UDP", 53}].Scheduler != "RoundRobin" {
+		t.Errorf("getExpectedListeners err, scheduler should be RoundRobin: %v", expected)
+	}
+	// ClientIP session affinity
+	svc.Spec.SessionAffinity = api.ServiceAffinityClientIP
+	expected, err = getExpectedListeners(svc)
+	if err != nil {
+		t.Errorf("getExpectedListeners err, err: %v", err)
+	}
+	if expected[listenerKey{"TCP", 80}].Scheduler != "Hash" || expected[listenerKey{"UDP", 53}].Scheduler != "Hash" {
+		t.Errorf("getExpectedListeners err, scheduler should be Hash: %v", expected)
+	}
+	// service annotation with per port override
+	svc.Annotations[ServiceAnnotationLoadBalancerScheduler] = "RoundRobin"
+	svc.Annotations[ServiceAnnotationLoadBalancerPortScheduler] = "80:LeastConnection"
+	expected, err = getExpectedListeners(svc)
+	if err != nil {
+		t.Errorf("getExpectedListeners err, err: %v", err)
+	}
+	if expected[listenerKey{"TCP", 80}].Scheduler != "LeastConnection" || expected[listenerKey{"UDP", 53}].Scheduler != "RoundRobin" {
+		t.Errorf("getExpectedListeners err, get %v", expected)
+	}
+	// UDP does not support LeastConnection
+	svc.Annotations[ServiceAnnotationLoadBalancerPortScheduler] = "53:LeastConnection"
+	_, err = getExpectedListeners(svc)
+	if err == nil {
+		t.Errorf("getExpectedListeners err, there should be an error but get nil")
+	}
+	// HTTP does not support Hash
+	svc.Annotations[ServiceAnnotationLoadBalancerPortScheduler] = "80:Hash"
+	svc.Annotations[ServiceAnnotationLoadBalancerListenerProtocol] = "80:HTTP"
+	_, err = getExpectedListeners(svc)
+	if err == nil {
+		t.Errorf("getExpectedListeners err, there should be an error but get nil")
+	}
+}
+
+func TestReconcileListenersScheduler(t *testing.T) {
+	cloud, resp, err := beforeTestListener()
+	if err != nil {
+		t.Errorf("beforeTestListener err, err: %v", err)
+	}
+	ctx := context.Background()
+	svc := &api.Service{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+			Annotations: map[string]string{
+				ServiceAnnotationCceAutoAddLoadBalancerID: resp.LoadBalancerId,
+			},
+		},
+		Spec: api.ServiceSpec{
+			Ports: []api.ServicePort{
+				{
+					Name:     "tcp",
+					Port:     11,
+					Protocol: "TCP",
+					NodePort: 30011,
+				},
+			},
+		},
+	}
+	lb := &blb.LoadBalancer{
+		BlbId: resp.LoadBalancerId,
+	}
+	err = cloud.reconcileListeners(ctx, cloud.ClusterName, svc)
+	if err != nil {
+		t.Errorf("reconcileListeners err, err %v", err)
+	}
+	svc.Annotations[ServiceAnnotationLoadBalancerScheduler] = "LeastConnection"
+	err = cloud.reconcileListeners(ctx, cloud.ClusterName, svc)
+	if err != nil {
+		t.Errorf("reconcileListeners err, err %v", err)
+	}
+	all, err := cloud.getAllListeners(ctx, lb)
+	if err != nil {
+		t.Errorf("getAllListeners err, err: %v", err)
+	}
+	if len(all) != 1 || all[0].Scheduler != "LeastConnection" {
+		t.Errorf("reconcileListeners err, scheduler not updated: %v", all)
+	}
+}
+
+// case1: TCP and UDP listeners on the same port are both created
+// case2: removing UDP port deletes only the UDP listener
+// case3: deleteListener deletes listener by protocol
+func TestReconcileListenersMixedProtocol(t *testing.T) {
+	cloud, resp, err := beforeTestListener()
+	if err != nil {
+		t.Errorf("beforeTestListener err, err: %v", err)
+	}
+	ctx := context.Background()
+	svc := &api.Service{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "dns",
+			Namespace: api.NamespaceDefault,
+			Annotations: map[string]string{
+				ServiceAnnotationCceAutoAddLoadBalancerID: resp.LoadBalancerId,
+			},
+		},
+		Spec: api.ServiceSpec{
+			Ports: []api.ServicePort{
+				{
+					Name:     "dns-tcp",
+					Port:     53,
+					Protocol: "TCP",
+					NodePort: 30053,
+				},
+				{
+					Name:     "dns-udp",
+					Port:     53,
+					Protocol: "UDP",
+					NodePort: 30054,
+				},
+			},
+		},
+	}
+	lb := &blb.LoadBalancer{
+		BlbId: resp.LoadBalancerId,
+	}
+	listenerKeys := func() map[listenerKey]PortListener {
+		all, err := cloud.getAllListeners(ctx, lb)
+		if err != nil {
+			t.Fatalf("getAllListeners err, err: %v", err)
+		}
+		result := make(map[listenerKey]PortListener)
+		for _, l := range all {
+			result[l.key()] = l
+		}
+		return result
+	}
+
+	// case1
+	expected, err := getExpectedListeners(svc)
+	if err != nil {
+		t.Fatalf("getExpectedListeners err, err: %v", err)
+	}
+	if len(expected) != 2 {
+		t.Fatalf("getExpectedListeners err, want 53/TCP and 53/UDP, get %v", expected)
+	}
+	for i := 0; i < 2; i++ {
+		err = cloud.reconcileListeners(ctx, cloud.ClusterName, svc)
+		if err != nil {
+			t.Fatalf("reconcileListeners err, err %v", err)
+		}
+	}
+	all := listenerKeys()
+	if len(all) != 2 {
+		t.Errorf("reconcileListeners err, want 53/TCP and 53/UDP, get %v", all)
+	}
+	if l := all[listenerKey{"TCP", 53}]; l.Protocol != "TCP" || l.NodePort != 30053 {
+		t.Errorf("reconcileListeners err, want 53/TCP to 30053, get %v", l)
+	}
+	if l := all[listenerKey{"UDP", 53}]; l.Protocol != "UDP" || l.NodePort != 30054 {
+		t.Errorf("reconcileListeners err, want 53/UDP to 30054, get %v", l)
+	}
+
+	// case2
+	svc.Spec.Ports = svc.Spec.Ports[:1]
+	err = cloud.reconcileListeners(ctx, cloud.ClusterName, svc)
+	if err != nil {
+		t.Fatalf("reconcileListeners err, err %v", err)
+	}
+	all = listenerKeys()
+	if _, ok := all[listenerKey{"TCP", 53}]; len(all) != 1 || !ok {
+		t.Errorf("reconcileListeners err, want only 53/TCP left, get %v", all)
+	}
+
+	// case3
+	err = cloud.createListener(ctx, lb, PortListener{Port: 53, Protocol: "UDP", NodePort: 30054})
+	if err != nil {
+		t.Fatalf("createListener err, err: %v", err)
+	}
+	err = cloud.deleteListener(ctx, lb, []PortListener{{Port: 53, Protocol: "TCP"}})
+	if err != nil {
+		t.Fatalf("deleteListener err, err: %v", err)
+	}
+	all = listenerKeys()
+	if _, ok := all[listenerKey{"UDP", 53}]; len(all) != 1 || !ok {
+		t.Errorf("deleteListener err, want only 53/UDP left, get %v", all)
+	}
+}
+
+// case1: listeners of Local service using healthCheckNodePort check nodes on it over HTTP
+// case2: health check goes back to the backend port when annotation is removed
+// case3: healthCheckNodePort is ignored by Cluster service
+func TestReconcileListenersHealthCheckNodePort(t *testing.T) {
+	cloud, resp, err := beforeTestListener()
+	if err != nil {
+		t.Errorf("beforeTestListener err, err: %v", err)
+	}
+	ctx := context.Background()
+	svc := &api.Service{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "web",
+			Namespace: api.NamespaceDefault,
+			Annotations: map[string]string{
+				ServiceAnnotationCceAutoAddLoadBalancerID:        resp.LoadBalancerId,
+				ServiceAnnotationLoadBalancerHealthCheckNodePort: "true",
+			},
+		},
+		Spec: api.ServiceSpec{
+			ExternalTrafficPolicy: api.ServiceExternalTrafficPolicyTypeLocal,
+			HealthCheckNodePort:   32000,
+			Ports: []api.ServicePort{
+				{
+					Port:     80,
+					Protocol: "TCP",
+					NodePort: 30080,
+				},
+			},
+		},
+	}
+	lb := &blb.LoadBalancer{
+		BlbId: resp.LoadBalancerId,
+	}
+	getListener := func() PortListener {
+		all, err := cloud.getAllListeners(ctx, lb)
+		if err != nil {
+			t.Fatalf("getAllListeners err, err: %v", err)
+		}
+		for _, l := range all {
+			if l.key() == (listenerKey{"TCP", 80}) {
+				return l
+			}
+		}
+		t.Fatalf("getAllListeners err, want 80/TCP, get %v", all)
+		return PortListener{}
+	}
+
+	// case1
+	for i := 0; i < 2; i++ {
+		err = cloud.reconcileListeners(ctx, cloud.ClusterName, svc)
+		if err != nil {
+			t.Fatalf("reconcileListeners err, err %v", err)
+		}
+	}
+	l := getListener()
+	if l.NodePort != 30080 || l.HealthCheckType != "HTTP" || l.HealthCheckPort != 32000 || l.HealthCheckURI != "/healthz" {
+		t.Errorf("reconcileListeners err, want HTTP health check on 32000/healthz, get %v", l)
+	}
+	healthChecks := cloud.clientSet.BLBClient.(*fake.BlbFakeClient).HealthCheckMap[resp.LoadBalancerId]
+	if len(healthChecks) != 1 || healthChecks[0].HealthCheckNormalStatus != "http_2xx" {
+		t.Errorf("reconcileListeners err, want one health check with status http_2xx, get %v", healthChecks)
+	}
+
+	// case2
+	delete(svc.Annotations, ServiceAnnotationLoadBalancerHealthCheckNodePort)
+	err = cloud.reconcileListeners(ctx, cloud.ClusterName, svc)
+	if err != nil {
+		t.Fatalf("reconcileListeners err, err %v", err)
+	}
+	if l := getListener(); l.HealthCheckPort != 0 || l.HealthCheckType != "" {
+		t.Errorf("reconcileListeners err, want health check on backend port, get %v", l)
+	}
+
+	// case3
+	svc.Annotations[ServiceAnnotationLoadBalancerHealthCheckNodePort] = "true"
+	svc.Spec.ExternalTrafficPolicy = api.ServiceExternalTrafficPolicyTypeCluster
+	expected, err := getExpectedListeners(svc)
+	if err != nil {
+		t.Fatalf("getExpectedListeners err, err: %v", err)
+	}
+	if l := expected[listenerKey{"TCP", 80}]; l.HealthCheckPort != 0 {
+		t.Errorf("getExpectedListeners err, want no healthCheckNodePort for Cluster service, get %v", l)
+	}
+}
@@ -0,0 +1,283 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloud_provider
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/blb"
+	v1 "k8s.io/api/core/v1"
+	servicehelper "k8s.io/cloud-provider/service/helpers"
+	"k8s.io/klog"
+
+	bcc "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/temp-bcc"
+	blbext "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/temp-blb"
+)
+
+// reconcileSecurityGroup turns LoadBalancerSourceRanges of service into a security group bound to the BLB.
+// The security group is owned by the service, and is removed when source ranges allow all.
+func (bc *Baiducloud) reconcileSecurityGroup(ctx context.Context, service *v1.Service, lb *blb.LoadBalancer) error {
+	startTime := time.Now()
+	serviceKey := fmt.Sprintf("%s/%s", service.Namespace, service.Name)
+	defer func() {
+		klog.V(4).Infof(Message(ctx, fmt.Sprintf("Finished reconcileSecurityGroup for service %q (%v)", serviceKey, time.Since(startTime))))
+	}()
+
+	sourceRanges, err := getServiceSourceRanges(service)
+	if err != nil {
+		return err
+	}
+	if len(sourceRanges) == 0 {
+		klog.Infof(Message(ctx, fmt.Sprintf("reconcileSecurityGroup for service %s: source ranges allow all", serviceKey)))
+		err = bc.ensureSecurityGroupDeleted(ctx, service, lb)
+		if err != nil {
+			return err
+		}
+		delete(service.Annotations, ServiceAnnotationLoadBalancerSecurityGroupID)
+		return nil
+	}
+
+	expected, err := getExpectedSecurityGroupRules(service, sourceRanges)
+	if err != nil {
+		return err
+	}
+
+	sg, exist, err := bc.getServiceAssociatedSecurityGroup(ctx, service)
+	if err != nil {
+		return err
+	}
+	if !exist {
+		vpcID, err := bc.getVpcID(ctx)
+		if err != nil {
+			return fmt.Errorf("reconcileSecurityGroup get vpc failed: %v", err)
+		}
+		args := bcc.CreateSecurityGroupArgs{
+			Name:  getSecurityGroupName(bc.ClusterID, service),
+			Desc:  "auto generated by cce:" + bc.ClusterID,
+			VpcID: vpcID,
+			Rules: append(expected, bcc.SecurityGroupRule{
+				Direction: bcc.SecurityGroupRuleDirectionEgress,
+				Ethertype: "IPv4",
+				Protocol:  "all",
+				PortRange: "1-65535",
+				DestIP:    "0.0.0.0/0",
+			}),
+		}
+		klog.Infof(Message(ctx, fmt.Sprintf("reconcileSecurityGroup for service %s: create security group %v", serviceKey, args)))
+		resp, err := bc.clientSet.BCCClient.CreateSecurityGroup(ctx, &args, bc.getSignOption(ctx))
+		if err != nil {
+			return err
+		}
+		sg = &bcc.SecurityGroup{ID: resp.SecurityGroupID}
+	} else {
+		err = bc.reconcileSecurityGroupRules(ctx, service, sg, expected)
+		if err != nil {
+			return err
+		}
+	}
+
+	if service.Annotations == nil {
+		service.Annotations = make(map[string]string, 0)
+	}
+	service.Annotations[ServiceAnnotationLoadBalancerSecurityGroupID] = sg.ID
+
+	// bind security group to BLB
+	bound, err := bc.clientSet.BLBClient.DescribeSecurityGroups(ctx, lb.BlbId, bc.getSignOption(ctx))
+	if err != nil {
+		return err
+	}
+	for _, b := range bound {
+		if b.SecurityGroupID == sg.ID {
+			return nil
+		}
+	}
+	klog.Infof(Message(ctx, fmt.Sprintf("reconcileSecurityGroup for service %s: bind security group %s to blb %s", serviceKey, sg.ID, lb.BlbId)))
+	return bc.clientSet.BLBClient.BindSecurityGroups(ctx, &blbext.UpdateSecurityGroupsArgs{
+		LoadBalancerId:   lb.BlbId,
+		SecurityGroupIds: []string{sg.ID},
+	}, bc.getSignOption(ctx))
+}
+
+// reconcileSecurityGroupRules authorizes missing ingress rules and revokes unexpected ones, egress rules are left alone
+func (bc *Baiducloud) reconcileSecurityGroupRules(ctx context.Context, service *v1.Service, sg *bcc.SecurityGroup, expected []bcc.SecurityGroupRule) error {
+	serviceKey := fmt.Sprintf("%s/%s", service.Namespace, service.Name)
+	expectedMap := make(map[string]bcc.SecurityGroupRule, len(expected))
+	for _, rule := range expected {
+		expectedMap[securityGroupRuleKey(rule)] = rule
+	}
+	var revokeList []bcc.SecurityGroupRule
+	for _, rule := range sg.Rules {
+		if rule.Direction != bcc.SecurityGroupRuleDirectionIngress {
+			continue
+		}
+		key := securityGroupRuleKey(rule)
+		if _, ok := expectedMap[key]; ok {
+			delete(expectedMap, key)
+			continue
+		}
+		revokeList = append(revokeList, rule)
+	}
+
+	for _, rule := range revokeList {
+		klog.Infof(Message(ctx, fmt.Sprintf("reconcileSecurityGroup for service %s: revoke rule %v", serviceKey, rule)))
+		err := bc.clientSet.BCCClient.RevokeSecurityGroupRule(ctx, &bcc.SecurityGroupRuleArgs{
+			SecurityGroupID: sg.ID,
+			Rule:            rule,
+		}, bc.getSignOption(ctx))
+		if err != nil {
+			return err
+		}
+	}
+	for _, rule := range expected {
+		if _, ok := expectedMap[securityGroupRuleKey(rule)]; !ok {
+			continue
+		}
+		klog.Infof(Message(ctx, fmt.Sprintf("reconcileSecurityGroup for service %s: authorize rule %v", serviceKey, rule)))
+		err := bc.clientSet.BCCClient.AuthorizeSecurityGroupRule(ctx, &bcc.SecurityGroupRuleArgs{
+			SecurityGroupID: sg.ID,
+			Rule:            rule,
+		}, bc.getSignOption(ctx))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ensureSecurityGroupDeleted unbinds the security group owned by service from BLB and deletes it. The security group
+// is only looked up in VPC if it is recorded by annotation or bound to BLB, so that services without source ranges do
+// not list security groups on every sync.
+func (bc *Baiducloud) ensureSecurityGroupDeleted(ctx context.Context, service *v1.Service, lb *blb.LoadBalancer) error {
+	serviceKey := fmt.Sprintf("%s/%s", service.Namespace, service.Name)
+	var bound []blbext.BlbSecurityGroup
+	if lb != nil && lb.BlbId != "" {
+		var err error
+		bound, err = bc.clientSet.BLBClient.DescribeSecurityGroups(ctx, lb.BlbId, bc.getSignOption(ctx))
+		if err != nil {
+			return err
+		}
+	}
+	if service.Annotations[ServiceAnnotationLoadBalancerSecurityGroupID] == "" && !hasSecurityGroupNamed(bound, getSecurityGroupName(bc.ClusterID, service)) {
+		return nil
+	}
+
+	sg, exist, err := bc.getServiceAssociatedSecurityGroup(ctx, service)
+	if err != nil {
+		return err
+	}
+	if !exist {
+		return nil
+	}
+
+	for _, b := range bound {
+		if b.SecurityGroupID != sg.ID {
+			continue
+		}
+		klog.Infof(Message(ctx, fmt.Sprintf("ensureSecurityGroupDeleted for service %s: unbind security group %s from blb %s", serviceKey, sg.ID, lb.BlbId)))
+		err = bc.clientSet.BLBClient.UnbindSecurityGroups(ctx, &blbext.UpdateSecurityGroupsArgs{
+			LoadBalancerId:   lb.BlbId,
+			SecurityGroupIds: []string{sg.ID},
+		}, bc.getSignOption(ctx))
+		if err != nil {
+			return err
+		}
+	}
+
+	klog.Infof(Message(ctx, fmt.Sprintf("ensureSecurityGroupDeleted for service %s: delete security group %s", serviceKey, sg.ID)))
+	return bc.clientSet.BCCClient.DeleteSecurityGroup(ctx, sg.ID, bc.getSignOption(ctx))
+}
+
+func hasSecurityGroupNamed(bound []blbext.BlbSecurityGroup, name string) bool {
+	for _, b := range bound {
+		if b.SecurityGroupName == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (bc *Baiducloud) getServiceAssociatedSecurityGroup(ctx context.Context, service *v1.Service) (*bcc.SecurityGroup, bool, error) {
+	vpcID, err := bc.getVpcID(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("getServiceAssociatedSecurityGroup get vpc failed: %v", err)
+	}
+	sgs, err := bc.clientSet.BCCClient.ListSecurityGroups(ctx, &bcc.ListSecurityGroupsArgs{VpcID: vpcID}, bc.getSignOption(ctx))
+	if err != nil {
+		return nil, false, err
+	}
+	name := getSecurityGroupName(bc.ClusterID, service)
+	for i := range sgs {
+		if sgs[i].Name == name {
+			return &sgs[i], true, nil
+		}
+	}
+	return nil, false, nil
+}
+
+// getServiceSourceRanges returns sorted source ranges from spec or annotation, nil means allow all
+func getServiceSourceRanges(service *v1.Service) ([]string, error) {
+	ipnets, err := servicehelper.GetLoadBalancerSourceRanges(service)
+	if err != nil {
+		return nil, err
+	}
+	if servicehelper.IsAllowAll(ipnets) {
+		return nil, nil
+	}
+	ranges := ipnets.StringSlice()
+	sort.Strings(ranges)
+	return ranges, nil
+}
+
+// getExpectedSecurityGroupRules allows source ranges to access every listener port of service
+func getExpectedSecurityGroupRules(service *v1.Service, sourceRanges []string) ([]bcc.SecurityGroupRule, error) {
+	var rules []bcc.SecurityGroupRule
+	for _, port := range service.Spec.Ports {
+		for _, cidr := range sourceRanges {
+			ip, _, err := net.ParseCIDR(cidr)
+			if err != nil {
+				return nil, err
+			}
+			ethertype := "IPv4"
+			if ip.To4() == nil {
+				ethertype = "IPv6"
+			}
+			rules = append(rules, bcc.SecurityGroupRule{
+				Remark:    fmt.Sprintf("%s/%s", service.Namespace, service.Name),
+				Direction: bcc.SecurityGroupRuleDirectionIngress,
+				Ethertype: ethertype,
+				Protocol:  strings.ToLower(string(port.Protocol)),
+				PortRange: strconv.Itoa(int(port.Port)),
+				SourceIP:  cidr,
+			})
+		}
+	}
+	return rules, nil
+}
+
+func securityGroupRuleKey(rule bcc.SecurityGroupRule) string {
+	return fmt.Sprintf("%s/%s/%s/%s", rule.Direction, strings.ToLower(rule.Protocol), rule.PortRange, rule.SourceIP)
+}
+
+func getSecurityGroupName(clusterID string, service *v1.Service) string {
+	return fmt.Sprintf("CCE/SVC/%s/%s/%s", clusterID, service.Namespace, service.Name)
+}
@@ -0,0 +1,241 @@
+package cloud_provider
+
+import (
+	"context"
+	"testing"
+
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/blb"
+	api "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/fake"
+	bcc "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/temp-bcc"
+)
+
+func countIngressRules(sg *bcc.SecurityGroup) int {
+	count := 0
+	for _, rule := range sg.Rules {
+		if rule.Direction == bcc.SecurityGroupRuleDirectionIngress {
+			count++
+		}
+	}
+	return count
+}
+
+func TestReconcileSecurityGroup(t *testing.T) {
+	cloud, _, resp, err := beforeTestBlb()
+	if err != nil {
+		t.Fatalf("beforeTestBlb err, err: %v", err)
+	}
+	ctx := context.Background()
+	lb := &blb.LoadBalancer{
+		BlbId: resp.LoadBalancerId,
+	}
+	svc := &api.Service{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:        "foo",
+			Namespace:   api.NamespaceDefault,
+			Annotations: map[string]string{},
+		},
+		Spec: api.ServiceSpec{
+			Ports: []api.ServicePort{
+				{
+					Name:     "tcp",
+					Port:     80,
+					Protocol: "TCP",
+				},
+				{
+					Name:     "udp",
+					Port:     53,
+					Protocol: "UDP",
+				},
+			},
+			LoadBalancerSourceRanges: []string{"10.0.0.0/8"},
+		},
+	}
+
+	// create security group
+	err = cloud.reconcileSecurityGroup(ctx, svc, lb)
+	if err != nil {
+		t.Errorf("reconcileSecurityGroup err, err: %v", err)
+	}
+	sg, exist, err := cloud.getServiceAssociatedSecurityGroup(ctx, svc)
+	if err != nil || !exist {
+		t.Fatalf("getServiceAssociatedSecurityGroup err, exist: %v, err: %v", exist, err)
+	}
+	if countIngressRules(sg) != 2 {
+		t.Errorf("reconcileSecurityGroup err, get rules %v", sg.Rules)
+	}
+	bound, err := cloud.clientSet.BLBClient.DescribeSecurityGroups(ctx, lb.BlbId, nil)
+	if err != nil {
+		t.Errorf("DescribeSecurityGroups err, err: %v", err)
+	}
+	if len(bound) != 1 || bound[0].SecurityGroupID != sg.ID {
+		t.Errorf("reconcileSecurityGroup err, security group not bound: %v", bound)
+	}
+
+	// source ranges from annotation replace the ones in spec
+	svc.Spec.LoadBalancerSourceRanges = nil
+	svc.Annotations[api.AnnotationLoadBalancerSourceRangesKey] = "192.168.0.0/16,172.16.0.0/12"
+	err = cloud.reconcileSecurityGroup(ctx, svc, lb)
+	if err != nil {
+		t.Errorf("reconcileSecurityGroup err, err: %v", err)
+	}
+	sg, exist, err = cloud.getServiceAssociatedSecurityGroup(ctx, svc)
+	if err != nil || !exist {
+		t.Fatalf("getServiceAssociatedSecurityGroup err, exist: %v, err: %v", exist, err)
+	}
+	if countIngressRules(sg) != 4 {
+		t.Errorf("reconcileSecurityGroup err, get rules %v", sg.Rules)
+	}
+	for _, rule := range sg.Rules {
+		if rule.SourceIP == "10.0.0.0/8" {
+			t.Errorf("reconcileSecurityGroup err, rule %v should be revoked", rule)
+		}
+	}
+
+	// allow all removes security group
+	delete(svc.Annotations, api.AnnotationLoadBalancerSourceRangesKey)
+	err = cloud.reconcileSecurityGroup(ctx, svc, lb)
+	if err != nil {
+		t.Errorf("reconcileSecurityGroup err, err: %v", err)
+	}
+	_, exist, err = cloud.getServiceAssociatedSecurityGroup(ctx, svc)
+	if err != nil || exist {
+		t.Errorf("reconcileSecurityGroup err, security group should be deleted, exist: %v, err: %v", exist, err)
+	}
+	bound, err = cloud.clientSet.BLBClient.DescribeSecurityGroups(ctx, lb.BlbId, nil)
+	if err != nil {
+		t.Errorf("DescribeSecurityGroups err, err: %v", err)
+	}
+	if len(bound) != 0 {
+		t.Errorf("reconcileSecurityGroup err, security group should be unbound: %v", bound)
+	}
+	if _, ok := svc.Annotations[ServiceAnnotationLoadBalancerSecurityGroupID]; ok {
+		t.Errorf("reconcileSecurityGroup err, security group annotation should be removed")
+	}
+
+	// wrong source ranges
+	svc.Spec.LoadBalancerSourceRanges = []string{"10.0.0.0"}
+	err = cloud.reconcileSecurityGroup(ctx, svc, lb)
+	if err == nil {
+		t.Errorf("reconcileSecurityGroup err, there should be an error but get nil")
+	}
+}
+
+func TestEnsureSecurityGroupDeleted(t *testing.T) {
+	cloud, _, resp, err := beforeTestBlb()
+	if err != nil {
+		t.Fatalf("beforeTestBlb err, err: %v", err)
+	}
+	ctx := context.Background()
+	lb := &blb.LoadBalancer{
+		BlbId: resp.LoadBalancerId,
+	}
+	svc := &api.Service{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+		},
+		Spec: api.ServiceSpec{
+			Ports: []api.ServicePort{
+				{
+					Name:     "tcp",
+					Port:     80,
+					Protocol: "TCP",
+				},
+			},
+			LoadBalancerSourceRanges: []string{"10.0.0.0/8"},
+		},
+	}
+	// nothing to delete
+	err = cloud.ensureSecurityGroupDeleted(ctx, svc, lb)
+	if err != nil {
+		t.Errorf("ensureSecurityGroupDeleted err, err: %v", err)
+	}
+	err = cloud.reconcileSecurityGroup(ctx, svc, lb)
+	if err != nil {
+		t.Errorf("reconcileSecurityGroup err, err: %v", err)
+	}
+	err = cloud.ensureSecurityGroupDeleted(ctx, svc, lb)
+	if err != nil {
+		t.Errorf("ensureSecurityGroupDeleted err, err: %v", err)
+	}
+	_, exist, err := cloud.getServiceAssociatedSecurityGroup(ctx, svc)
+	if err != nil || exist {
+		t.Errorf("ensureSecurityGroupDeleted err, exist: %v, err: %v", exist, err)
+	}
+}
+
+// case1: security groups are not listed for service without source ranges, annotation or bound security group
+// case2: security group bound to BLB by name is deleted without annotation
+// case3: security group recorded by annotation is deleted though it is not bound
+func TestEnsureSecurityGroupDeletedLookup(t *testing.T) {
+	cloud, _, resp, err := beforeTestBlb()
+	if err != nil {
+		t.Fatalf("beforeTestBlb err, err: %v", err)
+	}
+	ctx := context.Background()
+	lb := &blb.LoadBalancer{
+		BlbId: resp.LoadBalancerId,
+	}
+	faults := fake.NewFaults()
+	cloud.clientSet.BCCClient.(*fake.BccFakeClient).Faults = faults
+	svc := &api.Service{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+		},
+		Spec: api.ServiceSpec{
+			Ports: []api.ServicePort{
+				{
+					Name:     "tcp",
+					Port:     80,
+					Protocol: "TCP",
+				},
+			},
+		},
+	}
+
+	// case1
+	err = cloud.reconcileSecurityGroup(ctx, svc, lb)
+	if err != nil {
+		t.Errorf("reconcileSecurityGroup err, err: %v", err)
+	}
+	if n := faults.Calls("ListSecurityGroups"); n != 0 {
+		t.Errorf("reconcileSecurityGroup err, want security groups not listed, get %d calls", n)
+	}
+
+	// case2
+	svc.Spec.LoadBalancerSourceRanges = []string{"10.0.0.0/8"}
+	err = cloud.reconcileSecurityGroup(ctx, svc, lb)
+	if err != nil {
+		t.Errorf("reconcileSecurityGroup err, err: %v", err)
+	}
+	svc.Spec.LoadBalancerSourceRanges = nil
+	delete(svc.Annotations, ServiceAnnotationLoadBalancerSecurityGroupID)
+	err = cloud.reconcileSecurityGroup(ctx, svc, lb)
+	if err != nil {
+		t.Errorf("reconcileSecurityGroup err, err: %v", err)
+	}
+	_, exist, err := cloud.getServiceAssociatedSecurityGroup(ctx, svc)
+	if err != nil || exist {
+		t.Errorf("reconcileSecurityGroup err, bound security group should be deleted, exist: %v, err: %v", exist, err)
+	}
+
+	// case3
+	svc.Spec.LoadBalancerSourceRanges = []string{"10.0.0.0/8"}
+	err = cloud.reconcileSecurityGroup(ctx, svc, lb)
+	if err != nil {
+		t.Errorf("reconcileSecurityGroup err, err: %v", err)
+	}
+	svc.Spec.LoadBalancerSourceRanges = nil
+	err = cloud.ensureSecurityGroupDeleted(ctx, svc, nil)
+	if err != nil {
+		t.Errorf("ensureSecurityGroupDeleted err, err: %v", err)
+	}
+	_, exist, err = cloud.getServiceAssociatedSecurityGroup(ctx, svc)
+	if err != nil || exist {
+		t.Errorf("ensureSecurityGroupDeleted err, recorded security group should be deleted, exist: %v, err: %v", exist, err)
+	}
+}
@@ -0,0 +1,269 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloud_provider
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/blb"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/klog"
+)
+
+// maxSharedGroupLength keeps name of shared BLB within the 65 characters limit
+const maxSharedGroupLength = 32
+
+var sharedGroupRegexp = regexp.MustCompile(`^[a-zA-Z0-9._-]+$`)
+
+// sharedBLBLock serializes services sharing BLB, so that a group creates only one BLB and
+// port conflicts are checked against the latest listeners
+var sharedBLBLock sync.Mutex
+
+func isValidSharedGroup(group string) bool {
+	return len(group) <= maxSharedGroupLength && sharedGroupRegexp.MatchString(group)
+}
+
+// isSharedBLBService checks whether service shares BLB with other services
+func isSharedBLBService(service *v1.Service) bool {
+	return service.Annotations[ServiceAnnotationLoadBalancerSharedID] != "" ||
+		service.Annotations[ServiceAnnotationLoadBalancerSharedGroup] != ""
+}
+
+// sharedBackendAnnotations decide backend servers of service, they must be the same for services sharing BLB, as
+// backend servers of BLB are used by all listeners
+var sharedBackendAnnotations = []string{
+	ServiceAnnotationLoadBalancerRsMaxNum,
+	ServiceAnnotationLoadBalancerRsWeightPolicy,
+	ServiceAnnotationLoadBalancerRsDrainGracePeriod,
+}
+
+// isSameSharedBLB checks whether services share BLB by the same id or group annotation
+func isSameSharedBLB(a, b *v1.Service) bool {
+	for _, key := range []string{ServiceAnnotationLoadBalancerSharedID, ServiceAnnotationLoadBalancerSharedGroup} {
+		if value := a.Annotations[key]; value != "" && value == b.Annotations[key] {
+			return true
+		}
+	}
+	return false
+}
+
+// validateSharedBLBBackends returns error if backend servers of service differ from the services sharing BLB with it,
+// otherwise they would keep removing backend servers of each other
+func (bc *Baiducloud) validateSharedBLBBackends(service *v1.Service) error {
+	// nodes without local endpoints are removed by health check of each listener on healthCheckNodePort,
+	// otherwise backend servers of Local service are the nodes of its own endpoints
+	if isLocalTrafficService(service) && !usesHealthCheckNodePort(service) {
+		return fmt.Errorf("externalTrafficPolicy %s is not supported by service sharing BLB unless annotation %s is true, backends of BLB are used by all listeners",
+			v1.ServiceExternalTrafficPolicyTypeLocal, ServiceAnnotationLoadBalancerHealthCheckNodePort)
+	}
+	services, err := bc.listServices()
+	if err != nil {
+		return fmt.Errorf("list services sharing blb with service %s/%s failed: %v", service.Namespace, service.Name, err)
+	}
+	var conflicts []string
+	for _, peer := range services {
+		if peer.Namespace == service.Namespace && peer.Name == service.Name {
+			continue
+		}
+		if peer.Spec.Type != v1.ServiceTypeLoadBalancer || peer.DeletionTimestamp != nil || !isSameSharedBLB(service, peer) {
+			continue
+		}
+		for _, key := range sharedBackendAnnotations {
+			if service.Annotations[key] != peer.Annotations[key] {
+				conflicts = append(conflicts, fmt.Sprintf("annotation %s %q differs from %q of service %s/%s",
+					key, service.Annotations[key], peer.Annotations[key], peer.Namespace, peer.Name))
+			}
+		}
+	}
+	if len(conflicts) != 0 {
+		return fmt.Errorf("backends conflict with services sharing the BLB: %v", conflicts)
+	}
+	return nil
+}
+
+// getSharedBlbName returns name of the BLB shared by services in group
+func getSharedBlbName(clusterID string, group string) string {
+	return fmt.Sprintf("CCE/SVC/%s/shared-%s", clusterID, group)
+}
+
+// isSharingBLB checks whether service shares the BLB
+func (bc *Baiducloud) isSharingBLB(service *v1.Service, lb *blb.LoadBalancer) bool {
+	if service.Spec.Type != v1.ServiceTypeLoadBalancer {
+		return false
+	}
+	if id := service.Annotations[ServiceAnnotationLoadBalancerSharedID]; id != "" {
+		return id == lb.BlbId
+	}
+	if group := service.Annotations[ServiceAnnotationLoadBalancerSharedGroup]; group != "" {
+		return getSharedBlbName(bc.ClusterID, group) == lb.Name
+	}
+	return false
+}
+
+// getSharedBLBPeers returns the other services sharing the BLB with service, sorted by precedence
+func (bc *Baiducloud) getSharedBLBPeers(ctx context.Context, service *v1.Service, lb *blb.LoadBalancer) ([]*v1.Service, error) {
+	if !isSharedBLBService(service) || lb == nil {
+		return nil, nil
+	}
+	// services are read from lister, as it is called on every reconcile while holding sharedBLBLock
+	services, err := bc.listServices()
+	if err != nil {
+		return nil, fmt.Errorf("list services sharing blb %s failed: %v", lb.BlbId, err)
+	}
+	var peers []*v1.Service
+	for _, peer := range services {
+		if peer.Namespace == service.Namespace && peer.Name == service.Name {
+			continue
+		}
+		if peer.DeletionTimestamp != nil || !bc.isSharingBLB(peer, lb) {
+			continue
+		}
+		peers = append(peers, peer)
+	}
+	sort.Slice(peers, func(i, j int) bool {
+		return sharedBLBServicePrecedes(peers[i], peers[j])
+	})
+	klog.V(4).Infof(Message(ctx, fmt.Sprintf("service %s/%s shares blb %s with %d services", service.Namespace, service.Name, lb.BlbId, len(peers))))
+	return peers, nil
+}
+
+// sharedBLBServicePrecedes decides which service keeps a conflicting listener port, the earlier created one wins
+func sharedBLBServicePrecedes(a, b *v1.Service) bool {
+	if !a.CreationTimestamp.Equal(&b.CreationTimestamp) {
+		return a.CreationTimestamp.Before(&b.CreationTimestamp)
+	}
+	if a.Namespace != b.Namespace {
+		return a.Namespace < b.Namespace
+	}
+	return a.Name < b.Name
+}
+
+// getSharedListenerOwners returns the listeners used by peers and the service owning each listener
+func getSharedListenerOwners(peers []*v1.Service) map[listenerKey]*v1.Service {
+	owners := make(map[listenerKey]*v1.Service)
+	// peers are sorted by precedence, so that the listener is owned by the first one declaring it
+	for _, peer := range peers {
+		for _, port := range peer.Spec.Ports {
+			key := newListenerKey(string(port.Protocol), int(port.Port))
+			if _, ok := owners[key]; !ok {
+				owners[key] = peer
+			}
+		}
+	}
+	return owners
+}
+
+// checkSharedBLBConflict returns error if listener ports or LoadBalancerIP of service conflict with services taking precedence
+func (bc *Baiducloud) checkSharedBLBConflict(ctx context.Context, service *v1.Service, expected map[listenerKey]PortListener, owners map[listenerKey]*v1.Service, peers []*v1.Service) error {
+	var conflicts []string
+	keys := make([]listenerKey, 0, len(expected))
+	for key := range expected {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].String() < keys[j].String()
+	})
+	for _, key := range keys {
+		owner, ok := owners[key]
+		if ok && sharedBLBServicePrecedes(owner, service) {
+			conflicts = append(conflicts, fmt.Sprintf("port %s is used by service %s/%s", key, owner.Namespace, owner.Name))
+		}
+	}
+	if service.Spec.LoadBalancerIP != "" {
+		for _, peer := range peers {
+			if peer.Spec.LoadBalancerIP != "" && peer.Spec.LoadBalancerIP != service.Spec.LoadBalancerIP {
+				conflicts = append(conflicts, fmt.Sprintf("LoadBalancerIP %s differs from %s of service %s/%s",
+					service.Spec.LoadBalancerIP, peer.Spec.LoadBalancerIP, peer.Namespace, peer.Name))
+			}
+		}
+	}
+	if len(conflicts) == 0 {
+		return nil
+	}
+	msg := fmt.Sprintf("conflict with services sharing the BLB: %v", conflicts)
+	if bc.eventRecorder != nil {
+		Eventf(ctx, bc.eventRecorder, service, v1.EventTypeWarning, "SharedLoadBalancerConflict", msg)
+	}
+	return fmt.Errorf(msg)
+}
+
+// ensureSharedListenersDeleted deletes listeners of service which are not used by peers
+func (bc *Baiducloud) ensureSharedListenersDeleted(ctx context.Context, service *v1.Service, lb *blb.LoadBalancer, peers []*v1.Service) error {
+	owners := getSharedListenerOwners(peers)
+	all, err := bc.getAllListeners(ctx, lb)
+	if err != nil {
+		return err
+	}
+	keys := make(map[listenerKey]bool)
+	for _, port := range service.Spec.Ports {
+		keys[newListenerKey(string(port.Protocol), int(port.Port))] = true
+	}
+	var deleteList []PortListener
+	for _, l := range all {
+		if _, ok := owners[l.key()]; keys[l.key()] && !ok {
+			deleteList = append(deleteList, l)
+		}
+	}
+	if len(deleteList) == 0 {
+		return nil
+	}
+	klog.Infof(Message(ctx, fmt.Sprintf("ensureSharedListenersDeleted for service %s/%s: delete listener %v of blb %s", service.Namespace, service.Name, deleteList, lb.BlbId)))
+	return bc.deleteListener(ctx, lb, deleteList)
+}
+
+// ensureSharedBLBServiceDeleted deletes listeners and security group of service from the shared BLB.
+// It returns true if service is the last one using the BLB created by CCE, and the BLB should be deleted with its EIP.
+func (bc *Baiducloud) ensureSharedBLBServiceDeleted(ctx context.Context, service *v1.Service, lb *blb.LoadBalancer) (bool, error) {
+	serviceKey := fmt.Sprintf("%s/%s", service.Namespace, service.Name)
+	peers, err := bc.getSharedBLBPeers(ctx, service, lb)
+	if err != nil {
+		return false, err
+	}
+	owned := isClusterOwnedBLBDesc(bc.ClusterID, lb.Desc)
+	if len(peers) == 0 && owned {
+		klog.Infof(Message(ctx, fmt.Sprintf("service %s is the last one using shared blb %s, delete it", serviceKey, lb.BlbId)))
+		return true, nil
+	}
+
+	err = bc.ensureSharedListenersDeleted(ctx, service, lb, peers)
+	if err != nil {
+		return false, err
+	}
+	err = bc.ensureSecurityGroupDeleted(ctx, service, lb)
+	if err != nil {
+		return false, err
+	}
+	if len(peers) > 0 {
+		klog.Infof(Message(ctx, fmt.Sprintf("shared blb %s is still used by %d services, keep it after service %s deleted", lb.BlbId, len(peers), serviceKey)))
+		return false, nil
+	}
+
+	// BLB not created by CCE is kept, but the EIP created by CCE for it is deleted
+	klog.Infof(Message(ctx, fmt.Sprintf("shared blb %s is not created by cce, keep it after service %s deleted", lb.BlbId, serviceKey)))
+	if strings.HasPrefix(lb.Desc, "cce_auto_create_eip") && service.Annotations[ServiceAnnotationLoadBalancerInternalVpc] != "true" {
+		err = bc.ensureEipDeleted(ctx, service, lb)
+		if err != nil {
+			return false, err
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,287 @@
+package cloud_provider
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/blb"
+	api "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func buildSharedService(t *testing.T, cloud *Baiducloud, name string, created time.Time, annotations map[string]string, ports ...int32) *api.Service {
+	service := &api.Service{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:              name,
+			Namespace:         api.NamespaceDefault,
+			UID:               types.UID("uid-" + name),
+			CreationTimestamp: meta_v1.NewTime(created),
+			Annotations:       annotations,
+		},
+		Spec: api.ServiceSpec{
+			Type: api.ServiceTypeLoadBalancer,
+		},
+	}
+	for _, port := range ports {
+		service.Spec.Ports = append(service.Spec.Ports, api.ServicePort{
+			Port:     port,
+			Protocol: "TCP",
+			NodePort: port + 30000,
+		})
+	}
+	_, err := cloud.kubeClient.CoreV1().Services(service.Namespace).Create(service)
+	if err != nil {
+		t.Fatalf("create service %s err: %v", name, err)
+	}
+	return service
+}
+
+func listenerPorts(t *testing.T, cloud *Baiducloud, lb *blb.LoadBalancer) []int {
+	all, err := cloud.getAllListeners(context.Background(), lb)
+	if err != nil {
+		t.Fatalf("getAllListeners err: %v", err)
+	}
+	var ports []int
+	for _, l := range all {
+		ports = append(ports, l.Port)
+	}
+	sort.Ints(ports)
+	return ports
+}
+
+func equalPorts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// case1: services in a group share one BLB
+// case2: listeners of each service are kept when the other reconciles
+// case3: newer service using the same port is rejected
+// case4: BLB is kept until the last service is deleted
+func TestSharedBLBGroup(t *testing.T) {
+	ctx := context.Background()
+	cloud, _, err := newCluster()
+	if err != nil {
+		t.Fatalf("newCluster err: %v", err)
+	}
+	now := time.Now()
+	group := map[string]string{
+		ServiceAnnotationLoadBalancerSharedGroup: "web",
+		ServiceAnnotationLoadBalancerInternalVpc: "true",
+	}
+	copyAnnotations := func() map[string]string {
+		result := make(map[string]string)
+		for k, v := range group {
+			result[k] = v
+		}
+		return result
+	}
+	svcA := buildSharedService(t, cloud, "a", now, copyAnnotations(), 80)
+	svcB := buildSharedService(t, cloud, "b", now.Add(time.Second), copyAnnotations(), 81)
+
+	// case1
+	lbA, err := cloud.ensureBLB(ctx, "", svcA)
+	if err != nil {
+		t.Fatalf("ensureBLB for a err: %v", err)
+	}
+	lbB, err := cloud.ensureBLB(ctx, "", svcB)
+	if err != nil {
+		t.Fatalf("ensureBLB for b err: %v", err)
+	}
+	if lbA.BlbId != lbB.BlbId || lbA.Name != getSharedBlbName(cloud.ClusterID, "web") {
+		t.Fatalf("ensureBLB err, want shared blb %s, get %s(%s) and %s", getSharedBlbName(cloud.ClusterID, "web"), lbA.BlbId, lbA.Name, lbB.BlbId)
+	}
+	if !isClusterOwnedBLBDesc(cloud.ClusterID, lbA.Desc) || getBlbDescUID(lbA.Desc) != "" {
+		t.Errorf("ensureBLB err, want shared blb desc owned by cluster without uid, get %s", lbA.Desc)
+	}
+
+	// case2
+	for _, svc := range []*api.Service{svcA, svcB, svcA} {
+		if err := cloud.reconcileListeners(ctx, "", svc); err != nil {
+			t.Fatalf("reconcileListeners for %s err: %v", svc.Name, err)
+		}
+	}
+	if ports := listenerPorts(t, cloud, lbA); !equalPorts(ports, []int{80, 81}) {
+		t.Errorf("reconcileListeners err, want ports [80 81], get %v", ports)
+	}
+
+	// case3
+	svcC := buildSharedService(t, cloud, "c", now.Add(2*time.Second), copyAnnotations(), 80, 82)
+	if err := cloud.reconcileListeners(ctx, "", svcC); err == nil {
+		t.Errorf("reconcileListeners for c err, want port conflict")
+	}
+	if ports := listenerPorts(t, cloud, lbA); !equalPorts(ports, []int{80, 81}) {
+		t.Errorf("reconcileListeners err, want ports [80 81] after conflict, get %v", ports)
+	}
+	if err := cloud.kubeClient.CoreV1().Services(svcC.Namespace).Delete(svcC.Name, nil); err != nil {
+		t.Fatalf("delete service c err: %v", err)
+	}
+
+	// case4
+	if err := cloud.kubeClient.CoreV1().Services(svcA.Namespace).Delete(svcA.Name, nil); err != nil {
+		t.Fatalf("delete service a err: %v", err)
+	}
+	if err := cloud.EnsureLoadBalancerDeleted(ctx, "", svcA); err != nil {
+		t.Fatalf("EnsureLoadBalancerDeleted for a err: %v", err)
+	}
+	lb, exist, err := cloud.getBLBByID(ctx, lbA.BlbId)
+	if err != nil || !exist {
+		t.Fatalf("EnsureLoadBalancerDeleted for a err, want shared blb kept, get %v", err)
+	}
+	if ports := listenerPorts(t, cloud, lb); !equalPorts(ports, []int{81}) {
+		t.Errorf("EnsureLoadBalancerDeleted for a err, want ports [81], get %v", ports)
+	}
+	if err := cloud.kubeClient.CoreV1().Services(svcB.Namespace).Delete(svcB.Name, nil); err != nil {
+		t.Fatalf("delete service b err: %v", err)
+	}
+	if err := cloud.EnsureLoadBalancerDeleted(ctx, "", svcB); err != nil {
+		t.Fatalf("EnsureLoadBalancerDeleted for b err: %v", err)
+	}
+	if _, exist, _ := cloud.getBLBByID(ctx, lbA.BlbId); exist {
+		t.Errorf("EnsureLoadBalancerDeleted for b err, want shared blb deleted with the last service")
+	}
+}
+
+// case1: shared BLB by id is never created
+// case2: BLB not created by CCE is kept after the last service is deleted
+func TestSharedBLBID(t *testing.T) {
+	ctx := context.Background()
+	cloud, _, err := newCluster()
+	if err != nil {
+		t.Fatalf("newCluster err: %v", err)
+	}
+
+	// case1
+	svcA := buildSharedService(t, cloud, "a", time.Now(), map[string]string{
+		ServiceAnnotationLoadBalancerSharedID:    "lb-notexist",
+		ServiceAnnotationLoadBalancerInternalVpc: "true",
+	}, 80)
+	if _, err := cloud.ensureBLB(ctx, "", svcA); err == nil {
+		t.Errorf("ensureBLB err, want error for not exist shared blb")
+	}
+	if lbs, _ := cloud.clientSet.BLBClient.DescribeLoadBalancers(ctx, &blb.DescribeLoadBalancersArgs{}, nil); len(lbs) != 0 {
+		t.Errorf("ensureBLB err, want no blb created, get %v", lbs)
+	}
+
+	// case2
+	resp, err := cloud.clientSet.BLBClient.CreateLoadBalancer(ctx, &blb.CreateLoadBalancerArgs{
+		Name: "user-blb",
+		Desc: "created by user",
+	}, nil)
+	if err != nil {
+		t.Fatalf("CreateLoadBalancer err: %v", err)
+	}
+	svcB := buildSharedService(t, cloud, "b", time.Now(), map[string]string{
+		ServiceAnnotationLoadBalancerSharedID:    resp.LoadBalancerId,
+		ServiceAnnotationLoadBalancerInternalVpc: "true",
+	}, 80)
+	lb, err := cloud.ensureBLB(ctx, "", svcB)
+	if err != nil || lb.BlbId != resp.LoadBalancerId {
+		t.Fatalf("ensureBLB err, want blb %s, get %v, %v", resp.LoadBalancerId, lb, err)
+	}
+	if err := cloud.reconcileListeners(ctx, "", svcB); err != nil {
+		t.Fatalf("reconcileListeners err: %v", err)
+	}
+	if err := cloud.EnsureLoadBalancerDeleted(ctx, "", svcB); err != nil {
+		t.Fatalf("EnsureLoadBalancerDeleted err: %v", err)
+	}
+	lb, exist, err := cloud.getBLBByID(ctx, resp.LoadBalancerId)
+	if err != nil || !exist {
+		t.Fatalf("EnsureLoadBalancerDeleted err, want user blb kept, get %v", err)
+	}
+	if ports := listenerPorts(t, cloud, lb); len(ports) != 0 {
+		t.Errorf("EnsureLoadBalancerDeleted err, want listeners of service deleted, get %v", ports)
+	}
+}
+
+func TestValidateSharedBLBService(t *testing.T) {
+	cloud := NewFakeCloud("c-shared")
+	svc := buildService()
+	svc.Spec.Ports = []api.ServicePort{{Port: 80, Protocol: "TCP"}}
+
+	svc.SetAnnotations(map[string]string{
+		ServiceAnnotationLoadBalancerSharedID:    "lb-1",
+		ServiceAnnotationLoadBalancerSharedGroup: "web",
+	})
+	if err := cloud.validateService(svc); err == nil {
+		t.Errorf("validateService err, want error for both shared id and group")
+	}
+	svc.SetAnnotations(map[string]string{
+		ServiceAnnotationLoadBalancerSharedGroup: "web/1",
+	})
+	if err := cloud.validateService(svc); err == nil {
+		t.Errorf("validateService err, want error for invalid group")
+	}
+	svc.SetAnnotations(map[string]string{
+		ServiceAnnotationLoadBalancerSharedGroup: "web",
+	})
+	if err := cloud.validateService(svc); err != nil {
+		t.Errorf("validateService err: %v", err)
+	}
+	svc.Spec.LoadBalancerSourceRanges = []string{"10.0.0.0/8"}
+	if err := cloud.validateService(svc); err == nil {
+		t.Errorf("validateService err, want error for source ranges on shared blb")
+	}
+}
+
+// case1: Local service is rejected unless nodes without local endpoints are left to healthCheckNodePort
+// case2: backend annotations differing from peers are rejected
+// case3: services of other groups are not peers
+func TestValidateSharedBLBBackends(t *testing.T) {
+	cloud := NewFakeCloud("c-shared")
+	now := time.Now()
+	buildSharedService(t, cloud, "peer", now, map[string]string{
+		ServiceAnnotationLoadBalancerSharedGroup: "web",
+		ServiceAnnotationLoadBalancerRsMaxNum:    "10",
+	}, 80)
+	svc := &api.Service{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "svc",
+			Namespace: api.NamespaceDefault,
+			Annotations: map[string]string{
+				ServiceAnnotationLoadBalancerSharedGroup: "web",
+				ServiceAnnotationLoadBalancerRsMaxNum:    "10",
+			},
+		},
+		Spec: api.ServiceSpec{
+			Type:  api.ServiceTypeLoadBalancer,
+			Ports: []api.ServicePort{{Port: 81, Protocol: "TCP"}},
+		},
+	}
+	if err := cloud.validateService(svc); err != nil {
+		t.Errorf("validateService err: %v", err)
+	}
+
+	// case1
+	svc.Spec.ExternalTrafficPolicy = api.ServiceExternalTrafficPolicyTypeLocal
+	svc.Spec.HealthCheckNodePort = 32000
+	if err := cloud.validateService(svc); err == nil {
+		t.Errorf("validateService err, want error for Local service on shared blb")
+	}
+	svc.Annotations[ServiceAnnotationLoadBalancerHealthCheckNodePort] = "true"
+	if err := cloud.validateService(svc); err != nil {
+		t.Errorf("validateService err, want Local service using healthCheckNodePort allowed, get %v", err)
+	}
+
+	// case2
+	svc.Annotations[ServiceAnnotationLoadBalancerRsMaxNum] = "20"
+	if err := cloud.validateService(svc); err == nil {
+		t.Errorf("validateService err, want error for rs max num differing from peer")
+	}
+
+	// case3
+	svc.Annotations[ServiceAnnotationLoadBalancerSharedGroup] = "api"
+	if err := cloud.validateService(svc); err != nil {
+		t.Errorf("validateService err, want no peer in other group, get %v", err)
+	}
+}
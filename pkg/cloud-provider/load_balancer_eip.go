@@ -11,6 +11,8 @@ import (
 	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/blb"
 	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/eip"
 	v1 "k8s.io/api/core/v1"
+
+	eipext "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/temp-eip"
 )
 
 func (bc *Baiducloud) ensureEIP(ctx context.Context, clusterName string, service *v1.Service) (string, error) {
@@ -43,10 +45,10 @@ func (bc *Baiducloud) ensureEIPWithNoSpecificIP(ctx context.Context, service *v1
 	}
 	pubIP := lb.PublicIp
 	if len(pubIP) == 0 { // blb not bind eip, mostly case ==>
-		klog.V(2).Infof("[%v %v] EnsureLoadBalancer: createEIP!", service.Namespace, service.Name)
+		klog.V(2).Infof(Message(ctx, fmt.Sprintf("[%v %v] EnsureLoadBalancer: createEIP!", service.Namespace, service.Name)))
 		args, err := bc.getEipArgsFromAnnotation(serviceAnnotation)
 		if err != nil {
-			klog.Errorf("[%v %v] getEipArgsFromAnnotation failed: %v", service.Namespace, service.Name, err)
+			klog.Errorf(Message(ctx, fmt.Sprintf("[%v %v] getEipArgsFromAnnotation failed: %v", service.Namespace, service.Name, err)))
 			return "", err
 		}
 		if len(args.Name) == 0 {
@@ -65,7 +67,7 @@ func (bc *Baiducloud) ensureEIPWithNoSpecificIP(ctx context.Context, service *v1
 				return "", err
 			}
 		}
-		klog.V(3).Infof("lb.Desc: %s", lb.Desc)
+		klog.V(3).Infof(Message(ctx, fmt.Sprintf("lb.Desc: %s", lb.Desc)))
 
 		pubIP, err = bc.getServiceAssociatedEip(ctx, service)
 		if err != nil {
@@ -83,7 +85,7 @@ func (bc *Baiducloud) ensureEIPWithNoSpecificIP(ctx context.Context, service *v1
 			return "", err
 		}
 	} else { // blb already bind eip
-		klog.V(3).Infof("[%v %v] EnsureLoadBalancer: blb's eip already exists, start to ensure...", service.Namespace, service.Name)
+		klog.V(3).Infof(Message(ctx, fmt.Sprintf("[%v %v] EnsureLoadBalancer: blb's eip already exists, start to ensure...", service.Namespace, service.Name)))
 		eips, err := bc.getEipByIP(ctx, pubIP)
 		if err != nil {
 			return "", err
@@ -95,7 +97,7 @@ func (bc *Baiducloud) ensureEIPWithNoSpecificIP(ctx context.Context, service *v1
 		targetEip := eips[0]
 		if (len(serviceAnnotation.ElasticIPPaymentTiming) != 0 && serviceAnnotation.ElasticIPPaymentTiming != targetEip.PaymentTiming) ||
 			(len(serviceAnnotation.ElasticIPBillingMethod) != 0 && serviceAnnotation.ElasticIPBillingMethod != targetEip.BillingMethod) {
-			klog.V(3).Infof("[%v %v] EnsureLoadBalancer: EIP config change, need delete old eip and create new one", service.Namespace, service.Name)
+			klog.V(3).Infof(Message(ctx, fmt.Sprintf("[%v %v] EnsureLoadBalancer: EIP config change, need delete old eip and create new one", service.Namespace, service.Name)))
 			// TODO
 			//pubIP, err = bc.deleteOldAndCreateNewEip(service, serviceAnnotation, pubIP, lb)
 			//if err != nil {
@@ -104,11 +106,11 @@ func (bc *Baiducloud) ensureEIPWithNoSpecificIP(ctx context.Context, service *v1
 			return "", fmt.Errorf("not support change ElasticIP PaymentTiming or ElasticIP BillingMethod, you can delete old and create a new one")
 		}
 		if serviceAnnotation.ElasticIPBandwidthInMbps != 0 && serviceAnnotation.ElasticIPBandwidthInMbps != targetEip.BandwidthInMbps {
-			klog.V(3).Infof("[%v %v] EnsureLoadBalancer: EIP config change, need change ElasticIPBandwidthInMbps", service.Namespace, service.Name)
+			klog.V(3).Infof(Message(ctx, fmt.Sprintf("[%v %v] EnsureLoadBalancer: EIP config change, need change ElasticIPBandwidthInMbps", service.Namespace, service.Name)))
 			// just validate args
 			_, err := bc.getEipArgsFromAnnotation(serviceAnnotation)
 			if err != nil {
-				klog.Errorf("[%v %v] Eip Args error: %v", service.Namespace, service.Name, err)
+				klog.Errorf(Message(ctx, fmt.Sprintf("[%v %v] Eip Args error: %v", service.Namespace, service.Name, err)))
 				return "", err
 			}
 			err = bc.resizeEip(ctx, serviceAnnotation, pubIP)
@@ -123,7 +125,7 @@ func (bc *Baiducloud) ensureEIPWithNoSpecificIP(ctx context.Context, service *v1
 func (bc *Baiducloud) ensureEIPWithSpecificIP(ctx context.Context, service *v1.Service, lb *blb.LoadBalancer) (string, error) {
 	pubIP := lb.PublicIp
 	loadBalancerIP := service.Spec.LoadBalancerIP
-	klog.V(3).Infof("[%v %v] EnsureLoadBalancer: Try to bind Custom LoadBalancerIP %s to BLB %s.", service.Namespace, service.Name, loadBalancerIP, lb.BlbId)
+	klog.V(3).Infof(Message(ctx, fmt.Sprintf("[%v %v] EnsureLoadBalancer: Try to bind Custom LoadBalancerIP %s to BLB %s.", service.Namespace, service.Name, loadBalancerIP, lb.BlbId)))
 	if len(pubIP) == 0 { // blb not bind target eip
 		// check eip status & bind blb
 		lb, err := bc.bindEip(ctx, lb, loadBalancerIP, service)
@@ -132,12 +134,12 @@ func (bc *Baiducloud) ensureEIPWithSpecificIP(ctx context.Context, service *v1.S
 		}
 		lb.PublicIp = loadBalancerIP
 		pubIP = loadBalancerIP
-		klog.V(3).Infof("[%v %v] EnsureLoadBalancer: Bind EIP to BLB success.", service.Namespace, service.Name)
+		klog.V(3).Infof(Message(ctx, fmt.Sprintf("[%v %v] EnsureLoadBalancer: Bind EIP to BLB success.", service.Namespace, service.Name)))
 	} else { // blb already bind eip
 		if pubIP == loadBalancerIP { // blb bind correct LoadBalancerIP
-			klog.V(3).Infof("[%v %v] EnsureLoadBalancer: BLB %s already bind EIP %s.", service.Namespace, service.Name, lb.BlbId, pubIP)
+			klog.V(3).Infof(Message(ctx, fmt.Sprintf("[%v %v] EnsureLoadBalancer: BLB %s already bind EIP %s.", service.Namespace, service.Name, lb.BlbId, pubIP)))
 		} else { // blb not bind correct LoadBalancerIP, need update
-			klog.V(3).Infof("[%v %v] EnsureLoadBalancer: BLB %s already bind EIP %s, but need updating to %s.", service.Namespace, service.Name, lb.BlbId, pubIP, loadBalancerIP)
+			klog.V(3).Infof(Message(ctx, fmt.Sprintf("[%v %v] EnsureLoadBalancer: BLB %s already bind EIP %s, but need updating to %s.", service.Namespace, service.Name, lb.BlbId, pubIP, loadBalancerIP)))
 			err := bc.unbindEip(ctx, lb, pubIP)
 			if err != nil {
 				return "", err
@@ -149,7 +151,7 @@ func (bc *Baiducloud) ensureEIPWithSpecificIP(ctx context.Context, service *v1.S
 			}
 			lb.PublicIp = loadBalancerIP
 			pubIP = loadBalancerIP
-			klog.V(3).Infof("[%v %v] EnsureLoadBalancer: Bind EIP to BLB success.", service.Namespace, service.Name)
+			klog.V(3).Infof(Message(ctx, fmt.Sprintf("[%v %v] EnsureLoadBalancer: Bind EIP to BLB success.", service.Namespace, service.Name)))
 		}
 	}
 	return pubIP, nil
@@ -161,32 +163,21 @@ func (bc *Baiducloud) unbindEip(ctx context.Context, lb *blb.LoadBalancer, ip st
 		return err
 	}
 	if eips == nil || len(eips) == 0 {
-		klog.Warningf("EIP %s not found", ip)
+		klog.Warningf(Message(ctx, fmt.Sprintf("EIP %s not found", ip)))
 		return nil
 	}
 	err = bc.clientSet.EIPClient.UnbindEIP(ctx, ip, bc.getSignOption(ctx))
 	if err != nil {
-		klog.V(3).Infof("Unbind Eip error : %s", err.Error())
+		klog.V(3).Infof(Message(ctx, fmt.Sprintf("Unbind Eip error : %s", err.Error())))
 		return err
 	}
-	return nil
+	return bc.waitEipStatus(ctx, ip, string(eip.EIPAvailable))
 }
 
 func (bc *Baiducloud) bindEip(ctx context.Context, lb *blb.LoadBalancer, ip string, service *v1.Service) (*blb.LoadBalancer, error) {
-	for i := 0; i < 10; i++ {
-		eips, err := bc.getEipByIP(ctx, ip)
-		if err != nil {
-			return nil, err
-		}
-		if eips == nil || len(eips) == 0 {
-			err = fmt.Errorf("[%v %v] EnsureLoadBalancer: EIP %s not Exist", service.Namespace, service.Name, ip)
-			return nil, err
-		}
-		if eips[0].Status == eip.EIPAvailable {
-			break
-		}
-		klog.Infof(Message(ctx, fmt.Sprintf("eip %s status is %s, not available, wait...", ip, eips[0].Status)))
-		time.Sleep(3 * time.Second)
+	err := bc.waitEipStatus(ctx, ip, string(eip.EIPAvailable))
+	if err != nil {
+		return nil, fmt.Errorf("[%v %v] EnsureLoadBalancer: %v", service.Namespace, service.Name, err)
 	}
 
 	// bind blb
@@ -195,28 +186,50 @@ func (bc *Baiducloud) bindEip(ctx context.Context, lb *blb.LoadBalancer, ip stri
 		InstanceID:   lb.BlbId,
 		InstanceType: eip.BLB,
 	}
-	klog.V(3).Infof("[%v %v] Bind EIP: %v", service.Namespace, service.Name, argsBind)
-	klog.V(3).Infof("[%v %v] Bind BLB: %v", service.Namespace, service.Name, lb)
-	err := bc.clientSet.EIPClient.BindEIP(ctx, ip, argsBind, bc.getSignOption(ctx))
+	klog.V(3).Infof(Message(ctx, fmt.Sprintf("[%v %v] Bind EIP: %v", service.Namespace, service.Name, argsBind)))
+	klog.V(3).Infof(Message(ctx, fmt.Sprintf("[%v %v] Bind BLB: %v", service.Namespace, service.Name, lb)))
+	err = bc.clientSet.EIPClient.BindEIP(ctx, ip, argsBind, bc.getSignOption(ctx))
+	if err != nil {
+		klog.V(3).Infof(Message(ctx, fmt.Sprintf("BindEip error: %v", err)))
+		return nil, err
+	}
+	err = bc.waitEipStatus(ctx, ip, string(eip.EIPBinded))
 	if err != nil {
-		klog.V(3).Infof("BindEip error: %v", err)
 		return nil, err
 	}
 	return lb, nil
 }
 
+// waitEipStatus waits until status of eip is the expected one
+func (bc *Baiducloud) waitEipStatus(ctx context.Context, ip string, status string) error {
+	return waitForStatus(ctx, fmt.Sprintf("eip %s to be %s", ip, status), defaultWaitBackoff, defaultWaitTimeout, func(ctx context.Context) (bool, error) {
+		eips, err := bc.getEipByIP(ctx, ip)
+		if err != nil {
+			return false, err
+		}
+		if len(eips) == 0 {
+			return false, fmt.Errorf("EIP %s not Exist", ip)
+		}
+		if string(eips[0].Status) != status {
+			klog.Infof(Message(ctx, fmt.Sprintf("eip %s status is %s, not %s, wait...", ip, eips[0].Status, status)))
+			return false, nil
+		}
+		return true, nil
+	})
+}
+
 func (bc *Baiducloud) refreshBlb(ctx context.Context, lb *blb.LoadBalancer) (*blb.LoadBalancer, error) {
 	newlb, exist, err := bc.getBLBByID(ctx, lb.BlbId)
 	if err != nil {
-		klog.V(3).Infof("getBLBByName error: %s", lb.BlbId)
+		klog.V(3).Infof(Message(ctx, fmt.Sprintf("getBLBByName error: %s", lb.BlbId)))
 		return nil, err
 	}
 	if !exist {
-		klog.V(3).Infof("getBLBByName not exist: %s", lb.BlbId)
+		klog.V(3).Infof(Message(ctx, fmt.Sprintf("getBLBByName not exist: %s", lb.BlbId)))
 		return nil, fmt.Errorf("BLB not exists:%s", lb.BlbId)
 	}
 	lb = newlb
-	klog.V(3).Infof("BLB status is : %s", lb.Status)
+	klog.V(3).Infof(Message(ctx, fmt.Sprintf("BLB status is : %s", lb.Status)))
 	return lb, nil
 }
 
@@ -247,8 +260,11 @@ func (bc *Baiducloud) deleteEIP(ctx context.Context, ip string) error {
 		return nil
 	}
 
-	if eips[0].Status != "Available" {
-		time.Sleep(5 * time.Second)
+	if eips[0].Status != eip.EIPAvailable {
+		err = bc.waitEipStatus(ctx, ip, string(eip.EIPAvailable))
+		if err != nil {
+			return err
+		}
 	}
 
 	err = bc.clientSet.EIPClient.DeleteEIP(ctx, ip, bc.getSignOption(ctx))
@@ -415,8 +431,7 @@ func (bc *Baiducloud) getEipByIP(ctx context.Context, ip string) ([]*eip.EIP, er
 
 func (bc *Baiducloud) getEipsByName(ctx context.Context, name string) ([]*eip.EIP, error) {
 	result := make([]*eip.EIP, 0)
-	argsGet := eip.GetEIPsArgs{}
-	eips, err := bc.clientSet.EIPClient.GetEIPs(ctx, &argsGet, bc.getSignOption(ctx))
+	eips, err := eipext.ListAllEIPs(ctx, bc.clientSet.EIPClient, bc.getSignOption(ctx))
 	if err != nil {
 		return nil, err
 	}
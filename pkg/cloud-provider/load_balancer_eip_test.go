@@ -2,11 +2,14 @@ package cloud_provider
 
 import (
 	"context"
+	"fmt"
 	"testing"
 
 	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/bce"
 	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/blb"
 	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/eip"
+
+	"icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/fake"
 )
 
 func TestGetEipArgsFromAnnotation(t *testing.T) {
@@ -445,3 +448,20 @@ func TestEnsureEIPWithSpecificIP(t *testing.T) {
 		t.Errorf("ensureEIPWithSpecificIP err, err: %s", err)
 	}
 }
+
+func TestGetEipsByNamePaging(t *testing.T) {
+	cloud := NewFakeCloud("c-eip")
+	eipClient := cloud.clientSet.EIPClient.(*fake.EipFakeClient)
+	eipClient.MaxKeys = 2
+	for i, name := range []string{"a", "b", "a", "c", "a"} {
+		ip := fmt.Sprintf("100.0.0.%d", i+1)
+		eipClient.EIPMap[ip] = &eip.EIP{EIP: ip, Name: name}
+	}
+	eips, err := cloud.getEipsByName(context.Background(), "a")
+	if err != nil {
+		t.Fatalf("getEipsByName err: %v", err)
+	}
+	if len(eips) != 3 {
+		t.Errorf("getEipsByName err, want 3 eips from all pages, get %v", eips)
+	}
+}
@@ -0,0 +1,165 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloud_provider
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog"
+)
+
+// getService returns service from lister if informers are set, otherwise from API server.
+// Service from lister is shared by informers, so a copy is returned.
+func (bc *Baiducloud) getService(namespace, name string) (*v1.Service, error) {
+	if bc.serviceLister != nil {
+		service, err := bc.serviceLister.Services(namespace).Get(name)
+		if err != nil {
+			return nil, err
+		}
+		return service.DeepCopy(), nil
+	}
+	return bc.kubeClient.CoreV1().Services(namespace).Get(name, metav1.GetOptions{})
+}
+
+// getEndpoints returns endpoints from lister if informers are set and synced, otherwise from API server.
+// Lister is not used before synced, since endpoints missing from it would remove the backends of service.
+// The result is read-only.
+func (bc *Baiducloud) getEndpoints(namespace, name string) (*v1.Endpoints, error) {
+	if bc.endpointsLister != nil && (bc.endpointsListerSynced == nil || bc.endpointsListerSynced()) {
+		return bc.endpointsLister.Endpoints(namespace).Get(name)
+	}
+	return bc.kubeClient.CoreV1().Endpoints(namespace).Get(name, metav1.GetOptions{})
+}
+
+// listServices returns services of all namespaces from lister if informers are set, otherwise from API server.
+// The result is read-only.
+func (bc *Baiducloud) listServices() ([]*v1.Service, error) {
+	if bc.serviceLister != nil {
+		return bc.serviceLister.List(labels.Everything())
+	}
+	serviceList, err := bc.kubeClient.CoreV1().Services(metav1.NamespaceAll).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	services := make([]*v1.Service, 0, len(serviceList.Items))
+	for i := range serviceList.Items {
+		services = append(services, &serviceList.Items[i])
+	}
+	return services, nil
+}
+
+// listNodes returns nodes from lister if informers are set, otherwise from API server. The result is read-only.
+func (bc *Baiducloud) listNodes() ([]*v1.Node, error) {
+	if bc.nodeLister != nil {
+		return bc.nodeLister.List(labels.Everything())
+	}
+	nodeList, err := bc.kubeClient.CoreV1().Nodes().List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	nodes := make([]*v1.Node, 0, len(nodeList.Items))
+	for i := range nodeList.Items {
+		nodes = append(nodes, &nodeList.Items[i])
+	}
+	return nodes, nil
+}
+
+// getEndpointsNodeNames returns the sorted names of nodes running ready or not ready endpoints in all subsets
+func getEndpointsNodeNames(ep *v1.Endpoints) []string {
+	if ep == nil {
+		return nil
+	}
+	nodeSet := make(map[string]bool)
+	for _, subset := range ep.Subsets {
+		for _, addrs := range [][]v1.EndpointAddress{subset.Addresses, subset.NotReadyAddresses} {
+			for _, addr := range addrs {
+				if addr.NodeName != nil && *addr.NodeName != "" {
+					nodeSet[*addr.NodeName] = true
+				}
+			}
+		}
+	}
+	nodeNames := make([]string, 0, len(nodeSet))
+	for name := range nodeSet {
+		nodeNames = append(nodeNames, name)
+	}
+	sort.Strings(nodeNames)
+	return nodeNames
+}
+
+// isLocalTrafficService checks whether backends of service follow the nodes of its endpoints
+func isLocalTrafficService(service *v1.Service) bool {
+	return service.Spec.ExternalTrafficPolicy == v1.ServiceExternalTrafficPolicyTypeLocal
+}
+
+// usesHealthCheckNodePort checks whether all nodes are backends of Local service, and BLB removes the nodes without
+// local endpoints by checking spec.healthCheckNodePort served by kube-proxy
+func usesHealthCheckNodePort(service *v1.Service) bool {
+	if !isLocalTrafficService(service) || isPodBackendService(service) || service.Spec.HealthCheckNodePort == 0 {
+		return false
+	}
+	enabled, err := strconv.ParseBool(service.Annotations[ServiceAnnotationLoadBalancerHealthCheckNodePort])
+	return err == nil && enabled
+}
+
+// onEndpointsChanged enqueues the LoadBalancer service of endpoints when its backends change, i.e. the nodes
+// behind a Local service, or the ready pods behind a service using pod backends. Local service using healthCheckNodePort
+// is left to the health check of BLB.
+// Endpoints share name with service, so services are looked up by key instead of matching selectors of pods.
+func (bc *Baiducloud) onEndpointsChanged(old, cur *v1.Endpoints) {
+	if bc.serviceLister == nil {
+		return
+	}
+	service, err := bc.serviceLister.Services(cur.Namespace).Get(cur.Name)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			klog.Errorf("endpointsInformer failed to get service %s/%s: %v", cur.Namespace, cur.Name, err)
+		}
+		return
+	}
+	if service.Spec.Type != v1.ServiceTypeLoadBalancer {
+		return
+	}
+	switch {
+	case isPodBackendService(service):
+		if old != nil && !podBackendsChanged(old, cur) {
+			return
+		}
+	case usesHealthCheckNodePort(service):
+		return
+	case isLocalTrafficService(service):
+		if old != nil && strings.Join(getEndpointsNodeNames(old), ",") == strings.Join(getEndpointsNodeNames(cur), ",") {
+			return
+		}
+	default:
+		return
+	}
+	key, err := cache.MetaNamespaceKeyFunc(service)
+	if err != nil {
+		klog.Errorf("endpointsInformer failed to get key of service %s/%s: %v", cur.Namespace, cur.Name, err)
+		return
+	}
+	klog.V(3).Infof("backends of service %s changed, enqueue it", key)
+	bc.svcQueue.Add(key)
+}
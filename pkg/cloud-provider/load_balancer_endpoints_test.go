@@ -0,0 +1,156 @@
+package cloud_provider
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	api "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// setFakeListers sets listers backed by indexers containing objs, so that no API server is queried
+func setFakeListers(cloud *Baiducloud, objs ...interface{}) {
+	newIndexer := func() cache.Indexer {
+		return cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	}
+	serviceIndexer, endpointsIndexer, nodeIndexer := newIndexer(), newIndexer(), newIndexer()
+	for _, obj := range objs {
+		switch obj.(type) {
+		case *api.Service:
+			serviceIndexer.Add(obj)
+		case *api.Endpoints:
+			endpointsIndexer.Add(obj)
+		case *api.Node:
+			nodeIndexer.Add(obj)
+		}
+	}
+	cloud.serviceLister = corelisters.NewServiceLister(serviceIndexer)
+	cloud.endpointsLister = corelisters.NewEndpointsLister(endpointsIndexer)
+	cloud.nodeLister = corelisters.NewNodeLister(nodeIndexer)
+	cloud.svcQueue = workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "endpoints")
+}
+
+func buildLocalEndpoints(nodeNames ...string) *api.Endpoints {
+	ep := &api.Endpoints{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+		},
+		Subsets: []api.EndpointSubset{{}},
+	}
+	for i, name := range nodeNames {
+		nodeName := name
+		ep.Subsets[0].Addresses = append(ep.Subsets[0].Addresses, api.EndpointAddress{
+			IP:       fmt.Sprintf("172.16.0.%d", i+1),
+			NodeName: &nodeName,
+		})
+	}
+	return ep
+}
+
+// case1: enqueue Local service when endpoints are added
+// case2: pod moving within the same nodes is ignored
+// case3: enqueue Local service when node set changes
+// case4: Cluster service, service not LoadBalancer and Local service using healthCheckNodePort are ignored
+func TestOnEndpointsChanged(t *testing.T) {
+	cloud := NewFakeCloud("c-ep")
+	svc := &api.Service{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+		},
+		Spec: api.ServiceSpec{
+			Type:                  api.ServiceTypeLoadBalancer,
+			ExternalTrafficPolicy: api.ServiceExternalTrafficPolicyTypeLocal,
+		},
+	}
+	setFakeListers(cloud, svc)
+	drain := func() int {
+		n := cloud.svcQueue.Len()
+		for i := 0; i < n; i++ {
+			key, _ := cloud.svcQueue.Get()
+			cloud.svcQueue.Done(key)
+		}
+		return n
+	}
+
+	// case1
+	cloud.onEndpointsChanged(nil, buildLocalEndpoints("node-1"))
+	if n := drain(); n != 1 {
+		t.Errorf("onEndpointsChanged err, want service enqueued when endpoints added, get %d", n)
+	}
+	// case2
+	moved := buildLocalEndpoints("node-1", "node-1")
+	cloud.onEndpointsChanged(buildLocalEndpoints("node-1"), moved)
+	if n := drain(); n != 0 {
+		t.Errorf("onEndpointsChanged err, want nothing enqueued when node set not changed, get %d", n)
+	}
+	// case3
+	cloud.onEndpointsChanged(moved, buildLocalEndpoints("node-1", "node-2"))
+	if n := drain(); n != 1 {
+		t.Errorf("onEndpointsChanged err, want service enqueued when node set changed, get %d", n)
+	}
+	// case4
+	for _, mutate := range []func(*api.Service){
+		func(s *api.Service) { s.Spec.ExternalTrafficPolicy = api.ServiceExternalTrafficPolicyTypeCluster },
+		func(s *api.Service) { s.Spec.Type = api.ServiceTypeClusterIP },
+		func(s *api.Service) {
+			s.Annotations = map[string]string{ServiceAnnotationLoadBalancerHealthCheckNodePort: "true"}
+			s.Spec.HealthCheckNodePort = 32000
+		},
+	} {
+		other := svc.DeepCopy()
+		mutate(other)
+		setFakeListers(cloud, other)
+		cloud.onEndpointsChanged(buildLocalEndpoints("node-1"), buildLocalEndpoints("node-2"))
+		if n := drain(); n != 0 {
+			t.Errorf("onEndpointsChanged err, want service %v ignored, get %d", other.Spec, n)
+		}
+	}
+}
+
+func TestGetServiceAssociatedNodesFromListers(t *testing.T) {
+	cloud := NewFakeCloud("c-ep")
+	svc := buildService()
+	nodes := []*api.Node{
+		{ObjectMeta: meta_v1.ObjectMeta{Name: "node-1"}},
+		{ObjectMeta: meta_v1.ObjectMeta{Name: "node-2"}},
+	}
+	setFakeListers(cloud, svc, buildLocalEndpoints("node-2"), nodes[0], nodes[1])
+	result, err := cloud.getServiceAssociatedNodes(context.Background(), svc)
+	if err != nil {
+		t.Fatalf("getServiceAssociatedNodes err: %v", err)
+	}
+	if len(result) != 1 || result[0].Name != "node-2" {
+		t.Errorf("getServiceAssociatedNodes err, want [node-2], get %v", result)
+	}
+}
+
+// case1: endpoints are read from API server before lister is synced
+// case2: endpoints are read from lister after it is synced
+func TestGetEndpointsBeforeSynced(t *testing.T) {
+	cloud := NewFakeCloud("c-ep")
+	setFakeListers(cloud)
+	synced := false
+	cloud.endpointsListerSynced = func() bool { return synced }
+	if _, err := cloud.kubeClient.CoreV1().Endpoints(api.NamespaceDefault).Create(buildLocalEndpoints("node1")); err != nil {
+		t.Fatalf("create endpoints err: %v", err)
+	}
+
+	// case1
+	ep, err := cloud.getEndpoints(api.NamespaceDefault, "foo")
+	if err != nil || len(getEndpointsNodeNames(ep)) != 1 {
+		t.Errorf("case1: getEndpoints err, want endpoints from API server, get %v, err: %v", ep, err)
+	}
+
+	// case2
+	synced = true
+	ep, err = cloud.getEndpoints(api.NamespaceDefault, "foo")
+	if err == nil {
+		t.Errorf("case2: getEndpoints err, want not found in empty lister, get %v", ep)
+	}
+}
@@ -0,0 +1,244 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloud_provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/blb"
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/eip"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/klog"
+
+	blbext "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/temp-blb"
+	eipext "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/temp-eip"
+)
+
+const (
+	// OrphanedResourceTypeBLB is the type of orphaned BLB
+	OrphanedResourceTypeBLB = "BLB"
+	// OrphanedResourceTypeEIP is the type of orphaned EIP
+	OrphanedResourceTypeEIP = "EIP"
+
+	defaultOrphanGCPeriod      = 600
+	defaultOrphanGCGracePeriod = 3600
+
+	// blbReleasedDescPrefix is the description prefix of BLB reserved after its service is deleted
+	blbReleasedDescPrefix = "reserved by cce:"
+)
+
+// OrphanedResource is a BLB or EIP created by CCM for a LoadBalancer service which no longer exists
+type OrphanedResource struct {
+	Type string
+	// ID is BLB id or EIP address
+	ID   string
+	Name string
+	// ServiceNamespace and ServiceName is the service which created the resource, parsed from resource name, may be empty
+	ServiceNamespace string
+	ServiceName      string
+	// BoundTo is the BLB id which the EIP is bound to
+	BoundTo string
+}
+
+// Key identifies the resource
+func (r OrphanedResource) Key() string {
+	return r.Type + "/" + r.ID
+}
+
+// GetOrphanGCConfig returns config of orphaned resource garbage collection, with default values
+func (bc *Baiducloud) GetOrphanGCConfig() OrphanGCConfig {
+	config := bc.OrphanGC
+	if config.Period <= 0 {
+		config.Period = defaultOrphanGCPeriod
+	}
+	if config.GracePeriod <= 0 {
+		config.GracePeriod = defaultOrphanGCGracePeriod
+	}
+	return config
+}
+
+// ListOrphanedLoadBalancerResources lists BLBs and EIPs created by CCM for this cluster, which are not used by any
+// of the given services. A resource is used by a service if it is referenced by annotation, named after the service,
+// or records the UID of the service.
+func (bc *Baiducloud) ListOrphanedLoadBalancerResources(ctx context.Context, services []*v1.Service) ([]OrphanedResource, error) {
+	namePrefix := fmt.Sprintf("CCE/SVC/%s/", bc.ClusterID)
+	// all pages of BLBs and EIPs are read, a resource missed in later pages is never found orphaned
+	lbs, err := blbext.DescribeAllLoadBalancers(ctx, bc.clientSet.BLBClient, &blbext.DescribeLoadBalancersArgs{
+		LoadBalancerName: namePrefix,
+	}, bc.getSignOption(ctx))
+	if err != nil {
+		return nil, err
+	}
+	eips, err := eipext.ListAllEIPs(ctx, bc.clientSet.EIPClient, bc.getSignOption(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	usedIDs := make(map[string]bool)
+	usedNames := make(map[string]bool)
+	usedUIDs := make(map[string]bool)
+	for _, service := range services {
+		if service.Spec.Type != v1.ServiceTypeLoadBalancer {
+			continue
+		}
+		for _, key := range []string{ServiceAnnotationLoadBalancerId, ServiceAnnotationCceAutoAddLoadBalancerID,
+			ServiceAnnotationLoadBalancerExistID, ServiceAnnotationLoadBalancerSharedID, ServiceAnnotationCceAutoAddEip} {
+			if id, ok := service.Annotations[key]; ok && id != "" {
+				usedIDs[id] = true
+			}
+		}
+		for _, ingress := range service.Status.LoadBalancer.Ingress {
+			usedIDs[ingress.IP] = true
+		}
+		if service.Spec.LoadBalancerIP != "" {
+			usedIDs[service.Spec.LoadBalancerIP] = true
+		}
+		name := getBlbName(bc.ClusterID, service)
+		// according to blb and eip api doc, limit to 65
+		if len(name) > 65 {
+			name = name[:65]
+		}
+		usedNames[name] = true
+		usedUIDs[string(service.UID)] = true
+	}
+
+	var result []OrphanedResource
+	ownedBLBs := make(map[string]bool)
+	usedBLBs := make(map[string]bool)
+	for _, lb := range lbs {
+		if !strings.HasPrefix(lb.Name, namePrefix) || !isClusterOwnedBLBDesc(bc.ClusterID, lb.Desc) {
+			continue
+		}
+		ownedBLBs[lb.BlbId] = true
+		uid := getBlbDescUID(lb.Desc)
+		if usedIDs[lb.BlbId] || usedNames[lb.Name] || (uid != "" && usedUIDs[uid]) {
+			usedBLBs[lb.BlbId] = true
+			continue
+		}
+		namespace, name := parseServiceFromResourceName(bc.ClusterID, lb.Name)
+		result = append(result, OrphanedResource{
+			Type:             OrphanedResourceTypeBLB,
+			ID:               lb.BlbId,
+			Name:             lb.Name,
+			ServiceNamespace: namespace,
+			ServiceName:      name,
+		})
+	}
+
+	for _, e := range eips {
+		boundTo := ""
+		if e.InstanceType == eip.BLB {
+			boundTo = e.InstanceID
+		}
+		if !strings.HasPrefix(e.Name, namePrefix) && !ownedBLBs[boundTo] {
+			continue
+		}
+		if usedIDs[e.EIP] || usedNames[e.Name] || usedBLBs[boundTo] {
+			continue
+		}
+		// EIP bound to a BLB not created by CCM is not ours to judge
+		if boundTo != "" && !ownedBLBs[boundTo] {
+			continue
+		}
+		namespace, name := parseServiceFromResourceName(bc.ClusterID, e.Name)
+		result = append(result, OrphanedResource{
+			Type:             OrphanedResourceTypeEIP,
+			ID:               e.EIP,
+			Name:             e.Name,
+			ServiceNamespace: namespace,
+			ServiceName:      name,
+			BoundTo:          boundTo,
+		})
+	}
+	return result, nil
+}
+
+// DeleteOrphanedLoadBalancerResource deletes an orphaned BLB or EIP, with the security group created for it
+func (bc *Baiducloud) DeleteOrphanedLoadBalancerResource(ctx context.Context, r OrphanedResource) error {
+	startTime := time.Now()
+	defer func() {
+		klog.V(4).Infof(Message(ctx, fmt.Sprintf("Finished DeleteOrphanedLoadBalancerResource %s (%v)", r.Key(), time.Since(startTime))))
+	}()
+	switch r.Type {
+	case OrphanedResourceTypeEIP:
+		if r.BoundTo != "" {
+			err := bc.unbindEip(ctx, &blb.LoadBalancer{BlbId: r.BoundTo}, r.ID)
+			if err != nil {
+				return err
+			}
+		}
+		return bc.deleteEIP(ctx, r.ID)
+	case OrphanedResourceTypeBLB:
+		lb := &blb.LoadBalancer{BlbId: r.ID, Name: r.Name}
+		if r.ServiceName != "" {
+			service := &v1.Service{}
+			service.Namespace = r.ServiceNamespace
+			service.Name = r.ServiceName
+			err := bc.ensureSecurityGroupDeleted(ctx, service, lb)
+			if err != nil {
+				return err
+			}
+		}
+		return bc.ensureBLBDeleted(ctx, lb)
+	}
+	return fmt.Errorf("DeleteOrphanedLoadBalancerResource type not match: %s", r.Type)
+}
+
+// releaseBLB marks BLB reserved after its service is deleted as not owned by cluster, so it is never collected
+func (bc *Baiducloud) releaseBLB(ctx context.Context, lb *blb.LoadBalancer) error {
+	if !isClusterOwnedBLBDesc(bc.ClusterID, lb.Desc) {
+		return nil
+	}
+	klog.Infof(Message(ctx, fmt.Sprintf("release reserved blb %s from cluster %s", lb.BlbId, bc.ClusterID)))
+	return bc.clientSet.BLBClient.UpdateLoadBalancer(ctx, &blb.UpdateLoadBalancerArgs{
+		LoadBalancerId: lb.BlbId,
+		Name:           lb.Name,
+		Desc:           blbReleasedDescPrefix + bc.ClusterID,
+	}, bc.getSignOption(ctx))
+}
+
+// isClusterOwnedBLBDesc checks whether description is generated by getBlbDesc of the cluster
+func isClusterOwnedBLBDesc(clusterID string, desc string) bool {
+	desc = strings.TrimPrefix(desc, "cce_auto_create_eip")
+	base := "auto generated by cce:" + clusterID
+	return desc == base || strings.HasPrefix(desc, base+" ")
+}
+
+// getBlbDescUID returns service UID recorded in BLB description, empty if not recorded
+func getBlbDescUID(desc string) string {
+	index := strings.LastIndex(desc, " uid:")
+	if index < 0 {
+		return ""
+	}
+	return desc[index+len(" uid:"):]
+}
+
+// parseServiceFromResourceName parses namespace and name of service from resource name CCE/SVC/<cluster>/<namespace>/<name>
+func parseServiceFromResourceName(clusterID string, resourceName string) (string, string) {
+	prefix := fmt.Sprintf("CCE/SVC/%s/", clusterID)
+	if !strings.HasPrefix(resourceName, prefix) {
+		return "", ""
+	}
+	parts := strings.SplitN(strings.TrimPrefix(resourceName, prefix), "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", ""
+	}
+	return parts[0], parts[1]
+}
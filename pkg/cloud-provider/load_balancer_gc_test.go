@@ -0,0 +1,175 @@
+package cloud_provider
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/blb"
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/eip"
+	api "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/fake"
+)
+
+func buildGCService(namespace, name, uid string) *api.Service {
+	return &api.Service{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			UID:       types.UID(uid),
+		},
+		Spec: api.ServiceSpec{
+			Type: api.ServiceTypeLoadBalancer,
+		},
+	}
+}
+
+func createGCBLB(t *testing.T, cloud *Baiducloud, name, desc string) string {
+	resp, err := cloud.clientSet.BLBClient.CreateLoadBalancer(context.Background(), &blb.CreateLoadBalancerArgs{
+		Name: name,
+		Desc: desc,
+	}, nil)
+	if err != nil {
+		t.Fatalf("CreateLoadBalancer err: %v", err)
+	}
+	return resp.LoadBalancerId
+}
+
+func orphanKeys(orphans []OrphanedResource) []string {
+	keys := make([]string, 0, len(orphans))
+	for _, r := range orphans {
+		keys = append(keys, r.Key())
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// case1: blb of live service by name, by annotation and by uid are not orphaned
+// case2: blb of deleted service and the eip bound to it are orphaned
+// case3: blb of other cluster, released blb and eip of others are ignored
+// case4: delete orphans
+func TestListOrphanedLoadBalancerResources(t *testing.T) {
+	ctx := context.Background()
+	cloud := NewFakeCloud("c-gc")
+	eipClient := cloud.clientSet.EIPClient.(*fake.EipFakeClient)
+	live := buildGCService("default", "live", "uid-live")
+	renamed := buildGCService("default", "renamed", "uid-renamed")
+	annotated := buildGCService("default", "annotated", "uid-annotated")
+
+	// case1
+	createGCBLB(t, cloud, getBlbName("c-gc", live), getBlbDesc("c-gc", live))
+	createGCBLB(t, cloud, "CCE/SVC/c-gc/default/old-name", getBlbDesc("c-gc", renamed))
+	annotatedID := createGCBLB(t, cloud, "CCE/SVC/c-gc/default/other", "auto generated by cce:c-gc")
+	annotated.Annotations = map[string]string{ServiceAnnotationCceAutoAddLoadBalancerID: annotatedID}
+	// case2
+	deletedID := createGCBLB(t, cloud, "CCE/SVC/c-gc/default/deleted", getBlbDesc("c-gc", buildGCService("default", "deleted", "uid-deleted")))
+	ip, err := eipClient.CreateEIP(ctx, &eip.CreateEIPArgs{Name: "CCE/SVC/c-gc/default/deleted"}, nil)
+	if err != nil {
+		t.Fatalf("CreateEIP err: %v", err)
+	}
+	err = eipClient.BindEIP(ctx, ip, &eip.BindEIPArgs{InstanceID: deletedID, InstanceType: eip.BLB}, nil)
+	if err != nil {
+		t.Fatalf("BindEIP err: %v", err)
+	}
+	// case3
+	createGCBLB(t, cloud, "CCE/SVC/c-other/default/deleted", "auto generated by cce:c-other")
+	createGCBLB(t, cloud, "CCE/SVC/c-gc/default/reserved", blbReleasedDescPrefix+"c-gc")
+	eipClient.EIPMap["100.0.0.1"] = &eip.EIP{EIP: "100.0.0.1", Name: "user-eip", Status: eip.EIPAvailable}
+
+	orphans, err := cloud.ListOrphanedLoadBalancerResources(ctx, []*api.Service{live, renamed, annotated})
+	if err != nil {
+		t.Fatalf("ListOrphanedLoadBalancerResources err: %v", err)
+	}
+	want := []string{"BLB/" + deletedID, "EIP/" + ip}
+	if got := orphanKeys(orphans); len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("ListOrphanedLoadBalancerResources err, want %v, get %v", want, got)
+	}
+	for _, r := range orphans {
+		if r.ServiceNamespace != "default" || r.ServiceName != "deleted" {
+			t.Errorf("ListOrphanedLoadBalancerResources err, want service default/deleted, get %s/%s", r.ServiceNamespace, r.ServiceName)
+		}
+		if r.Type == OrphanedResourceTypeEIP && r.BoundTo != deletedID {
+			t.Errorf("ListOrphanedLoadBalancerResources err, want eip bound to %s, get %s", deletedID, r.BoundTo)
+		}
+	}
+
+	// case4
+	sort.Slice(orphans, func(i, j int) bool {
+		return orphans[i].Type == OrphanedResourceTypeEIP && orphans[j].Type != OrphanedResourceTypeEIP
+	})
+	for _, r := range orphans {
+		if err := cloud.DeleteOrphanedLoadBalancerResource(ctx, r); err != nil {
+			t.Errorf("DeleteOrphanedLoadBalancerResource %s err: %v", r.Key(), err)
+		}
+	}
+	orphans, err = cloud.ListOrphanedLoadBalancerResources(ctx, []*api.Service{live, renamed, annotated})
+	if err != nil || len(orphans) != 0 {
+		t.Errorf("ListOrphanedLoadBalancerResources err, want no orphans after delete, get %v, %v", orphanKeys(orphans), err)
+	}
+}
+
+func TestIsClusterOwnedBLBDesc(t *testing.T) {
+	cases := []struct {
+		desc string
+		want bool
+	}{
+		{"auto generated by cce:c-gc", true},
+		{"auto generated by cce:c-gc uid:123", true},
+		{"cce_auto_create_eipauto generated by cce:c-gc", true},
+		{"auto generated by cce:c-gc2", false},
+		{blbReleasedDescPrefix + "c-gc", false},
+		{"", false},
+	}
+	for _, c := range cases {
+		if got := isClusterOwnedBLBDesc("c-gc", c.desc); got != c.want {
+			t.Errorf("isClusterOwnedBLBDesc(%q) err, want %v, get %v", c.desc, c.want, got)
+		}
+	}
+}
+
+func TestParseServiceFromResourceName(t *testing.T) {
+	cases := []struct {
+		name      string
+		namespace string
+		service   string
+	}{
+		{"CCE/SVC/c-gc/default/foo", "default", "foo"},
+		{"CCE/SVC/c-gc/default/", "", ""},
+		{"CCE/SVC/c-other/default/foo", "", ""},
+		{"user-eip", "", ""},
+	}
+	for _, c := range cases {
+		namespace, service := parseServiceFromResourceName("c-gc", c.name)
+		if namespace != c.namespace || service != c.service {
+			t.Errorf("parseServiceFromResourceName(%q) err, want %s/%s, get %s/%s", c.name, c.namespace, c.service, namespace, service)
+		}
+	}
+}
+
+func TestListOrphanedLoadBalancerResourcesPaging(t *testing.T) {
+	ctx := context.Background()
+	cloud := NewFakeCloud("c-gc")
+	blbClient := cloud.clientSet.BLBClient.(*fake.BlbFakeClient)
+	eipClient := cloud.clientSet.EIPClient.(*fake.EipFakeClient)
+	blbClient.MaxKeys = 2
+	eipClient.MaxKeys = 2
+	want := []string{}
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		service := buildGCService("default", name, "uid-"+name)
+		id := createGCBLB(t, cloud, getBlbName("c-gc", service), getBlbDesc("c-gc", service))
+		want = append(want, "BLB/"+id)
+	}
+	sort.Strings(want)
+
+	orphans, err := cloud.ListOrphanedLoadBalancerResources(ctx, nil)
+	if err != nil {
+		t.Fatalf("ListOrphanedLoadBalancerResources err: %v", err)
+	}
+	if got := orphanKeys(orphans); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("ListOrphanedLoadBalancerResources err, want %v from all pages, get %v", want, got)
+	}
+}
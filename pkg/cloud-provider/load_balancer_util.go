@@ -23,6 +23,8 @@ import (
 	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/blb"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/klog"
+
+	blbext "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/temp-blb"
 )
 
 // workaround to support old version, can be removed if not support old version
@@ -54,26 +56,76 @@ func (bc *Baiducloud) validateService(service *v1.Service) error {
 			continue
 		case "UDP":
 			continue
-		case "HTTP":
-			return fmt.Errorf("HTTP is not supported")
-		case "HTTPS":
-			return fmt.Errorf("HTTPS is not supported")
+		case "HTTP", "HTTPS":
+			return fmt.Errorf("%s listener should be declared by annotation %s on TCP port", port.Protocol, ServiceAnnotationLoadBalancerListenerProtocol)
 		default:
 			return fmt.Errorf("target protocol is not supported: %v", port.Protocol)
 		}
 	}
-	return nil
+	if isSharedBLBService(service) {
+		sourceRanges, err := getServiceSourceRanges(service)
+		if err != nil {
+			return err
+		}
+		if len(sourceRanges) != 0 {
+			return fmt.Errorf("LoadBalancerSourceRanges is not supported by service sharing BLB, it would restrict other services")
+		}
+		if err := bc.validateSharedBLBBackends(service); err != nil {
+			return err
+		}
+	}
+	if isPodBackendService(service) && isSharedBLBService(service) {
+		return fmt.Errorf("backend type %s is not supported by service sharing BLB, backends of BLB are used by all listeners", backendTypePod)
+	}
+	return validateListenerAnnotation(service)
+}
+
+// validateListenerAnnotation validates HTTP/HTTPS listener annotations against service ports
+func validateListenerAnnotation(service *v1.Service) error {
+	serviceAnnotation, err := ExtractServiceAnnotation(service)
+	if err != nil {
+		return err
+	}
+	tcpPorts := make(map[int]bool)
+	for _, port := range service.Spec.Ports {
+		if port.Protocol == "TCP" {
+			tcpPorts[int(port.Port)] = true
+		}
+	}
+	needCert := false
+	for port, protocol := range serviceAnnotation.LoadBalancerListenerProtocol {
+		if !tcpPorts[port] {
+			return fmt.Errorf("%s listener port %d is not a TCP port of service", protocol, port)
+		}
+		if protocol == "HTTPS" {
+			needCert = true
+		}
+	}
+	if needCert && len(serviceAnnotation.LoadBalancerCertIDs) == 0 {
+		return fmt.Errorf("HTTPS listener need annotation %s", ServiceAnnotationLoadBalancerCertID)
+	}
+	for from, to := range serviceAnnotation.LoadBalancerRedirectPort {
+		if serviceAnnotation.LoadBalancerListenerProtocol[from] != "HTTP" {
+			return fmt.Errorf("redirect port %d is not a HTTP listener", from)
+		}
+		if serviceAnnotation.LoadBalancerListenerProtocol[to] != "HTTPS" {
+			return fmt.Errorf("redirect target port %d is not a HTTPS listener", to)
+		}
+	}
+	// scheduler must be supported by listener protocol
+	_, err = getExpectedListeners(service)
+	return err
 }
 
 func (bc *Baiducloud) getBLBByName(ctx context.Context, name string) (lb *blb.LoadBalancer, exists bool, err error) {
 	if len(name) == 0 {
 		return nil, false, fmt.Errorf("LoadBalancerName is empty")
 	}
-	args := blb.DescribeLoadBalancersArgs{
+	// all pages are read, so that a BLB of the name in later pages is not created again
+	lbs, err := blbext.DescribeAllLoadBalancers(ctx, bc.clientSet.BLBClient, &blbext.DescribeLoadBalancersArgs{
 		LoadBalancerName: name,
 		ExactlyMatch:     true,
-	}
-	lbs, err := bc.clientSet.BLBClient.DescribeLoadBalancers(ctx, &args, bc.getSignOption(ctx))
+	}, bc.getSignOption(ctx))
 	if err != nil {
 		klog.Errorf(Message(ctx, fmt.Sprintf("getBLBByName failed: %s", err)))
 		return &blb.LoadBalancer{}, false, err
@@ -116,9 +168,22 @@ func (bc *Baiducloud) getBLBByID(ctx context.Context, id string) (lb *blb.LoadBa
 // This returns a human-readable version of the Service used to tag some resources.
 // This is only used for human-readable convenience, and not to filter.
 func getBlbName(clusterID string, service *v1.Service) string {
+	if group, ok := service.Annotations[ServiceAnnotationLoadBalancerSharedGroup]; ok && group != "" {
+		return getSharedBlbName(clusterID, group)
+	}
 	blbName := fmt.Sprintf("CCE/SVC/%s/%s/%s", clusterID, service.Namespace, service.Name)
 	if annotationName, ok := service.Annotations[ServiceAnnotationLoadBalancerBLBName]; ok {
 		blbName = annotationName
 	}
 	return blbName
 }
+
+// getBlbDesc returns description of BLB created by CCM, which records cluster and service UID the BLB belongs to,
+// shared BLB does not belong to a single service
+func getBlbDesc(clusterID string, service *v1.Service) string {
+	desc := "auto generated by cce:" + clusterID
+	if service.UID != "" && !isSharedBLBService(service) {
+		desc = fmt.Sprintf("%s uid:%s", desc, service.UID)
+	}
+	return desc
+}
@@ -7,6 +7,7 @@ import (
 
 	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/bce"
 	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/blb"
+	"icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/fake"
 	cce "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/temp-cce"
 	api "k8s.io/api/core/v1"
 	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -210,4 +211,68 @@ func TestValidateService(t *testing.T) {
 	if err == nil {
 		t.Errorf("validateService err, there should be err, err is nil")
 	}
+
+	// HTTP/HTTPS listeners declared by annotation
+	case7 := case1.DeepCopy()
+	case7.Spec.Ports = []api.ServicePort{
+		{
+			Name:     "http",
+			Port:     80,
+			Protocol: "TCP",
+		},
+		{
+			Name:     "https",
+			Port:     443,
+			Protocol: "TCP",
+		},
+	}
+	case7.Annotations = map[string]string{
+		ServiceAnnotationLoadBalancerListenerProtocol: "80:HTTP,443:HTTPS",
+		ServiceAnnotationLoadBalancerCertID:           "cert-a",
+		ServiceAnnotationLoadBalancerRedirectPort:     "80:443",
+	}
+	err = cloud.validateService(case7)
+	if err != nil {
+		t.Errorf("validateService err, err: %v", err)
+	}
+	wrongAnnotations := []map[string]string{
+		// HTTPS without cert
+		{ServiceAnnotationLoadBalancerListenerProtocol: "443:HTTPS"},
+		// port not in service
+		{ServiceAnnotationLoadBalancerListenerProtocol: "8080:HTTP"},
+		// redirect to a non HTTPS listener
+		{ServiceAnnotationLoadBalancerListenerProtocol: "80:HTTP", ServiceAnnotationLoadBalancerRedirectPort: "80:443"},
+	}
+	for _, annotations := range wrongAnnotations {
+		case7.Annotations = annotations
+		err = cloud.validateService(case7)
+		if err == nil {
+			t.Errorf("validateService err, there should be err for %v, err is nil", annotations)
+		}
+	}
+}
+
+// case1: BLB of the name is found when a page holds one BLB
+// case2: BLBs of the same name in different pages are conflict
+func TestGetBLBByNamePaging(t *testing.T) {
+	cloud := NewFakeCloud("c-paging")
+	blbClient := cloud.clientSet.BLBClient.(*fake.BlbFakeClient)
+	blbClient.MaxKeys = 1
+	ctx := context.Background()
+	sharedName := getSharedBlbName("c-paging", "group1")
+	blbClient.LoadBalancerMap["lb-1"] = blb.LoadBalancer{BlbId: "lb-1", Name: "CCE/SVC/c-paging/default/other"}
+	blbClient.LoadBalancerMap["lb-2"] = blb.LoadBalancer{BlbId: "lb-2", Name: sharedName}
+
+	// case1
+	lb, exist, err := cloud.getBLBByName(ctx, sharedName)
+	if err != nil || !exist || lb.BlbId != "lb-2" {
+		t.Errorf("case1: getBLBByName err, want lb-2, get %v, exist: %v, err: %v", lb, exist, err)
+	}
+
+	// case2
+	blbClient.LoadBalancerMap["lb-3"] = blb.LoadBalancer{BlbId: "lb-3", Name: sharedName}
+	lb, exist, err = cloud.getBLBByName(ctx, sharedName)
+	if err == nil || exist {
+		t.Errorf("case2: getBLBByName err, want err of multi BLBs, get %v, exist: %v, err: %v", lb, exist, err)
+	}
 }
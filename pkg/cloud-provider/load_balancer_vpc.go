@@ -37,7 +37,7 @@ func (bc *Baiducloud) getVpcInfoForBLB(ctx context.Context, service *v1.Service)
 	subnetID, ok := service.Annotations[ServiceAnnotationLoadBalancerSubnetID]
 	if ok {
 		if subnetID != "" {
-			klog.V(3).Infof("Find subnetId %v in annotation for BLB", subnetID)
+			klog.V(3).Infof(Message(ctx, fmt.Sprintf("Find subnetId %v in annotation for BLB", subnetID)))
 			subnetIsTypeBCC, err := bc.subnetIsTypeBCC(ctx, subnetID)
 			if err != nil {
 				return "", "", err
@@ -45,17 +45,16 @@ func (bc *Baiducloud) getVpcInfoForBLB(ctx context.Context, service *v1.Service)
 			if !subnetIsTypeBCC {
 				return "", "", fmt.Errorf("SubnetId %v in annotation is not type BCC", subnetID)
 			}
-			klog.V(3).Infof("Use subnet with id %v in annotation for BLB", subnetID)
+			klog.V(3).Infof(Message(ctx, fmt.Sprintf("Use subnet with id %v in annotation for BLB", subnetID)))
 			return vpcID, subnetID, nil
 		}
 	}
 
 	// get subnet id from instance
-	instanceResponse, err := bc.clientSet.CCEClient.ListClusterNodes(ctx, bc.ClusterID, bc.getSignOption(ctx))
+	ins, err := bc.listClusterInstances(ctx)
 	if err != nil {
 		return "", "", err
 	}
-	ins := instanceResponse.Nodes
 	if len(ins) == 0 {
 		return "", "", fmt.Errorf("getVpcInfoForBLB failed since instance num is zero")
 	}
@@ -0,0 +1,164 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloud_provider
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+const (
+	instanceCacheSubsystem = "instance_cache"
+	cloudAPISubsystem      = "cloud_api"
+	reconcileSubsystem     = "reconcile"
+	credentialsSubsystem   = "credentials"
+)
+
+var (
+	// instanceCacheLookups is the number of instance cache lookups, a miss means instances are listed from CCE
+	instanceCacheLookups = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Subsystem:      instanceCacheSubsystem,
+			Name:           "lookups_total",
+			Help:           "Number of instance cache lookups, by index and result.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"index", "result"},
+	)
+	// instanceCacheRefreshes is the number of cluster instance listings made by instance cache
+	instanceCacheRefreshes = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Subsystem:      instanceCacheSubsystem,
+			Name:           "refreshes_total",
+			Help:           "Number of cluster instance listings made by instance cache, by result.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"result"},
+	)
+	// instanceCacheSize is the number of instances in cache
+	instanceCacheSize = metrics.NewGauge(
+		&metrics.GaugeOpts{
+			Subsystem:      instanceCacheSubsystem,
+			Name:           "instances",
+			Help:           "Number of cluster instances in cache.",
+			StabilityLevel: metrics.ALPHA,
+		},
+	)
+
+	// cloudAPIRequests is the number of BCE API requests, a retried call makes a request for each attempt
+	cloudAPIRequests = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Subsystem:      cloudAPISubsystem,
+			Name:           "requests_total",
+			Help:           "Number of BCE API requests, by service, method and result code.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"service", "method", "code"},
+	)
+	// cloudAPIRequestDuration is the latency of BCE API requests, rate limiting and retry backoff excluded
+	cloudAPIRequestDuration = metrics.NewHistogramVec(
+		&metrics.HistogramOpts{
+			Subsystem:      cloudAPISubsystem,
+			Name:           "request_duration_seconds",
+			Help:           "Latency of BCE API requests in seconds, by service and method.",
+			Buckets:        metrics.ExponentialBuckets(0.05, 2, 10),
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"service", "method"},
+	)
+	// cloudAPIRetries is the number of retried BCE API requests
+	cloudAPIRetries = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Subsystem:      cloudAPISubsystem,
+			Name:           "retries_total",
+			Help:           "Number of retried BCE API requests, by service and method.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"service", "method"},
+	)
+
+	// reconcileDuration is the duration of controller reconciles
+	reconcileDuration = metrics.NewHistogramVec(
+		&metrics.HistogramOpts{
+			Subsystem:      reconcileSubsystem,
+			Name:           "duration_seconds",
+			Help:           "Duration of reconciles in seconds, by controller.",
+			Buckets:        metrics.ExponentialBuckets(0.1, 2, 12),
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"controller"},
+	)
+	// reconcileErrors is the number of failed controller reconciles
+	reconcileErrors = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Subsystem:      reconcileSubsystem,
+			Name:           "errors_total",
+			Help:           "Number of failed reconciles, by controller.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"controller"},
+	)
+
+	// credentialsReloads is the number of credentials reloads
+	credentialsReloads = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Subsystem:      credentialsSubsystem,
+			Name:           "reloads_total",
+			Help:           "Number of credentials reloads, by result.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"result"},
+	)
+	// credentialsTokenExpiry is when the CCE plugin token expires, 0 if there is no token
+	credentialsTokenExpiry = metrics.NewGauge(
+		&metrics.GaugeOpts{
+			Subsystem:      credentialsSubsystem,
+			Name:           "token_expiry_timestamp_seconds",
+			Help:           "Unix time the CCE plugin token expires at, 0 if there is no token.",
+			StabilityLevel: metrics.ALPHA,
+		},
+	)
+)
+
+var registerMetrics sync.Once
+
+// RegisterMetrics registers cloud provider metrics
+func RegisterMetrics() {
+	registerMetrics.Do(func() {
+		legacyregistry.MustRegister(instanceCacheLookups)
+		legacyregistry.MustRegister(instanceCacheRefreshes)
+		legacyregistry.MustRegister(instanceCacheSize)
+		legacyregistry.MustRegister(cloudAPIRequests)
+		legacyregistry.MustRegister(cloudAPIRequestDuration)
+		legacyregistry.MustRegister(cloudAPIRetries)
+		legacyregistry.MustRegister(reconcileDuration)
+		legacyregistry.MustRegister(reconcileErrors)
+		legacyregistry.MustRegister(credentialsReloads)
+		legacyregistry.MustRegister(credentialsTokenExpiry)
+	})
+}
+
+// ObserveReconcile records duration and result of a reconcile of controller started at startTime
+func ObserveReconcile(controller string, startTime time.Time, err error) {
+	reconcileDuration.WithLabelValues(controller).Observe(time.Since(startTime).Seconds())
+	if err != nil {
+		reconcileErrors.WithLabelValues(controller).Inc()
+	}
+}
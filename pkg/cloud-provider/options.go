@@ -19,6 +19,7 @@ package cloud_provider
 import (
 	"fmt"
 	"strconv"
+	"strings"
 
 	"k8s.io/klog"
 	v1 "k8s.io/api/core/v1"
@@ -49,25 +50,56 @@ const (
 
 	ServiceAnnotationLoadBalancerBLBName = ServiceAnnotationLoadBalancerPrefix + "lb-name"
 
-	// TODO:
+	// ServiceAnnotationLoadBalancerSharedID is the annotation of the existing BLB id shared with other services
+	ServiceAnnotationLoadBalancerSharedID = ServiceAnnotationLoadBalancerPrefix + "shared-id"
+	// ServiceAnnotationLoadBalancerSharedGroup is the annotation of the group name, services in the same group share one BLB created by CCE
+	ServiceAnnotationLoadBalancerSharedGroup = ServiceAnnotationLoadBalancerPrefix + "shared-group"
+
 	// ServiceAnnotationLoadBalancerScheduler is the annotation of load balancer which can be "RoundRobin"/"LeastConnection"/"Hash"
 	ServiceAnnotationLoadBalancerScheduler = ServiceAnnotationLoadBalancerPrefix + "scheduler"
-	// TODO:
+	// ServiceAnnotationLoadBalancerPortScheduler is the annotation which overrides scheduler per port, e.g. "80:LeastConnection,53:Hash"
+	ServiceAnnotationLoadBalancerPortScheduler = ServiceAnnotationLoadBalancerPrefix + "port-scheduler"
 	// ServiceAnnotationLoadBalancerHealthCheckTimeoutInSecond is the annotation of health check timeout, default 3s, [1, 60]
 	ServiceAnnotationLoadBalancerHealthCheckTimeoutInSecond = ServiceAnnotationLoadBalancerPrefix + "health-check-timeout-in-second"
-	// TODO:
 	// ServiceAnnotationLoadBalancerHealthCheckInterval is the annotation of health check interval, default 3s, [1, 10]
 	ServiceAnnotationLoadBalancerHealthCheckInterval = ServiceAnnotationLoadBalancerPrefix + "health-check-interval"
-	// TODO:
 	// ServiceAnnotationLoadBalancerUnhealthyThreshold is the annotation of unhealthy threshold, default 3, [2, 5]
 	ServiceAnnotationLoadBalancerUnhealthyThreshold = ServiceAnnotationLoadBalancerPrefix + "unhealthy-threshold"
-	// TODO:
 	// ServiceAnnotationLoadBalancerHealthyThreshold is the annotation of healthy threshold, default 3, [2, 5]
 	ServiceAnnotationLoadBalancerHealthyThreshold = ServiceAnnotationLoadBalancerPrefix + "healthy-threshold"
-	// TODO:
-	// ServiceAnnotationLoadBalancerHealthCheckString is the annotation of health check string
+	// ServiceAnnotationLoadBalancerHealthCheckString is the annotation of health check string of UDP listener, default "HealthCheck"
 	ServiceAnnotationLoadBalancerHealthCheckString = ServiceAnnotationLoadBalancerPrefix + "health-check-string"
 
+	// ServiceAnnotationLoadBalancerListenerProtocol is the annotation which declares HTTP/HTTPS listeners on TCP ports, e.g. "80:HTTP,443:HTTPS"
+	ServiceAnnotationLoadBalancerListenerProtocol = ServiceAnnotationLoadBalancerPrefix + "listener-protocol"
+	// ServiceAnnotationLoadBalancerCertID is the annotation of the certificate ids used by HTTPS listeners, separated by ","
+	ServiceAnnotationLoadBalancerCertID = ServiceAnnotationLoadBalancerPrefix + "cert-id"
+	// ServiceAnnotationLoadBalancerRedirectPort is the annotation which redirects HTTP listener to HTTPS listener, e.g. "80:443"
+	ServiceAnnotationLoadBalancerRedirectPort = ServiceAnnotationLoadBalancerPrefix + "redirect-port"
+	// ServiceAnnotationLoadBalancerKeepSession is the annotation which enables cookie based session keeping of HTTP/HTTPS listeners
+	ServiceAnnotationLoadBalancerKeepSession = ServiceAnnotationLoadBalancerPrefix + "keep-session"
+	// ServiceAnnotationLoadBalancerKeepSessionDuration is the annotation of session keeping duration, default 3600s, [1, 86400]
+	ServiceAnnotationLoadBalancerKeepSessionDuration = ServiceAnnotationLoadBalancerPrefix + "keep-session-duration"
+	// ServiceAnnotationLoadBalancerServerTimeout is the annotation of backend keepalive timeout of HTTP/HTTPS listeners, default 30s, [1, 3600]
+	ServiceAnnotationLoadBalancerServerTimeout = ServiceAnnotationLoadBalancerPrefix + "server-timeout"
+	// ServiceAnnotationLoadBalancerRsWeightPolicy is the annotation of how to weight backend servers, "default" or "cpu"
+	ServiceAnnotationLoadBalancerRsWeightPolicy = ServiceAnnotationLoadBalancerPrefix + "rs-weight-policy"
+	// ServiceAnnotationLoadBalancerRsDrainGracePeriod is the annotation of seconds to drain backend servers before removing them, [0, 3600], 0 means no draining
+	ServiceAnnotationLoadBalancerRsDrainGracePeriod = ServiceAnnotationLoadBalancerPrefix + "rs-drain-grace-period"
+	// ServiceAnnotationLoadBalancerSecurityGroupID records the security group created for loadBalancerSourceRanges, managed by CCM
+	ServiceAnnotationLoadBalancerSecurityGroupID = ServiceAnnotationLoadBalancerPrefix + "security-group-id"
+	// ServiceAnnotationLoadBalancerDrainingRs records draining backend servers and their removal deadline, managed by CCM
+	ServiceAnnotationLoadBalancerDrainingRs = ServiceAnnotationLoadBalancerPrefix + "draining-rs"
+	// ServiceAnnotationLoadBalancerBackendType is the annotation of backend type, "nodeport"(default) or "pod", "pod" registers
+	// ready endpoints as BLB backends directly, which requires pod IPs routed in VPC
+	ServiceAnnotationLoadBalancerBackendType = ServiceAnnotationLoadBalancerPrefix + "backend-type"
+	// ServiceAnnotationLoadBalancerZeroEndpointsPolicy is the annotation of what to do with backend servers when Local service
+	// has no endpoints, or service using pod backends has no ready pods, "keep"(default) or "drain"
+	ServiceAnnotationLoadBalancerZeroEndpointsPolicy = ServiceAnnotationLoadBalancerPrefix + "zero-endpoints-policy"
+	// ServiceAnnotationLoadBalancerHealthCheckNodePort is the annotation which registers all nodes as backends of Local service,
+	// and lets BLB check nodes on spec.healthCheckNodePort, so that nodes without local endpoints are removed by BLB
+	ServiceAnnotationLoadBalancerHealthCheckNodePort = ServiceAnnotationLoadBalancerPrefix + "health-check-node-port"
+
 	// ServiceAnnotationElasticIPPrefix is the annotation prefix of ElasticIP
 	ServiceAnnotationElasticIPPrefix = "service.beta.kubernetes.io/cce-elastic-ip-"
 	// ServiceAnnotationElasticIPName is the annotation of ElasticIPName
@@ -97,6 +129,9 @@ const (
 
 	// NodeAnnotationAdvertiseRoute indicates whether to advertise route to vpc route table
 	NodeAnnotationAdvertiseRoute = NodeAnnotationPrefix + "advertise-route"
+
+	// NodeAnnotationBLBRsWeight is the weight of node as BLB backend server, [1, 100], may also be set as node label
+	NodeAnnotationBLBRsWeight = NodeAnnotationPrefix + "blb-rs-weight"
 )
 
 // ServiceAnnotation contains annotations from service
@@ -113,6 +148,10 @@ type ServiceAnnotation struct {
 	LoadBalancerScheduler    string
 	LoadBalancerRsMaxNum     int
 	LoadBalancerReserveLB    string
+	LoadBalancerSharedID     string
+	LoadBalancerSharedGroup  string
+
+	LoadBalancerPortScheduler map[int]string
 
 	LoadBalancerHealthCheckTimeoutInSecond int
 	LoadBalancerHealthCheckInterval        int
@@ -120,6 +159,21 @@ type ServiceAnnotation struct {
 	LoadBalancerHealthyThreshold           int
 	LoadBalancerHealthCheckString          string
 
+	LoadBalancerListenerProtocol    map[int]string
+	LoadBalancerCertIDs             []string
+	LoadBalancerRedirectPort        map[int]int
+	LoadBalancerKeepSession         bool
+	LoadBalancerKeepSessionDuration int
+	LoadBalancerServerTimeout       int
+
+	LoadBalancerRsWeightPolicy string
+	// nil means not set by annotation
+	LoadBalancerRsDrainGracePeriod *int
+
+	LoadBalancerBackendType         string
+	LoadBalancerZeroEndpointsPolicy string
+	LoadBalancerHealthCheckNodePort bool
+
 	/* EIP */
 	ElasticIPName              string
 	ElasticIPPaymentTiming     string
@@ -199,19 +253,57 @@ func ExtractServiceAnnotation(service *v1.Service) (*ServiceAnnotation, error) {
 
 	loadBalancerScheduler, ok := annotation[ServiceAnnotationLoadBalancerScheduler]
 	if ok {
+		if !isValidScheduler(loadBalancerScheduler) {
+			return nil, fmt.Errorf("ServiceAnnotationLoadBalancerScheduler must be RoundRobin, LeastConnection or Hash, get %s", loadBalancerScheduler)
+		}
 		result.LoadBalancerScheduler = loadBalancerScheduler
 	}
 
+	loadBalancerPortScheduler, ok := annotation[ServiceAnnotationLoadBalancerPortScheduler]
+	if ok {
+		schedulers, err := parsePortMapping(loadBalancerPortScheduler)
+		if err != nil {
+			return nil, fmt.Errorf("ServiceAnnotationLoadBalancerPortScheduler syntax error: %v", err)
+		}
+		for port, scheduler := range schedulers {
+			if !isValidScheduler(scheduler) {
+				return nil, fmt.Errorf("ServiceAnnotationLoadBalancerPortScheduler of port %d must be RoundRobin, LeastConnection or Hash, get %s", port, scheduler)
+			}
+		}
+		result.LoadBalancerPortScheduler = schedulers
+	}
+
 	loadBalancerReserveLB, ok := annotation[ServiceAnnotationLoadBalancerReserveLB]
 	if ok {
 		result.LoadBalancerReserveLB = loadBalancerReserveLB
 	}
 
+	loadBalancerSharedID, ok := annotation[ServiceAnnotationLoadBalancerSharedID]
+	if ok {
+		if loadBalancerSharedID == "" {
+			return nil, fmt.Errorf("ServiceAnnotationLoadBalancerSharedID must not be empty")
+		}
+		result.LoadBalancerSharedID = loadBalancerSharedID
+	}
+
+	loadBalancerSharedGroup, ok := annotation[ServiceAnnotationLoadBalancerSharedGroup]
+	if ok {
+		if !isValidSharedGroup(loadBalancerSharedGroup) {
+			return nil, fmt.Errorf("ServiceAnnotationLoadBalancerSharedGroup must consist of letters, digits, '-', '_' or '.', and at most %d characters, get %q", maxSharedGroupLength, loadBalancerSharedGroup)
+		}
+		if result.LoadBalancerSharedID != "" {
+			return nil, fmt.Errorf("ServiceAnnotationLoadBalancerSharedGroup and ServiceAnnotationLoadBalancerSharedID can not be set together")
+		}
+		result.LoadBalancerSharedGroup = loadBalancerSharedGroup
+	}
+
 	loadBalancerHealthCheckTimeoutInSecond, exist := annotation[ServiceAnnotationLoadBalancerHealthCheckTimeoutInSecond]
 	if exist {
 		i, err := strconv.Atoi(loadBalancerHealthCheckTimeoutInSecond)
 		if err != nil {
 			return nil, fmt.Errorf("ServiceAnnotationLoadBalancerHealthCheckTimeoutInSecond must be int")
+		} else if i < 1 || i > 60 {
+			return nil, fmt.Errorf("ServiceAnnotationLoadBalancerHealthCheckTimeoutInSecond must be in [1, 60]")
 		} else {
 			result.LoadBalancerHealthCheckTimeoutInSecond = i
 		}
@@ -222,6 +314,8 @@ func ExtractServiceAnnotation(service *v1.Service) (*ServiceAnnotation, error) {
 		i, err := strconv.Atoi(loadBalancerHealthCheckInterval)
 		if err != nil {
 			return nil, fmt.Errorf("ServiceAnnotationLoadBalancerHealthCheckInterval must be int")
+		} else if i < 1 || i > 10 {
+			return nil, fmt.Errorf("ServiceAnnotationLoadBalancerHealthCheckInterval must be in [1, 10]")
 		} else {
 			result.LoadBalancerHealthCheckInterval = i
 		}
@@ -232,6 +326,8 @@ func ExtractServiceAnnotation(service *v1.Service) (*ServiceAnnotation, error) {
 		i, err := strconv.Atoi(loadBalancerUnhealthyThreshold)
 		if err != nil {
 			return nil, fmt.Errorf("ServiceAnnotationLoadBalancerUnhealthyThreshold must be int")
+		} else if i < 2 || i > 5 {
+			return nil, fmt.Errorf("ServiceAnnotationLoadBalancerUnhealthyThreshold must be in [2, 5]")
 		} else {
 			result.LoadBalancerUnhealthyThreshold = i
 		}
@@ -242,6 +338,8 @@ func ExtractServiceAnnotation(service *v1.Service) (*ServiceAnnotation, error) {
 		i, err := strconv.Atoi(loadBalancerHealthyThreshold)
 		if err != nil {
 			return nil, fmt.Errorf("ServiceAnnotationLoadBalancerHealthyThreshold must be int")
+		} else if i < 2 || i > 5 {
+			return nil, fmt.Errorf("ServiceAnnotationLoadBalancerHealthyThreshold must be in [2, 5]")
 		} else {
 			result.LoadBalancerHealthyThreshold = i
 		}
@@ -252,6 +350,126 @@ func ExtractServiceAnnotation(service *v1.Service) (*ServiceAnnotation, error) {
 		result.LoadBalancerHealthCheckString = loadBalancerHealthCheckString
 	}
 
+	loadBalancerListenerProtocol, exist := annotation[ServiceAnnotationLoadBalancerListenerProtocol]
+	if exist {
+		protocols, err := parsePortMapping(loadBalancerListenerProtocol)
+		if err != nil {
+			return nil, fmt.Errorf("ServiceAnnotationLoadBalancerListenerProtocol syntax error: %v", err)
+		}
+		result.LoadBalancerListenerProtocol = make(map[int]string, len(protocols))
+		for port, protocol := range protocols {
+			protocol = strings.ToUpper(protocol)
+			if protocol != "HTTP" && protocol != "HTTPS" {
+				return nil, fmt.Errorf("ServiceAnnotationLoadBalancerListenerProtocol only support HTTP and HTTPS, get %s", protocol)
+			}
+			result.LoadBalancerListenerProtocol[port] = protocol
+		}
+	}
+
+	loadBalancerCertID, exist := annotation[ServiceAnnotationLoadBalancerCertID]
+	if exist {
+		for _, certID := range strings.Split(loadBalancerCertID, ",") {
+			certID = strings.TrimSpace(certID)
+			if certID != "" {
+				result.LoadBalancerCertIDs = append(result.LoadBalancerCertIDs, certID)
+			}
+		}
+	}
+
+	loadBalancerRedirectPort, exist := annotation[ServiceAnnotationLoadBalancerRedirectPort]
+	if exist {
+		redirects, err := parsePortMapping(loadBalancerRedirectPort)
+		if err != nil {
+			return nil, fmt.Errorf("ServiceAnnotationLoadBalancerRedirectPort syntax error: %v", err)
+		}
+		result.LoadBalancerRedirectPort = make(map[int]int, len(redirects))
+		for port, target := range redirects {
+			i, err := strconv.Atoi(target)
+			if err != nil || i <= 0 || i > 65535 {
+				return nil, fmt.Errorf("ServiceAnnotationLoadBalancerRedirectPort target port must be in [1, 65535], get %s", target)
+			}
+			result.LoadBalancerRedirectPort[port] = i
+		}
+	}
+
+	loadBalancerKeepSession, exist := annotation[ServiceAnnotationLoadBalancerKeepSession]
+	if exist {
+		keepSession, err := strconv.ParseBool(loadBalancerKeepSession)
+		if err != nil {
+			return nil, fmt.Errorf("ServiceAnnotationLoadBalancerKeepSession syntax error: %v", err)
+		}
+		result.LoadBalancerKeepSession = keepSession
+	}
+
+	loadBalancerKeepSessionDuration, exist := annotation[ServiceAnnotationLoadBalancerKeepSessionDuration]
+	if exist {
+		i, err := strconv.Atoi(loadBalancerKeepSessionDuration)
+		if err != nil {
+			return nil, fmt.Errorf("ServiceAnnotationLoadBalancerKeepSessionDuration must be int")
+		} else if i < 1 || i > 86400 {
+			return nil, fmt.Errorf("ServiceAnnotationLoadBalancerKeepSessionDuration must be in [1, 86400]")
+		} else {
+			result.LoadBalancerKeepSessionDuration = i
+		}
+	}
+
+	loadBalancerServerTimeout, exist := annotation[ServiceAnnotationLoadBalancerServerTimeout]
+	if exist {
+		i, err := strconv.Atoi(loadBalancerServerTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("ServiceAnnotationLoadBalancerServerTimeout must be int")
+		} else if i < 1 || i > 3600 {
+			return nil, fmt.Errorf("ServiceAnnotationLoadBalancerServerTimeout must be in [1, 3600]")
+		} else {
+			result.LoadBalancerServerTimeout = i
+		}
+	}
+
+	loadBalancerRsWeightPolicy, exist := annotation[ServiceAnnotationLoadBalancerRsWeightPolicy]
+	if exist {
+		if loadBalancerRsWeightPolicy != rsWeightPolicyDefault && loadBalancerRsWeightPolicy != rsWeightPolicyCPU {
+			return nil, fmt.Errorf("ServiceAnnotationLoadBalancerRsWeightPolicy must be %s or %s, get %s", rsWeightPolicyDefault, rsWeightPolicyCPU, loadBalancerRsWeightPolicy)
+		}
+		result.LoadBalancerRsWeightPolicy = loadBalancerRsWeightPolicy
+	}
+
+	loadBalancerRsDrainGracePeriod, exist := annotation[ServiceAnnotationLoadBalancerRsDrainGracePeriod]
+	if exist {
+		i, err := strconv.Atoi(loadBalancerRsDrainGracePeriod)
+		if err != nil {
+			return nil, fmt.Errorf("ServiceAnnotationLoadBalancerRsDrainGracePeriod must be int")
+		} else if i < 0 || i > maxRsDrainGracePeriod {
+			return nil, fmt.Errorf("ServiceAnnotationLoadBalancerRsDrainGracePeriod must be in [0, %d]", maxRsDrainGracePeriod)
+		} else {
+			result.LoadBalancerRsDrainGracePeriod = &i
+		}
+	}
+
+	loadBalancerBackendType, exist := annotation[ServiceAnnotationLoadBalancerBackendType]
+	if exist {
+		if loadBalancerBackendType != backendTypeNodePort && loadBalancerBackendType != backendTypePod {
+			return nil, fmt.Errorf("ServiceAnnotationLoadBalancerBackendType must be %s or %s, get %s", backendTypeNodePort, backendTypePod, loadBalancerBackendType)
+		}
+		result.LoadBalancerBackendType = loadBalancerBackendType
+	}
+
+	loadBalancerZeroEndpointsPolicy, exist := annotation[ServiceAnnotationLoadBalancerZeroEndpointsPolicy]
+	if exist {
+		if loadBalancerZeroEndpointsPolicy != zeroEndpointsPolicyKeep && loadBalancerZeroEndpointsPolicy != zeroEndpointsPolicyDrain {
+			return nil, fmt.Errorf("ServiceAnnotationLoadBalancerZeroEndpointsPolicy must be %s or %s, get %s", zeroEndpointsPolicyKeep, zeroEndpointsPolicyDrain, loadBalancerZeroEndpointsPolicy)
+		}
+		result.LoadBalancerZeroEndpointsPolicy = loadBalancerZeroEndpointsPolicy
+	}
+
+	loadBalancerHealthCheckNodePort, exist := annotation[ServiceAnnotationLoadBalancerHealthCheckNodePort]
+	if exist {
+		healthCheckNodePort, err := strconv.ParseBool(loadBalancerHealthCheckNodePort)
+		if err != nil {
+			return nil, fmt.Errorf("ServiceAnnotationLoadBalancerHealthCheckNodePort syntax error: %v", err)
+		}
+		result.LoadBalancerHealthCheckNodePort = healthCheckNodePort
+	}
+
 	elasticIPName, exist := annotation[ServiceAnnotationElasticIPName]
 	if exist {
 		result.ElasticIPName = elasticIPName
@@ -290,6 +508,34 @@ func ExtractServiceAnnotation(service *v1.Service) (*ServiceAnnotation, error) {
 	return result, nil
 }
 
+func isValidScheduler(scheduler string) bool {
+	return scheduler == blbSchedulerRoundRobin || scheduler == blbSchedulerLeastConnection || scheduler == blbSchedulerHash
+}
+
+// parsePortMapping parses annotation value like "80:HTTP,443:HTTPS" into a map keyed by port
+func parsePortMapping(value string) (map[int]string, error) {
+	result := make(map[int]string)
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		kv := strings.Split(item, ":")
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("%q should be in format port:value", item)
+		}
+		port, err := strconv.Atoi(strings.TrimSpace(kv[0]))
+		if err != nil || port <= 0 || port > 65535 {
+			return nil, fmt.Errorf("%q has invalid port", item)
+		}
+		if _, ok := result[port]; ok {
+			return nil, fmt.Errorf("port %d is duplicated", port)
+		}
+		result[port] = strings.TrimSpace(kv[1])
+	}
+	return result, nil
+}
+
 // ExtractNodeAnnotation extract annotations from node
 func ExtractNodeAnnotation(node *v1.Node) (*NodeAnnotation, error) {
 	klog.V(4).Infof("start to ExtractNodeAnnotation: %v", node.Annotations)
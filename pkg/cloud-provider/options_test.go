@@ -77,11 +77,12 @@ func TestExtractServiceAnnotationBLB(t *testing.T) {
 	data[ServiceAnnotationLoadBalancerAllocateVip] = "10.12.1.1"
 	data[ServiceAnnotationLoadBalancerSubnetID] = "10.12.1.1"
 	data[ServiceAnnotationLoadBalancerRsMaxNum] = "11"
-	data[ServiceAnnotationLoadBalancerScheduler] = "dd"
+	data[ServiceAnnotationLoadBalancerScheduler] = "LeastConnection"
+	data[ServiceAnnotationLoadBalancerPortScheduler] = "53:Hash"
 	data[ServiceAnnotationLoadBalancerHealthCheckTimeoutInSecond] = "11"
-	data[ServiceAnnotationLoadBalancerHealthCheckInterval] = "11"
-	data[ServiceAnnotationLoadBalancerUnhealthyThreshold] = "11"
-	data[ServiceAnnotationLoadBalancerHealthyThreshold] = "11"
+	data[ServiceAnnotationLoadBalancerHealthCheckInterval] = "10"
+	data[ServiceAnnotationLoadBalancerUnhealthyThreshold] = "4"
+	data[ServiceAnnotationLoadBalancerHealthyThreshold] = "5"
 	data[ServiceAnnotationLoadBalancerHealthCheckString] = "dsada11"
 
 	svc.SetAnnotations(data)
@@ -101,7 +102,7 @@ func TestExtractServiceAnnotationBLB(t *testing.T) {
 	if result.LoadBalancerExistID != "dsada11" {
 		t.Errorf("extract service LoadBalancerExistID annotation wrong")
 	}
-	if strconv.Itoa(result.LoadBalancerHealthCheckInterval) != "11" {
+	if strconv.Itoa(result.LoadBalancerHealthCheckInterval) != "10" {
 		t.Errorf("extract service LoadBalancerHealthCheckInterval annotation wrong")
 	}
 	if result.LoadBalancerHealthCheckString != "dsada11" {
@@ -110,7 +111,7 @@ func TestExtractServiceAnnotationBLB(t *testing.T) {
 	if strconv.Itoa(result.LoadBalancerHealthCheckTimeoutInSecond) != "11" {
 		t.Errorf("extract service LoadBalancerHealthCheckTimeoutInSecond annotation wrong")
 	}
-	if strconv.Itoa(result.LoadBalancerHealthyThreshold) != "11" {
+	if strconv.Itoa(result.LoadBalancerHealthyThreshold) != "5" {
 		t.Errorf("extract service LoadBalancerHealthyThreshold annotation wrong")
 	}
 	if result.LoadBalancerInternalVpc != "10.12.1.1" {
@@ -119,10 +120,13 @@ func TestExtractServiceAnnotationBLB(t *testing.T) {
 	if strconv.Itoa(result.LoadBalancerRsMaxNum) != "11" {
 		t.Errorf("extract service LoadBalancerRsMaxNum annotation wrong")
 	}
-	if result.LoadBalancerScheduler != "dd" {
+	if result.LoadBalancerScheduler != "LeastConnection" {
 		t.Errorf("extract service LoadBalancerScheduler annotation wrong")
 	}
-	if strconv.Itoa(result.LoadBalancerUnhealthyThreshold) != "11" {
+	if result.LoadBalancerPortScheduler[53] != "Hash" {
+		t.Errorf("extract service LoadBalancerPortScheduler annotation wrong")
+	}
+	if strconv.Itoa(result.LoadBalancerUnhealthyThreshold) != "4" {
 		t.Errorf("extract service LoadBalancerUnhealthyThreshold annotation wrong")
 	}
 	if result.LoadBalancerSubnetID != "10.12.1.1" {
@@ -170,6 +174,27 @@ func TestExtractServiceAnnotationBLB(t *testing.T) {
 		t.Errorf("extract service LoadBalancerRsMaxNum annotation wrong, should exist wrong")
 	}
 
+	// scheduler and health check annotations out of BLB's range
+	outOfRange := []map[string]string{
+		{ServiceAnnotationLoadBalancerScheduler: "dd"},
+		{ServiceAnnotationLoadBalancerPortScheduler: "80:dd"},
+		{ServiceAnnotationLoadBalancerHealthCheckTimeoutInSecond: "0"},
+		{ServiceAnnotationLoadBalancerHealthCheckTimeoutInSecond: "61"},
+		{ServiceAnnotationLoadBalancerHealthCheckInterval: "0"},
+		{ServiceAnnotationLoadBalancerHealthCheckInterval: "11"},
+		{ServiceAnnotationLoadBalancerUnhealthyThreshold: "1"},
+		{ServiceAnnotationLoadBalancerUnhealthyThreshold: "6"},
+		{ServiceAnnotationLoadBalancerHealthyThreshold: "1"},
+		{ServiceAnnotationLoadBalancerHealthyThreshold: "6"},
+	}
+	for _, data := range outOfRange {
+		svc.SetAnnotations(data)
+		_, err = ExtractServiceAnnotation(svc)
+		if err == nil {
+			t.Errorf("extract service annotation %v wrong, should exist wrong", data)
+		}
+	}
+
 }
 
 func TestExtractServiceAnnotationEIP(t *testing.T) {
@@ -239,6 +264,56 @@ func TestExtractServiceAnnotationEIP(t *testing.T) {
 	}
 }
 
+func TestExtractServiceAnnotationListener(t *testing.T) {
+	svc := buildService()
+
+	data := map[string]string{}
+	data[ServiceAnnotationLoadBalancerListenerProtocol] = "80:http, 443:HTTPS"
+	data[ServiceAnnotationLoadBalancerCertID] = "cert-a,cert-b"
+	data[ServiceAnnotationLoadBalancerRedirectPort] = "80:443"
+	data[ServiceAnnotationLoadBalancerKeepSession] = "true"
+	data[ServiceAnnotationLoadBalancerKeepSessionDuration] = "600"
+	data[ServiceAnnotationLoadBalancerServerTimeout] = "120"
+	svc.SetAnnotations(data)
+
+	result, err := ExtractServiceAnnotation(svc)
+	if err != nil {
+		t.Errorf("failed to extract service annotation: %v", err)
+	}
+	if result.LoadBalancerListenerProtocol[80] != "HTTP" || result.LoadBalancerListenerProtocol[443] != "HTTPS" {
+		t.Errorf("extract service LoadBalancerListenerProtocol annotation wrong: %v", result.LoadBalancerListenerProtocol)
+	}
+	if len(result.LoadBalancerCertIDs) != 2 || result.LoadBalancerCertIDs[1] != "cert-b" {
+		t.Errorf("extract service LoadBalancerCertIDs annotation wrong: %v", result.LoadBalancerCertIDs)
+	}
+	if result.LoadBalancerRedirectPort[80] != 443 {
+		t.Errorf("extract service LoadBalancerRedirectPort annotation wrong: %v", result.LoadBalancerRedirectPort)
+	}
+	if !result.LoadBalancerKeepSession || result.LoadBalancerKeepSessionDuration != 600 {
+		t.Errorf("extract service LoadBalancerKeepSession annotation wrong")
+	}
+	if result.LoadBalancerServerTimeout != 120 {
+		t.Errorf("extract service LoadBalancerServerTimeout annotation wrong")
+	}
+
+	wrongCases := []map[string]string{
+		{ServiceAnnotationLoadBalancerListenerProtocol: "80:FTP"},
+		{ServiceAnnotationLoadBalancerListenerProtocol: "80"},
+		{ServiceAnnotationLoadBalancerListenerProtocol: "80:HTTP,80:HTTPS"},
+		{ServiceAnnotationLoadBalancerRedirectPort: "80:abc"},
+		{ServiceAnnotationLoadBalancerKeepSession: "yes"},
+		{ServiceAnnotationLoadBalancerKeepSessionDuration: "0"},
+		{ServiceAnnotationLoadBalancerServerTimeout: "3601"},
+	}
+	for _, c := range wrongCases {
+		svc.SetAnnotations(c)
+		_, err = ExtractServiceAnnotation(svc)
+		if err == nil {
+			t.Errorf("extract service annotation %v should exist wrong", c)
+		}
+	}
+}
+
 func TestExtractNodeAnnotation(t *testing.T) {
 	case1 := &api.Node{
 		ObjectMeta: meta_v1.ObjectMeta{
@@ -306,3 +381,127 @@ func TestExtractNodeAnnotation(t *testing.T) {
 		t.Errorf("extract node NodeAnnotationAdvertiseRoute annotation wrong, should exist wrong")
 	}
 }
+
+func TestExtractNodeAnnotationRsWeight(t *testing.T) {
+	node := &api.Node{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:   "foo",
+			Labels: map[string]string{NodeAnnotationBLBRsWeight: "10"},
+		},
+	}
+	weight, err := getNodeRsWeight(node)
+	if err != nil || weight != 10 {
+		t.Errorf("get node rs weight from label wrong, want 10, get %d, err: %v", weight, err)
+	}
+
+	node.SetAnnotations(map[string]string{NodeAnnotationBLBRsWeight: "50"})
+	weight, err = getNodeRsWeight(node)
+	if err != nil || weight != 50 {
+		t.Errorf("get node rs weight from annotation wrong, want 50, get %d, err: %v", weight, err)
+	}
+
+	for _, w := range []string{"0", "101", "abc"} {
+		node.SetAnnotations(map[string]string{NodeAnnotationBLBRsWeight: w})
+		if _, err = getNodeRsWeight(node); err == nil {
+			t.Errorf("get node rs weight %s should fail", w)
+		}
+		// invalid weight must not fail the extractor shared by routes
+		if _, err = ExtractNodeAnnotation(node); err != nil {
+			t.Errorf("extract node annotation with rs weight %s wrong, %s", w, err)
+		}
+	}
+
+	svc := buildService()
+	svc.SetAnnotations(map[string]string{ServiceAnnotationLoadBalancerRsWeightPolicy: rsWeightPolicyCPU})
+	anno, err := ExtractServiceAnnotation(svc)
+	if err != nil {
+		t.Errorf("failed to extract service annotation: %v", err)
+	}
+	if anno.LoadBalancerRsWeightPolicy != rsWeightPolicyCPU {
+		t.Errorf("extract service LoadBalancerRsWeightPolicy annotation wrong")
+	}
+	svc.SetAnnotations(map[string]string{ServiceAnnotationLoadBalancerRsWeightPolicy: "memory"})
+	_, err = ExtractServiceAnnotation(svc)
+	if err == nil {
+		t.Errorf("extract service LoadBalancerRsWeightPolicy annotation memory should fail")
+	}
+}
+
+func TestExtractServiceAnnotationRsDrainGracePeriod(t *testing.T) {
+	svc := buildService()
+	result, err := ExtractServiceAnnotation(svc)
+	if err != nil {
+		t.Errorf("failed to extract service annotation: %v", err)
+	}
+	if result.LoadBalancerRsDrainGracePeriod != nil {
+		t.Errorf("extract service LoadBalancerRsDrainGracePeriod annotation wrong")
+	}
+
+	svc.SetAnnotations(map[string]string{ServiceAnnotationLoadBalancerRsDrainGracePeriod: "0"})
+	result, err = ExtractServiceAnnotation(svc)
+	if err != nil {
+		t.Errorf("failed to extract service annotation: %v", err)
+	}
+	if result.LoadBalancerRsDrainGracePeriod == nil || *result.LoadBalancerRsDrainGracePeriod != 0 {
+		t.Errorf("extract service LoadBalancerRsDrainGracePeriod annotation wrong")
+	}
+
+	for _, period := range []string{"-1", "3601", "abc"} {
+		svc.SetAnnotations(map[string]string{ServiceAnnotationLoadBalancerRsDrainGracePeriod: period})
+		_, err = ExtractServiceAnnotation(svc)
+		if err == nil {
+			t.Errorf("extract service LoadBalancerRsDrainGracePeriod annotation %s should fail", period)
+		}
+	}
+}
+
+func TestExtractServiceAnnotationBackendType(t *testing.T) {
+	svc := buildService()
+	svc.SetAnnotations(map[string]string{ServiceAnnotationLoadBalancerBackendType: backendTypePod})
+	result, err := ExtractServiceAnnotation(svc)
+	if err != nil {
+		t.Errorf("failed to extract service annotation: %v", err)
+	}
+	if result.LoadBalancerBackendType != backendTypePod {
+		t.Errorf("extract service LoadBalancerBackendType annotation wrong")
+	}
+	svc.SetAnnotations(map[string]string{ServiceAnnotationLoadBalancerBackendType: "eni"})
+	_, err = ExtractServiceAnnotation(svc)
+	if err == nil {
+		t.Errorf("extract service LoadBalancerBackendType annotation eni should fail")
+	}
+}
+
+func TestExtractServiceAnnotationZeroEndpointsPolicy(t *testing.T) {
+	svc := buildService()
+	svc.SetAnnotations(map[string]string{ServiceAnnotationLoadBalancerZeroEndpointsPolicy: zeroEndpointsPolicyDrain})
+	result, err := ExtractServiceAnnotation(svc)
+	if err != nil {
+		t.Errorf("failed to extract service annotation: %v", err)
+	}
+	if result.LoadBalancerZeroEndpointsPolicy != zeroEndpointsPolicyDrain {
+		t.Errorf("extract service LoadBalancerZeroEndpointsPolicy annotation wrong")
+	}
+	svc.SetAnnotations(map[string]string{ServiceAnnotationLoadBalancerZeroEndpointsPolicy: "delete"})
+	_, err = ExtractServiceAnnotation(svc)
+	if err == nil {
+		t.Errorf("extract service LoadBalancerZeroEndpointsPolicy annotation delete should fail")
+	}
+}
+
+func TestExtractServiceAnnotationHealthCheckNodePort(t *testing.T) {
+	svc := buildService()
+	svc.SetAnnotations(map[string]string{ServiceAnnotationLoadBalancerHealthCheckNodePort: "true"})
+	result, err := ExtractServiceAnnotation(svc)
+	if err != nil {
+		t.Errorf("failed to extract service annotation: %v", err)
+	}
+	if !result.LoadBalancerHealthCheckNodePort {
+		t.Errorf("extract service LoadBalancerHealthCheckNodePort annotation wrong")
+	}
+	svc.SetAnnotations(map[string]string{ServiceAnnotationLoadBalancerHealthCheckNodePort: "yes"})
+	_, err = ExtractServiceAnnotation(svc)
+	if err == nil {
+		t.Errorf("extract service LoadBalancerHealthCheckNodePort annotation yes should fail")
+	}
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloud_provider
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/util"
+)
+
+// redacted replaces secrets where they are printed
+const redacted = "******"
+
+// minSecretLength keeps short values, which are unlikely secrets, from redacting unrelated text
+const minSecretLength = 6
+
+var (
+	secretPatterns = []*regexp.Regexp{
+		// http headers of BCE API requests, e.g. "Authorization: bce-auth-v1/<AK>/..." in SDK debug dumps
+		regexp.MustCompile(`(?i)((?:authorization|` + TokenHeaderKey + `|x-bce-security-token)["']?\s*[:=]\s*\[?["']?)[^\s"',\]}]+`),
+		// secret fields in JSON, e.g. cloud config
+		regexp.MustCompile(`(?i)("(?:AccessKeyID|SecretAccessKey|token)"\s*:\s*")[^"]*`),
+	}
+
+	// secretValues are the current secrets, which are redacted wherever they appear
+	secretValuesLock sync.RWMutex
+	secretValues     []string
+
+	redactSDKOutput sync.Once
+)
+
+// setSecretValues sets credentials to redact, replacing the rotated ones
+func setSecretValues(credentials *Credentials) {
+	values := []string{}
+	for _, value := range []string{credentials.AccessKeyID, credentials.SecretAccessKey, credentials.Token} {
+		if len(value) >= minSecretLength {
+			values = append(values, value)
+		}
+	}
+	secretValuesLock.Lock()
+	defer secretValuesLock.Unlock()
+	secretValues = values
+}
+
+// redactSecrets replaces current credentials and secret headers and fields in s
+func redactSecrets(s string) string {
+	secretValuesLock.RLock()
+	for _, value := range secretValues {
+		s = strings.Replace(s, value, redacted, -1)
+	}
+	secretValuesLock.RUnlock()
+	for _, pattern := range secretPatterns {
+		s = pattern.ReplaceAllString(s, "${1}"+redacted)
+	}
+	return s
+}
+
+// redactingLogger is the logger of SDK debug dumps, which prints with secrets redacted
+type redactingLogger struct {
+	logger util.LoggerItf
+}
+
+func (l *redactingLogger) Println(v ...interface{}) {
+	l.logger.Println(strings.TrimSuffix(redactSecrets(fmt.Sprintln(v...)), "\n"))
+}
+
+func (l *redactingLogger) Printf(format string, v ...interface{}) {
+	l.logger.Printf("%s", redactSecrets(fmt.Sprintf(format, v...)))
+}
+
+// redactSDKDebugOutput redacts secrets in the debug dumps of SDK, which are printed by the SDK logger
+func redactSDKDebugOutput() {
+	redactSDKOutput.Do(func() {
+		util.DefaultLogger = &redactingLogger{logger: util.DefaultLogger}
+	})
+}
+
+// String prints cloud config with AK/SK redacted
+func (c CloudConfig) String() string {
+	// config has no String method, so it is printed by fields
+	type config CloudConfig
+	redactedConfig := config(c)
+	if redactedConfig.AccessKeyID != "" {
+		redactedConfig.AccessKeyID = redacted
+	}
+	if redactedConfig.SecretAccessKey != "" {
+		redactedConfig.SecretAccessKey = redacted
+	}
+	return fmt.Sprintf("%+v", redactedConfig)
+}
+
+// String prints credentials with secrets redacted
+func (c *Credentials) String() string {
+	redactedCredentials := *c
+	for _, value := range []*string{&redactedCredentials.AccessKeyID, &redactedCredentials.SecretAccessKey, &redactedCredentials.Token} {
+		if *value != "" {
+			*value = redacted
+		}
+	}
+	return fmt.Sprintf("{AccessKeyID:%s SecretAccessKey:%s Token:%s ExpiredAt:%s}", redactedCredentials.AccessKeyID,
+		redactedCredentials.SecretAccessKey, redactedCredentials.Token, redactedCredentials.ExpiredAt)
+}
@@ -0,0 +1,53 @@
+package cloud_provider
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestRedactSecrets(t *testing.T) {
+	setSecretValues(&Credentials{AccessKeyID: "ak-123456", SecretAccessKey: "sk-123456", Token: "token-123456"})
+	defer setSecretValues(&Credentials{})
+
+	testCases := []struct {
+		s    string
+		want string
+	}{
+		{"Request: header = Authorization: bce-auth-v1/ak-0/2020-01-01T00:00:00Z/1800/host/signature",
+			"Request: header = Authorization: ******"},
+		{"map[Cce-Token:[token-0] Host:[blb.bj.baidubce.com]]", "map[Cce-Token:[******] Host:[blb.bj.baidubce.com]]"},
+		{`{"AccessKeyID": "ak-0", "SecretAccessKey": "sk-0", "Region": "bj"}`,
+			`{"AccessKeyID": "******", "SecretAccessKey": "******", "Region": "bj"}`},
+		{"sign with ak-123456 and sk-123456 and token-123456", "sign with ****** and ****** and ******"},
+		{"Region: bj", "Region: bj"},
+	}
+	for i, tc := range testCases {
+		if got := redactSecrets(tc.s); got != tc.want {
+			t.Errorf("case%d: redactSecrets err, want %s, get %s", i+1, tc.want, got)
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	logger := &redactingLogger{logger: log.New(buf, "", 0)}
+	logger.Println("secret", "sk-123456")
+	logger.Printf("token %s", "token-123456")
+	if buf.String() != "secret ******\ntoken ******\n" {
+		t.Errorf("redactingLogger err, want redacted, get %q", buf.String())
+	}
+}
+
+func TestRedactedString(t *testing.T) {
+	config := CloudConfig{ClusterID: "c-test", AccessKeyID: "ak-0", SecretAccessKey: "sk-0"}
+	for _, s := range []string{fmt.Sprintf("%v", config), fmt.Sprintf("%+v", &config)} {
+		if strings.Contains(s, "ak-0") || strings.Contains(s, "sk-0") || !strings.Contains(s, "c-test") {
+			t.Errorf("CloudConfig String err, want AK/SK redacted, get %s", s)
+		}
+	}
+	credentials := &Credentials{AccessKeyID: "ak-0", SecretAccessKey: "sk-0", Token: "token-0"}
+	if s := fmt.Sprintf("%v", credentials); strings.Contains(s, "ak-0") || strings.Contains(s, "sk-0") || strings.Contains(s, "token-0") {
+		t.Errorf("Credentials String err, want secrets redacted, get %s", s)
+	}
+}
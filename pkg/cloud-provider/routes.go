@@ -42,7 +42,7 @@ func (bc *Baiducloud) Routes() (cloudprovider.Routes, bool) {
 
 // ListRoutes lists all managed routes that belong to the specified clusterName
 func (bc *Baiducloud) ListRoutes(ctx context.Context, clusterName string) (routes []*cloudprovider.Route, err error) {
-	ctx = context.WithValue(ctx, RequestID, GetRandom())
+	ctx = WithRequestID(ctx)
 	startTime := time.Now()
 	defer func() {
 		klog.Infof(Message(ctx, fmt.Sprintf("Finished ListRoutes (%v)", time.Since(startTime))))
@@ -55,11 +55,10 @@ func (bc *Baiducloud) ListRoutes(ctx context.Context, clusterName string) (route
 	// routeTableConflictDetection
 	go bc.routeTableConflictDetection(ctx, rs)
 
-	instanceResponse, err := bc.clientSet.CCEClient.ListClusterNodes(ctx, bc.ClusterID, bc.getSignOption(ctx))
+	inss, err := bc.listClusterInstances(ctx)
 	if err != nil {
 		return nil, err
 	}
-	inss := instanceResponse.Nodes
 	// Deprecated: there is no need to check node annotaions every cycle
 	//vpcID := inss[0].VPCID
 	nodename := make(map[string]string)
@@ -120,7 +119,7 @@ func (bc *Baiducloud) CreateRoute(ctx context.Context, clusterName string, nameH
 	}
 
 	if !advertiseRoute {
-		klog.V(3).Infof("Node %s has annotation not to advertise route", string(kubeRoute.TargetNode))
+		klog.V(3).Infof(Message(ctx, fmt.Sprintf("Node %s has annotation not to advertise route", string(kubeRoute.TargetNode))))
 		return nil
 	}
 
@@ -157,15 +156,15 @@ func (bc *Baiducloud) DeleteRoute(ctx context.Context, clusterName string, kubeR
 	klog.Infof(Message(ctx, fmt.Sprintf("DeleteRoute: instance=%q cidr=%q", kubeRoute.TargetNode, kubeRoute.DestinationCIDR)))
 	vpcTable, err := bc.getVpcRouteTable(ctx)
 	if err != nil {
-		klog.V(3).Infof("getVpcRouteTable error %s", err.Error())
+		klog.V(3).Infof(Message(ctx, fmt.Sprintf("getVpcRouteTable error %s", err.Error())))
 		return err
 	}
 	for _, vr := range vpcTable {
 		if vr.DestinationAddress == kubeRoute.DestinationCIDR && vr.SourceAddress == "0.0.0.0/0" {
-			klog.V(3).Infof("DeleteRoute: DestinationAddress is %s .", vr.DestinationAddress)
+			klog.V(3).Infof(Message(ctx, fmt.Sprintf("DeleteRoute: DestinationAddress is %s .", vr.DestinationAddress)))
 			err := bc.clientSet.VPCClient.DeleteRoute(ctx, vr.RouteRuleID, bc.getSignOption(ctx))
 			if err != nil {
-				klog.V(3).Infof("Delete VPC route error %s", err.Error())
+				klog.V(3).Infof(Message(ctx, fmt.Sprintf("Delete VPC route error %s", err.Error())))
 				return err
 			}
 		}
@@ -250,11 +249,10 @@ func (bc *Baiducloud) ensureRouteInfoToNode(nodeName, vpcID, vpcRouteTableID, vp
 
 func (bc *Baiducloud) getVpcID(ctx context.Context) (string, error) {
 	if bc.VpcID == "" {
-		instanceResponse, err := bc.clientSet.CCEClient.ListClusterNodes(ctx, bc.ClusterID, bc.getSignOption(ctx))
+		ins, err := bc.listClusterInstances(ctx)
 		if err != nil {
 			return "", err
 		}
-		ins := instanceResponse.Nodes
 		if len(ins) > 0 {
 			bc.VpcID = ins[0].VPCID
 			bc.SubnetID = ins[0].SubnetID
@@ -285,9 +283,9 @@ func (bc *Baiducloud) routeTableConflictDetection(ctx context.Context, rs []vpc.
 	for i := 0; i < len(otherRR); i++ {
 		for j := 0; j < len(cceRR); j++ {
 			if bc.isConflict(otherRR[i], cceRR[j]) {
-				klog.V(4).Infof("RouteTable conflict detected, custom routeRule %v may conflict with cce routeRule %v", otherRR[i], cceRR[j])
+				klog.V(4).Infof(Message(ctx, fmt.Sprintf("RouteTable conflict detected, custom routeRule %v may conflict with cce routeRule %v", otherRR[i], cceRR[j])))
 				if bc.eventRecorder != nil {
-					bc.eventRecorder.Eventf(&v1.ObjectReference{
+					Eventf(ctx, bc.eventRecorder, &v1.ObjectReference{
 						Kind: "VPC",
 						Name: "RouteTableConflict",
 					}, v1.EventTypeWarning, "RouteTableConflictDetection", "RouteTable conflict detected, custom routeRule %v may conflict with cce routeRule %v", otherRR[i], cceRR[j])
@@ -348,19 +346,11 @@ func (bc *Baiducloud) advertiseRoute(nodename string) (bool, error) {
 }
 
 func (bc *Baiducloud) checkClusterNode(ctx context.Context, kubeRoute *cloudprovider.Route) (string, error) {
-	var node *cce.Node
-	instanceResponse, err := bc.clientSet.CCEClient.ListClusterNodes(ctx, bc.ClusterID, bc.getSignOption(ctx))
+	node, err := bc.getClusterInstanceByName(ctx, string(kubeRoute.TargetNode))
 	if err != nil {
 		return "", err
 	}
 
-	for _, ins := range instanceResponse.Nodes {
-		if ins.Hostname == string(kubeRoute.TargetNode) || ins.IP == string(kubeRoute.TargetNode) {
-			node = ins
-			break
-		}
-	}
-
 	if node == nil {
 		klog.Errorf(Message(ctx, fmt.Sprintf("InstanceId not found for k8s node %s, not create route", string(kubeRoute.TargetNode))))
 		return "", fmt.Errorf("InstanceId not found for k8s node %s, create route failed", string(kubeRoute.TargetNode))
@@ -368,7 +358,7 @@ func (bc *Baiducloud) checkClusterNode(ctx context.Context, kubeRoute *cloudprov
 
 	if node.Status == cce.InstanceStatusCreateFailed || node.Status == cce.InstanceStatusDeleted ||
 		node.Status == cce.InstanceStatusDeleting || node.Status == cce.InstanceStatusError {
-		klog.V(3).Infof("No need to create route, instance has a wrong status: %s", node.Status)
+		klog.V(3).Infof(Message(ctx, fmt.Sprintf("No need to create route, instance has a wrong status: %s", node.Status)))
 		return "", nil
 	}
 
@@ -389,7 +379,7 @@ func (bc *Baiducloud) ensureCreateRule(ctx context.Context, kubeRoute *cloudprov
 		if vr.DestinationAddress == kubeRoute.DestinationCIDR && vr.SourceAddress == "0.0.0.0/0" {
 			err := bc.clientSet.VPCClient.DeleteRoute(ctx, vr.RouteRuleID, bc.getSignOption(ctx))
 			if err != nil {
-				klog.Infof("Delete VPC route error %s", err)
+				klog.Infof(Message(ctx, fmt.Sprintf("Delete VPC route error %s", err)))
 				return vpc.RouteRule{}, err
 			}
 		}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 
 	uuid "github.com/satori/go.uuid"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 )
 
 // ContextKeyType for context.WithValue(
@@ -15,20 +17,48 @@ const (
 	RequestID ContextKeyType = "RequestID"
 )
 
+const (
+	// RequestIDHeader is the http header request ID is sent to BCE in, so that BCE API calls can be traced
+	RequestIDHeader = "x-bce-request-id"
+	// RequestIDAnnotation is the annotation of events request ID is recorded in
+	RequestIDAnnotation = "cce.baidubce.com/request-id"
+)
+
 // GetRandom 返回 64 位随机字符
 func GetRandom() string {
 	uuid := uuid.NewV4()
 	return uuid.String()
 }
 
+// WithRequestID returns ctx with a new request ID, or ctx itself if it already has one, so that the request ID started
+// by a controller reconcile is kept through cloud provider calls
+func WithRequestID(ctx context.Context) context.Context {
+	if GetRequestID(ctx) != "" {
+		return ctx
+	}
+	return context.WithValue(ctx, RequestID, GetRandom())
+}
+
+// GetRequestID returns request ID of ctx, empty if not set
+func GetRequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(RequestID).(string)
+	return requestID
+}
+
 // Message 返回打标的信息
 func Message(ctx context.Context, msg string) string {
-	requestID := ""
+	return fmt.Sprintf("[ReqID:%s] %s", GetRequestID(ctx), msg)
+}
 
-	if ctx.Value(RequestID) != nil {
-		requestID = ctx.Value(RequestID).(string)
+// Eventf records event with request ID of ctx, which is tagged to message like Message and set in annotation
+// RequestIDAnnotation
+func Eventf(ctx context.Context, recorder record.EventRecorder, object runtime.Object, eventType, reason, messageFmt string, args ...interface{}) {
+	requestID := GetRequestID(ctx)
+	if requestID == "" {
+		recorder.Eventf(object, eventType, reason, messageFmt, args...)
+		return
 	}
-
-	return fmt.Sprintf("[ReqID:%s] %s", requestID, msg)
+	recorder.AnnotatedEventf(object, map[string]string{RequestIDAnnotation: requestID}, eventType, reason, "%s",
+		Message(ctx, fmt.Sprintf(messageFmt, args...)))
 }
 
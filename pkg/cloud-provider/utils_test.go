@@ -0,0 +1,44 @@
+package cloud_provider
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestWithRequestID(t *testing.T) {
+	// case1: new request ID
+	ctx := WithRequestID(context.Background())
+	requestID := GetRequestID(ctx)
+	if requestID == "" {
+		t.Fatalf("WithRequestID err, want request ID")
+	}
+	// case2: existing request ID is kept
+	if got := GetRequestID(WithRequestID(ctx)); got != requestID {
+		t.Errorf("WithRequestID err, want %s, get %s", requestID, got)
+	}
+	// case3: no request ID
+	if got := GetRequestID(context.Background()); got != "" {
+		t.Errorf("GetRequestID err, want empty, get %s", got)
+	}
+}
+
+func TestEventf(t *testing.T) {
+	recorder := record.NewFakeRecorder(2)
+	svc := &v1.Service{}
+
+	ctx := WithRequestID(context.Background())
+	Eventf(ctx, recorder, svc, v1.EventTypeNormal, "EnsuredLoadBalancer", "Ensured load balancer %s", "lb-1")
+	event := <-recorder.Events
+	if !strings.Contains(event, GetRequestID(ctx)) || !strings.Contains(event, "Ensured load balancer lb-1") {
+		t.Errorf("Eventf err, want request ID in message, get %s", event)
+	}
+
+	Eventf(context.Background(), recorder, svc, v1.EventTypeNormal, "EnsuredLoadBalancer", "Ensured load balancer")
+	if event := <-recorder.Events; event != "Normal EnsuredLoadBalancer Ensured load balancer" {
+		t.Errorf("Eventf err, want plain event, get %s", event)
+	}
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloud_provider
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog"
+)
+
+const (
+	// defaultWaitTimeout is how long to wait for cloud resources to reach expected status
+	defaultWaitTimeout = 30 * time.Second
+
+	// blbStatusAvailable is the status of BLB ready for use
+	blbStatusAvailable = "available"
+)
+
+// defaultWaitBackoff is the interval to poll status of cloud resources
+var defaultWaitBackoff = wait.Backoff{
+	Duration: 1 * time.Second,
+	Factor:   1.5,
+	Jitter:   0.1,
+	Steps:    10,
+	Cap:      5 * time.Second,
+}
+
+// statusConditionFunc returns true if the expected status is reached, polling stops if error is returned
+type statusConditionFunc func(ctx context.Context) (bool, error)
+
+// waitForStatus polls condition with backoff until it returns true, it returns error if condition fails,
+// timeout is reached or ctx is cancelled.
+// After backoff steps are used up, condition is polled at the last interval until timeout.
+func waitForStatus(ctx context.Context, desc string, backoff wait.Backoff, timeout time.Duration, condition statusConditionFunc) error {
+	startTime := time.Now()
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	for {
+		done, err := condition(ctx)
+		if err != nil {
+			return err
+		}
+		if done {
+			klog.V(4).Infof(Message(ctx, fmt.Sprintf("wait for %s done (%v)", desc, time.Since(startTime))))
+			return nil
+		}
+
+		timer := time.NewTimer(backoff.Step())
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("wait for %s failed after %v: %v", desc, time.Since(startTime), ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
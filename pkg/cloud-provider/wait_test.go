@@ -0,0 +1,63 @@
+package cloud_provider
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+var testWaitBackoff = wait.Backoff{
+	Duration: 10 * time.Millisecond,
+	Factor:   2,
+	Steps:    3,
+	Cap:      40 * time.Millisecond,
+}
+
+// case1: condition becomes true after several polls
+// case2: condition fails
+// case3: timeout
+// case4: ctx cancelled
+func TestWaitForStatus(t *testing.T) {
+	// case1
+	count := 0
+	err := waitForStatus(context.Background(), "case1", testWaitBackoff, time.Second, func(ctx context.Context) (bool, error) {
+		count++
+		return count == 5, nil
+	})
+	if err != nil || count != 5 {
+		t.Errorf("waitForStatus err, want nil after 5 polls, get %v after %d polls", err, count)
+	}
+
+	// case2
+	count = 0
+	err = waitForStatus(context.Background(), "case2", testWaitBackoff, time.Second, func(ctx context.Context) (bool, error) {
+		count++
+		return false, fmt.Errorf("not exist")
+	})
+	if err == nil || count != 1 {
+		t.Errorf("waitForStatus err, want error after 1 poll, get %v after %d polls", err, count)
+	}
+
+	// case3
+	startTime := time.Now()
+	err = waitForStatus(context.Background(), "case3", testWaitBackoff, 100*time.Millisecond, func(ctx context.Context) (bool, error) {
+		return false, nil
+	})
+	if err == nil || time.Since(startTime) > time.Second {
+		t.Errorf("waitForStatus err, want timeout error, get %v after %v", err, time.Since(startTime))
+	}
+
+	// case4
+	ctx, cancel := context.WithCancel(context.Background())
+	startTime = time.Now()
+	err = waitForStatus(ctx, "case4", testWaitBackoff, time.Minute, func(ctx context.Context) (bool, error) {
+		cancel()
+		return false, nil
+	})
+	if err == nil || time.Since(startTime) > time.Second {
+		t.Errorf("waitForStatus err, want cancelled error, get %v after %v", err, time.Since(startTime))
+	}
+}
@@ -183,12 +183,13 @@ func (cnc *CloudNodeController) UpdateNodeStatus() {
 	}
 
 	for i := range nodes.Items {
-		cnc.updateNodeAddress(&nodes.Items[i], instances)
+		ctx := cloud_provider.WithRequestID(context.Background())
+		cnc.updateNodeAddress(ctx, &nodes.Items[i], instances)
 	}
 }
 
 // UpdateNodeAddress updates the nodeAddress of a single node
-func (cnc *CloudNodeController) updateNodeAddress(node *v1.Node, instances cloudprovider.Instances) {
+func (cnc *CloudNodeController) updateNodeAddress(ctx context.Context, node *v1.Node, instances cloudprovider.Instances) {
 	// Do not process nodes that are still tainted
 	cloudTaint := getCloudTaint(node.Spec.Taints)
 	if cloudTaint != nil {
@@ -196,7 +197,7 @@ func (cnc *CloudNodeController) updateNodeAddress(node *v1.Node, instances cloud
 		return
 	}
 	// Node that isn't present according to the cloud provider shouldn't have its address updated
-	exists, err := ensureNodeExistsByProviderID(instances, node)
+	exists, err := ensureNodeExistsByProviderID(ctx, instances, node)
 	if err != nil {
 		// Continue to update node address when not sure the node is not exists
 		klog.Errorf("%v", err)
@@ -205,7 +206,7 @@ func (cnc *CloudNodeController) updateNodeAddress(node *v1.Node, instances cloud
 		return
 	}
 
-	nodeAddresses, err := getNodeAddressesByProviderIDOrName(instances, node)
+	nodeAddresses, err := getNodeAddressesByProviderIDOrName(ctx, instances, node)
 	if err != nil {
 		klog.Errorf("%v", err)
 		return
@@ -293,7 +294,7 @@ func (cnc *CloudNodeController) initializeNode(node *v1.Node) {
 		// TODO(wlan0): Move this logic to the route controller using the node taint instead of condition
 		// Since there are node taints, do we still need this?
 		// This condition marks the node as unusable until routes are initialized in the cloud provider
-		ReqID := cloud_provider.GetRandom()
+		ctx := cloud_provider.WithRequestID(context.Background())
 		if cnc.cloud.ProviderName() == "gce" {
 			if err := nodeutil.SetNodeCondition(cnc.kubeClient, types.NodeName(node.Name), v1.NodeCondition{
 				Type:               v1.NodeNetworkUnavailable,
@@ -310,7 +311,7 @@ func (cnc *CloudNodeController) initializeNode(node *v1.Node) {
 		if err != nil {
 			return err
 		}
-		klog.Infof("ReqID: %s. Before update node is %s %v", ReqID, curNode.Name, curNode.ResourceVersion)
+		klog.Infof(cloud_provider.Message(ctx, fmt.Sprintf("Before update node is %s %v", curNode.Name, curNode.ResourceVersion)))
 
 		cloudTaint := getCloudTaint(curNode.Spec.Taints)
 		if cloudTaint == nil {
@@ -320,7 +321,7 @@ func (cnc *CloudNodeController) initializeNode(node *v1.Node) {
 		}
 
 		if curNode.Spec.ProviderID == "" {
-			providerID, err := cloudprovider.GetInstanceProviderID(context.TODO(), cnc.cloud, types.NodeName(curNode.Name))
+			providerID, err := cloudprovider.GetInstanceProviderID(ctx, cnc.cloud, types.NodeName(curNode.Name))
 			if err == nil {
 				curNode.Spec.ProviderID = providerID
 			} else {
@@ -331,7 +332,7 @@ func (cnc *CloudNodeController) initializeNode(node *v1.Node) {
 			}
 		}
 
-		nodeAddresses, err := getNodeAddressesByProviderIDOrName(instances, curNode)
+		nodeAddresses, err := getNodeAddressesByProviderIDOrName(ctx, instances, curNode)
 		if err != nil {
 			return err
 		}
@@ -344,7 +345,7 @@ func (cnc *CloudNodeController) initializeNode(node *v1.Node) {
 			}
 		}
 
-		if instanceType, err := getInstanceTypeByProviderIDOrName(instances, curNode); err != nil {
+		if instanceType, err := getInstanceTypeByProviderIDOrName(ctx, instances, curNode); err != nil {
 			return err
 		} else if instanceType != "" {
 			klog.V(2).Infof("Adding node label from cloud provider: %s=%s", v1.LabelInstanceType, instanceType)
@@ -352,7 +353,7 @@ func (cnc *CloudNodeController) initializeNode(node *v1.Node) {
 		}
 
 		if zones, ok := cnc.cloud.Zones(); ok {
-			zone, err := getZoneByProviderIDOrName(zones, curNode)
+			zone, err := getZoneByProviderIDOrName(ctx, zones, curNode)
 			if err != nil {
 				return fmt.Errorf("failed to get zone from cloud provider: %v", err)
 			}
@@ -367,13 +368,13 @@ func (cnc *CloudNodeController) initializeNode(node *v1.Node) {
 		}
 
 		curNode.Spec.Taints = excludeCloudTaint(curNode.Spec.Taints)
-		klog.Infof("ReqID: %s. After exclude taint node is %s %v", ReqID, curNode.Name, curNode.ResourceVersion)
+		klog.Infof(cloud_provider.Message(ctx, fmt.Sprintf("After exclude taint node is %s %v", curNode.Name, curNode.ResourceVersion)))
 
 		newestNode, err := cnc.kubeClient.CoreV1().Nodes().Get(node.Name, metav1.GetOptions{})
 		if err != nil {
 			return err
 		}
-		klog.Infof("ReqID: %s. Newest node resource version is %v", ReqID, newestNode.ResourceVersion)
+		klog.Infof(cloud_provider.Message(ctx, fmt.Sprintf("Newest node resource version is %v", newestNode.ResourceVersion)))
 		curNode.ResourceVersion = newestNode.ResourceVersion
 
 		_, err = cnc.kubeClient.CoreV1().Nodes().Update(curNode)
@@ -382,7 +383,7 @@ func (cnc *CloudNodeController) initializeNode(node *v1.Node) {
 		}
 		// After adding, call UpdateNodeAddress to set the CloudProvider provided IPAddresses
 		// So that users do not see any significant delay in IP addresses being filled into the node
-		cnc.updateNodeAddress(curNode, instances)
+		cnc.updateNodeAddress(ctx, curNode, instances)
 
 		klog.Infof("Successfully initialized node %s with cloud provider", node.Name)
 		return nil
@@ -415,11 +416,11 @@ func excludeCloudTaint(taints []v1.Taint) []v1.Taint {
 
 // ensureNodeExistsByProviderID checks if the instance exists by the provider id,
 // If provider id in spec is empty it calls instanceId with node name to get provider id
-func ensureNodeExistsByProviderID(instances cloudprovider.Instances, node *v1.Node) (bool, error) {
+func ensureNodeExistsByProviderID(ctx context.Context, instances cloudprovider.Instances, node *v1.Node) (bool, error) {
 	providerID := node.Spec.ProviderID
 	if providerID == "" {
 		var err error
-		providerID, err = instances.InstanceID(context.TODO(), types.NodeName(node.Name))
+		providerID, err = instances.InstanceID(ctx, types.NodeName(node.Name))
 		if err != nil {
 			if err == cloudprovider.InstanceNotFound {
 				return false, nil
@@ -433,14 +434,14 @@ func ensureNodeExistsByProviderID(instances cloudprovider.Instances, node *v1.No
 		}
 	}
 
-	return instances.InstanceExistsByProviderID(context.TODO(), providerID)
+	return instances.InstanceExistsByProviderID(ctx, providerID)
 }
 
-func getNodeAddressesByProviderIDOrName(instances cloudprovider.Instances, node *v1.Node) ([]v1.NodeAddress, error) {
-	nodeAddresses, err := instances.NodeAddressesByProviderID(context.TODO(), node.Spec.ProviderID)
+func getNodeAddressesByProviderIDOrName(ctx context.Context, instances cloudprovider.Instances, node *v1.Node) ([]v1.NodeAddress, error) {
+	nodeAddresses, err := instances.NodeAddressesByProviderID(ctx, node.Spec.ProviderID)
 	if err != nil {
 		providerIDErr := err
-		nodeAddresses, err = instances.NodeAddresses(context.TODO(), types.NodeName(node.Name))
+		nodeAddresses, err = instances.NodeAddresses(ctx, types.NodeName(node.Name))
 		if err != nil {
 			return nil, fmt.Errorf("NodeAddress: Error fetching by providerID: %v Error fetching by NodeName: %v", providerIDErr, err)
 		}
@@ -481,11 +482,11 @@ func ensureNodeProvidedIPExists(node *v1.Node, nodeAddresses []v1.NodeAddress) (
 	return nodeIP, nodeIPExists
 }
 
-func getInstanceTypeByProviderIDOrName(instances cloudprovider.Instances, node *v1.Node) (string, error) {
-	instanceType, err := instances.InstanceTypeByProviderID(context.TODO(), node.Spec.ProviderID)
+func getInstanceTypeByProviderIDOrName(ctx context.Context, instances cloudprovider.Instances, node *v1.Node) (string, error) {
+	instanceType, err := instances.InstanceTypeByProviderID(ctx, node.Spec.ProviderID)
 	if err != nil {
 		providerIDErr := err
-		instanceType, err = instances.InstanceType(context.TODO(), types.NodeName(node.Name))
+		instanceType, err = instances.InstanceType(ctx, types.NodeName(node.Name))
 		if err != nil {
 			return "", fmt.Errorf("InstanceType: Error fetching by providerID: %v Error fetching by NodeName: %v", providerIDErr, err)
 		}
@@ -495,11 +496,11 @@ func getInstanceTypeByProviderIDOrName(instances cloudprovider.Instances, node *
 
 // getZoneByProviderIDorName will attempt to get the zone of node using its providerID
 // then it's name. If both attempts fail, an error is returned
-func getZoneByProviderIDOrName(zones cloudprovider.Zones, node *v1.Node) (cloudprovider.Zone, error) {
-	zone, err := zones.GetZoneByProviderID(context.TODO(), node.Spec.ProviderID)
+func getZoneByProviderIDOrName(ctx context.Context, zones cloudprovider.Zones, node *v1.Node) (cloudprovider.Zone, error) {
+	zone, err := zones.GetZoneByProviderID(ctx, node.Spec.ProviderID)
 	if err != nil {
 		providerIDErr := err
-		zone, err = zones.GetZoneByNodeName(context.TODO(), types.NodeName(node.Name))
+		zone, err = zones.GetZoneByNodeName(ctx, types.NodeName(node.Name))
 		if err != nil {
 			return cloudprovider.Zone{}, fmt.Errorf("Zone: Error fetching by providerID: %v Error fetching by NodeName: %v", providerIDErr, err)
 		}
@@ -38,6 +38,8 @@ import (
 	"k8s.io/kubernetes/pkg/controller"
 	nodeutil "k8s.io/kubernetes/pkg/controller/util/node"
 	schedulerapi "k8s.io/kubernetes/pkg/scheduler/api"
+
+	cloud_provider "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/cloud-provider"
 )
 
 const (
@@ -149,7 +151,8 @@ func (c *CloudNodeLifecycleController) MonitorNodes() {
 		// we need to check this first to get taint working in similar in all cloudproviders
 		// current problem is that shutdown nodes are not working in similar way ie. all cloudproviders
 		// does not delete node from kubernetes cluster when instance it is shutdown see issue #46442
-		shutdown, err := shutdownInCloudProvider(context.TODO(), c.cloud, node)
+		ctx := cloud_provider.WithRequestID(context.Background())
+		shutdown, err := shutdownInCloudProvider(ctx, c.cloud, node)
 		if err != nil {
 			klog.Errorf("error checking if node %s is shutdown: %v", node.Name, err)
 		}
@@ -166,7 +169,7 @@ func (c *CloudNodeLifecycleController) MonitorNodes() {
 
 		// At this point the node has NotReady status, we need to check if the node has been removed
 		// from the cloud provider. If node cannot be found in cloudprovider, then delete the node
-		exists, err := ensureNodeExistsByProviderID(instances, node)
+		exists, err := ensureNodeExistsByProviderID(ctx, instances, node)
 		if err != nil {
 			klog.Errorf("error checking if node %s exists: %v", node.Name, err)
 			continue
@@ -186,7 +189,7 @@ func (c *CloudNodeLifecycleController) MonitorNodes() {
 			Namespace: "",
 		}
 
-		c.recorder.Eventf(ref, v1.EventTypeNormal,
+		cloud_provider.Eventf(ctx, c.recorder, ref, v1.EventTypeNormal,
 			fmt.Sprintf("Deleting node %v because it does not exist in the cloud provider", node.Name),
 			"Node %s event: %s", node.Name, deleteNodeEvent)
 
@@ -0,0 +1,163 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package emulator
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	authVersion = "bce-auth-v1"
+	// authTimeFormat is the format of timestamp in authorization
+	authTimeFormat = "2006-01-02T15:04:05Z"
+	// maxClockSkew is how far timestamp of authorization may be ahead of the emulator
+	maxClockSkew = 5 * time.Minute
+
+	// tokenHeader and remoteHostHeader are set by CCM on requests through CCE gateway
+	tokenHeader      = "cce-token"
+	remoteHostHeader = "cce-remote-host"
+)
+
+// defaultSignedHeaders are signed if signedHeaders of authorization is empty, along with headers prefixed with x-bce-
+var defaultSignedHeaders = map[string]bool{
+	"host":           true,
+	"content-length": true,
+	"content-type":   true,
+	"content-md5":    true,
+}
+
+// authenticate checks the bce-auth-v1 authorization of r is signed with AK/SK of config, and the CCE plugin token
+// if the emulator has one
+func (e *Emulator) authenticate(r *http.Request) error {
+	if e.config.Token != "" && r.Header.Get(tokenHeader) != e.config.Token {
+		return fmt.Errorf("%s header does not match", tokenHeader)
+	}
+	if e.config.AccessKeyID == "" {
+		return nil
+	}
+
+	authorization := r.Header.Get("Authorization")
+	// bce-auth-v1/{accessKeyId}/{timestamp}/{expirationPeriodInSeconds}/{signedHeaders}/{signature}
+	parts := strings.Split(authorization, "/")
+	if len(parts) != 6 || parts[0] != authVersion {
+		return fmt.Errorf("authorization %q is not %s", authorization, authVersion)
+	}
+	if parts[1] != e.config.AccessKeyID {
+		return fmt.Errorf("access key %s is unknown", parts[1])
+	}
+	timestamp, err := time.Parse(authTimeFormat, parts[2])
+	if err != nil {
+		return fmt.Errorf("authorization timestamp %q is invalid: %v", parts[2], err)
+	}
+	expiration, err := strconv.Atoi(parts[3])
+	if err != nil || expiration <= 0 {
+		return fmt.Errorf("authorization expiration %q is invalid", parts[3])
+	}
+	now := e.now()
+	if timestamp.After(now.Add(maxClockSkew)) || now.After(timestamp.Add(time.Duration(expiration)*time.Second)) {
+		return fmt.Errorf("authorization is expired or not valid yet at %s", now.UTC().Format(authTimeFormat))
+	}
+
+	authPrefix := strings.Join(parts[:4], "/")
+	signingKey := hmacSHA256Hex(e.config.SecretAccessKey, authPrefix)
+	signature := hmacSHA256Hex(signingKey, canonicalRequest(r, parts[4]))
+	if !hmac.Equal([]byte(signature), []byte(parts[5])) {
+		return fmt.Errorf("signature does not match")
+	}
+	return nil
+}
+
+// canonicalRequest returns the canonical request of r, which is what the signature is computed from
+func canonicalRequest(r *http.Request, signedHeaders string) string {
+	query := []string{}
+	for key, values := range r.URL.Query() {
+		if strings.ToLower(key) == "authorization" {
+			continue
+		}
+		for _, value := range values {
+			query = append(query, uriEncode(key, true)+"="+uriEncode(value, true))
+		}
+	}
+	sort.Strings(query)
+
+	// host is not in r.Header of server side. Requests through CCE gateway are signed with the host of service,
+	// which is kept in remoteHostHeader.
+	headers := map[string]string{
+		"host": r.Host,
+	}
+	if remoteHost := r.Header.Get(remoteHostHeader); remoteHost != "" {
+		headers["host"] = remoteHost
+	}
+	for key := range r.Header {
+		headers[strings.ToLower(key)] = r.Header.Get(key)
+	}
+	signed := map[string]bool{}
+	if signedHeaders != "" {
+		for _, key := range strings.Split(signedHeaders, ";") {
+			signed[strings.ToLower(key)] = true
+		}
+	}
+	canonicalHeaders := []string{}
+	for key, value := range headers {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		if signedHeaders != "" && !signed[key] ||
+			signedHeaders == "" && !defaultSignedHeaders[key] && !strings.HasPrefix(key, "x-bce-") {
+			continue
+		}
+		canonicalHeaders = append(canonicalHeaders, uriEncode(key, true)+":"+uriEncode(value, true))
+	}
+	sort.Strings(canonicalHeaders)
+
+	return strings.Join([]string{
+		r.Method,
+		uriEncode(r.URL.Path, false),
+		strings.Join(query, "&"),
+		strings.Join(canonicalHeaders, "\n"),
+	}, "\n")
+}
+
+// uriEncode encodes s as RFC 3986 except the unreserved characters, and slashes if encodeSlash is false
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' || c == '/' && !encodeSlash {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func hmacSHA256Hex(key, data string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(data))
+	return hex.EncodeToString(mac.Sum(nil))
+}
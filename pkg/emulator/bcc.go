@@ -0,0 +1,64 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package emulator
+
+import (
+	"net/http"
+
+	bcc "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/temp-bcc"
+)
+
+// serveBCC serves /v2/securityGroup
+func (e *Emulator) serveBCC(req *request) (interface{}, error) {
+	switch len(req.path) {
+	case 2:
+		switch req.method {
+		case http.MethodPost:
+			args := &bcc.CreateSecurityGroupArgs{}
+			if err := req.decode(args); err != nil {
+				return nil, err
+			}
+			return e.state.BCC.CreateSecurityGroup(req.ctx, args, nil)
+		case http.MethodGet:
+			groups, err := e.state.BCC.ListSecurityGroups(req.ctx, &bcc.ListSecurityGroupsArgs{
+				VpcID: req.query.Get("vpcId"),
+			}, nil)
+			if err != nil {
+				return nil, err
+			}
+			// all security groups are in one page
+			return &bcc.ListSecurityGroupsResponse{SecurityGroups: groups}, nil
+		}
+	case 3:
+		securityGroupID := req.path[2]
+		switch {
+		case req.method == http.MethodDelete:
+			return nil, e.state.BCC.DeleteSecurityGroup(req.ctx, securityGroupID, nil)
+		case req.method == http.MethodPut && (req.hasAction("authorizeRule") || req.hasAction("revokeRule")):
+			args := &bcc.SecurityGroupRuleArgs{}
+			if err := req.decode(args); err != nil {
+				return nil, err
+			}
+			args.SecurityGroupID = securityGroupID
+			if req.hasAction("authorizeRule") {
+				return nil, e.state.BCC.AuthorizeSecurityGroupRule(req.ctx, args, nil)
+			}
+			return nil, e.state.BCC.RevokeSecurityGroupRule(req.ctx, args, nil)
+		}
+	}
+	return nil, notFound(req)
+}
@@ -0,0 +1,379 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package emulator
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/blb"
+	blbext "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/temp-blb"
+)
+
+// serveBLB serves /v1/blb
+func (e *Emulator) serveBLB(req *request) (interface{}, error) {
+	switch len(req.path) {
+	case 2:
+		switch req.method {
+		case http.MethodPost:
+			args := &blb.CreateLoadBalancerArgs{}
+			if err := req.decode(args); err != nil {
+				return nil, err
+			}
+			return e.state.BLB.CreateLoadBalancer(req.ctx, args, nil)
+		case http.MethodGet:
+			args := &blbext.DescribeLoadBalancersArgs{
+				LoadBalancerId:   req.query.Get("blbId"),
+				LoadBalancerName: req.query.Get("name"),
+				Address:          req.query.Get("address"),
+				ExactlyMatch:     req.query.Get("exactlyMatch") == "true",
+				Marker:           req.query.Get("marker"),
+			}
+			if maxKeys := req.query.Get("maxKeys"); maxKeys != "" {
+				var err error
+				if args.MaxKeys, err = strconv.Atoi(maxKeys); err != nil {
+					return nil, badRequest("maxKeys %q is invalid", maxKeys)
+				}
+			}
+			page, err := e.state.BLB.DescribeLoadBalancersPage(req.ctx, args, nil)
+			if err != nil {
+				// BLB API returns an empty list if nothing matches, which fake client returns as error
+				page = &blbext.DescribeLoadBalancersResponse{
+					Marker:  args.Marker,
+					MaxKeys: args.MaxKeys,
+					BlbList: []blb.LoadBalancer{},
+				}
+			}
+			return page, nil
+		}
+	case 3:
+		blbID := req.path[2]
+		switch req.method {
+		case http.MethodPut:
+			args := &blb.UpdateLoadBalancerArgs{}
+			if err := req.decode(args); err != nil {
+				return nil, err
+			}
+			args.LoadBalancerId = blbID
+			return nil, e.state.BLB.UpdateLoadBalancer(req.ctx, args, nil)
+		case http.MethodDelete:
+			return nil, e.state.BLB.DeleteLoadBalancer(req.ctx, &blb.DeleteLoadBalancerArgs{LoadBalancerId: blbID}, nil)
+		}
+	case 4:
+		blbID := req.path[2]
+		switch req.path[3] {
+		case "TCPlistener":
+			return e.serveListener(req, blbID, "TCP")
+		case "UDPlistener":
+			return e.serveListener(req, blbID, "UDP")
+		case "HTTPlistener":
+			return e.serveListener(req, blbID, "HTTP")
+		case "HTTPSlistener":
+			return e.serveListener(req, blbID, "HTTPS")
+		case "listener":
+			return e.serveListenerDeletion(req, blbID)
+		case "backendserver":
+			return e.serveBackendServer(req, blbID)
+		case "backendip":
+			return e.serveBackendIP(req, blbID)
+		case "securitygroup":
+			return e.serveBLBSecurityGroup(req, blbID)
+		}
+	}
+	return nil, notFound(req)
+}
+
+// serveListener serves /v1/blb/{blbID}/{listenerType}listener. Health check targets are set and returned by the
+// same API as listeners, while fake client keeps them apart.
+func (e *Emulator) serveListener(req *request, blbID, listenerType string) (interface{}, error) {
+	switch req.method {
+	case http.MethodPost:
+		return nil, e.createListener(req, blbID, listenerType)
+	case http.MethodGet:
+		port, err := listenerPort(req, false)
+		if err != nil {
+			return nil, err
+		}
+		listeners, err := e.describeListeners(req, blbID, listenerType)
+		if err != nil {
+			return nil, err
+		}
+		healthChecks, err := e.state.BLB.DescribeListenerHealthChecks(req.ctx, &blbext.DescribeListenerHealthChecksArgs{
+			LoadBalancerId: blbID,
+			Type:           listenerType,
+		}, nil)
+		if err != nil {
+			return nil, err
+		}
+		listenerList, err := withHealthChecks(listeners, healthChecks, port)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"listenerList": listenerList}, nil
+	case http.MethodPut:
+		port, err := listenerPort(req, true)
+		if err != nil {
+			return nil, err
+		}
+		// UpdateListenerHealthCheck always sends healthCheckPort, which listener updating args do not have
+		if req.hasBodyField("healthCheckPort") {
+			args := &blbext.UpdateListenerHealthCheckArgs{}
+			if err := req.decode(args); err != nil {
+				return nil, err
+			}
+			args.LoadBalancerId, args.ListenerPort, args.Type = blbID, port, listenerType
+			return nil, e.state.BLB.UpdateListenerHealthCheck(req.ctx, args, nil)
+		}
+		return nil, e.updateListener(req, blbID, listenerType, port)
+	}
+	return nil, notFound(req)
+}
+
+func (e *Emulator) createListener(req *request, blbID, listenerType string) error {
+	switch listenerType {
+	case "TCP":
+		args := &blb.CreateTCPListenerArgs{}
+		if err := req.decode(args); err != nil {
+			return err
+		}
+		args.LoadBalancerId = blbID
+		return e.state.BLB.CreateTCPListener(req.ctx, args, nil)
+	case "UDP":
+		args := &blb.CreateUDPListenerArgs{}
+		if err := req.decode(args); err != nil {
+			return err
+		}
+		args.LoadBalancerId = blbID
+		return e.state.BLB.CreateUDPListener(req.ctx, args, nil)
+	case "HTTP":
+		args := &blb.CreateHTTPListenerArgs{}
+		if err := req.decode(args); err != nil {
+			return err
+		}
+		args.LoadBalancerId = blbID
+		return e.state.BLB.CreateHTTPListener(req.ctx, args, nil)
+	default:
+		args := &blbext.CreateHTTPSListenerArgs{}
+		if err := req.decode(args); err != nil {
+			return err
+		}
+		args.LoadBalancerId = blbID
+		return e.state.BLB.CreateHTTPSListener(req.ctx, args, nil)
+	}
+}
+
+func (e *Emulator) describeListeners(req *request, blbID, listenerType string) (interface{}, error) {
+	switch listenerType {
+	case "TCP":
+		return e.state.BLB.DescribeTCPListener(req.ctx, &blb.DescribeTCPListenerArgs{LoadBalancerId: blbID}, nil)
+	case "UDP":
+		return e.state.BLB.DescribeUDPListener(req.ctx, &blb.DescribeUDPListenerArgs{LoadBalancerId: blbID}, nil)
+	case "HTTP":
+		return e.state.BLB.DescribeHTTPListener(req.ctx, &blbext.DescribeHTTPListenerArgs{LoadBalancerId: blbID}, nil)
+	default:
+		return e.state.BLB.DescribeHTTPSListener(req.ctx, &blbext.DescribeHTTPSListenerArgs{LoadBalancerId: blbID}, nil)
+	}
+}
+
+func (e *Emulator) updateListener(req *request, blbID, listenerType string, port int) error {
+	switch listenerType {
+	case "TCP":
+		args := &blb.UpdateTCPListenerArgs{}
+		if err := req.decode(args); err != nil {
+			return err
+		}
+		args.LoadBalancerId, args.ListenerPort = blbID, port
+		return e.state.BLB.UpdateTCPListener(req.ctx, args, nil)
+	case "UDP":
+		args := &blb.UpdateUDPListenerArgs{}
+		if err := req.decode(args); err != nil {
+			return err
+		}
+		args.LoadBalancerId, args.ListenerPort = blbID, port
+		return e.state.BLB.UpdateUDPListener(req.ctx, args, nil)
+	case "HTTP":
+		args := &blbext.UpdateHTTPListenerArgs{}
+		if err := req.decode(args); err != nil {
+			return err
+		}
+		args.LoadBalancerId, args.ListenerPort = blbID, port
+		return e.state.BLB.UpdateHTTPListener(req.ctx, args, nil)
+	default:
+		args := &blbext.UpdateHTTPSListenerArgs{}
+		if err := req.decode(args); err != nil {
+			return err
+		}
+		args.LoadBalancerId, args.ListenerPort = blbID, port
+		return e.state.BLB.UpdateHTTPSListener(req.ctx, args, nil)
+	}
+}
+
+// serveListenerDeletion serves /v1/blb/{blbID}/listener?batchdelete, by ports or by ports and types
+func (e *Emulator) serveListenerDeletion(req *request, blbID string) (interface{}, error) {
+	if req.method != http.MethodPut || !req.hasAction("batchdelete") {
+		return nil, notFound(req)
+	}
+	if req.hasBodyField("portTypeList") {
+		args := &blbext.DeleteListenersByTypeArgs{}
+		if err := req.decode(args); err != nil {
+			return nil, err
+		}
+		args.LoadBalancerId = blbID
+		return nil, e.state.BLB.DeleteListenersByType(req.ctx, args, nil)
+	}
+	args := &blb.DeleteListenersArgs{}
+	if err := req.decode(args); err != nil {
+		return nil, err
+	}
+	args.LoadBalancerId = blbID
+	return nil, e.state.BLB.DeleteListeners(req.ctx, args, nil)
+}
+
+// serveBackendServer serves /v1/blb/{blbID}/backendserver
+func (e *Emulator) serveBackendServer(req *request, blbID string) (interface{}, error) {
+	switch {
+	case req.method == http.MethodPost:
+		args := &blb.AddBackendServersArgs{}
+		if err := req.decode(args); err != nil {
+			return nil, err
+		}
+		args.LoadBalancerId = blbID
+		return nil, e.state.BLB.AddBackendServers(req.ctx, args, nil)
+	case req.method == http.MethodGet:
+		servers, err := e.state.BLB.DescribeBackendServers(req.ctx, &blb.DescribeBackendServersArgs{LoadBalancerId: blbID}, nil)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"backendServerList": servers}, nil
+	case req.method == http.MethodPut && req.hasAction("update"):
+		args := &blb.UpdateBackendServersArgs{}
+		if err := req.decode(args); err != nil {
+			return nil, err
+		}
+		args.LoadBalancerId = blbID
+		return nil, e.state.BLB.UpdateBackendServers(req.ctx, args, nil)
+	case req.method == http.MethodPut && req.hasAction("batchdelete"):
+		args := &blb.RemoveBackendServersArgs{}
+		if err := req.decode(args); err != nil {
+			return nil, err
+		}
+		args.LoadBalancerId = blbID
+		return nil, e.state.BLB.RemoveBackendServers(req.ctx, args, nil)
+	}
+	return nil, notFound(req)
+}
+
+// serveBackendIP serves /v1/blb/{blbID}/backendip
+func (e *Emulator) serveBackendIP(req *request, blbID string) (interface{}, error) {
+	switch req.method {
+	case http.MethodPost:
+		args := &blbext.AddBackendIPsArgs{}
+		if err := req.decode(args); err != nil {
+			return nil, err
+		}
+		args.LoadBalancerId = blbID
+		return nil, e.state.BLB.AddBackendIPs(req.ctx, args, nil)
+	case http.MethodGet:
+		ips, err := e.state.BLB.DescribeBackendIPs(req.ctx, &blbext.DescribeBackendIPsArgs{LoadBalancerId: blbID}, nil)
+		if err != nil {
+			return nil, err
+		}
+		return &blbext.DescribeBackendIPsResponse{BackendIPList: ips}, nil
+	case http.MethodPut:
+		args := &blbext.RemoveBackendIPsArgs{}
+		if err := req.decode(args); err != nil {
+			return nil, err
+		}
+		args.LoadBalancerId = blbID
+		return nil, e.state.BLB.RemoveBackendIPs(req.ctx, args, nil)
+	}
+	return nil, notFound(req)
+}
+
+// serveBLBSecurityGroup serves /v1/blb/{blbID}/securitygroup
+func (e *Emulator) serveBLBSecurityGroup(req *request, blbID string) (interface{}, error) {
+	switch {
+	case req.method == http.MethodGet:
+		groups, err := e.state.BLB.DescribeSecurityGroups(req.ctx, blbID, nil)
+		if err != nil {
+			return nil, err
+		}
+		return &blbext.DescribeSecurityGroupsResponse{BlbSecurityGroups: groups}, nil
+	case req.method == http.MethodPut && (req.hasAction("bind") || req.hasAction("unbind")):
+		args := &blbext.UpdateSecurityGroupsArgs{}
+		if err := req.decode(args); err != nil {
+			return nil, err
+		}
+		args.LoadBalancerId = blbID
+		if req.hasAction("bind") {
+			return nil, e.state.BLB.BindSecurityGroups(req.ctx, args, nil)
+		}
+		return nil, e.state.BLB.UnbindSecurityGroups(req.ctx, args, nil)
+	}
+	return nil, notFound(req)
+}
+
+// listenerPort returns listenerPort query parameter of req, 0 if it is not set and not required
+func listenerPort(req *request, required bool) (int, error) {
+	value := req.query.Get("listenerPort")
+	if value == "" && !required {
+		return 0, nil
+	}
+	port, err := strconv.Atoi(value)
+	if err != nil || port <= 0 || port > 65535 {
+		return 0, badRequest("listenerPort %q is invalid", value)
+	}
+	return port, nil
+}
+
+// withHealthChecks returns listeners of port, or all if port is 0, with their health check targets set
+func withHealthChecks(listeners interface{}, healthChecks []blbext.ListenerHealthCheck, port int) ([]map[string]interface{}, error) {
+	data, err := json.Marshal(listeners)
+	if err != nil {
+		return nil, err
+	}
+	listenerList := []map[string]interface{}{}
+	if err := json.Unmarshal(data, &listenerList); err != nil {
+		return nil, err
+	}
+	healthCheckByPort := make(map[int]blbext.ListenerHealthCheck, len(healthChecks))
+	for _, h := range healthChecks {
+		healthCheckByPort[h.ListenerPort] = h
+	}
+	result := make([]map[string]interface{}, 0, len(listenerList))
+	for _, listener := range listenerList {
+		listenerPort, _ := listener["listenerPort"].(float64)
+		if port != 0 && int(listenerPort) != port {
+			continue
+		}
+		if h, ok := healthCheckByPort[int(listenerPort)]; ok {
+			listener["healthCheckPort"] = h.HealthCheckPort
+			if h.HealthCheckType != "" {
+				listener["healthCheckType"] = h.HealthCheckType
+			}
+			if h.HealthCheckURI != "" {
+				listener["healthCheckURI"] = h.HealthCheckURI
+			}
+			if h.HealthCheckNormalStatus != "" {
+				listener["healthCheckNormalStatus"] = h.HealthCheckNormalStatus
+			}
+		}
+		result = append(result, listener)
+	}
+	return result, nil
+}
@@ -0,0 +1,51 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package emulator
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	cce "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/temp-cce"
+)
+
+// serveCCE serves /v1/node and /v1/cluster
+func (e *Emulator) serveCCE(req *request) (interface{}, error) {
+	switch strings.Join(req.path[1:], "/") {
+	case "node":
+		if req.method == http.MethodGet {
+			args := &cce.ListClusterNodesArgs{
+				ClusterID: req.query.Get("clusterUuid"),
+				Marker:    req.query.Get("marker"),
+			}
+			if maxKeys := req.query.Get("maxKeys"); maxKeys != "" {
+				var err error
+				if args.MaxKeys, err = strconv.Atoi(maxKeys); err != nil {
+					return nil, badRequest("maxKeys %q is invalid", maxKeys)
+				}
+			}
+			return e.state.CCE.ListClusterNodesPage(req.ctx, args, nil)
+		}
+	case "cluster/check_white_list":
+		// no feature is in white list of the emulator
+		if req.method == http.MethodGet {
+			return map[string]bool{"isExist": false}, nil
+		}
+	}
+	return nil, notFound(req)
+}
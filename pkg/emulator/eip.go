@@ -0,0 +1,84 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package emulator
+
+import (
+	"net/http"
+	"strconv"
+
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/eip"
+	eipext "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/temp-eip"
+)
+
+// serveEIP serves /v1/eip
+func (e *Emulator) serveEIP(req *request) (interface{}, error) {
+	switch len(req.path) {
+	case 2:
+		switch req.method {
+		case http.MethodPost:
+			args := &eip.CreateEIPArgs{}
+			if err := req.decode(args); err != nil {
+				return nil, err
+			}
+			ip, err := e.state.EIP.CreateEIP(req.ctx, args, nil)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]string{"eip": ip}, nil
+		case http.MethodGet:
+			// EIPs are got by ip, or listed page by page
+			if ip := req.query.Get("eip"); ip != "" {
+				eips, err := e.state.EIP.GetEIPs(req.ctx, &eip.GetEIPsArgs{EIP: ip}, nil)
+				if err != nil {
+					return nil, err
+				}
+				return &eipext.ListEIPsResponse{EIPList: eips}, nil
+			}
+			args := &eipext.ListEIPsArgs{
+				Marker: req.query.Get("marker"),
+			}
+			if maxKeys := req.query.Get("maxKeys"); maxKeys != "" {
+				var err error
+				if args.MaxKeys, err = strconv.Atoi(maxKeys); err != nil {
+					return nil, badRequest("maxKeys %q is invalid", maxKeys)
+				}
+			}
+			return e.state.EIP.ListEIPs(req.ctx, args, nil)
+		}
+	case 3:
+		ip := req.path[2]
+		switch {
+		case req.method == http.MethodDelete:
+			return nil, e.state.EIP.DeleteEIP(req.ctx, ip, nil)
+		case req.method == http.MethodPut && req.hasAction("bind"):
+			args := &eip.BindEIPArgs{}
+			if err := req.decode(args); err != nil {
+				return nil, err
+			}
+			return nil, e.state.EIP.BindEIP(req.ctx, ip, args, nil)
+		case req.method == http.MethodPut && req.hasAction("unbind"):
+			return nil, e.state.EIP.UnbindEIP(req.ctx, ip, nil)
+		case req.method == http.MethodPut && req.hasAction("resize"):
+			args := &eip.ResizeEIPArgs{}
+			if err := req.decode(args); err != nil {
+				return nil, err
+			}
+			return nil, e.state.EIP.ResizeEIP(req.ctx, ip, args, nil)
+		}
+	}
+	return nil, notFound(req)
+}
@@ -0,0 +1,257 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package emulator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/klog"
+
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/util"
+	"icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/fake"
+)
+
+const (
+	requestIDHeader = "x-bce-request-id"
+	// StatePath is where the state of emulator is dumped, it is not authenticated
+	StatePath = "/emulator/state"
+)
+
+// Config is the config of Emulator
+type Config struct {
+	// AccessKeyID and SecretAccessKey are what requests must be signed with, signature is not checked if AccessKeyID
+	// is empty
+	AccessKeyID     string
+	SecretAccessKey string
+	// Token is what requests must carry in cce-token header, not checked if it is empty
+	Token string
+}
+
+// State is the resources of emulator, which are kept by the fake clients. It is also the format of the state file
+// emulator is seeded with, e.g. CCE cluster nodes and VPC route tables.
+type State struct {
+	BLB *fake.BlbFakeClient
+	EIP *fake.EipFakeClient
+	VPC *fake.VpcFakeClient
+	CCE *fake.CceFakeClient
+	BCC *fake.BccFakeClient
+}
+
+// Emulator serves the BLB, EIP, VPC, BCC and CCE APIs called by CCM over http, backed by the fake clients, so that
+// CCM can run against it with endpoints of cloud config overridden
+type Emulator struct {
+	config Config
+	now    func() time.Time
+
+	// lock serializes requests, since fake clients are not safe for concurrent use
+	lock  sync.Mutex
+	state *State
+}
+
+// apiError is the error response of BCE APIs
+type apiError struct {
+	StatusCode int    `json:"-"`
+	Code       string `json:"code"`
+	Message    string `json:"message"`
+	RequestID  string `json:"requestId"`
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("%d %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// request is an API request routed to a handler
+type request struct {
+	ctx    context.Context
+	method string
+	// path is the segments of url path, e.g. [v1 blb lb-xxx TCPlistener]
+	path  []string
+	query url.Values
+	body  []byte
+}
+
+// NewEmulator creates emulator without resources
+func NewEmulator(config Config) *Emulator {
+	state := &State{
+		BLB: fake.NewBlbFakeClient(),
+		EIP: fake.NewEipFakeClient(),
+		VPC: fake.NewVpcFakeClient(),
+		CCE: fake.NewCceFakeClient(),
+		BCC: fake.NewBccFakeClient(),
+	}
+	state.BLB.BCC = state.BCC
+	return &Emulator{
+		config: config,
+		now:    time.Now,
+		state:  state,
+	}
+}
+
+// LoadState adds resources of state read from r to emulator
+func (e *Emulator) LoadState(r io.Reader) error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if err := json.NewDecoder(r).Decode(e.state); err != nil {
+		return fmt.Errorf("decode state failed: %v", err)
+	}
+	return nil
+}
+
+func (e *Emulator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	requestID := r.Header.Get(requestIDHeader)
+	if requestID == "" {
+		requestID = util.GenerateBCEShortID("emulator")
+	}
+	w.Header().Set(requestIDHeader, requestID)
+
+	var result interface{}
+	err := e.serve(r, &result)
+	if err != nil {
+		apiErr, ok := err.(*apiError)
+		if !ok {
+			apiErr = toAPIError(err)
+		}
+		apiErr.RequestID = requestID
+		klog.Warningf("[ReqID:%s] %s %s failed: %v", requestID, r.Method, r.URL.RequestURI(), apiErr)
+		writeJSON(w, apiErr.StatusCode, apiErr)
+		return
+	}
+	klog.V(3).Infof("[ReqID:%s] %s %s succeeded", requestID, r.Method, r.URL.RequestURI())
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (e *Emulator) serve(r *http.Request, result *interface{}) error {
+	if r.URL.Path == StatePath && r.Method == http.MethodGet {
+		e.lock.Lock()
+		defer e.lock.Unlock()
+		// encode in lock, since the maps of state are modified by requests
+		data, err := json.Marshal(e.state)
+		*result = json.RawMessage(data)
+		return err
+	}
+	if err := e.authenticate(r); err != nil {
+		return &apiError{StatusCode: http.StatusForbidden, Code: "AccessDenied", Message: err.Error()}
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return &apiError{StatusCode: http.StatusBadRequest, Code: "InvalidHTTPRequest", Message: err.Error()}
+	}
+	req := &request{
+		ctx:    r.Context(),
+		method: r.Method,
+		path:   strings.Split(strings.Trim(r.URL.Path, "/"), "/"),
+		query:  r.URL.Query(),
+		body:   body,
+	}
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	*result, err = e.route(req)
+	return err
+}
+
+// route calls the handler of req by the service of its path
+func (e *Emulator) route(req *request) (interface{}, error) {
+	if len(req.path) >= 2 {
+		switch req.path[0] + "/" + req.path[1] {
+		case "v1/blb":
+			return e.serveBLB(req)
+		case "v1/eip":
+			return e.serveEIP(req)
+		case "v1/vpc", "v1/subnet", "v1/route":
+			return e.serveVPC(req)
+		case "v1/node", "v1/cluster":
+			return e.serveCCE(req)
+		case "v2/securityGroup":
+			return e.serveBCC(req)
+		}
+	}
+	return nil, notFound(req)
+}
+
+// decode decodes body of req into args, an empty body is decoded as {}
+func (req *request) decode(args interface{}) error {
+	if len(req.body) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(req.body, args); err != nil {
+		return &apiError{StatusCode: http.StatusBadRequest, Code: "MalformedJSON", Message: err.Error()}
+	}
+	return nil
+}
+
+// hasBodyField returns whether the JSON body of req has field
+func (req *request) hasBodyField(field string) bool {
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(req.body, &fields); err != nil {
+		return false
+	}
+	_, ok := fields[field]
+	return ok
+}
+
+// hasAction returns whether req has the key only query parameter of action, e.g. ?bind
+func (req *request) hasAction(action string) bool {
+	_, ok := req.query[action]
+	return ok
+}
+
+func notFound(req *request) error {
+	return &apiError{
+		StatusCode: http.StatusNotFound,
+		Code:       "NotImplemented",
+		Message:    fmt.Sprintf("%s /%s is not emulated", req.method, strings.Join(req.path, "/")),
+	}
+}
+
+func badRequest(format string, args ...interface{}) error {
+	return &apiError{StatusCode: http.StatusBadRequest, Code: "InvalidParameter", Message: fmt.Sprintf(format, args...)}
+}
+
+// toAPIError converts the error of fake clients, whose messages tell missing resources from invalid args
+func toAPIError(err error) *apiError {
+	message := err.Error()
+	lower := strings.ToLower(message)
+	if strings.Contains(lower, "not found") || strings.Contains(lower, "not exist") || strings.Contains(lower, "nosuchobject") {
+		return &apiError{StatusCode: http.StatusNotFound, Code: "NoSuchObject", Message: message}
+	}
+	return &apiError{StatusCode: http.StatusBadRequest, Code: "BadRequest", Message: message}
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, body interface{}) {
+	if body == nil {
+		w.WriteHeader(statusCode)
+		return
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		statusCode = http.StatusInternalServerError
+		data = []byte(fmt.Sprintf(`{"code":"InternalError","message":%q}`, err.Error()))
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(statusCode)
+	w.Write(data)
+}
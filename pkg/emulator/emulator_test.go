@@ -0,0 +1,279 @@
+package emulator
+
+import (
+	"context"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/bce"
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/blb"
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/eip"
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/vpc"
+
+	blbext "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/temp-blb"
+	cce "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/temp-cce"
+	eipext "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/temp-eip"
+)
+
+const (
+	testAccessKeyID     = "ak"
+	testSecretAccessKey = "sk"
+)
+
+// testState seeds the CCE cluster, which CCM only reads, with nodes in two pages
+const testState = `{
+	"CCE": {
+		"ClusterMap": {"c-emulator": {"clusterUuid": "c-emulator"}},
+		"NodeMap": {
+			"i-node1": {"instanceShortId": "i-node1", "hostname": "node1", "fixIp": "10.0.0.2", "clusterUuid": "c-emulator"},
+			"i-node2": {"instanceShortId": "i-node2", "hostname": "node2", "fixIp": "10.0.0.3", "clusterUuid": "c-emulator"},
+			"i-node3": {"instanceShortId": "i-node3", "hostname": "node3", "fixIp": "10.0.0.4", "clusterUuid": "c-emulator"}
+		},
+		"MaxKeys": 2
+	}
+}`
+
+// newTestEmulator starts emulator at now, with the state seeded
+func newTestEmulator(t *testing.T, now func() time.Time) (*Emulator, *httptest.Server) {
+	e := NewEmulator(Config{
+		AccessKeyID:     testAccessKeyID,
+		SecretAccessKey: testSecretAccessKey,
+	})
+	e.now = now
+	if err := e.LoadState(strings.NewReader(testState)); err != nil {
+		t.Fatalf("LoadState err: %v", err)
+	}
+	return e, httptest.NewServer(e)
+}
+
+// withState runs f with the state of e, which is shared with the requests being served
+func withState(e *Emulator, f func(state *State)) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	f(e.state)
+}
+
+// newTestBCEConfig returns the config of SDK clients calling server, signed with secretAccessKey
+func newTestBCEConfig(server *httptest.Server, secretAccessKey string) *bce.Config {
+	return &bce.Config{
+		Credentials: bce.NewCredentials(testAccessKeyID, secretAccessKey),
+		Checksum:    true,
+		Timeout:     10 * time.Second,
+		Region:      "bj",
+		Endpoint:    server.URL,
+	}
+}
+
+func TestEmulatorBLB(t *testing.T) {
+	e, server := newTestEmulator(t, time.Now)
+	defer server.Close()
+	ctx := context.Background()
+	client := blbext.NewClient(&blb.Config{Config: newTestBCEConfig(server, testSecretAccessKey)})
+
+	resp, err := client.CreateLoadBalancer(ctx, &blb.CreateLoadBalancerArgs{
+		Name:     "CCE/SVC/c-emulator/default/svc",
+		VpcID:    "vpc-emulator",
+		SubnetID: "sbn-emulator",
+		Desc:     "auto generated by cce:c-emulator",
+	}, nil)
+	if err != nil || resp.LoadBalancerId == "" {
+		t.Fatalf("CreateLoadBalancer err, want BLB created, get %v, err: %v", resp, err)
+	}
+	lbs, err := client.DescribeLoadBalancers(ctx, &blb.DescribeLoadBalancersArgs{
+		LoadBalancerId: resp.LoadBalancerId,
+		ExactlyMatch:   true,
+	}, nil)
+	if err != nil || len(lbs) != 1 || lbs[0].Name != "CCE/SVC/c-emulator/default/svc" {
+		t.Errorf("DescribeLoadBalancers err, want BLB %s, get %v, err: %v", resp.LoadBalancerId, lbs, err)
+	}
+
+	// BLBs are paged by marker
+	withState(e, func(state *State) { state.BLB.MaxKeys = 1 })
+	if _, err := client.CreateLoadBalancer(ctx, &blb.CreateLoadBalancerArgs{Name: "CCE/SVC/c-emulator/default/other"}, nil); err != nil {
+		t.Fatalf("CreateLoadBalancer err: %v", err)
+	}
+	page, err := client.DescribeLoadBalancersPage(ctx, &blbext.DescribeLoadBalancersArgs{LoadBalancerName: "CCE/SVC/c-emulator/"}, nil)
+	if err != nil || len(page.BlbList) != 1 || !page.IsTruncated || page.NextMarker == "" {
+		t.Errorf("DescribeLoadBalancersPage err, want truncated page of 1 BLB, get %+v, err: %v", page, err)
+	}
+	all, err := blbext.DescribeAllLoadBalancers(ctx, client, &blbext.DescribeLoadBalancersArgs{LoadBalancerName: "CCE/SVC/c-emulator/"}, nil)
+	if err != nil || len(all) != 2 {
+		t.Errorf("DescribeAllLoadBalancers err, want 2 BLBs, get %v, err: %v", all, err)
+	}
+	withState(e, func(state *State) { state.BLB.MaxKeys = 0 })
+
+	err = client.DeleteLoadBalancer(ctx, &blb.DeleteLoadBalancerArgs{LoadBalancerId: resp.LoadBalancerId}, nil)
+	if err != nil {
+		t.Errorf("DeleteLoadBalancer err: %v", err)
+	}
+	lbs, err = client.DescribeLoadBalancers(ctx, &blb.DescribeLoadBalancersArgs{
+		LoadBalancerId: resp.LoadBalancerId,
+		ExactlyMatch:   true,
+	}, nil)
+	if err != nil || len(lbs) != 0 {
+		t.Errorf("DescribeLoadBalancers err, want no BLB after delete, get %v, err: %v", lbs, err)
+	}
+}
+
+func TestEmulatorEIP(t *testing.T) {
+	e, server := newTestEmulator(t, time.Now)
+	defer server.Close()
+	ctx := context.Background()
+	client := eipext.NewClient(&eip.Config{Config: newTestBCEConfig(server, testSecretAccessKey)})
+
+	ip, err := client.CreateEIP(ctx, &eip.CreateEIPArgs{
+		Name:            "CCE/SVC/c-emulator/default/svc",
+		BandwidthInMbps: 100,
+		Billing: &eip.Billing{
+			PaymentTiming: eip.PAYMENTTIMING_POSTPAID,
+			BillingMethod: eip.BILLINGMETHOD_BYTRAFFIC,
+		},
+	}, nil)
+	if err != nil || ip == "" {
+		t.Fatalf("CreateEIP err, want EIP created, get %q, err: %v", ip, err)
+	}
+	eips, err := client.GetEIPs(ctx, &eip.GetEIPsArgs{EIP: ip}, nil)
+	if err != nil || len(eips) != 1 || eips[0].BandwidthInMbps != 100 {
+		t.Errorf("GetEIPs err, want EIP %s of 100Mbps, get %v, err: %v", ip, eips, err)
+	}
+	all, err := eipext.ListAllEIPs(ctx, client, nil)
+	if err != nil || len(all) != 1 || all[0].EIP != ip {
+		t.Errorf("ListAllEIPs err, want EIP %s, get %v, err: %v", ip, all, err)
+	}
+
+	if err := client.DeleteEIP(ctx, ip, nil); err != nil {
+		t.Errorf("DeleteEIP err: %v", err)
+	}
+	withState(e, func(state *State) {
+		if len(state.EIP.EIPMap) != 0 {
+			t.Errorf("DeleteEIP err, want no EIP after delete, get %v", state.EIP.EIPMap)
+		}
+	})
+}
+
+func TestEmulatorVPC(t *testing.T) {
+	e, server := newTestEmulator(t, time.Now)
+	defer server.Close()
+	ctx := context.Background()
+	client := vpc.NewClient(&vpc.Config{Config: newTestBCEConfig(server, testSecretAccessKey)})
+
+	vpcID, err := client.CreateVPC(ctx, &vpc.CreateVPCArgs{Name: "vpc-test", CIDR: "10.0.0.0/16"}, nil)
+	if err != nil || vpcID == "" {
+		t.Fatalf("CreateVPC err, want VPC created, get %q, err: %v", vpcID, err)
+	}
+	subnetID, err := client.CreateSubnet(ctx, &vpc.CreateSubnetArgs{
+		Name:     "sbn-test",
+		ZoneName: "cn-bj-a",
+		CIDR:     "10.0.1.0/24",
+		VPCID:    vpcID,
+	}, nil)
+	if err != nil || subnetID == "" {
+		t.Fatalf("CreateSubnet err, want subnet created, get %q, err: %v", subnetID, err)
+	}
+	subnet, err := client.DescribeSubnet(ctx, subnetID, nil)
+	if err != nil || subnet.VPCID != vpcID || subnet.CIDR != "10.0.1.0/24" {
+		t.Errorf("DescribeSubnet err, want subnet of %s, get %+v, err: %v", vpcID, subnet, err)
+	}
+
+	// route table of VPC is created along with it, route rules are what CCM creates and deletes
+	routeTableID := ""
+	withState(e, func(state *State) {
+		for id, owner := range state.VPC.VpcRuleTableMap {
+			if owner == vpcID {
+				routeTableID = id
+			}
+		}
+	})
+	routeRuleID, err := client.CreateRouteRule(ctx, &vpc.CreateRouteRuleArgs{
+		RouteTableID:       routeTableID,
+		SourceAddress:      "0.0.0.0/0",
+		DestinationAddress: "172.16.0.0/24",
+		NexthopID:          "i-node1",
+		NexthopType:        "custom",
+		Description:        "auto generated by cce:c-emulator",
+	}, nil)
+	if err != nil || routeRuleID == "" {
+		t.Fatalf("CreateRouteRule err, want route rule created, get %q, err: %v", routeRuleID, err)
+	}
+	rules, err := client.ListRouteTable(ctx, &vpc.ListRouteArgs{VpcID: vpcID}, nil)
+	if err != nil || len(rules) != 1 || rules[0].RouteRuleID != routeRuleID {
+		t.Errorf("ListRouteTable err, want route rule %s, get %v, err: %v", routeRuleID, rules, err)
+	}
+
+	if err := client.DeleteRoute(ctx, routeRuleID, nil); err != nil {
+		t.Errorf("DeleteRoute err: %v", err)
+	}
+	rules, err = client.ListRouteTable(ctx, &vpc.ListRouteArgs{VpcID: vpcID}, nil)
+	if err != nil || len(rules) != 0 {
+		t.Errorf("ListRouteTable err, want no route rule after delete, get %v, err: %v", rules, err)
+	}
+}
+
+func TestEmulatorCCE(t *testing.T) {
+	_, server := newTestEmulator(t, time.Now)
+	defer server.Close()
+	ctx := context.Background()
+	client := cce.NewClient(&cce.Config{Config: newTestBCEConfig(server, testSecretAccessKey)})
+
+	// nodes are seeded by state in two pages
+	page, err := client.ListClusterNodesPage(ctx, &cce.ListClusterNodesArgs{ClusterID: "c-emulator"}, nil)
+	if err != nil || len(page.Nodes) != 2 || !page.IsTruncated || page.NextMarker != "i-node3" {
+		t.Errorf("ListClusterNodesPage err, want truncated page of 2 nodes, get %+v, err: %v", page, err)
+	}
+	resp, err := client.ListClusterNodes(ctx, "c-emulator", nil)
+	if err != nil {
+		t.Fatalf("ListClusterNodes err: %v", err)
+	}
+	hostnames := []string{}
+	for _, node := range resp.Nodes {
+		hostnames = append(hostnames, node.Hostname)
+	}
+	sort.Strings(hostnames)
+	if strings.Join(hostnames, ",") != "node1,node2,node3" {
+		t.Errorf("ListClusterNodes err, want nodes of all pages, get %v", hostnames)
+	}
+	if _, err := client.ListClusterNodes(ctx, "c-unknown", nil); err == nil {
+		t.Errorf("ListClusterNodes err, want err of unknown cluster, get nil")
+	}
+	if exist, err := client.CheckWhiteList(ctx, cce.EnableClusterRBAC, nil); err != nil || exist {
+		t.Errorf("CheckWhiteList err, want not in white list, get %v, err: %v", exist, err)
+	}
+}
+
+// case1: request signed with a wrong SK is rejected
+// case2: request signed longer ago than its expiration is rejected
+func TestEmulatorAuthenticate(t *testing.T) {
+	ctx := context.Background()
+	args := &blb.CreateLoadBalancerArgs{Name: "CCE/SVC/c-emulator/default/svc"}
+
+	// case1
+	e, server := newTestEmulator(t, time.Now)
+	defer server.Close()
+	client := blbext.NewClient(&blb.Config{Config: newTestBCEConfig(server, "wrong-sk")})
+	if _, err := client.CreateLoadBalancer(ctx, args, nil); err == nil || !strings.Contains(err.Error(), "AccessDenied") {
+		t.Errorf("case1: CreateLoadBalancer err, want AccessDenied, get %v", err)
+	}
+	withState(e, func(state *State) {
+		if len(state.BLB.LoadBalancerMap) != 0 {
+			t.Errorf("case1: CreateLoadBalancer err, want no BLB created, get %v", state.BLB.LoadBalancerMap)
+		}
+	})
+
+	// case2
+	expired, expiredServer := newTestEmulator(t, func() time.Time {
+		return time.Now().Add(24 * time.Hour)
+	})
+	defer expiredServer.Close()
+	client = blbext.NewClient(&blb.Config{Config: newTestBCEConfig(expiredServer, testSecretAccessKey)})
+	if _, err := client.CreateLoadBalancer(ctx, args, nil); err == nil || !strings.Contains(err.Error(), "AccessDenied") {
+		t.Errorf("case2: CreateLoadBalancer err, want AccessDenied, get %v", err)
+	}
+	withState(expired, func(state *State) {
+		if len(state.BLB.LoadBalancerMap) != 0 {
+			t.Errorf("case2: CreateLoadBalancer err, want no BLB created, get %v", state.BLB.LoadBalancerMap)
+		}
+	})
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package emulator
+
+import (
+	"net/http"
+	"strings"
+
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/vpc"
+)
+
+// serveVPC serves /v1/vpc, /v1/subnet and /v1/route
+func (e *Emulator) serveVPC(req *request) (interface{}, error) {
+	switch strings.Join(req.path[1:], "/") {
+	case "vpc":
+		switch req.method {
+		case http.MethodPost:
+			args := &vpc.CreateVPCArgs{}
+			if err := req.decode(args); err != nil {
+				return nil, err
+			}
+			// fake client returns vpcID/routeTableID
+			id, err := e.state.VPC.CreateVPC(req.ctx, args, nil)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]string{"vpcId": strings.Split(id, "/")[0]}, nil
+		case http.MethodGet:
+			vpcs, err := e.state.VPC.ListVPC(req.ctx, &vpc.ListVPCArgs{}, nil)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]interface{}{"vpcs": vpcs}, nil
+		}
+	case "subnet":
+		switch req.method {
+		case http.MethodPost:
+			args := &vpc.CreateSubnetArgs{}
+			if err := req.decode(args); err != nil {
+				return nil, err
+			}
+			subnetID, err := e.state.VPC.CreateSubnet(req.ctx, args, nil)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]string{"subnetId": subnetID}, nil
+		case http.MethodGet:
+			subnets, err := e.state.VPC.ListSubnet(req.ctx, &vpc.ListSubnetArgs{
+				VPCID:      req.query.Get("vpcId"),
+				ZoneName:   req.query.Get("zoneName"),
+				SubnetType: vpc.SubnetType(req.query.Get("subnetType")),
+			}, nil)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]interface{}{"subnets": subnets}, nil
+		}
+	case "route":
+		if req.method == http.MethodGet {
+			args := &vpc.ListRouteArgs{
+				VpcID:        req.query.Get("vpcId"),
+				RouteTableID: req.query.Get("routeTableId"),
+			}
+			rules, err := e.state.VPC.ListRouteTable(req.ctx, args, nil)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]interface{}{
+				"routeTableId": e.routeTableID(args),
+				"vpcId":        args.VpcID,
+				"routeRules":   rules,
+			}, nil
+		}
+	case "route/rule":
+		if req.method == http.MethodPost {
+			args := &vpc.CreateRouteRuleArgs{}
+			if err := req.decode(args); err != nil {
+				return nil, err
+			}
+			routeRuleID, err := e.state.VPC.CreateRouteRule(req.ctx, args, nil)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]string{"routeRuleId": routeRuleID}, nil
+		}
+	}
+
+	switch {
+	case len(req.path) == 3 && req.path[1] == "subnet" && req.method == http.MethodGet:
+		subnet, err := e.state.VPC.DescribeSubnet(req.ctx, req.path[2], nil)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"subnet": subnet}, nil
+	case len(req.path) == 4 && req.path[1] == "route" && req.path[2] == "rule" && req.method == http.MethodDelete:
+		return nil, e.state.VPC.DeleteRoute(req.ctx, req.path[3], nil)
+	}
+	return nil, notFound(req)
+}
+
+// routeTableID returns the route table of args, which is looked up by VPC as fake client does if it is not set
+func (e *Emulator) routeTableID(args *vpc.ListRouteArgs) string {
+	for routeTableID, vpcID := range e.state.VPC.VpcRuleTableMap {
+		if vpcID == args.VpcID {
+			return routeTableID
+		}
+	}
+	return args.RouteTableID
+}
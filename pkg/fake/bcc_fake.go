@@ -0,0 +1,133 @@
+package fake
+
+import (
+	"context"
+	"fmt"
+
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/bce"
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/util"
+	bcc "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/temp-bcc"
+)
+
+// BccFakeClient implement of bcc.Interface
+type BccFakeClient struct {
+	SecurityGroupMap map[string]*bcc.SecurityGroup
+	// Faults are injected into calls, nil injects nothing
+	Faults *Faults `json:"-"`
+}
+
+// NewBccFakeClient for BCC fake client
+func NewBccFakeClient() *BccFakeClient {
+	return &BccFakeClient{
+		SecurityGroupMap: map[string]*bcc.SecurityGroup{},
+	}
+}
+
+// CreateSecurityGroup fake func
+func (f *BccFakeClient) CreateSecurityGroup(ctx context.Context, args *bcc.CreateSecurityGroupArgs, option *bce.SignOption) (*bcc.CreateSecurityGroupResponse, error) {
+	if err := f.Faults.call(ctx, "CreateSecurityGroup"); err != nil {
+		return nil, err
+	}
+	if args == nil || args.Name == "" {
+		return nil, fmt.Errorf("CreateSecurityGroup need Name")
+	}
+	sg := &bcc.SecurityGroup{
+		Name:  args.Name,
+		Desc:  args.Desc,
+		VpcID: args.VpcID,
+	}
+	for {
+		sgID := util.GenerateBCEShortID("g")
+		if _, ok := f.SecurityGroupMap[sgID]; !ok {
+			sg.ID = sgID
+			break
+		}
+	}
+	for _, rule := range args.Rules {
+		rule.SecurityGroupID = sg.ID
+		sg.Rules = append(sg.Rules, rule)
+	}
+	f.SecurityGroupMap[sg.ID] = sg
+	f.Faults.created("SecurityGroup", sg.ID)
+	return &bcc.CreateSecurityGroupResponse{SecurityGroupID: sg.ID}, nil
+}
+
+// ListSecurityGroups fake func
+func (f *BccFakeClient) ListSecurityGroups(ctx context.Context, args *bcc.ListSecurityGroupsArgs, option *bce.SignOption) ([]bcc.SecurityGroup, error) {
+	if err := f.Faults.call(ctx, "ListSecurityGroups"); err != nil {
+		return nil, err
+	}
+	result := make([]bcc.SecurityGroup, 0)
+	for _, sg := range f.SecurityGroupMap {
+		if args != nil && args.VpcID != "" && sg.VpcID != args.VpcID || !f.Faults.visible("SecurityGroup", sg.ID) {
+			continue
+		}
+		result = append(result, *sg)
+	}
+	return result, nil
+}
+
+// DeleteSecurityGroup fake func
+func (f *BccFakeClient) DeleteSecurityGroup(ctx context.Context, securityGroupID string, option *bce.SignOption) error {
+	if err := f.Faults.call(ctx, "DeleteSecurityGroup"); err != nil {
+		return err
+	}
+	if _, ok := f.SecurityGroupMap[securityGroupID]; !ok {
+		return fmt.Errorf("SecurityGroup %s not found", securityGroupID)
+	}
+	delete(f.SecurityGroupMap, securityGroupID)
+	return nil
+}
+
+// AuthorizeSecurityGroupRule fake func
+func (f *BccFakeClient) AuthorizeSecurityGroupRule(ctx context.Context, args *bcc.SecurityGroupRuleArgs, option *bce.SignOption) error {
+	if err := f.Faults.call(ctx, "AuthorizeSecurityGroupRule"); err != nil {
+		return err
+	}
+	if args == nil {
+		return fmt.Errorf("AuthorizeSecurityGroupRule need args")
+	}
+	sg, ok := f.SecurityGroupMap[args.SecurityGroupID]
+	if !ok {
+		return fmt.Errorf("SecurityGroup %s not found", args.SecurityGroupID)
+	}
+	rule := args.Rule
+	rule.SecurityGroupID = sg.ID
+	for _, r := range sg.Rules {
+		if r == rule {
+			return fmt.Errorf("SecurityGroup rule %v already exists", rule)
+		}
+	}
+	sg.Rules = append(sg.Rules, rule)
+	return nil
+}
+
+// RevokeSecurityGroupRule fake func
+func (f *BccFakeClient) RevokeSecurityGroupRule(ctx context.Context, args *bcc.SecurityGroupRuleArgs, option *bce.SignOption) error {
+	if err := f.Faults.call(ctx, "RevokeSecurityGroupRule"); err != nil {
+		return err
+	}
+	if args == nil {
+		return fmt.Errorf("RevokeSecurityGroupRule need args")
+	}
+	sg, ok := f.SecurityGroupMap[args.SecurityGroupID]
+	if !ok {
+		return fmt.Errorf("SecurityGroup %s not found", args.SecurityGroupID)
+	}
+	rule := args.Rule
+	rule.SecurityGroupID = sg.ID
+	rules := make([]bcc.SecurityGroupRule, 0)
+	found := false
+	for _, r := range sg.Rules {
+		if r == rule {
+			found = true
+			continue
+		}
+		rules = append(rules, r)
+	}
+	if !found {
+		return fmt.Errorf("SecurityGroup rule %v not found", rule)
+	}
+	sg.Rules = rules
+	return nil
+}
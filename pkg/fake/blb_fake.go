@@ -3,10 +3,13 @@ package fake
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strings"
 
 	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/bce"
 	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/blb"
 	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/util"
+	blbext "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/temp-blb"
 )
 
 // FakeClient implement of vpc.Interface
@@ -15,7 +18,18 @@ type BlbFakeClient struct {
 	TCPListenerMap   map[string][]blb.TCPListener
 	UDPListenerMap   map[string][]blb.UDPListener
 	HTTPListenerMap  map[string][]blb.HTTPListener
+	HTTPSListenerMap map[string][]blbext.HTTPSListener
 	BackendServerMap map[string][]blb.BackendServer
+	BackendIPMap     map[string][]blbext.BackendIP
+	SecurityGroupMap map[string][]string
+	// BCC resolves names of the security groups bound to BLBs, nil leaves names empty
+	BCC *BccFakeClient `json:"-"`
+	// HealthCheckMap keeps the listeners whose health check target is not the backend port
+	HealthCheckMap map[string][]blbext.ListenerHealthCheck
+	// MaxKeys caps BLBs in a page to simulate truncated responses, 0 means no limit
+	MaxKeys int
+	// Faults are injected into calls, nil injects nothing
+	Faults *Faults `json:"-"`
 }
 
 // NewFakeClient for VPC fake client
@@ -25,20 +39,40 @@ func NewBlbFakeClient() *BlbFakeClient {
 		TCPListenerMap:   map[string][]blb.TCPListener{},
 		UDPListenerMap:   map[string][]blb.UDPListener{},
 		HTTPListenerMap:  map[string][]blb.HTTPListener{},
+		HTTPSListenerMap: map[string][]blbext.HTTPSListener{},
 		BackendServerMap: map[string][]blb.BackendServer{},
+		BackendIPMap:     map[string][]blbext.BackendIP{},
+		SecurityGroupMap: map[string][]string{},
+		HealthCheckMap:   map[string][]blbext.ListenerHealthCheck{},
 	}
 }
 
 // LoadBalance fake func
 func (f *BlbFakeClient) DescribeLoadBalancers(ctx context.Context, args *blb.DescribeLoadBalancersArgs, option *bce.SignOption) ([]blb.LoadBalancer, error) {
+	if err := f.Faults.call(ctx, "DescribeLoadBalancers"); err != nil {
+		return nil, err
+	}
 	if args == nil {
 		return nil, fmt.Errorf("args is nil")
 	}
 	loadbalancers := []blb.LoadBalancer{}
+	// fuzzy match by name, e.g. list BLBs with name prefix
+	if !args.ExactlyMatch && args.LoadBalancerId == "" && args.Address == "" {
+		for loadBalancerID, LoadBalancer := range f.LoadBalancerMap {
+			if strings.Contains(LoadBalancer.Name, args.LoadBalancerName) && f.Faults.visible("BLB", loadBalancerID) {
+				loadbalancers = append(loadbalancers, LoadBalancer)
+			}
+		}
+		return loadbalancers, nil
+	}
 	for loadBalancerID, LoadBalancer := range f.LoadBalancerMap {
 		if loadBalancerID != "" && args.LoadBalancerId != "" && loadBalancerID == args.LoadBalancerId ||
 			LoadBalancer.Name != "" && args.LoadBalancerName != "" && LoadBalancer.Name == args.LoadBalancerName ||
 			LoadBalancer.Address != "" && args.Address != "" && LoadBalancer.Address == args.Address {
+			// newly created BLB may be hidden, as BLB API is eventually consistent
+			if !f.Faults.visible("BLB", loadBalancerID) {
+				continue
+			}
 			loadbalancers = append(loadbalancers, LoadBalancer)
 		}
 	}
@@ -47,7 +81,63 @@ func (f *BlbFakeClient) DescribeLoadBalancers(ctx context.Context, args *blb.Des
 	}
 	return loadbalancers, nil
 }
+
+// DescribeLoadBalancersPage describes BLBs matching args sorted by id from marker, at most MaxKeys of args or fake
+// client. BLBs are matched the same as DescribeLoadBalancers.
+func (f *BlbFakeClient) DescribeLoadBalancersPage(ctx context.Context, args *blbext.DescribeLoadBalancersArgs, option *bce.SignOption) (*blbext.DescribeLoadBalancersResponse, error) {
+	if err := f.Faults.call(ctx, "DescribeLoadBalancersPage"); err != nil {
+		return nil, err
+	}
+	if args == nil {
+		return nil, fmt.Errorf("DescribeLoadBalancersPage failed: args is nil")
+	}
+	fuzzy := !args.ExactlyMatch && args.LoadBalancerId == "" && args.Address == ""
+	loadbalancers := []blb.LoadBalancer{}
+	matched := false
+	for loadBalancerID, LoadBalancer := range f.LoadBalancerMap {
+		if !f.Faults.visible("BLB", loadBalancerID) {
+			continue
+		}
+		if fuzzy && !strings.Contains(LoadBalancer.Name, args.LoadBalancerName) {
+			continue
+		}
+		if !fuzzy && !(loadBalancerID != "" && args.LoadBalancerId != "" && loadBalancerID == args.LoadBalancerId ||
+			LoadBalancer.Name != "" && args.LoadBalancerName != "" && LoadBalancer.Name == args.LoadBalancerName ||
+			LoadBalancer.Address != "" && args.Address != "" && LoadBalancer.Address == args.Address) {
+			continue
+		}
+		matched = true
+		if loadBalancerID >= args.Marker {
+			loadbalancers = append(loadbalancers, LoadBalancer)
+		}
+	}
+	if !fuzzy && !matched {
+		return nil, fmt.Errorf("DescribeLoadBalancersPage error: can not get LoadBalancer from args: %v", args)
+	}
+	sort.Slice(loadbalancers, func(i, j int) bool {
+		return loadbalancers[i].BlbId < loadbalancers[j].BlbId
+	})
+	maxKeys := args.MaxKeys
+	if f.MaxKeys > 0 && (maxKeys <= 0 || f.MaxKeys < maxKeys) {
+		maxKeys = f.MaxKeys
+	}
+	resp := &blbext.DescribeLoadBalancersResponse{
+		Marker:  args.Marker,
+		MaxKeys: maxKeys,
+		BlbList: loadbalancers,
+	}
+	if maxKeys > 0 && len(loadbalancers) > maxKeys {
+		resp.IsTruncated = true
+		resp.NextMarker = loadbalancers[maxKeys].BlbId
+		resp.BlbList = loadbalancers[:maxKeys]
+	}
+	return resp, nil
+}
+
 func (f *BlbFakeClient) CreateLoadBalancer(ctx context.Context, args *blb.CreateLoadBalancerArgs, option *bce.SignOption) (*blb.CreateLoadBalancerResponse, error) {
+	if err := f.Faults.call(ctx, "CreateLoadBalancer"); err != nil {
+		return nil, err
+	}
 	if args == nil {
 		return nil, fmt.Errorf("args is nil")
 	}
@@ -68,6 +158,7 @@ func (f *BlbFakeClient) CreateLoadBalancer(ctx context.Context, args *blb.Create
 			loadbalancer.BlbId = loadbalancerID
 			resp.LoadBalancerId = loadbalancerID
 			f.LoadBalancerMap[loadbalancerID] = loadbalancer
+			f.Faults.created("BLB", loadbalancerID)
 			break
 		}
 	}
@@ -77,6 +168,9 @@ func (f *BlbFakeClient) CreateLoadBalancer(ctx context.Context, args *blb.Create
 	return resp, nil
 }
 func (f *BlbFakeClient) UpdateLoadBalancer(ctx context.Context, args *blb.UpdateLoadBalancerArgs, option *bce.SignOption) error {
+	if err := f.Faults.call(ctx, "UpdateLoadBalancer"); err != nil {
+		return err
+	}
 	if args == nil {
 		return fmt.Errorf("args is nil")
 	}
@@ -90,6 +184,9 @@ func (f *BlbFakeClient) UpdateLoadBalancer(ctx context.Context, args *blb.Update
 	return fmt.Errorf("blbID does not exist")
 }
 func (f *BlbFakeClient) DeleteLoadBalancer(ctx context.Context, args *blb.DeleteLoadBalancerArgs, option *bce.SignOption) error {
+	if err := f.Faults.call(ctx, "DeleteLoadBalancer"); err != nil {
+		return err
+	}
 	if args == nil {
 		return fmt.Errorf("args is nil")
 	}
@@ -102,6 +199,9 @@ func (f *BlbFakeClient) DeleteLoadBalancer(ctx context.Context, args *blb.Delete
 
 // Listenr fake func
 func (f *BlbFakeClient) CreateTCPListener(ctx context.Context, args *blb.CreateTCPListenerArgs, option *bce.SignOption) (err error) {
+	if err := f.Faults.call(ctx, "CreateTCPListener"); err != nil {
+		return err
+	}
 	if args == nil {
 		return fmt.Errorf("args is nil")
 	}
@@ -114,18 +214,17 @@ func (f *BlbFakeClient) CreateTCPListener(ctx context.Context, args *blb.CreateT
 		UnhealthyThreshold:         args.UnhealthyThreshold,
 		HealthyThreshold:           args.HealthyThreshold,
 	}
-	// check LoadBalancerId if nil
-	argsLb := &blb.DescribeLoadBalancersArgs{
-		LoadBalancerId: args.LoadBalancerId,
-	}
-	lbs, err := f.DescribeLoadBalancers(ctx, argsLb, nil)
-	if err != nil || len(lbs) == 0 {
-		return fmt.Errorf("can not get lb according to args’ BlbID err: %v", err)
+	// check LoadBalancerId if nil, not by DescribeLoadBalancers which faults are injected into
+	if _, ok := f.LoadBalancerMap[args.LoadBalancerId]; !ok {
+		return fmt.Errorf("can not get lb according to args’ BlbID %s", args.LoadBalancerId)
 	}
 	f.TCPListenerMap[args.LoadBalancerId] = append(f.TCPListenerMap[args.LoadBalancerId], tcp)
 	return nil
 }
 func (f *BlbFakeClient) CreateUDPListener(ctx context.Context, args *blb.CreateUDPListenerArgs, option *bce.SignOption) (err error) {
+	if err := f.Faults.call(ctx, "CreateUDPListener"); err != nil {
+		return err
+	}
 	if args == nil {
 		return fmt.Errorf("args is nil")
 	}
@@ -139,19 +238,17 @@ func (f *BlbFakeClient) CreateUDPListener(ctx context.Context, args *blb.CreateU
 		HealthyThreshold:           args.HealthyThreshold,
 		HealthCheckString:          args.HealthCheckString,
 	}
-	// check LoadBalancerId if nil
-	// check LoadBalancerId if nil
-	argsLb := &blb.DescribeLoadBalancersArgs{
-		LoadBalancerId: args.LoadBalancerId,
-	}
-	lbs, err := f.DescribeLoadBalancers(ctx, argsLb, nil)
-	if err != nil || len(lbs) == 0 {
-		return fmt.Errorf("can not get lb according to args’ BlbID err: %v", err)
+	// check LoadBalancerId if nil, not by DescribeLoadBalancers which faults are injected into
+	if _, ok := f.LoadBalancerMap[args.LoadBalancerId]; !ok {
+		return fmt.Errorf("can not get lb according to args’ BlbID %s", args.LoadBalancerId)
 	}
 	f.UDPListenerMap[args.LoadBalancerId] = append(f.UDPListenerMap[args.LoadBalancerId], udp)
 	return nil
 }
 func (f *BlbFakeClient) CreateHTTPListener(ctx context.Context, args *blb.CreateHTTPListenerArgs, option *bce.SignOption) (err error) {
+	if err := f.Faults.call(ctx, "CreateHTTPListener"); err != nil {
+		return err
+	}
 	if args == nil {
 		return fmt.Errorf("args is nil")
 	}
@@ -173,10 +270,16 @@ func (f *BlbFakeClient) CreateHTTPListener(ctx context.Context, args *blb.Create
 		ServerTimeout:              args.ServerTimeout,
 		RedirectPort:               args.RedirectPort,
 	}
+	if _, ok := f.LoadBalancerMap[args.LoadBalancerId]; !ok {
+		return fmt.Errorf("Specified BLB %s not found", args.LoadBalancerId)
+	}
 	f.HTTPListenerMap[args.LoadBalancerId] = append(f.HTTPListenerMap[args.LoadBalancerId], http)
 	return nil
 }
 func (f *BlbFakeClient) DescribeTCPListener(ctx context.Context, args *blb.DescribeTCPListenerArgs, option *bce.SignOption) ([]blb.TCPListener, error) {
+	if err := f.Faults.call(ctx, "DescribeTCPListener"); err != nil {
+		return nil, err
+	}
 	if args == nil {
 		return nil, fmt.Errorf("args is nil")
 	}
@@ -187,9 +290,15 @@ func (f *BlbFakeClient) DescribeTCPListener(ctx context.Context, args *blb.Descr
 		}
 		return tcpListeners, nil
 	}
+	if _, ok := f.LoadBalancerMap[args.LoadBalancerId]; ok {
+		return tcpListeners, nil
+	}
 	return nil, fmt.Errorf("DescribeTCPListener failed, can not get tcpListeners from args %v", args)
 }
 func (f *BlbFakeClient) DescribeUDPListener(ctx context.Context, args *blb.DescribeUDPListenerArgs, option *bce.SignOption) ([]blb.UDPListener, error) {
+	if err := f.Faults.call(ctx, "DescribeUDPListener"); err != nil {
+		return nil, err
+	}
 	if args == nil {
 		return nil, fmt.Errorf("DescribeUDPListeners need args")
 	}
@@ -198,6 +307,9 @@ func (f *BlbFakeClient) DescribeUDPListener(ctx context.Context, args *blb.Descr
 	}
 	udpListenerList, found := f.UDPListenerMap[args.LoadBalancerId]
 	if !found {
+		if _, ok := f.LoadBalancerMap[args.LoadBalancerId]; ok {
+			return []blb.UDPListener{}, nil
+		}
 		return nil, fmt.Errorf("Sepcified BLB %s not found", args.LoadBalancerId)
 	}
 	result := make([]blb.UDPListener, 0)
@@ -210,6 +322,9 @@ func (f *BlbFakeClient) DescribeUDPListener(ctx context.Context, args *blb.Descr
 	return result, nil
 }
 func (f *BlbFakeClient) UpdateTCPListener(ctx context.Context, args *blb.UpdateTCPListenerArgs, option *bce.SignOption) error {
+	if err := f.Faults.call(ctx, "UpdateTCPListener"); err != nil {
+		return err
+	}
 	if args == nil || args.LoadBalancerId == "" || args.ListenerPort == 0 {
 		return fmt.Errorf("UpdateTCPListener need args")
 	}
@@ -233,9 +348,13 @@ func (f *BlbFakeClient) UpdateTCPListener(ctx context.Context, args *blb.UpdateT
 		Scheduler:                  args.Scheduler,
 	}
 	tcpList = append(tcpList, newTcpListner)
+	f.TCPListenerMap[args.LoadBalancerId] = tcpList
 	return nil
 }
 func (f *BlbFakeClient) UpdateUDPListener(ctx context.Context, args *blb.UpdateUDPListenerArgs, option *bce.SignOption) error {
+	if err := f.Faults.call(ctx, "UpdateUDPListener"); err != nil {
+		return err
+	}
 	err := validateUpdateUDPListenerArgs(args)
 	if err != nil {
 		return err
@@ -261,9 +380,13 @@ func (f *BlbFakeClient) UpdateUDPListener(ctx context.Context, args *blb.UpdateU
 		Scheduler:                  args.Scheduler,
 	}
 	udpList = append(udpList, newUdpListener)
+	f.UDPListenerMap[args.LoadBalancerId] = udpList
 	return nil
 }
 func (f *BlbFakeClient) DeleteListeners(ctx context.Context, args *blb.DeleteListenersArgs, option *bce.SignOption) error {
+	if err := f.Faults.call(ctx, "DeleteListeners"); err != nil {
+		return err
+	}
 	err := validateDeleteListenersArgs(args)
 	if err != nil {
 		return err
@@ -306,7 +429,319 @@ func (f *BlbFakeClient) DeleteListeners(ctx context.Context, args *blb.DeleteLis
 		}
 		f.HTTPListenerMap[args.LoadBalancerId] = httpList
 	}
-	// TODO: https
+	// https
+	rawHttpsList, found := f.HTTPSListenerMap[args.LoadBalancerId]
+	if found {
+		httpsList := make([]blbext.HTTPSListener, 0)
+		for _, h := range rawHttpsList {
+			if _, in := listenerToRemove[h.ListenerPort]; !in {
+				httpsList = append(httpsList, h)
+			}
+		}
+		f.HTTPSListenerMap[args.LoadBalancerId] = httpsList
+	}
+	// health check
+	healthChecks := make([]blbext.ListenerHealthCheck, 0)
+	for _, h := range f.HealthCheckMap[args.LoadBalancerId] {
+		if _, in := listenerToRemove[h.ListenerPort]; !in {
+			healthChecks = append(healthChecks, h)
+		}
+	}
+	f.HealthCheckMap[args.LoadBalancerId] = healthChecks
+	return nil
+}
+
+func (f *BlbFakeClient) DeleteListenersByType(ctx context.Context, args *blbext.DeleteListenersByTypeArgs, option *bce.SignOption) error {
+	if err := f.Faults.call(ctx, "DeleteListenersByType"); err != nil {
+		return err
+	}
+	if args == nil || args.LoadBalancerId == "" {
+		return fmt.Errorf("DeleteListenersByTypeArgs need LoadBalancerId")
+	}
+	if args.PortTypeList == nil {
+		return fmt.Errorf("DeleteListenersByTypeArgs need PortTypeList")
+	}
+	// listener port and type to remove
+	listenerToRemove := make(map[blbext.ListenerPortType]bool, len(args.PortTypeList))
+	for _, p := range args.PortTypeList {
+		listenerToRemove[p] = true
+	}
+	// tcp
+	tcpList := make([]blb.TCPListener, 0)
+	for _, t := range f.TCPListenerMap[args.LoadBalancerId] {
+		if !listenerToRemove[blbext.ListenerPortType{Port: t.ListenerPort, Type: "TCP"}] {
+			tcpList = append(tcpList, t)
+		}
+	}
+	f.TCPListenerMap[args.LoadBalancerId] = tcpList
+	// udp
+	udpList := make([]blb.UDPListener, 0)
+	for _, u := range f.UDPListenerMap[args.LoadBalancerId] {
+		if !listenerToRemove[blbext.ListenerPortType{Port: u.ListenerPort, Type: "UDP"}] {
+			udpList = append(udpList, u)
+		}
+	}
+	f.UDPListenerMap[args.LoadBalancerId] = udpList
+	// http
+	httpList := make([]blb.HTTPListener, 0)
+	for _, h := range f.HTTPListenerMap[args.LoadBalancerId] {
+		if !listenerToRemove[blbext.ListenerPortType{Port: h.ListenerPort, Type: "HTTP"}] {
+			httpList = append(httpList, h)
+		}
+	}
+	f.HTTPListenerMap[args.LoadBalancerId] = httpList
+	// https
+	httpsList := make([]blbext.HTTPSListener, 0)
+	for _, h := range f.HTTPSListenerMap[args.LoadBalancerId] {
+		if !listenerToRemove[blbext.ListenerPortType{Port: h.ListenerPort, Type: "HTTPS"}] {
+			httpsList = append(httpsList, h)
+		}
+	}
+	f.HTTPSListenerMap[args.LoadBalancerId] = httpsList
+	// health check
+	healthChecks := make([]blbext.ListenerHealthCheck, 0)
+	for _, h := range f.HealthCheckMap[args.LoadBalancerId] {
+		if !listenerToRemove[blbext.ListenerPortType{Port: h.ListenerPort, Type: h.Type}] {
+			healthChecks = append(healthChecks, h)
+		}
+	}
+	f.HealthCheckMap[args.LoadBalancerId] = healthChecks
+	return nil
+}
+
+func (f *BlbFakeClient) DescribeListenerHealthChecks(ctx context.Context, args *blbext.DescribeListenerHealthChecksArgs, option *bce.SignOption) ([]blbext.ListenerHealthCheck, error) {
+	if err := f.Faults.call(ctx, "DescribeListenerHealthChecks"); err != nil {
+		return nil, err
+	}
+	if args == nil || args.LoadBalancerId == "" || args.Type == "" {
+		return nil, fmt.Errorf("DescribeListenerHealthChecks need args")
+	}
+	if _, ok := f.LoadBalancerMap[args.LoadBalancerId]; !ok {
+		return nil, fmt.Errorf("Specified BLB %s not found", args.LoadBalancerId)
+	}
+	result := make([]blbext.ListenerHealthCheck, 0)
+	for _, h := range f.HealthCheckMap[args.LoadBalancerId] {
+		if h.Type == args.Type {
+			result = append(result, h)
+		}
+	}
+	return result, nil
+}
+func (f *BlbFakeClient) UpdateListenerHealthCheck(ctx context.Context, args *blbext.UpdateListenerHealthCheckArgs, option *bce.SignOption) error {
+	if err := f.Faults.call(ctx, "UpdateListenerHealthCheck"); err != nil {
+		return err
+	}
+	if args == nil || args.LoadBalancerId == "" || args.ListenerPort == 0 || args.Type == "" {
+		return fmt.Errorf("UpdateListenerHealthCheck need args")
+	}
+	if _, ok := f.LoadBalancerMap[args.LoadBalancerId]; !ok {
+		return fmt.Errorf("Specified BLB %s not found", args.LoadBalancerId)
+	}
+	if !f.listenerExists(args.LoadBalancerId, args.Type, args.ListenerPort) {
+		return fmt.Errorf("%s listener %d of BLB %s not found", args.Type, args.ListenerPort, args.LoadBalancerId)
+	}
+	healthChecks := make([]blbext.ListenerHealthCheck, 0)
+	for _, h := range f.HealthCheckMap[args.LoadBalancerId] {
+		if h.ListenerPort != args.ListenerPort || h.Type != args.Type {
+			healthChecks = append(healthChecks, h)
+		}
+	}
+	// checking on backend port is the default, which is not kept
+	if args.HealthCheckPort != 0 {
+		healthChecks = append(healthChecks, blbext.ListenerHealthCheck{
+			ListenerPort:            args.ListenerPort,
+			Type:                    args.Type,
+			HealthCheckType:         args.HealthCheckType,
+			HealthCheckPort:         args.HealthCheckPort,
+			HealthCheckURI:          args.HealthCheckURI,
+			HealthCheckNormalStatus: args.HealthCheckNormalStatus,
+		})
+	}
+	f.HealthCheckMap[args.LoadBalancerId] = healthChecks
+	return nil
+}
+func (f *BlbFakeClient) listenerExists(blbID string, listenerType string, port int) bool {
+	switch listenerType {
+	case "TCP":
+		for _, l := range f.TCPListenerMap[blbID] {
+			if l.ListenerPort == port {
+				return true
+			}
+		}
+	case "UDP":
+		for _, l := range f.UDPListenerMap[blbID] {
+			if l.ListenerPort == port {
+				return true
+			}
+		}
+	case "HTTP":
+		for _, l := range f.HTTPListenerMap[blbID] {
+			if l.ListenerPort == port {
+				return true
+			}
+		}
+	case "HTTPS":
+		for _, l := range f.HTTPSListenerMap[blbID] {
+			if l.ListenerPort == port {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (f *BlbFakeClient) DescribeHTTPListener(ctx context.Context, args *blbext.DescribeHTTPListenerArgs, option *bce.SignOption) ([]blb.HTTPListener, error) {
+	if err := f.Faults.call(ctx, "DescribeHTTPListener"); err != nil {
+		return nil, err
+	}
+	if args == nil || args.LoadBalancerId == "" {
+		return nil, fmt.Errorf("DescribeHTTPListener need LoadBalancerId")
+	}
+	if _, ok := f.LoadBalancerMap[args.LoadBalancerId]; !ok {
+		return nil, fmt.Errorf("Specified BLB %s not found", args.LoadBalancerId)
+	}
+	result := make([]blb.HTTPListener, 0)
+	for _, h := range f.HTTPListenerMap[args.LoadBalancerId] {
+		if args.ListenerPort != 0 && h.ListenerPort != args.ListenerPort {
+			continue
+		}
+		result = append(result, h)
+	}
+	return result, nil
+}
+func (f *BlbFakeClient) UpdateHTTPListener(ctx context.Context, args *blbext.UpdateHTTPListenerArgs, option *bce.SignOption) error {
+	if err := f.Faults.call(ctx, "UpdateHTTPListener"); err != nil {
+		return err
+	}
+	if args == nil || args.LoadBalancerId == "" || args.ListenerPort == 0 {
+		return fmt.Errorf("UpdateHTTPListener need args")
+	}
+	rawHttpList, found := f.HTTPListenerMap[args.LoadBalancerId]
+	if !found {
+		return fmt.Errorf("Specified BLB %s not found", args.LoadBalancerId)
+	}
+	httpList := make([]blb.HTTPListener, 0)
+	for _, h := range rawHttpList {
+		if h.ListenerPort != args.ListenerPort {
+			httpList = append(httpList, h)
+		}
+	}
+	httpList = append(httpList, blb.HTTPListener{
+		ListenerPort:               args.ListenerPort,
+		BackendPort:                args.BackendPort,
+		Scheduler:                  args.Scheduler,
+		KeepSession:                args.KeepSession,
+		KeepSessionType:            args.KeepSessionType,
+		KeepSessionDuration:        args.KeepSessionDuration,
+		XForwardFor:                args.XForwardFor,
+		HealthCheckType:            args.HealthCheckType,
+		HealthCheckURI:             args.HealthCheckURI,
+		HealthCheckTimeoutInSecond: args.HealthCheckTimeoutInSecond,
+		UnhealthyThreshold:         args.UnhealthyThreshold,
+		HealthyThreshold:           args.HealthyThreshold,
+		HealthCheckNormalStatus:    args.HealthCheckNormalStatus,
+		ServerTimeout:              args.ServerTimeout,
+		RedirectPort:               args.RedirectPort,
+	})
+	f.HTTPListenerMap[args.LoadBalancerId] = httpList
+	return nil
+}
+func (f *BlbFakeClient) CreateHTTPSListener(ctx context.Context, args *blbext.CreateHTTPSListenerArgs, option *bce.SignOption) error {
+	if err := f.Faults.call(ctx, "CreateHTTPSListener"); err != nil {
+		return err
+	}
+	if args == nil {
+		return fmt.Errorf("args is nil")
+	}
+	if len(args.CertIds) == 0 {
+		return fmt.Errorf("CreateHTTPSListener need CertIds")
+	}
+	if _, ok := f.LoadBalancerMap[args.LoadBalancerId]; !ok {
+		return fmt.Errorf("Specified BLB %s not found", args.LoadBalancerId)
+	}
+	https := blbext.HTTPSListener{
+		ListenerPort:               args.ListenerPort,
+		BackendPort:                args.BackendPort,
+		Scheduler:                  args.Scheduler,
+		KeepSession:                args.KeepSession,
+		KeepSessionType:            args.KeepSessionType,
+		KeepSessionDuration:        args.KeepSessionDuration,
+		XForwardFor:                args.XForwardFor,
+		HealthCheckType:            args.HealthCheckType,
+		HealthCheckURI:             args.HealthCheckURI,
+		HealthCheckTimeoutInSecond: args.HealthCheckTimeoutInSecond,
+		HealthCheckInterval:        args.HealthCheckInterval,
+		UnhealthyThreshold:         args.UnhealthyThreshold,
+		HealthyThreshold:           args.HealthyThreshold,
+		HealthCheckNormalStatus:    args.HealthCheckNormalStatus,
+		ServerTimeout:              args.ServerTimeout,
+		CertIds:                    args.CertIds,
+	}
+	f.HTTPSListenerMap[args.LoadBalancerId] = append(f.HTTPSListenerMap[args.LoadBalancerId], https)
+	return nil
+}
+func (f *BlbFakeClient) DescribeHTTPSListener(ctx context.Context, args *blbext.DescribeHTTPSListenerArgs, option *bce.SignOption) ([]blbext.HTTPSListener, error) {
+	if err := f.Faults.call(ctx, "DescribeHTTPSListener"); err != nil {
+		return nil, err
+	}
+	if args == nil || args.LoadBalancerId == "" {
+		return nil, fmt.Errorf("DescribeHTTPSListener need LoadBalancerId")
+	}
+	if _, ok := f.LoadBalancerMap[args.LoadBalancerId]; !ok {
+		return nil, fmt.Errorf("Specified BLB %s not found", args.LoadBalancerId)
+	}
+	result := make([]blbext.HTTPSListener, 0)
+	for _, h := range f.HTTPSListenerMap[args.LoadBalancerId] {
+		if args.ListenerPort != 0 && h.ListenerPort != args.ListenerPort {
+			continue
+		}
+		result = append(result, h)
+	}
+	return result, nil
+}
+func (f *BlbFakeClient) UpdateHTTPSListener(ctx context.Context, args *blbext.UpdateHTTPSListenerArgs, option *bce.SignOption) error {
+	if err := f.Faults.call(ctx, "UpdateHTTPSListener"); err != nil {
+		return err
+	}
+	if args == nil || args.LoadBalancerId == "" || args.ListenerPort == 0 {
+		return fmt.Errorf("UpdateHTTPSListener need args")
+	}
+	rawHttpsList, found := f.HTTPSListenerMap[args.LoadBalancerId]
+	if !found {
+		return fmt.Errorf("Specified BLB %s not found", args.LoadBalancerId)
+	}
+	httpsList := make([]blbext.HTTPSListener, 0)
+	var old blbext.HTTPSListener
+	for _, h := range rawHttpsList {
+		if h.ListenerPort != args.ListenerPort {
+			httpsList = append(httpsList, h)
+		} else {
+			old = h
+		}
+	}
+	certIds := args.CertIds
+	if len(certIds) == 0 {
+		certIds = old.CertIds
+	}
+	httpsList = append(httpsList, blbext.HTTPSListener{
+		ListenerPort:               args.ListenerPort,
+		BackendPort:                args.BackendPort,
+		Scheduler:                  args.Scheduler,
+		KeepSession:                args.KeepSession,
+		KeepSessionType:            args.KeepSessionType,
+		KeepSessionDuration:        args.KeepSessionDuration,
+		XForwardFor:                args.XForwardFor,
+		HealthCheckType:            args.HealthCheckType,
+		HealthCheckURI:             args.HealthCheckURI,
+		HealthCheckTimeoutInSecond: args.HealthCheckTimeoutInSecond,
+		HealthCheckInterval:        args.HealthCheckInterval,
+		UnhealthyThreshold:         args.UnhealthyThreshold,
+		HealthyThreshold:           args.HealthyThreshold,
+		HealthCheckNormalStatus:    args.HealthCheckNormalStatus,
+		ServerTimeout:              args.ServerTimeout,
+		CertIds:                    certIds,
+	})
+	f.HTTPSListenerMap[args.LoadBalancerId] = httpsList
 	return nil
 }
 
@@ -315,21 +750,28 @@ func (f *BlbFakeClient) AddBackendServers(ctx context.Context, args *blb.AddBack
 	if err := validateAddBackendServersArgs(args); err != nil {
 		return err
 	}
+	applied, injected := batchFault(f.Faults.call(ctx, "AddBackendServers"), len(args.BackendServerList))
+	if applied == 0 && injected != nil {
+		return injected
+	}
 	_, found := f.LoadBalancerMap[args.LoadBalancerId]
 	if !found {
 		return fmt.Errorf("Specified BLB %s not found", args.LoadBalancerId)
 	}
 	backendList := make([]blb.BackendServer, 0)
-	for _, rs := range args.BackendServerList {
+	for _, rs := range args.BackendServerList[:applied] {
 		backendList = append(backendList, blb.BackendServer{
 			InstanceId: rs.InstanceId,
 			Weight:     rs.Weight,
 		})
 	}
 	f.BackendServerMap[args.LoadBalancerId] = append(f.BackendServerMap[args.LoadBalancerId], backendList...)
-	return nil
+	return injected
 }
 func (f *BlbFakeClient) DescribeBackendServers(ctx context.Context, args *blb.DescribeBackendServersArgs, option *bce.SignOption) ([]blb.BackendServer, error) {
+	if err := f.Faults.call(ctx, "DescribeBackendServers"); err != nil {
+		return nil, err
+	}
 	err := validateDescribeBackendServersArgs(args)
 	if err != nil {
 		return nil, err
@@ -349,13 +791,17 @@ func (f *BlbFakeClient) UpdateBackendServers(ctx context.Context, args *blb.Upda
 	if err != nil {
 		return err
 	}
+	applied, injected := batchFault(f.Faults.call(ctx, "UpdateBackendServers"), len(args.BackendServerList))
+	if applied == 0 && injected != nil {
+		return injected
+	}
 	rawBackendList, found := f.BackendServerMap[args.LoadBalancerId]
 	if !found {
 		return fmt.Errorf("Specified BLB %s not found", args.LoadBalancerId)
 	}
 	backendList := make([]blb.BackendServer, 0)
 	backendsToUpdate := make(map[string]string)
-	for _, rs := range args.BackendServerList {
+	for _, rs := range args.BackendServerList[:applied] {
 		backendsToUpdate[rs.InstanceId] = rs.InstanceId
 		backendList = append(backendList, blb.BackendServer{
 			InstanceId: rs.InstanceId,
@@ -368,19 +814,23 @@ func (f *BlbFakeClient) UpdateBackendServers(ctx context.Context, args *blb.Upda
 		}
 	}
 	f.BackendServerMap[args.LoadBalancerId] = backendList
-	return nil
+	return injected
 }
 func (f *BlbFakeClient) RemoveBackendServers(ctx context.Context, args *blb.RemoveBackendServersArgs, option *bce.SignOption) error {
 	err := validateRemoveBackendServersArgs(args)
 	if err != nil {
 		return err
 	}
+	applied, injected := batchFault(f.Faults.call(ctx, "RemoveBackendServers"), len(args.BackendServerList))
+	if applied == 0 && injected != nil {
+		return injected
+	}
 	rsList, found := f.BackendServerMap[args.LoadBalancerId]
 	if !found {
 		return fmt.Errorf("BLB %s not found", args.LoadBalancerId)
 	}
 	rsToRemove := make(map[string]string, len(args.BackendServerList))
-	for _, instanceID := range args.BackendServerList {
+	for _, instanceID := range args.BackendServerList[:applied] {
 		rsToRemove[instanceID] = instanceID
 	}
 	leftRs := make([]blb.BackendServer, 0)
@@ -390,8 +840,135 @@ func (f *BlbFakeClient) RemoveBackendServers(ctx context.Context, args *blb.Remo
 		}
 	}
 	f.BackendServerMap[args.LoadBalancerId] = leftRs
+	return injected
+}
+
+// backend ip fake func
+func (f *BlbFakeClient) AddBackendIPs(ctx context.Context, args *blbext.AddBackendIPsArgs, option *bce.SignOption) error {
+	if args == nil || args.LoadBalancerId == "" || len(args.BackendIPList) == 0 {
+		return fmt.Errorf("AddBackendIPs need args")
+	}
+	applied, injected := batchFault(f.Faults.call(ctx, "AddBackendIPs"), len(args.BackendIPList))
+	if applied == 0 && injected != nil {
+		return injected
+	}
+	if _, ok := f.LoadBalancerMap[args.LoadBalancerId]; !ok {
+		return fmt.Errorf("Specified BLB %s not found", args.LoadBalancerId)
+	}
+	existing := make(map[string]bool)
+	for _, b := range f.BackendIPMap[args.LoadBalancerId] {
+		existing[b.IP] = true
+	}
+	for _, b := range args.BackendIPList[:applied] {
+		if existing[b.IP] {
+			return fmt.Errorf("backend ip %s already exists in BLB %s", b.IP, args.LoadBalancerId)
+		}
+	}
+	f.BackendIPMap[args.LoadBalancerId] = append(f.BackendIPMap[args.LoadBalancerId], args.BackendIPList[:applied]...)
+	return injected
+}
+func (f *BlbFakeClient) DescribeBackendIPs(ctx context.Context, args *blbext.DescribeBackendIPsArgs, option *bce.SignOption) ([]blbext.BackendIP, error) {
+	if err := f.Faults.call(ctx, "DescribeBackendIPs"); err != nil {
+		return nil, err
+	}
+	if args == nil || args.LoadBalancerId == "" {
+		return nil, fmt.Errorf("DescribeBackendIPs need LoadBalancerId")
+	}
+	if _, ok := f.LoadBalancerMap[args.LoadBalancerId]; !ok {
+		return nil, fmt.Errorf("Specified BLB %s not found", args.LoadBalancerId)
+	}
+	return append([]blbext.BackendIP{}, f.BackendIPMap[args.LoadBalancerId]...), nil
+}
+func (f *BlbFakeClient) RemoveBackendIPs(ctx context.Context, args *blbext.RemoveBackendIPsArgs, option *bce.SignOption) error {
+	if args == nil || args.LoadBalancerId == "" || len(args.BackendIPList) == 0 {
+		return fmt.Errorf("RemoveBackendIPs need args")
+	}
+	applied, injected := batchFault(f.Faults.call(ctx, "RemoveBackendIPs"), len(args.BackendIPList))
+	if applied == 0 && injected != nil {
+		return injected
+	}
+	if _, ok := f.LoadBalancerMap[args.LoadBalancerId]; !ok {
+		return fmt.Errorf("Specified BLB %s not found", args.LoadBalancerId)
+	}
+	toRemove := make(map[string]bool, len(args.BackendIPList))
+	for _, ip := range args.BackendIPList[:applied] {
+		toRemove[ip] = true
+	}
+	left := make([]blbext.BackendIP, 0)
+	for _, b := range f.BackendIPMap[args.LoadBalancerId] {
+		if !toRemove[b.IP] {
+			left = append(left, b)
+		}
+	}
+	f.BackendIPMap[args.LoadBalancerId] = left
+	return injected
+}
+
+// security group fake func
+func (f *BlbFakeClient) BindSecurityGroups(ctx context.Context, args *blbext.UpdateSecurityGroupsArgs, option *bce.SignOption) error {
+	if err := f.Faults.call(ctx, "BindSecurityGroups"); err != nil {
+		return err
+	}
+	if args == nil || args.LoadBalancerId == "" || len(args.SecurityGroupIds) == 0 {
+		return fmt.Errorf("BindSecurityGroups need args")
+	}
+	if _, ok := f.LoadBalancerMap[args.LoadBalancerId]; !ok {
+		return fmt.Errorf("Specified BLB %s not found", args.LoadBalancerId)
+	}
+	bound := make(map[string]bool)
+	for _, id := range f.SecurityGroupMap[args.LoadBalancerId] {
+		bound[id] = true
+	}
+	for _, id := range args.SecurityGroupIds {
+		if !bound[id] {
+			f.SecurityGroupMap[args.LoadBalancerId] = append(f.SecurityGroupMap[args.LoadBalancerId], id)
+		}
+	}
 	return nil
 }
+func (f *BlbFakeClient) UnbindSecurityGroups(ctx context.Context, args *blbext.UpdateSecurityGroupsArgs, option *bce.SignOption) error {
+	if err := f.Faults.call(ctx, "UnbindSecurityGroups"); err != nil {
+		return err
+	}
+	if args == nil || args.LoadBalancerId == "" || len(args.SecurityGroupIds) == 0 {
+		return fmt.Errorf("UnbindSecurityGroups need args")
+	}
+	if _, ok := f.LoadBalancerMap[args.LoadBalancerId]; !ok {
+		return fmt.Errorf("Specified BLB %s not found", args.LoadBalancerId)
+	}
+	toUnbind := make(map[string]bool)
+	for _, id := range args.SecurityGroupIds {
+		toUnbind[id] = true
+	}
+	left := make([]string, 0)
+	for _, id := range f.SecurityGroupMap[args.LoadBalancerId] {
+		if !toUnbind[id] {
+			left = append(left, id)
+		}
+	}
+	f.SecurityGroupMap[args.LoadBalancerId] = left
+	return nil
+}
+func (f *BlbFakeClient) DescribeSecurityGroups(ctx context.Context, blbID string, option *bce.SignOption) ([]blbext.BlbSecurityGroup, error) {
+	if err := f.Faults.call(ctx, "DescribeSecurityGroups"); err != nil {
+		return nil, err
+	}
+	if _, ok := f.LoadBalancerMap[blbID]; !ok {
+		return nil, fmt.Errorf("Specified BLB %s not found", blbID)
+	}
+	result := make([]blbext.BlbSecurityGroup, 0)
+	for _, id := range f.SecurityGroupMap[blbID] {
+		sg := blbext.BlbSecurityGroup{
+			SecurityGroupID: id,
+		}
+		if f.BCC != nil && f.BCC.SecurityGroupMap[id] != nil {
+			sg.SecurityGroupName = f.BCC.SecurityGroupMap[id].Name
+		}
+		result = append(result, sg)
+	}
+	return result, nil
+}
+
 func validateUpdateUDPListenerArgs(args *blb.UpdateUDPListenerArgs) error {
 	if args.LoadBalancerId == "" {
 		return fmt.Errorf("UpdateUDPListener need LoadBalancerId")
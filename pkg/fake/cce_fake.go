@@ -3,6 +3,7 @@ package fake
 import (
 	"context"
 	"fmt"
+	"sort"
 
 	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/bce"
 	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/util"
@@ -13,6 +14,10 @@ import (
 type CceFakeClient struct {
 	ClusterMap map[string]*cce.Cluster
 	NodeMap    map[string]*cce.Node
+	// MaxKeys caps nodes in a page to simulate truncated responses, 0 means no limit
+	MaxKeys int
+	// Faults are injected into calls, nil injects nothing
+	Faults *Faults `json:"-"`
 }
 
 // NewFakeClient for AppBLB fake client
@@ -65,18 +70,44 @@ func (f *CceFakeClient) CreateCluster(ctx context.Context, args *cce.CreateClust
 	}, nil
 }
 
-// ListClusterNodes list cluster nodes
+// ListClusterNodes list cluster nodes page by page
 func (f *CceFakeClient) ListClusterNodes(ctx context.Context, clusterID string, option *bce.SignOption) (*cce.ListClusterNodesResponse, error) {
-	nodes := []*cce.Node{}
-	if _, ok := f.ClusterMap[clusterID]; ok == false {
-		return nil, fmt.Errorf("ClusterID %s not exist: NoSuchObject", clusterID)
+	return cce.ListAllClusterNodes(ctx, f, clusterID, option)
+}
+
+// ListClusterNodesPage list cluster nodes sorted by instance id from marker, at most MaxKeys of args or fake client
+func (f *CceFakeClient) ListClusterNodesPage(ctx context.Context, args *cce.ListClusterNodesArgs, option *bce.SignOption) (*cce.ListClusterNodesResponse, error) {
+	if err := f.Faults.call(ctx, "ListClusterNodesPage"); err != nil {
+		return nil, err
+	}
+	if args == nil {
+		return nil, fmt.Errorf("ListClusterNodesPage failed: args is nil")
+	}
+	if _, ok := f.ClusterMap[args.ClusterID]; ok == false {
+		return nil, fmt.Errorf("ClusterID %s not exist: NoSuchObject", args.ClusterID)
 	}
+	nodes := []*cce.Node{}
 	for _, node := range f.NodeMap {
-		if node.ClusterID == clusterID {
+		if node.ClusterID == args.ClusterID && node.InstanceID >= args.Marker {
 			nodes = append(nodes, node)
 		}
 	}
-	return &cce.ListClusterNodesResponse{
-		Nodes: nodes,
-	}, nil
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].InstanceID < nodes[j].InstanceID
+	})
+	maxKeys := args.MaxKeys
+	if f.MaxKeys > 0 && (maxKeys <= 0 || f.MaxKeys < maxKeys) {
+		maxKeys = f.MaxKeys
+	}
+	resp := &cce.ListClusterNodesResponse{
+		Marker:  args.Marker,
+		MaxKeys: maxKeys,
+		Nodes:   nodes,
+	}
+	if maxKeys > 0 && len(nodes) > maxKeys {
+		resp.IsTruncated = true
+		resp.NextMarker = nodes[maxKeys].InstanceID
+		resp.Nodes = nodes[:maxKeys]
+	}
+	return resp, nil
 }
@@ -4,15 +4,21 @@ import (
 	"context"
 	"fmt"
 	"math/rand"
+	"sort"
 	"time"
 
 	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/bce"
 	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/eip"
+	eipext "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/temp-eip"
 )
 
 // FakeClient for unit test
 type EipFakeClient struct {
 	EIPMap map[string]*eip.EIP
+	// MaxKeys caps EIPs in a page to simulate truncated responses, 0 means no limit
+	MaxKeys int
+	// Faults are injected into calls, nil injects nothing
+	Faults *Faults `json:"-"`
 }
 
 // NewFakeClient for EIP fake client
@@ -24,6 +30,9 @@ func NewEipFakeClient() *EipFakeClient {
 
 // CreateEIP create EIP
 func (f *EipFakeClient) CreateEIP(ctx context.Context, args *eip.CreateEIPArgs, option *bce.SignOption) (string, error) {
+	if err := f.Faults.call(ctx, "CreateEIP"); err != nil {
+		return "", err
+	}
 	if args == nil {
 		return "", fmt.Errorf("CreateEIP faile: args is nil")
 	}
@@ -37,6 +46,7 @@ func (f *EipFakeClient) CreateEIP(ctx context.Context, args *eip.CreateEIPArgs,
 		if _, ok := f.EIPMap[ip]; !ok {
 			eip.EIP = ip
 			f.EIPMap[ip] = eip
+			f.Faults.created("EIP", ip)
 			break
 		}
 	}
@@ -45,6 +55,9 @@ func (f *EipFakeClient) CreateEIP(ctx context.Context, args *eip.CreateEIPArgs,
 
 // BindEIP bind eip with instance
 func (f *EipFakeClient) BindEIP(ctx context.Context, ip string, args *eip.BindEIPArgs, option *bce.SignOption) error {
+	if err := f.Faults.call(ctx, "BindEIP"); err != nil {
+		return err
+	}
 	e, ok := f.EIPMap[ip]
 	if !ok {
 		return fmt.Errorf("EIP %s not exist", ip)
@@ -61,6 +74,9 @@ func (f *EipFakeClient) BindEIP(ctx context.Context, ip string, args *eip.BindEI
 // UnbindEIP unbind EIP with instance
 // If eip.status == EIPAvailable, return nil
 func (f *EipFakeClient) UnbindEIP(ctx context.Context, ip string, option *bce.SignOption) error {
+	if err := f.Faults.call(ctx, "UnbindEIP"); err != nil {
+		return err
+	}
 	e, ok := f.EIPMap[ip]
 	if !ok {
 		return fmt.Errorf("EIP %s not exist", ip)
@@ -73,6 +89,9 @@ func (f *EipFakeClient) UnbindEIP(ctx context.Context, ip string, option *bce.Si
 
 // DeleteEIP delete pointed EIP
 func (f *EipFakeClient) DeleteEIP(ctx context.Context, eip string, option *bce.SignOption) error {
+	if err := f.Faults.call(ctx, "DeleteEIP"); err != nil {
+		return err
+	}
 	if _, ok := f.EIPMap[eip]; ok {
 		delete(f.EIPMap, eip)
 		return nil
@@ -82,6 +101,9 @@ func (f *EipFakeClient) DeleteEIP(ctx context.Context, eip string, option *bce.S
 
 // ResizeEIP resize EIP bindwidth
 func (f *EipFakeClient) ResizeEIP(ctx context.Context, eip string, args *eip.ResizeEIPArgs, option *bce.SignOption) error {
+	if err := f.Faults.call(ctx, "ResizeEIP"); err != nil {
+		return err
+	}
 	if args == nil {
 		return fmt.Errorf("ResizeEIP failed: args is nil")
 	}
@@ -98,18 +120,23 @@ func (f *EipFakeClient) ResizeEIP(ctx context.Context, eip string, args *eip.Res
 
 // GetEIPs to get eips by condition
 func (f *EipFakeClient) GetEIPs(ctx context.Context, args *eip.GetEIPsArgs, option *bce.SignOption) ([]*eip.EIP, error) {
+	if err := f.Faults.call(ctx, "GetEIPs"); err != nil {
+		return nil, err
+	}
 	result := []*eip.EIP{}
 	// Return all EIPs
 	if args == nil || args.EIP == "" {
-		for _, eip := range f.EIPMap {
-			result = append(result, eip)
+		for ip, eip := range f.EIPMap {
+			if f.Faults.visible("EIP", ip) {
+				result = append(result, eip)
+			}
 		}
 		return result, nil
 	}
 	// Only process EIP
 	if args != nil && args.EIP != "" {
 		for _, eip := range f.EIPMap {
-			if eip.EIP == args.EIP {
+			if eip.EIP == args.EIP && f.Faults.visible("EIP", eip.EIP) {
 				result = append(result, eip)
 				return result, nil
 			}
@@ -117,6 +144,40 @@ func (f *EipFakeClient) GetEIPs(ctx context.Context, args *eip.GetEIPsArgs, opti
 	}
 	return []*eip.EIP{}, nil
 }
+
+// ListEIPs list EIPs sorted by ip from marker, at most MaxKeys of args or fake client
+func (f *EipFakeClient) ListEIPs(ctx context.Context, args *eipext.ListEIPsArgs, option *bce.SignOption) (*eipext.ListEIPsResponse, error) {
+	if err := f.Faults.call(ctx, "ListEIPs"); err != nil {
+		return nil, err
+	}
+	if args == nil {
+		return nil, fmt.Errorf("ListEIPs failed: args is nil")
+	}
+	eips := []*eip.EIP{}
+	for _, e := range f.EIPMap {
+		if e.EIP >= args.Marker && f.Faults.visible("EIP", e.EIP) {
+			eips = append(eips, e)
+		}
+	}
+	sort.Slice(eips, func(i, j int) bool {
+		return eips[i].EIP < eips[j].EIP
+	})
+	maxKeys := args.MaxKeys
+	if f.MaxKeys > 0 && (maxKeys <= 0 || f.MaxKeys < maxKeys) {
+		maxKeys = f.MaxKeys
+	}
+	resp := &eipext.ListEIPsResponse{
+		Marker:  args.Marker,
+		MaxKeys: maxKeys,
+		EIPList: eips,
+	}
+	if maxKeys > 0 && len(eips) > maxKeys {
+		resp.IsTruncated = true
+		resp.NextMarker = eips[maxKeys].EIP
+		resp.EIPList = eips[:maxKeys]
+	}
+	return resp, nil
+}
 func generateRandomEIP() string {
 	rand.Seed(time.Now().Unix())
 	ip := fmt.Sprintf("100.%d.%d.%d", rand.Intn(255), rand.Intn(255), rand.Intn(255))
@@ -0,0 +1,156 @@
+package fake
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Faults injects scripted errors, latency and delayed visibility into fake clients, to test retrying and waiting on
+// BCE APIs. A nil Faults injects nothing. Methods are keyed by name, e.g. "AddBackendServers", which is unique across
+// the fake clients, so a Faults may be shared by them.
+type Faults struct {
+	lock sync.Mutex
+	// errors are returned by the next calls of method in order, one per call
+	errors map[string][]error
+	// latencies delay the calls of method, latency of "" delays all methods
+	latencies map[string]time.Duration
+	calls     map[string]int
+	// hideReads is how many reads resources created afterwards are hidden from
+	hideReads int
+	// hidden is how many more reads a created resource is hidden from, by kind/id
+	hidden map[string]int
+}
+
+// PartialFailure makes a batch method, e.g. AddBackendServers, apply the first Applied items of args and then
+// return Err. Other methods return it as an error without applying anything.
+type PartialFailure struct {
+	Applied int
+	Err     error
+}
+
+func (e *PartialFailure) Error() string {
+	return fmt.Sprintf("partial failure after %d applied: %v", e.Applied, e.Err)
+}
+
+// NewFaults creates Faults which injects nothing until configured
+func NewFaults() *Faults {
+	return &Faults{
+		errors:    map[string][]error{},
+		latencies: map[string]time.Duration{},
+		calls:     map[string]int{},
+		hidden:    map[string]int{},
+	}
+}
+
+// InjectErrors makes the next calls of method return errs in order, one per call. A nil in errs lets that call
+// succeed, e.g. InjectErrors("CreateLoadBalancer", throttlingErr, nil, serverErr).
+func (f *Faults) InjectErrors(method string, errs ...error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.errors[method] = append(f.errors[method], errs...)
+}
+
+// SetLatency delays every call of method, or of all methods without their own latency if method is empty. A call
+// returns the error of its context if the context is done before.
+func (f *Faults) SetLatency(method string, latency time.Duration) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.latencies[method] = latency
+}
+
+// HideCreated hides BLBs, EIPs, route rules and security groups created afterwards from the next reads describing
+// or listing them, as the eventually consistent BCE APIs do. 0 makes them visible at once.
+func (f *Faults) HideCreated(reads int) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.hideReads = reads
+}
+
+// Calls returns how many times method is called, including the failed calls
+func (f *Faults) Calls(method string) int {
+	if f == nil {
+		return 0
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.calls[method]
+}
+
+// call is called at the start of method. It counts the call, waits for the latency, and returns the injected error.
+func (f *Faults) call(ctx context.Context, method string) error {
+	if f == nil {
+		return nil
+	}
+	f.lock.Lock()
+	f.calls[method]++
+	latency, ok := f.latencies[method]
+	if !ok {
+		latency = f.latencies[""]
+	}
+	var err error
+	if errs := f.errors[method]; len(errs) > 0 {
+		err = errs[0]
+		f.errors[method] = errs[1:]
+	}
+	f.lock.Unlock()
+
+	if latency > 0 {
+		timer := time.NewTimer(latency)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+	return err
+}
+
+// created records the resource of kind with id is created, which is hidden from reads if HideCreated
+func (f *Faults) created(kind, id string) {
+	if f == nil {
+		return
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.hideReads > 0 {
+		f.hidden[kind+"/"+id] = f.hideReads
+	}
+}
+
+// visible returns whether the resource of kind with id is visible to a read, counting the read if it is hidden
+func (f *Faults) visible(kind, id string) bool {
+	if f == nil {
+		return true
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	key := kind + "/" + id
+	reads, ok := f.hidden[key]
+	if !ok {
+		return true
+	}
+	if reads <= 1 {
+		delete(f.hidden, key)
+	} else {
+		f.hidden[key] = reads - 1
+	}
+	return false
+}
+
+// batchFault returns how many of the n items a batch method applies with the injected err, and the error it returns
+// after applying them
+func batchFault(err error, n int) (int, error) {
+	if err == nil {
+		return n, nil
+	}
+	if partial, ok := err.(*PartialFailure); ok {
+		if partial.Applied < n {
+			n = partial.Applied
+		}
+		return n, partial.Err
+	}
+	return 0, err
+}
@@ -19,6 +19,8 @@ type VpcFakeClient struct {
 	RouteRuleMap map[string]vpc.RouteRule
 	//  RuleTableID | VpcID
 	VpcRuleTableMap map[string]string
+	// Faults are injected into calls, nil injects nothing
+	Faults *Faults `json:"-"`
 }
 
 // NewFakeClient for VPC fake client
@@ -33,6 +35,9 @@ func NewVpcFakeClient() *VpcFakeClient {
 
 // CreateVPC create VPC
 func (f *VpcFakeClient) CreateVPC(ctx context.Context, args *vpc.CreateVPCArgs, option *bce.SignOption) (string, error) {
+	if err := f.Faults.call(ctx, "CreateVPC"); err != nil {
+		return "", err
+	}
 	if args == nil {
 		return "", fmt.Errorf("CreateVPC faile: args is nil")
 	}
@@ -67,6 +72,9 @@ func (f *VpcFakeClient) generateVpcRoutetableMap(vpcID string) string {
 
 // ListVPC to list VPC of region
 func (f *VpcFakeClient) ListVPC(ctx context.Context, args *vpc.ListVPCArgs, option *bce.SignOption) ([]*vpc.VPC, error) {
+	if err := f.Faults.call(ctx, "ListVPC"); err != nil {
+		return nil, err
+	}
 	vpcs := []*vpc.VPC{}
 	for _, vpc := range f.VPCMap {
 		vpcs = append(vpcs, vpc)
@@ -76,6 +84,9 @@ func (f *VpcFakeClient) ListVPC(ctx context.Context, args *vpc.ListVPCArgs, opti
 
 // CreateSubnet to Create Subnet under VPC
 func (f *VpcFakeClient) CreateSubnet(ctx context.Context, args *vpc.CreateSubnetArgs, option *bce.SignOption) (string, error) {
+	if err := f.Faults.call(ctx, "CreateSubnet"); err != nil {
+		return "", err
+	}
 	if args == nil {
 		return "", fmt.Errorf("CreateSubnet faile: args is nil")
 	}
@@ -100,6 +111,9 @@ func (f *VpcFakeClient) CreateSubnet(ctx context.Context, args *vpc.CreateSubnet
 
 // ListSubnet to List Subnet under VPC
 func (f *VpcFakeClient) ListSubnet(ctx context.Context, args *vpc.ListSubnetArgs, option *bce.SignOption) ([]*vpc.Subnet, error) {
+	if err := f.Faults.call(ctx, "ListSubnet"); err != nil {
+		return nil, err
+	}
 	if args == nil {
 		return []*vpc.Subnet{}, fmt.Errorf("ListSubnet failed: args is nil")
 	}
@@ -128,6 +142,9 @@ func (f *VpcFakeClient) ListSubnet(ctx context.Context, args *vpc.ListSubnetArgs
 
 // DescribeSubnet to Describe Subnet under VPC
 func (f *VpcFakeClient) DescribeSubnet(ctx context.Context, subnetID string, option *bce.SignOption) (*vpc.Subnet, error) {
+	if err := f.Faults.call(ctx, "DescribeSubnet"); err != nil {
+		return nil, err
+	}
 	for _, subnet := range f.SubnetMap {
 		if subnet.SubnetID == subnetID {
 			return subnet, nil
@@ -136,6 +153,9 @@ func (f *VpcFakeClient) DescribeSubnet(ctx context.Context, subnetID string, opt
 	return nil, fmt.Errorf("NoSuchObject")
 }
 func (f *VpcFakeClient) ListRouteTable(ctx context.Context, args *vpc.ListRouteArgs, option *bce.SignOption) ([]vpc.RouteRule, error) {
+	if err := f.Faults.call(ctx, "ListRouteTable"); err != nil {
+		return nil, err
+	}
 	if args == nil {
 		return nil, fmt.Errorf("args is nil")
 	}
@@ -147,13 +167,16 @@ func (f *VpcFakeClient) ListRouteTable(ctx context.Context, args *vpc.ListRouteA
 	}
 	routerules := []vpc.RouteRule{}
 	for _, routerule := range f.RouteRuleMap {
-		if routerule.RouteTableID == routeTableID {
+		if routerule.RouteTableID == routeTableID && f.Faults.visible("RouteRule", routerule.RouteRuleID) {
 			routerules = append(routerules, routerule)
 		}
 	}
 	return routerules, nil
 }
 func (f *VpcFakeClient) DeleteRoute(ctx context.Context, routeID string, option *bce.SignOption) error {
+	if err := f.Faults.call(ctx, "DeleteRoute"); err != nil {
+		return err
+	}
 	if len(routeID) == 0 {
 		return fmt.Errorf("routeID is nil")
 	}
@@ -166,6 +189,9 @@ func (f *VpcFakeClient) DeleteRoute(ctx context.Context, routeID string, option
 	return fmt.Errorf("DeleteRoute %s not exist", routeID)
 }
 func (f *VpcFakeClient) CreateRouteRule(ctx context.Context, args *vpc.CreateRouteRuleArgs, option *bce.SignOption) (string, error) {
+	if err := f.Faults.call(ctx, "CreateRouteRule"); err != nil {
+		return "", err
+	}
 	if args == nil {
 		return "", fmt.Errorf("args is nil")
 	}
@@ -182,6 +208,7 @@ func (f *VpcFakeClient) CreateRouteRule(ctx context.Context, args *vpc.CreateRou
 		if _, ok := f.RouteRuleMap[routeruleID]; !ok {
 			routerule.RouteRuleID = routeruleID
 			f.RouteRuleMap[routeruleID] = routerule
+			f.Faults.created("RouteRule", routeruleID)
 			break
 		}
 	}
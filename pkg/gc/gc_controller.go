@@ -0,0 +1,211 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gc
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	v1core "k8s.io/client-go/kubernetes/typed/core/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog"
+
+	cloud_provider "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/cloud-provider"
+)
+
+// Collector lists and deletes orphaned BLBs and EIPs, implemented by the cloud provider
+type Collector interface {
+	GetOrphanGCConfig() cloud_provider.OrphanGCConfig
+	ListOrphanedLoadBalancerResources(ctx context.Context, services []*v1.Service) ([]cloud_provider.OrphanedResource, error)
+	DeleteOrphanedLoadBalancerResource(ctx context.Context, r cloud_provider.OrphanedResource) error
+}
+
+// OrphanGCController periodically reports orphaned BLBs and EIPs, and deletes them after grace period if enabled
+type OrphanGCController struct {
+	collector           Collector
+	config              cloud_provider.OrphanGCConfig
+	kubeClient          clientset.Interface
+	serviceLister       corelisters.ServiceLister
+	serviceListerSynced cache.InformerSynced
+	broadcaster         record.EventBroadcaster
+	recorder            record.EventRecorder
+	// now returns the current time, which grace period is counted by
+	now func() time.Time
+
+	// firstSeen is when each orphaned resource is found, only accessed by the sync loop
+	firstSeen map[string]time.Time
+}
+
+// New returns a new OrphanGCController
+func New(collector Collector, kubeClient clientset.Interface, serviceInformer coreinformers.ServiceInformer) *OrphanGCController {
+	RegisterMetrics()
+
+	eventBroadcaster := record.NewBroadcaster()
+	eventBroadcaster.StartLogging(klog.Infof)
+	recorder := eventBroadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: "orphan_gc_controller"})
+
+	return &OrphanGCController{
+		collector:           collector,
+		config:              collector.GetOrphanGCConfig(),
+		kubeClient:          kubeClient,
+		serviceLister:       serviceInformer.Lister(),
+		serviceListerSynced: serviceInformer.Informer().HasSynced,
+		broadcaster:         eventBroadcaster,
+		recorder:            recorder,
+		now:                 time.Now,
+		firstSeen:           make(map[string]time.Time),
+	}
+}
+
+// Run starts collecting until stopCh is closed
+func (gc *OrphanGCController) Run(stopCh <-chan struct{}) {
+	defer utilruntime.HandleCrash()
+
+	klog.Infof("Starting orphan gc controller with config %+v", gc.config)
+	defer klog.Info("Shutting down orphan gc controller")
+
+	if !cache.WaitForNamedCacheSync("orphan gc", stopCh, gc.serviceListerSynced) {
+		return
+	}
+
+	if gc.broadcaster != nil {
+		gc.broadcaster.StartRecordingToSink(&v1core.EventSinkImpl{Interface: gc.kubeClient.CoreV1().Events("")})
+	}
+
+	go wait.NonSlidingUntil(func() {
+		startTime := time.Now()
+		err := gc.sync()
+		cloud_provider.ObserveReconcile("orphan-gc", startTime, err)
+		if err != nil {
+			syncErrors.Inc()
+			klog.Errorf("Couldn't collect orphaned resources: %v", err)
+		}
+	}, time.Duration(gc.config.Period)*time.Second, stopCh)
+
+	<-stopCh
+}
+
+func (gc *OrphanGCController) sync() error {
+	ctx := cloud_provider.WithRequestID(context.Background())
+	// services are listed before cloud resources, so that a resource created after listing services is never
+	// taken as orphaned
+	services, err := gc.serviceLister.List(labels.Everything())
+	if err != nil {
+		return fmt.Errorf("error listing services: %v", err)
+	}
+	orphans, err := gc.collector.ListOrphanedLoadBalancerResources(ctx, services)
+	if err != nil {
+		return fmt.Errorf("error listing orphaned resources: %v", err)
+	}
+	gc.collect(ctx, orphans, gc.now())
+	return nil
+}
+
+// collect reports orphans, and deletes the ones orphaned longer than grace period
+func (gc *OrphanGCController) collect(ctx context.Context, orphans []cloud_provider.OrphanedResource, now time.Time) {
+	// EIPs are deleted before the BLBs they are bound to
+	sort.Slice(orphans, func(i, j int) bool {
+		if orphans[i].Type != orphans[j].Type {
+			return orphans[i].Type == cloud_provider.OrphanedResourceTypeEIP
+		}
+		return orphans[i].ID < orphans[j].ID
+	})
+
+	counts := map[string]int{
+		cloud_provider.OrphanedResourceTypeBLB: 0,
+		cloud_provider.OrphanedResourceTypeEIP: 0,
+	}
+	seen := make(map[string]bool, len(orphans))
+	grace := time.Duration(gc.config.GracePeriod) * time.Second
+	for _, r := range orphans {
+		key := r.Key()
+		seen[key] = true
+		counts[r.Type]++
+		firstSeen, ok := gc.firstSeen[key]
+		if !ok {
+			firstSeen = now
+			gc.firstSeen[key] = now
+			gc.event(ctx, r, v1.EventTypeWarning, "OrphanedResourceFound",
+				fmt.Sprintf("%s %s (%s) is not used by any service", r.Type, r.ID, r.Name))
+		}
+		if !gc.config.Delete || now.Sub(firstSeen) < grace {
+			continue
+		}
+
+		if gc.config.DryRun {
+			klog.Infof("Dry run: would delete orphaned %s", key)
+			deletedResources.WithLabelValues(r.Type, "success", "true").Inc()
+			gc.event(ctx, r, v1.EventTypeNormal, "OrphanedResourceDryRunDelete",
+				fmt.Sprintf("Dry run: would delete %s %s (%s) orphaned since %s", r.Type, r.ID, r.Name, firstSeen.Format(time.RFC3339)))
+			continue
+		}
+		err := gc.collector.DeleteOrphanedLoadBalancerResource(ctx, r)
+		if err != nil {
+			klog.Errorf("Couldn't delete orphaned %s: %v", key, err)
+			deletedResources.WithLabelValues(r.Type, "error", "false").Inc()
+			gc.event(ctx, r, v1.EventTypeWarning, "OrphanedResourceDeleteFailed",
+				fmt.Sprintf("Error deleting %s %s (%s): %v", r.Type, r.ID, r.Name, err))
+			continue
+		}
+		klog.Infof("Deleted orphaned %s", key)
+		deletedResources.WithLabelValues(r.Type, "success", "false").Inc()
+		delete(gc.firstSeen, key)
+		gc.event(ctx, r, v1.EventTypeNormal, "OrphanedResourceDeleted",
+			fmt.Sprintf("Deleted %s %s (%s)", r.Type, r.ID, r.Name))
+	}
+
+	// resources no longer orphaned, e.g. adopted by a new service or deleted by others, start over
+	for key := range gc.firstSeen {
+		if !seen[key] {
+			delete(gc.firstSeen, key)
+		}
+	}
+	for t, count := range counts {
+		orphanedResources.WithLabelValues(t).Set(float64(count))
+	}
+	klog.V(2).Infof("Orphan gc found %s BLBs and %s EIPs", strconv.Itoa(counts[cloud_provider.OrphanedResourceTypeBLB]),
+		strconv.Itoa(counts[cloud_provider.OrphanedResourceTypeEIP]))
+}
+
+// event records event on the service which created the resource, or on kube-system namespace if it is unknown
+func (gc *OrphanGCController) event(ctx context.Context, r cloud_provider.OrphanedResource, eventType, reason, message string) {
+	ref := &v1.ObjectReference{
+		Kind:      "Service",
+		Namespace: r.ServiceNamespace,
+		Name:      r.ServiceName,
+	}
+	if r.ServiceName == "" {
+		ref = &v1.ObjectReference{
+			Kind: "Namespace",
+			Name: metav1.NamespaceSystem,
+		}
+	}
+	cloud_provider.Eventf(ctx, gc.recorder, ref, eventType, reason, "%s", message)
+}
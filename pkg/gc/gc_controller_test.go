@@ -0,0 +1,192 @@
+package gc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+
+	cloud_provider "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/cloud-provider"
+)
+
+// fakeCollector returns orphans as listed, and records the resources deleted
+type fakeCollector struct {
+	orphans    []cloud_provider.OrphanedResource
+	deleteErrs map[string]error
+	deleted    []string
+}
+
+func (c *fakeCollector) GetOrphanGCConfig() cloud_provider.OrphanGCConfig {
+	return cloud_provider.OrphanGCConfig{}
+}
+
+func (c *fakeCollector) ListOrphanedLoadBalancerResources(ctx context.Context, services []*v1.Service) ([]cloud_provider.OrphanedResource, error) {
+	return append([]cloud_provider.OrphanedResource{}, c.orphans...), nil
+}
+
+func (c *fakeCollector) DeleteOrphanedLoadBalancerResource(ctx context.Context, r cloud_provider.OrphanedResource) error {
+	if err := c.deleteErrs[r.Key()]; err != nil {
+		return err
+	}
+	c.deleted = append(c.deleted, r.Key())
+	for i := range c.orphans {
+		if c.orphans[i].Key() == r.Key() {
+			c.orphans = append(c.orphans[:i], c.orphans[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func newTestController(collector *fakeCollector, config cloud_provider.OrphanGCConfig, now func() time.Time) *OrphanGCController {
+	return &OrphanGCController{
+		collector:     collector,
+		config:        config,
+		serviceLister: corelisters.NewServiceLister(cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})),
+		recorder:      record.NewFakeRecorder(100),
+		now:           now,
+		firstSeen:     make(map[string]time.Time),
+	}
+}
+
+var (
+	orphanedBLB = cloud_provider.OrphanedResource{Type: cloud_provider.OrphanedResourceTypeBLB, ID: "lb-1",
+		Name: "CCE/SVC/c-gc/default/deleted", ServiceNamespace: "default", ServiceName: "deleted"}
+	orphanedEIP = cloud_provider.OrphanedResource{Type: cloud_provider.OrphanedResourceTypeEIP, ID: "100.0.0.1",
+		Name: "CCE/SVC/c-gc/default/deleted", ServiceNamespace: "default", ServiceName: "deleted", BoundTo: "lb-1"}
+)
+
+// gcRound is a collection at elapsed seconds, with orphans listed by collector, nil keeps the orphans not deleted
+type gcRound struct {
+	elapsed     int
+	orphans     []cloud_provider.OrphanedResource
+	wantDeleted []string
+}
+
+// case1: orphans in grace period are reported but not deleted
+// case2: orphans are deleted after grace period, EIP before the BLB it is bound to
+// case3: dry run deletes nothing
+// case4: nothing is deleted if deletion is disabled
+// case5: failed deletion is retried in the next collection
+// case6: resource adopted in grace period starts over when it is orphaned again
+func TestCollect(t *testing.T) {
+	both := []cloud_provider.OrphanedResource{orphanedBLB, orphanedEIP}
+	deleteConfig := cloud_provider.OrphanGCConfig{Delete: true, GracePeriod: 60}
+	testCases := []struct {
+		name       string
+		config     cloud_provider.OrphanGCConfig
+		deleteErrs map[string]error
+		rounds     []gcRound
+	}{
+		// case1
+		{
+			name:   "in grace period",
+			config: deleteConfig,
+			rounds: []gcRound{
+				{elapsed: 0, orphans: both},
+				{elapsed: 59},
+			},
+		},
+		// case2
+		{
+			name:   "after grace period",
+			config: deleteConfig,
+			rounds: []gcRound{
+				{elapsed: 0, orphans: both},
+				{elapsed: 60, wantDeleted: []string{orphanedEIP.Key(), orphanedBLB.Key()}},
+			},
+		},
+		// case3
+		{
+			name:   "dry run",
+			config: cloud_provider.OrphanGCConfig{Delete: true, DryRun: true, GracePeriod: 60},
+			rounds: []gcRound{
+				{elapsed: 0, orphans: both},
+				{elapsed: 60},
+				{elapsed: 120},
+			},
+		},
+		// case4
+		{
+			name:   "deletion disabled",
+			config: cloud_provider.OrphanGCConfig{GracePeriod: 60},
+			rounds: []gcRound{
+				{elapsed: 0, orphans: both},
+				{elapsed: 3600},
+			},
+		},
+		// case5
+		{
+			name:       "deletion failed",
+			config:     deleteConfig,
+			deleteErrs: map[string]error{orphanedBLB.Key(): fmt.Errorf("blb is busy")},
+			rounds: []gcRound{
+				{elapsed: 0, orphans: both},
+				{elapsed: 60, wantDeleted: []string{orphanedEIP.Key()}},
+				{elapsed: 90, orphans: []cloud_provider.OrphanedResource{orphanedBLB}},
+			},
+		},
+		// case6
+		{
+			name:   "adopted in grace period",
+			config: deleteConfig,
+			rounds: []gcRound{
+				{elapsed: 0, orphans: []cloud_provider.OrphanedResource{orphanedBLB}},
+				{elapsed: 30, orphans: []cloud_provider.OrphanedResource{}},
+				{elapsed: 40, orphans: []cloud_provider.OrphanedResource{orphanedBLB}},
+				{elapsed: 90},
+				{elapsed: 100, wantDeleted: []string{orphanedBLB.Key()}},
+			},
+		},
+	}
+
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, tc := range testCases {
+		collector := &fakeCollector{deleteErrs: tc.deleteErrs}
+		now := start
+		gc := newTestController(collector, tc.config, func() time.Time { return now })
+		for i, round := range tc.rounds {
+			now = start.Add(time.Duration(round.elapsed) * time.Second)
+			if round.orphans != nil {
+				collector.orphans = append([]cloud_provider.OrphanedResource{}, round.orphans...)
+			}
+			collector.deleted = nil
+			if err := gc.sync(); err != nil {
+				t.Errorf("%s: sync err in round %d: %v", tc.name, i, err)
+				continue
+			}
+			if strings.Join(collector.deleted, ",") != strings.Join(round.wantDeleted, ",") {
+				t.Errorf("%s: collect err in round %d, want deleted %v, get %v", tc.name, i, round.wantDeleted, collector.deleted)
+			}
+		}
+		if tc.deleteErrs != nil {
+			// case5
+			collector.deleteErrs = nil
+			collector.deleted = nil
+			now = now.Add(time.Second)
+			if err := gc.sync(); err != nil || strings.Join(collector.deleted, ",") != orphanedBLB.Key() {
+				t.Errorf("%s: collect err after deletion recovered, want deleted %s, get %v, err: %v", tc.name, orphanedBLB.Key(), collector.deleted, err)
+			}
+		}
+	}
+}
+
+func TestCollectFirstSeen(t *testing.T) {
+	collector := &fakeCollector{orphans: []cloud_provider.OrphanedResource{orphanedBLB}}
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	gc := newTestController(collector, cloud_provider.OrphanGCConfig{GracePeriod: 60}, func() time.Time { return start })
+	gc.collect(context.Background(), collector.orphans, start)
+	if firstSeen, ok := gc.firstSeen[orphanedBLB.Key()]; !ok || !firstSeen.Equal(start) {
+		t.Errorf("collect err, want %s first seen at %v, get %v", orphanedBLB.Key(), start, gc.firstSeen)
+	}
+	gc.collect(context.Background(), nil, start.Add(time.Second))
+	if len(gc.firstSeen) != 0 {
+		t.Errorf("collect err, want first seen cleared when no orphans, get %v", gc.firstSeen)
+	}
+}
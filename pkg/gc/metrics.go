@@ -0,0 +1,69 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gc
+
+import (
+	"sync"
+
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+const orphanGCSubsystem = "orphan_gc"
+
+var (
+	// orphanedResources is the number of orphaned resources found in the last collection
+	orphanedResources = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Subsystem:      orphanGCSubsystem,
+			Name:           "orphaned_resources",
+			Help:           "Number of orphaned resources found in the last collection, by resource type.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"type"},
+	)
+	// deletedResources is the number of orphaned resources deleted, dry run included
+	deletedResources = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Subsystem:      orphanGCSubsystem,
+			Name:           "deleted_resources_total",
+			Help:           "Number of orphaned resources deleted, by resource type, result and whether it is a dry run.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"type", "result", "dry_run"},
+	)
+	// syncErrors is the number of failed collections
+	syncErrors = metrics.NewCounter(
+		&metrics.CounterOpts{
+			Subsystem:      orphanGCSubsystem,
+			Name:           "sync_errors_total",
+			Help:           "Number of collections failed to list resources.",
+			StabilityLevel: metrics.ALPHA,
+		},
+	)
+)
+
+var registerMetrics sync.Once
+
+// RegisterMetrics registers orphan gc metrics
+func RegisterMetrics() {
+	registerMetrics.Do(func() {
+		legacyregistry.MustRegister(orphanedResources)
+		legacyregistry.MustRegister(deletedResources)
+		legacyregistry.MustRegister(syncErrors)
+	})
+}
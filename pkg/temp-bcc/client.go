@@ -0,0 +1,49 @@
+package temp_bcc
+
+import (
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/bce"
+)
+
+// Endpoint contains all endpoints of Baidu Cloud BCC.
+var Endpoint = map[string]string{
+	"bj":  "bcc.bj.baidubce.com",
+	"gz":  "bcc.gz.baidubce.com",
+	"su":  "bcc.su.baidubce.com",
+	"hkg": "bcc.hkg.baidubce.com",
+	"fwh": "bcc.fwh.baidubce.com",
+	"bd":  "bcc.bd.baidubce.com",
+}
+
+// Config contains all options for bcc.Client.
+type Config struct {
+	*bce.Config
+}
+
+// NewConfig config of BCC Client
+func NewConfig(config *bce.Config) *Config {
+	return &Config{config}
+}
+
+// Client is the BCC client with the APIs which bce-sdk-go has not supported yet.
+type Client struct {
+	*bce.Client
+}
+
+// NewClient client of BCC
+func NewClient(config *Config) *Client {
+	bceClient := bce.NewClient(config.Config)
+	return &Client{bceClient}
+}
+
+// GetURL generates the full URL of http request for Baidu Cloud BCC API.
+func (c *Client) GetURL(objectKey string, params map[string]string) string {
+	host := c.Endpoint
+
+	if host == "" {
+		host = Endpoint[c.GetRegion()]
+	}
+
+	uriPath := objectKey
+
+	return c.Client.GetURL(host, uriPath, params)
+}
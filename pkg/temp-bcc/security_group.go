@@ -0,0 +1,144 @@
+package temp_bcc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/bce"
+)
+
+// CreateSecurityGroup creates a security group
+func (c *Client) CreateSecurityGroup(ctx context.Context, args *CreateSecurityGroupArgs, option *bce.SignOption) (*CreateSecurityGroupResponse, error) {
+	if args == nil || args.Name == "" {
+		return nil, fmt.Errorf("CreateSecurityGroup need Name")
+	}
+	params := map[string]string{
+		"clientToken": c.GenerateClientToken(),
+	}
+
+	postContent, err := json.Marshal(args)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := bce.NewRequest("POST", c.GetURL("v2/securityGroup", params), bytes.NewBuffer(postContent))
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.SendRequest(ctx, req, option)
+	if err != nil {
+		return nil, err
+	}
+
+	bodyContent, err := resp.GetBodyContent()
+	if err != nil {
+		return nil, err
+	}
+
+	var createResp CreateSecurityGroupResponse
+	err = json.Unmarshal(bodyContent, &createResp)
+	if err != nil {
+		return nil, err
+	}
+
+	return &createResp, nil
+}
+
+// ListSecurityGroups lists all security groups in vpc
+func (c *Client) ListSecurityGroups(ctx context.Context, args *ListSecurityGroupsArgs, option *bce.SignOption) ([]SecurityGroup, error) {
+	if args == nil {
+		args = &ListSecurityGroupsArgs{}
+	}
+
+	var result []SecurityGroup
+	marker := ""
+	for {
+		params := map[string]string{}
+		if args.VpcID != "" {
+			params["vpcId"] = args.VpcID
+		}
+		if marker != "" {
+			params["marker"] = marker
+		}
+
+		req, err := bce.NewRequest("GET", c.GetURL("v2/securityGroup", params), nil)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := c.SendRequest(ctx, req, option)
+		if err != nil {
+			return nil, err
+		}
+
+		bodyContent, err := resp.GetBodyContent()
+		if err != nil {
+			return nil, err
+		}
+
+		var listResp ListSecurityGroupsResponse
+		err = json.Unmarshal(bodyContent, &listResp)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, listResp.SecurityGroups...)
+
+		if !listResp.IsTruncated || listResp.NextMarker == "" {
+			break
+		}
+		marker = listResp.NextMarker
+	}
+
+	return result, nil
+}
+
+// DeleteSecurityGroup deletes a security group
+func (c *Client) DeleteSecurityGroup(ctx context.Context, securityGroupID string, option *bce.SignOption) error {
+	if securityGroupID == "" {
+		return fmt.Errorf("DeleteSecurityGroup need securityGroupID")
+	}
+
+	req, err := bce.NewRequest("DELETE", c.GetURL("v2/securityGroup"+"/"+securityGroupID, nil), nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.SendRequest(ctx, req, option)
+	return err
+}
+
+// AuthorizeSecurityGroupRule adds a rule to security group
+func (c *Client) AuthorizeSecurityGroupRule(ctx context.Context, args *SecurityGroupRuleArgs, option *bce.SignOption) error {
+	return c.updateSecurityGroupRule(ctx, "authorizeRule", args, option)
+}
+
+// RevokeSecurityGroupRule removes a rule from security group
+func (c *Client) RevokeSecurityGroupRule(ctx context.Context, args *SecurityGroupRuleArgs, option *bce.SignOption) error {
+	return c.updateSecurityGroupRule(ctx, "revokeRule", args, option)
+}
+
+func (c *Client) updateSecurityGroupRule(ctx context.Context, action string, args *SecurityGroupRuleArgs, option *bce.SignOption) error {
+	if args == nil || args.SecurityGroupID == "" {
+		return fmt.Errorf("%s need SecurityGroupID", action)
+	}
+	params := map[string]string{
+		action:        "",
+		"clientToken": c.GenerateClientToken(),
+	}
+
+	postContent, err := json.Marshal(args)
+	if err != nil {
+		return err
+	}
+
+	req, err := bce.NewRequest("PUT", c.GetURL("v2/securityGroup"+"/"+args.SecurityGroupID, params), bytes.NewBuffer(postContent))
+	if err != nil {
+		return err
+	}
+
+	_, err = c.SendRequest(ctx, req, option)
+	return err
+}
@@ -0,0 +1,79 @@
+package temp_bcc
+
+import (
+	"context"
+
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/bce"
+)
+
+const (
+	// SecurityGroupRuleDirectionIngress is the direction of ingress rule
+	SecurityGroupRuleDirectionIngress = "ingress"
+	// SecurityGroupRuleDirectionEgress is the direction of egress rule
+	SecurityGroupRuleDirectionEgress = "egress"
+)
+
+// Interface defines the interface of BCC Client
+type Interface interface {
+	CreateSecurityGroup(ctx context.Context, args *CreateSecurityGroupArgs, option *bce.SignOption) (*CreateSecurityGroupResponse, error)
+	ListSecurityGroups(ctx context.Context, args *ListSecurityGroupsArgs, option *bce.SignOption) ([]SecurityGroup, error)
+	DeleteSecurityGroup(ctx context.Context, securityGroupID string, option *bce.SignOption) error
+	AuthorizeSecurityGroupRule(ctx context.Context, args *SecurityGroupRuleArgs, option *bce.SignOption) error
+	RevokeSecurityGroupRule(ctx context.Context, args *SecurityGroupRuleArgs, option *bce.SignOption) error
+}
+
+// SecurityGroupRule is the rule of security group
+type SecurityGroupRule struct {
+	Remark          string `json:"remark,omitempty"`
+	Direction       string `json:"direction"`
+	Ethertype       string `json:"ethertype,omitempty"`
+	PortRange       string `json:"portRange,omitempty"`
+	Protocol        string `json:"protocol,omitempty"`
+	SourceGroupID   string `json:"sourceGroupId,omitempty"`
+	SourceIP        string `json:"sourceIp,omitempty"`
+	DestGroupID     string `json:"destGroupId,omitempty"`
+	DestIP          string `json:"destIp,omitempty"`
+	SecurityGroupID string `json:"securityGroupId,omitempty"`
+}
+
+// SecurityGroup is the security group of BCC
+type SecurityGroup struct {
+	ID    string              `json:"id"`
+	Name  string              `json:"name"`
+	Desc  string              `json:"desc"`
+	VpcID string              `json:"vpcId"`
+	Rules []SecurityGroupRule `json:"rules"`
+}
+
+// CreateSecurityGroupArgs is the args of CreateSecurityGroup
+type CreateSecurityGroupArgs struct {
+	Name  string              `json:"name"`
+	Desc  string              `json:"desc,omitempty"`
+	VpcID string              `json:"vpcId,omitempty"`
+	Rules []SecurityGroupRule `json:"rules"`
+}
+
+// CreateSecurityGroupResponse is the response of CreateSecurityGroup
+type CreateSecurityGroupResponse struct {
+	SecurityGroupID string `json:"securityGroupId"`
+}
+
+// ListSecurityGroupsArgs is the args of ListSecurityGroups
+type ListSecurityGroupsArgs struct {
+	VpcID string `json:"-"`
+}
+
+// ListSecurityGroupsResponse is the response of ListSecurityGroups
+type ListSecurityGroupsResponse struct {
+	Marker         string          `json:"marker"`
+	IsTruncated    bool            `json:"isTruncated"`
+	NextMarker     string          `json:"nextMarker"`
+	MaxKeys        int             `json:"maxKeys"`
+	SecurityGroups []SecurityGroup `json:"securityGroups"`
+}
+
+// SecurityGroupRuleArgs is the args of AuthorizeSecurityGroupRule and RevokeSecurityGroupRule
+type SecurityGroupRuleArgs struct {
+	SecurityGroupID string            `json:"-"`
+	Rule            SecurityGroupRule `json:"rule"`
+}
@@ -0,0 +1,92 @@
+package temp_blb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/bce"
+)
+
+// AddBackendIPs adds IP backends, e.g. pod IPs routed in VPC, to a BLB
+func (c *Client) AddBackendIPs(ctx context.Context, args *AddBackendIPsArgs, option *bce.SignOption) error {
+	if args == nil || args.LoadBalancerId == "" {
+		return fmt.Errorf("AddBackendIPs need LoadBalancerId")
+	}
+	if len(args.BackendIPList) == 0 {
+		return fmt.Errorf("AddBackendIPs need BackendIPList")
+	}
+	params := map[string]string{
+		"clientToken": c.GenerateClientToken(),
+	}
+
+	postContent, err := json.Marshal(args)
+	if err != nil {
+		return err
+	}
+
+	req, err := bce.NewRequest("POST", c.GetURL("v1/blb"+"/"+args.LoadBalancerId+"/backendip", params), bytes.NewBuffer(postContent))
+	if err != nil {
+		return err
+	}
+
+	_, err = c.SendRequest(ctx, req, option)
+	return err
+}
+
+// DescribeBackendIPs describes the IP backends of a BLB
+func (c *Client) DescribeBackendIPs(ctx context.Context, args *DescribeBackendIPsArgs, option *bce.SignOption) ([]BackendIP, error) {
+	if args == nil || args.LoadBalancerId == "" {
+		return nil, fmt.Errorf("DescribeBackendIPs need LoadBalancerId")
+	}
+
+	req, err := bce.NewRequest("GET", c.GetURL("v1/blb"+"/"+args.LoadBalancerId+"/backendip", nil), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.SendRequest(ctx, req, option)
+	if err != nil {
+		return nil, err
+	}
+
+	bodyContent, err := resp.GetBodyContent()
+	if err != nil {
+		return nil, err
+	}
+
+	var ipResp DescribeBackendIPsResponse
+	err = json.Unmarshal(bodyContent, &ipResp)
+	if err != nil {
+		return nil, err
+	}
+
+	return ipResp.BackendIPList, nil
+}
+
+// RemoveBackendIPs removes IP backends from a BLB
+func (c *Client) RemoveBackendIPs(ctx context.Context, args *RemoveBackendIPsArgs, option *bce.SignOption) error {
+	if args == nil || args.LoadBalancerId == "" {
+		return fmt.Errorf("RemoveBackendIPs need LoadBalancerId")
+	}
+	if len(args.BackendIPList) == 0 {
+		return fmt.Errorf("RemoveBackendIPs need BackendIPList")
+	}
+	params := map[string]string{
+		"clientToken": c.GenerateClientToken(),
+	}
+
+	postContent, err := json.Marshal(args)
+	if err != nil {
+		return err
+	}
+
+	req, err := bce.NewRequest("PUT", c.GetURL("v1/blb"+"/"+args.LoadBalancerId+"/backendip", params), bytes.NewBuffer(postContent))
+	if err != nil {
+		return err
+	}
+
+	_, err = c.SendRequest(ctx, req, option)
+	return err
+}
@@ -0,0 +1,28 @@
+package temp_blb
+
+import (
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/blb"
+)
+
+// Client is the BLB client with the APIs which bce-sdk-go has not supported yet.
+type Client struct {
+	*blb.Client
+}
+
+// NewClient client of BLB
+func NewClient(config *blb.Config) *Client {
+	return &Client{blb.NewBLBClient(config)}
+}
+
+// GetURL generates the full URL of http request for Baidu Cloud BLB API.
+func (c *Client) GetURL(objectKey string, params map[string]string) string {
+	host := c.Endpoint
+
+	if host == "" {
+		host = blb.Endpoint[c.GetRegion()]
+	}
+
+	uriPath := objectKey
+
+	return c.Client.Client.GetURL(host, uriPath, params)
+}
@@ -0,0 +1,239 @@
+package temp_blb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/bce"
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/blb"
+)
+
+// DescribeHTTPListener describes the HTTP listeners of a BLB
+func (c *Client) DescribeHTTPListener(ctx context.Context, args *DescribeHTTPListenerArgs, option *bce.SignOption) ([]blb.HTTPListener, error) {
+	if args == nil || args.LoadBalancerId == "" {
+		return nil, fmt.Errorf("DescribeHTTPListener need LoadBalancerId")
+	}
+	params := map[string]string{}
+	if args.ListenerPort != 0 {
+		params["listenerPort"] = strconv.Itoa(args.ListenerPort)
+	}
+
+	req, err := bce.NewRequest("GET", c.GetURL("v1/blb"+"/"+args.LoadBalancerId+"/HTTPlistener", params), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.SendRequest(ctx, req, option)
+	if err != nil {
+		return nil, err
+	}
+
+	bodyContent, err := resp.GetBodyContent()
+	if err != nil {
+		return nil, err
+	}
+
+	var listenersResp DescribeHTTPListenerResponse
+	err = json.Unmarshal(bodyContent, &listenersResp)
+	if err != nil {
+		return nil, err
+	}
+
+	return listenersResp.ListenerList, nil
+}
+
+// UpdateHTTPListener updates a HTTP listener of a BLB
+func (c *Client) UpdateHTTPListener(ctx context.Context, args *UpdateHTTPListenerArgs, option *bce.SignOption) error {
+	if args == nil || args.LoadBalancerId == "" || args.ListenerPort == 0 {
+		return fmt.Errorf("UpdateHTTPListener need LoadBalancerId and ListenerPort")
+	}
+	params := map[string]string{
+		"listenerPort": strconv.Itoa(args.ListenerPort),
+		"clientToken":  c.GenerateClientToken(),
+	}
+
+	postContent, err := json.Marshal(args)
+	if err != nil {
+		return err
+	}
+
+	req, err := bce.NewRequest("PUT", c.GetURL("v1/blb"+"/"+args.LoadBalancerId+"/HTTPlistener", params), bytes.NewBuffer(postContent))
+	if err != nil {
+		return err
+	}
+
+	_, err = c.SendRequest(ctx, req, option)
+	return err
+}
+
+// CreateHTTPSListener creates a HTTPS listener on a BLB
+func (c *Client) CreateHTTPSListener(ctx context.Context, args *CreateHTTPSListenerArgs, option *bce.SignOption) error {
+	if args == nil || args.LoadBalancerId == "" || args.ListenerPort == 0 {
+		return fmt.Errorf("CreateHTTPSListener need LoadBalancerId and ListenerPort")
+	}
+	if len(args.CertIds) == 0 {
+		return fmt.Errorf("CreateHTTPSListener need CertIds")
+	}
+	params := map[string]string{
+		"clientToken": c.GenerateClientToken(),
+	}
+
+	postContent, err := json.Marshal(args)
+	if err != nil {
+		return err
+	}
+
+	req, err := bce.NewRequest("POST", c.GetURL("v1/blb"+"/"+args.LoadBalancerId+"/HTTPSlistener", params), bytes.NewBuffer(postContent))
+	if err != nil {
+		return err
+	}
+
+	_, err = c.SendRequest(ctx, req, option)
+	return err
+}
+
+// DescribeHTTPSListener describes the HTTPS listeners of a BLB
+func (c *Client) DescribeHTTPSListener(ctx context.Context, args *DescribeHTTPSListenerArgs, option *bce.SignOption) ([]HTTPSListener, error) {
+	if args == nil || args.LoadBalancerId == "" {
+		return nil, fmt.Errorf("DescribeHTTPSListener need LoadBalancerId")
+	}
+	params := map[string]string{}
+	if args.ListenerPort != 0 {
+		params["listenerPort"] = strconv.Itoa(args.ListenerPort)
+	}
+
+	req, err := bce.NewRequest("GET", c.GetURL("v1/blb"+"/"+args.LoadBalancerId+"/HTTPSlistener", params), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.SendRequest(ctx, req, option)
+	if err != nil {
+		return nil, err
+	}
+
+	bodyContent, err := resp.GetBodyContent()
+	if err != nil {
+		return nil, err
+	}
+
+	var listenersResp DescribeHTTPSListenerResponse
+	err = json.Unmarshal(bodyContent, &listenersResp)
+	if err != nil {
+		return nil, err
+	}
+
+	return listenersResp.ListenerList, nil
+}
+
+// UpdateHTTPSListener updates a HTTPS listener of a BLB
+func (c *Client) UpdateHTTPSListener(ctx context.Context, args *UpdateHTTPSListenerArgs, option *bce.SignOption) error {
+	if args == nil || args.LoadBalancerId == "" || args.ListenerPort == 0 {
+		return fmt.Errorf("UpdateHTTPSListener need LoadBalancerId and ListenerPort")
+	}
+	params := map[string]string{
+		"listenerPort": strconv.Itoa(args.ListenerPort),
+		"clientToken":  c.GenerateClientToken(),
+	}
+
+	postContent, err := json.Marshal(args)
+	if err != nil {
+		return err
+	}
+
+	req, err := bce.NewRequest("PUT", c.GetURL("v1/blb"+"/"+args.LoadBalancerId+"/HTTPSlistener", params), bytes.NewBuffer(postContent))
+	if err != nil {
+		return err
+	}
+
+	_, err = c.SendRequest(ctx, req, option)
+	return err
+}
+
+// DeleteListenersByType deletes listeners of a BLB by port and protocol
+func (c *Client) DeleteListenersByType(ctx context.Context, args *DeleteListenersByTypeArgs, option *bce.SignOption) error {
+	if args == nil || args.LoadBalancerId == "" {
+		return fmt.Errorf("DeleteListenersByType need LoadBalancerId")
+	}
+	if len(args.PortTypeList) == 0 {
+		return fmt.Errorf("DeleteListenersByType need PortTypeList")
+	}
+	params := map[string]string{
+		"batchdelete": "",
+		"clientToken": c.GenerateClientToken(),
+	}
+
+	postContent, err := json.Marshal(args)
+	if err != nil {
+		return err
+	}
+
+	req, err := bce.NewRequest("PUT", c.GetURL("v1/blb"+"/"+args.LoadBalancerId+"/listener", params), bytes.NewBuffer(postContent))
+	if err != nil {
+		return err
+	}
+
+	_, err = c.SendRequest(ctx, req, option)
+	return err
+}
+
+// DescribeListenerHealthChecks describes the health check targets of the listeners with the protocol on a BLB,
+// which are not returned by the listener describing APIs of bce-sdk-go
+func (c *Client) DescribeListenerHealthChecks(ctx context.Context, args *DescribeListenerHealthChecksArgs, option *bce.SignOption) ([]ListenerHealthCheck, error) {
+	if args == nil || args.LoadBalancerId == "" || args.Type == "" {
+		return nil, fmt.Errorf("DescribeListenerHealthChecks need LoadBalancerId and Type")
+	}
+
+	req, err := bce.NewRequest("GET", c.GetURL("v1/blb"+"/"+args.LoadBalancerId+"/"+args.Type+"listener", nil), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.SendRequest(ctx, req, option)
+	if err != nil {
+		return nil, err
+	}
+
+	bodyContent, err := resp.GetBodyContent()
+	if err != nil {
+		return nil, err
+	}
+
+	var listenersResp DescribeListenerHealthChecksResponse
+	err = json.Unmarshal(bodyContent, &listenersResp)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range listenersResp.ListenerList {
+		listenersResp.ListenerList[i].Type = args.Type
+	}
+	return listenersResp.ListenerList, nil
+}
+
+// UpdateListenerHealthCheck updates the health check target of a listener
+func (c *Client) UpdateListenerHealthCheck(ctx context.Context, args *UpdateListenerHealthCheckArgs, option *bce.SignOption) error {
+	if args == nil || args.LoadBalancerId == "" || args.ListenerPort == 0 || args.Type == "" {
+		return fmt.Errorf("UpdateListenerHealthCheck need LoadBalancerId, ListenerPort and Type")
+	}
+	params := map[string]string{
+		"listenerPort": strconv.Itoa(args.ListenerPort),
+		"clientToken":  c.GenerateClientToken(),
+	}
+
+	postContent, err := json.Marshal(args)
+	if err != nil {
+		return err
+	}
+
+	req, err := bce.NewRequest("PUT", c.GetURL("v1/blb"+"/"+args.LoadBalancerId+"/"+args.Type+"listener", params), bytes.NewBuffer(postContent))
+	if err != nil {
+		return err
+	}
+
+	_, err = c.SendRequest(ctx, req, option)
+	return err
+}
@@ -0,0 +1,86 @@
+package temp_blb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/bce"
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/blb"
+)
+
+// DescribeLoadBalancersPage describes one page of the BLBs matching args
+func (c *Client) DescribeLoadBalancersPage(ctx context.Context, args *DescribeLoadBalancersArgs, option *bce.SignOption) (*DescribeLoadBalancersResponse, error) {
+	if args == nil {
+		return nil, fmt.Errorf("DescribeLoadBalancersPage need args")
+	}
+	params := map[string]string{}
+	if args.LoadBalancerId != "" {
+		params["blbId"] = args.LoadBalancerId
+	}
+	if args.LoadBalancerName != "" {
+		params["name"] = args.LoadBalancerName
+	}
+	if args.Address != "" {
+		params["address"] = args.Address
+	}
+	if args.ExactlyMatch {
+		params["exactlyMatch"] = "true"
+	}
+	if args.Marker != "" {
+		params["marker"] = args.Marker
+	}
+	if args.MaxKeys > 0 {
+		params["maxKeys"] = strconv.Itoa(args.MaxKeys)
+	}
+
+	req, err := bce.NewRequest("GET", c.GetURL("v1/blb", params), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.SendRequest(ctx, req, option)
+	if err != nil {
+		return nil, err
+	}
+
+	bodyContent, err := resp.GetBodyContent()
+	if err != nil {
+		return nil, err
+	}
+
+	var blbsResp DescribeLoadBalancersResponse
+	err = json.Unmarshal(bodyContent, &blbsResp)
+	if err != nil {
+		return nil, err
+	}
+
+	return &blbsResp, nil
+}
+
+// DescribeAllLoadBalancers describes the BLBs matching args page by page until the last page. A truncated page
+// without a new marker is an error, since returning part of the BLBs may make BLBs in use be treated as not exist.
+func DescribeAllLoadBalancers(ctx context.Context, c Interface, args *DescribeLoadBalancersArgs, option *bce.SignOption) ([]blb.LoadBalancer, error) {
+	if args == nil {
+		return nil, fmt.Errorf("DescribeAllLoadBalancers need args")
+	}
+	result := []blb.LoadBalancer{}
+	pageArgs := *args
+	pageArgs.Marker = ""
+	pageArgs.MaxKeys = DefaultMaxKeys
+	for {
+		page, err := c.DescribeLoadBalancersPage(ctx, &pageArgs, option)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, page.BlbList...)
+		if !page.IsTruncated {
+			return result, nil
+		}
+		if page.NextMarker == "" || page.NextMarker == pageArgs.Marker {
+			return nil, fmt.Errorf("DescribeLoadBalancers is truncated at marker %q without next marker", pageArgs.Marker)
+		}
+		pageArgs.Marker = page.NextMarker
+	}
+}
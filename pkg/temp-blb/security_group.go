@@ -0,0 +1,76 @@
+package temp_blb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/bce"
+)
+
+// BindSecurityGroups binds security groups to a BLB
+func (c *Client) BindSecurityGroups(ctx context.Context, args *UpdateSecurityGroupsArgs, option *bce.SignOption) error {
+	return c.updateSecurityGroups(ctx, "bind", args, option)
+}
+
+// UnbindSecurityGroups unbinds security groups from a BLB
+func (c *Client) UnbindSecurityGroups(ctx context.Context, args *UpdateSecurityGroupsArgs, option *bce.SignOption) error {
+	return c.updateSecurityGroups(ctx, "unbind", args, option)
+}
+
+func (c *Client) updateSecurityGroups(ctx context.Context, action string, args *UpdateSecurityGroupsArgs, option *bce.SignOption) error {
+	if args == nil || args.LoadBalancerId == "" {
+		return fmt.Errorf("%s security groups need LoadBalancerId", action)
+	}
+	if len(args.SecurityGroupIds) == 0 {
+		return fmt.Errorf("%s security groups need SecurityGroupIds", action)
+	}
+	params := map[string]string{
+		action:        "",
+		"clientToken": c.GenerateClientToken(),
+	}
+
+	postContent, err := json.Marshal(args)
+	if err != nil {
+		return err
+	}
+
+	req, err := bce.NewRequest("PUT", c.GetURL("v1/blb"+"/"+args.LoadBalancerId+"/securitygroup", params), bytes.NewBuffer(postContent))
+	if err != nil {
+		return err
+	}
+
+	_, err = c.SendRequest(ctx, req, option)
+	return err
+}
+
+// DescribeSecurityGroups describes the security groups bound to a BLB
+func (c *Client) DescribeSecurityGroups(ctx context.Context, blbID string, option *bce.SignOption) ([]BlbSecurityGroup, error) {
+	if blbID == "" {
+		return nil, fmt.Errorf("DescribeSecurityGroups need blbID")
+	}
+
+	req, err := bce.NewRequest("GET", c.GetURL("v1/blb"+"/"+blbID+"/securitygroup", nil), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.SendRequest(ctx, req, option)
+	if err != nil {
+		return nil, err
+	}
+
+	bodyContent, err := resp.GetBodyContent()
+	if err != nil {
+		return nil, err
+	}
+
+	var sgResp DescribeSecurityGroupsResponse
+	err = json.Unmarshal(bodyContent, &sgResp)
+	if err != nil {
+		return nil, err
+	}
+
+	return sgResp.BlbSecurityGroups, nil
+}
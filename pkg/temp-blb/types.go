@@ -0,0 +1,271 @@
+package temp_blb
+
+import (
+	"context"
+
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/bce"
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/blb"
+)
+
+// DefaultMaxKeys is the max BLBs in a page of DescribeLoadBalancersPage
+const DefaultMaxKeys = 1000
+
+// Interface defines the interface of BLB Client
+type Interface interface {
+	blb.Interface
+
+	DescribeLoadBalancersPage(ctx context.Context, args *DescribeLoadBalancersArgs, option *bce.SignOption) (*DescribeLoadBalancersResponse, error)
+
+	DescribeHTTPListener(ctx context.Context, args *DescribeHTTPListenerArgs, option *bce.SignOption) ([]blb.HTTPListener, error)
+	UpdateHTTPListener(ctx context.Context, args *UpdateHTTPListenerArgs, option *bce.SignOption) error
+
+	CreateHTTPSListener(ctx context.Context, args *CreateHTTPSListenerArgs, option *bce.SignOption) error
+	DescribeHTTPSListener(ctx context.Context, args *DescribeHTTPSListenerArgs, option *bce.SignOption) ([]HTTPSListener, error)
+	UpdateHTTPSListener(ctx context.Context, args *UpdateHTTPSListenerArgs, option *bce.SignOption) error
+	DeleteListenersByType(ctx context.Context, args *DeleteListenersByTypeArgs, option *bce.SignOption) error
+	DescribeListenerHealthChecks(ctx context.Context, args *DescribeListenerHealthChecksArgs, option *bce.SignOption) ([]ListenerHealthCheck, error)
+	UpdateListenerHealthCheck(ctx context.Context, args *UpdateListenerHealthCheckArgs, option *bce.SignOption) error
+
+	AddBackendIPs(ctx context.Context, args *AddBackendIPsArgs, option *bce.SignOption) error
+	DescribeBackendIPs(ctx context.Context, args *DescribeBackendIPsArgs, option *bce.SignOption) ([]BackendIP, error)
+	RemoveBackendIPs(ctx context.Context, args *RemoveBackendIPsArgs, option *bce.SignOption) error
+
+	BindSecurityGroups(ctx context.Context, args *UpdateSecurityGroupsArgs, option *bce.SignOption) error
+	UnbindSecurityGroups(ctx context.Context, args *UpdateSecurityGroupsArgs, option *bce.SignOption) error
+	DescribeSecurityGroups(ctx context.Context, blbID string, option *bce.SignOption) ([]BlbSecurityGroup, error)
+}
+
+// DescribeLoadBalancersArgs is the args of DescribeLoadBalancersPage
+type DescribeLoadBalancersArgs struct {
+	LoadBalancerId   string `json:"-"`
+	LoadBalancerName string `json:"-"`
+	Address          string `json:"-"`
+	ExactlyMatch     bool   `json:"-"`
+	// Marker is where the page starts, empty means the first page
+	Marker string `json:"-"`
+	// MaxKeys is the max BLBs in a page, 0 means the default of BLB
+	MaxKeys int `json:"-"`
+}
+
+// DescribeLoadBalancersResponse is the response of DescribeLoadBalancersPage
+type DescribeLoadBalancersResponse struct {
+	Marker      string             `json:"marker"`
+	IsTruncated bool               `json:"isTruncated"`
+	NextMarker  string             `json:"nextMarker"`
+	MaxKeys     int                `json:"maxKeys"`
+	BlbList     []blb.LoadBalancer `json:"blbList"`
+}
+
+// DescribeHTTPListenerArgs is the args of DescribeHTTPListener
+type DescribeHTTPListenerArgs struct {
+	LoadBalancerId string `json:"-"`
+	ListenerPort   int    `json:"-"`
+}
+
+// DescribeHTTPListenerResponse is the response of DescribeHTTPListener
+type DescribeHTTPListenerResponse struct {
+	Marker       string             `json:"marker"`
+	IsTruncated  bool               `json:"isTruncated"`
+	NextMarker   string             `json:"nextMarker"`
+	MaxKeys      int                `json:"maxKeys"`
+	ListenerList []blb.HTTPListener `json:"listenerList"`
+}
+
+// UpdateHTTPListenerArgs is the args of UpdateHTTPListener
+type UpdateHTTPListenerArgs struct {
+	LoadBalancerId             string `json:"-"`
+	ListenerPort               int    `json:"-"`
+	BackendPort                int    `json:"backendPort,omitempty"`
+	Scheduler                  string `json:"scheduler,omitempty"`
+	KeepSession                bool   `json:"keepSession"`
+	KeepSessionType            string `json:"keepSessionType,omitempty"`
+	KeepSessionDuration        int    `json:"keepSessionDuration,omitempty"`
+	XForwardFor                bool   `json:"xForwardFor"`
+	HealthCheckType            string `json:"healthCheckType,omitempty"`
+	HealthCheckURI             string `json:"healthCheckURI,omitempty"`
+	HealthCheckTimeoutInSecond int    `json:"healthCheckTimeoutInSecond,omitempty"`
+	HealthCheckInterval        int    `json:"healthCheckInterval,omitempty"`
+	UnhealthyThreshold         int    `json:"unhealthyThreshold,omitempty"`
+	HealthyThreshold           int    `json:"healthyThreshold,omitempty"`
+	HealthCheckNormalStatus    string `json:"healthCheckNormalStatus,omitempty"`
+	ServerTimeout              int    `json:"serverTimeout,omitempty"`
+	RedirectPort               int    `json:"redirectPort"`
+}
+
+// HTTPSListener is the HTTPS listener of BLB
+type HTTPSListener struct {
+	ListenerPort               int      `json:"listenerPort"`
+	BackendPort                int      `json:"backendPort"`
+	Scheduler                  string   `json:"scheduler"`
+	KeepSession                bool     `json:"keepSession"`
+	KeepSessionType            string   `json:"keepSessionType"`
+	KeepSessionDuration        int      `json:"keepSessionDuration"`
+	KeepSessionCookieName      string   `json:"keepSessionCookieName"`
+	XForwardFor                bool     `json:"xForwardFor"`
+	HealthCheckType            string   `json:"healthCheckType"`
+	HealthCheckPort            int      `json:"healthCheckPort"`
+	HealthCheckURI             string   `json:"healthCheckURI"`
+	HealthCheckTimeoutInSecond int      `json:"healthCheckTimeoutInSecond"`
+	HealthCheckInterval        int      `json:"healthCheckInterval"`
+	UnhealthyThreshold         int      `json:"unhealthyThreshold"`
+	HealthyThreshold           int      `json:"healthyThreshold"`
+	HealthCheckNormalStatus    string   `json:"healthCheckNormalStatus"`
+	ServerTimeout              int      `json:"serverTimeout"`
+	CertIds                    []string `json:"certIds"`
+	Ie6Compatible              bool     `json:"ie6Compatible"`
+}
+
+// CreateHTTPSListenerArgs is the args of CreateHTTPSListener
+type CreateHTTPSListenerArgs struct {
+	LoadBalancerId             string   `json:"-"`
+	ListenerPort               int      `json:"listenerPort"`
+	BackendPort                int      `json:"backendPort"`
+	Scheduler                  string   `json:"scheduler"`
+	KeepSession                bool     `json:"keepSession,omitempty"`
+	KeepSessionType            string   `json:"keepSessionType,omitempty"`
+	KeepSessionDuration        int      `json:"keepSessionDuration,omitempty"`
+	XForwardFor                bool     `json:"xForwardFor,omitempty"`
+	HealthCheckType            string   `json:"healthCheckType,omitempty"`
+	HealthCheckURI             string   `json:"healthCheckURI,omitempty"`
+	HealthCheckTimeoutInSecond int      `json:"healthCheckTimeoutInSecond,omitempty"`
+	HealthCheckInterval        int      `json:"healthCheckInterval,omitempty"`
+	UnhealthyThreshold         int      `json:"unhealthyThreshold,omitempty"`
+	HealthyThreshold           int      `json:"healthyThreshold,omitempty"`
+	HealthCheckNormalStatus    string   `json:"healthCheckNormalStatus,omitempty"`
+	ServerTimeout              int      `json:"serverTimeout,omitempty"`
+	CertIds                    []string `json:"certIds"`
+}
+
+// DescribeHTTPSListenerArgs is the args of DescribeHTTPSListener
+type DescribeHTTPSListenerArgs struct {
+	LoadBalancerId string `json:"-"`
+	ListenerPort   int    `json:"-"`
+}
+
+// DescribeHTTPSListenerResponse is the response of DescribeHTTPSListener
+type DescribeHTTPSListenerResponse struct {
+	Marker       string          `json:"marker"`
+	IsTruncated  bool            `json:"isTruncated"`
+	NextMarker   string          `json:"nextMarker"`
+	MaxKeys      int             `json:"maxKeys"`
+	ListenerList []HTTPSListener `json:"listenerList"`
+}
+
+// UpdateHTTPSListenerArgs is the args of UpdateHTTPSListener
+type UpdateHTTPSListenerArgs struct {
+	LoadBalancerId             string   `json:"-"`
+	ListenerPort               int      `json:"-"`
+	BackendPort                int      `json:"backendPort,omitempty"`
+	Scheduler                  string   `json:"scheduler,omitempty"`
+	KeepSession                bool     `json:"keepSession"`
+	KeepSessionType            string   `json:"keepSessionType,omitempty"`
+	KeepSessionDuration        int      `json:"keepSessionDuration,omitempty"`
+	XForwardFor                bool     `json:"xForwardFor"`
+	HealthCheckType            string   `json:"healthCheckType,omitempty"`
+	HealthCheckURI             string   `json:"healthCheckURI,omitempty"`
+	HealthCheckTimeoutInSecond int      `json:"healthCheckTimeoutInSecond,omitempty"`
+	HealthCheckInterval        int      `json:"healthCheckInterval,omitempty"`
+	UnhealthyThreshold         int      `json:"unhealthyThreshold,omitempty"`
+	HealthyThreshold           int      `json:"healthyThreshold,omitempty"`
+	HealthCheckNormalStatus    string   `json:"healthCheckNormalStatus,omitempty"`
+	ServerTimeout              int      `json:"serverTimeout,omitempty"`
+	CertIds                    []string `json:"certIds,omitempty"`
+}
+
+// DeleteListenersByTypeArgs is the args of DeleteListenersByType
+type DeleteListenersByTypeArgs struct {
+	LoadBalancerId string             `json:"-"`
+	PortTypeList   []ListenerPortType `json:"portTypeList"`
+}
+
+// ListenerPortType identifies a listener by port and protocol, since TCP and UDP listeners may use the same port
+type ListenerPortType struct {
+	Port int    `json:"port"`
+	Type string `json:"type"`
+}
+
+// BackendIP is an IP backend of BLB, traffic is sent to the backend port of listeners on it
+type BackendIP struct {
+	IP     string `json:"ip"`
+	Weight int    `json:"weight"`
+}
+
+// AddBackendIPsArgs is the args of AddBackendIPs
+type AddBackendIPsArgs struct {
+	LoadBalancerId string      `json:"-"`
+	BackendIPList  []BackendIP `json:"backendIpList"`
+}
+
+// DescribeBackendIPsArgs is the args of DescribeBackendIPs
+type DescribeBackendIPsArgs struct {
+	LoadBalancerId string `json:"-"`
+}
+
+// DescribeBackendIPsResponse is the response of DescribeBackendIPs
+type DescribeBackendIPsResponse struct {
+	BackendIPList []BackendIP `json:"backendIpList"`
+}
+
+// RemoveBackendIPsArgs is the args of RemoveBackendIPs
+type RemoveBackendIPsArgs struct {
+	LoadBalancerId string   `json:"-"`
+	BackendIPList  []string `json:"backendIpList"`
+}
+
+// UpdateSecurityGroupsArgs is the args of BindSecurityGroups and UnbindSecurityGroups
+type UpdateSecurityGroupsArgs struct {
+	LoadBalancerId   string   `json:"-"`
+	SecurityGroupIds []string `json:"securityGroupIds"`
+}
+
+// BlbSecurityGroup is the security group bound to BLB
+type BlbSecurityGroup struct {
+	SecurityGroupID   string `json:"securityGroupId"`
+	SecurityGroupName string `json:"securityGroupName"`
+	SecurityGroupDesc string `json:"securityGroupDesc"`
+	VpcName           string `json:"vpcName"`
+}
+
+// DescribeSecurityGroupsResponse is the response of DescribeSecurityGroups
+type DescribeSecurityGroupsResponse struct {
+	BlbSecurityGroups []BlbSecurityGroup `json:"blbSecurityGroups"`
+}
+
+// ListenerHealthCheck is the health check target of a listener.
+// HealthCheckPort 0 means backends are checked on the backend port of listener.
+type ListenerHealthCheck struct {
+	ListenerPort            int    `json:"listenerPort"`
+	Type                    string `json:"-"`
+	HealthCheckType         string `json:"healthCheckType"`
+	HealthCheckPort         int    `json:"healthCheckPort"`
+	HealthCheckURI          string `json:"healthCheckURI"`
+	HealthCheckNormalStatus string `json:"healthCheckNormalStatus"`
+}
+
+// DescribeListenerHealthChecksArgs is the args of DescribeListenerHealthChecks
+type DescribeListenerHealthChecksArgs struct {
+	LoadBalancerId string `json:"-"`
+	// Type is the listener protocol, TCP/UDP/HTTP/HTTPS
+	Type string `json:"-"`
+}
+
+// DescribeListenerHealthChecksResponse is the response of DescribeListenerHealthChecks
+type DescribeListenerHealthChecksResponse struct {
+	Marker       string                `json:"marker"`
+	IsTruncated  bool                  `json:"isTruncated"`
+	NextMarker   string                `json:"nextMarker"`
+	MaxKeys      int                   `json:"maxKeys"`
+	ListenerList []ListenerHealthCheck `json:"listenerList"`
+}
+
+// UpdateListenerHealthCheckArgs is the args of UpdateListenerHealthCheck
+type UpdateListenerHealthCheckArgs struct {
+	LoadBalancerId string `json:"-"`
+	ListenerPort   int    `json:"-"`
+	// Type is the listener protocol, TCP/UDP/HTTP/HTTPS
+	Type                    string `json:"-"`
+	HealthCheckType         string `json:"healthCheckType,omitempty"`
+	HealthCheckPort         int    `json:"healthCheckPort"`
+	HealthCheckURI          string `json:"healthCheckURI,omitempty"`
+	HealthCheckNormalStatus string `json:"healthCheckNormalStatus,omitempty"`
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strconv"
 
 	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/bce"
 )
@@ -13,14 +14,25 @@ func (c *Client) CreateCluster(ctx context.Context, args *CreateClusterArgs) (*C
 	return &CreateClusterResponse{}, nil
 }
 
-// ListClusterNodes gets all Instances of a cluster.
+// ListClusterNodes gets all Instances of a cluster, following the markers of truncated pages.
 func (c *Client) ListClusterNodes(ctx context.Context, clusterID string, option *bce.SignOption) (*ListClusterNodesResponse, error) {
-	if clusterID == "" {
+	return ListAllClusterNodes(ctx, c, clusterID, option)
+}
+
+// ListClusterNodesPage gets one page of Instances of a cluster.
+func (c *Client) ListClusterNodesPage(ctx context.Context, args *ListClusterNodesArgs, option *bce.SignOption) (*ListClusterNodesResponse, error) {
+	if args == nil || args.ClusterID == "" {
 		return nil, fmt.Errorf("clusterID is nil")
 	}
 
 	params := map[string]string{
-		"clusterUuid": clusterID,
+		"clusterUuid": args.ClusterID,
+	}
+	if args.Marker != "" {
+		params["marker"] = args.Marker
+	}
+	if args.MaxKeys > 0 {
+		params["maxKeys"] = strconv.Itoa(args.MaxKeys)
 	}
 
 	req, err := bce.NewRequest("GET", c.GetURL("v1/node", params), nil)
@@ -50,3 +62,33 @@ func (c *Client) ListClusterNodes(ctx context.Context, clusterID string, option
 
 	return &nodesResq, nil
 }
+
+// ListAllClusterNodes lists Instances of a cluster page by page until the last page. A truncated page without a new
+// marker is an error, since returning part of the nodes may make nodes be treated as deleted.
+func ListAllClusterNodes(ctx context.Context, c Interface, clusterID string, option *bce.SignOption) (*ListClusterNodesResponse, error) {
+	if clusterID == "" {
+		return nil, fmt.Errorf("clusterID is nil")
+	}
+	result := &ListClusterNodesResponse{
+		Nodes: []*Node{},
+	}
+	marker := ""
+	for {
+		page, err := c.ListClusterNodesPage(ctx, &ListClusterNodesArgs{
+			ClusterID: clusterID,
+			Marker:    marker,
+			MaxKeys:   DefaultMaxKeys,
+		}, option)
+		if err != nil {
+			return nil, err
+		}
+		result.Nodes = append(result.Nodes, page.Nodes...)
+		if !page.IsTruncated {
+			return result, nil
+		}
+		if page.NextMarker == "" || page.NextMarker == marker {
+			return nil, fmt.Errorf("ListClusterNodes of cluster %s is truncated at marker %q without next marker", clusterID, marker)
+		}
+		marker = page.NextMarker
+	}
+}
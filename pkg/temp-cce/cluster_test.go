@@ -3,6 +3,7 @@ package temp_cce
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"testing"
 	"time"
 
@@ -43,3 +44,41 @@ func testListClusterNodes(t *testing.T) {
 	str, _ := json.Marshal(nodesResq)
 	t.Errorf("ListClusterNodes failed: %v", string(str))
 }
+
+// pagedClient returns the pages in order, keyed by marker
+type pagedClient struct {
+	FakeClient
+	pages map[string]*ListClusterNodesResponse
+}
+
+func (c *pagedClient) ListClusterNodesPage(ctx context.Context, args *ListClusterNodesArgs, option *bce.SignOption) (*ListClusterNodesResponse, error) {
+	page, ok := c.pages[args.Marker]
+	if !ok {
+		return nil, fmt.Errorf("unexpected marker %q", args.Marker)
+	}
+	return page, nil
+}
+
+func TestListAllClusterNodes(t *testing.T) {
+	ctx := context.Background()
+	c := &pagedClient{
+		pages: map[string]*ListClusterNodesResponse{
+			"":    {IsTruncated: true, NextMarker: "i-2", Nodes: []*Node{{InstanceID: "i-1"}}},
+			"i-2": {IsTruncated: true, NextMarker: "i-3", Nodes: []*Node{{InstanceID: "i-2"}}},
+			"i-3": {Nodes: []*Node{{InstanceID: "i-3"}}},
+		},
+	}
+	resp, err := ListAllClusterNodes(ctx, c, "c-1", nil)
+	if err != nil {
+		t.Fatalf("ListAllClusterNodes err: %v", err)
+	}
+	if len(resp.Nodes) != 3 {
+		t.Errorf("ListAllClusterNodes err, want 3 nodes, get %d", len(resp.Nodes))
+	}
+
+	// truncated page without next marker must not return part of the nodes
+	c.pages["i-3"] = &ListClusterNodesResponse{IsTruncated: true, Nodes: []*Node{{InstanceID: "i-3"}}}
+	if _, err := ListAllClusterNodes(ctx, c, "c-1", nil); err == nil {
+		t.Errorf("ListAllClusterNodes err, want error for truncated page without next marker")
+	}
+}
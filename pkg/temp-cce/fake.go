@@ -85,3 +85,11 @@ func (f *FakeClient) ListClusterNodes(ctx context.Context, clusterID string, opt
 		Nodes: nodes,
 	}, nil
 }
+
+// ListClusterNodesPage list cluster nodes in one page
+func (f *FakeClient) ListClusterNodesPage(ctx context.Context, args *ListClusterNodesArgs, option *bce.SignOption) (*ListClusterNodesResponse, error) {
+	if args == nil {
+		return nil, fmt.Errorf("ListClusterNodesPage failed: args is nil")
+	}
+	return f.ListClusterNodes(ctx, args.ClusterID, option)
+}
@@ -18,11 +18,15 @@ const (
 	InstanceStatusReady        InstanceStatus = "READY"
 )
 
+// DefaultMaxKeys is the max items in a page of list APIs
+const DefaultMaxKeys = 1000
+
 // Interface defines the interface of CCE Client
 type Interface interface {
 	CreateCluster(ctxd context.Context, args *CreateClusterArgs) (*CreateClusterResponse, error)
 
 	ListClusterNodes(ctx context.Context, clusterID string, option *bce.SignOption) (*ListClusterNodesResponse, error)
+	ListClusterNodesPage(ctx context.Context, args *ListClusterNodesArgs, option *bce.SignOption) (*ListClusterNodesResponse, error)
 
 	// TODO: Add more
 }
@@ -98,6 +102,15 @@ type PaymentType string
 type CDSDisk struct {
 }
 
+// ListClusterNodesArgs the args of ListClusterNodesPage
+type ListClusterNodesArgs struct {
+	ClusterID string
+	// Marker is where the page starts, empty means the first page
+	Marker string
+	// MaxKeys is the max nodes in a page, 0 means the default of CCE
+	MaxKeys int
+}
+
 // ListClusterNodesResponse the return of ListClusterNodes
 type ListClusterNodesResponse struct {
 	Marker      string  `json:"marker"`
@@ -0,0 +1,28 @@
+package temp_eip
+
+import (
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/eip"
+)
+
+// Client is the EIP client with the APIs which bce-sdk-go has not supported yet.
+type Client struct {
+	*eip.Client
+}
+
+// NewClient client of EIP
+func NewClient(config *eip.Config) *Client {
+	return &Client{eip.NewClient(config)}
+}
+
+// GetURL generates the full URL of http request for Baidu Cloud EIP API.
+func (c *Client) GetURL(objectKey string, params map[string]string) string {
+	host := c.Endpoint
+
+	if host == "" {
+		host = eip.Endpoint[c.GetRegion()]
+	}
+
+	uriPath := objectKey
+
+	return c.Client.Client.GetURL(host, uriPath, params)
+}
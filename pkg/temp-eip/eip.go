@@ -0,0 +1,72 @@
+package temp_eip
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/bce"
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/eip"
+)
+
+// ListEIPs lists one page of the EIPs
+func (c *Client) ListEIPs(ctx context.Context, args *ListEIPsArgs, option *bce.SignOption) (*ListEIPsResponse, error) {
+	if args == nil {
+		return nil, fmt.Errorf("ListEIPs need args")
+	}
+	params := map[string]string{}
+	if args.Marker != "" {
+		params["marker"] = args.Marker
+	}
+	if args.MaxKeys > 0 {
+		params["maxKeys"] = strconv.Itoa(args.MaxKeys)
+	}
+
+	req, err := bce.NewRequest("GET", c.GetURL("v1/eip", params), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.SendRequest(ctx, req, option)
+	if err != nil {
+		return nil, err
+	}
+
+	bodyContent, err := resp.GetBodyContent()
+	if err != nil {
+		return nil, err
+	}
+
+	var eipsResp ListEIPsResponse
+	err = json.Unmarshal(bodyContent, &eipsResp)
+	if err != nil {
+		return nil, err
+	}
+
+	return &eipsResp, nil
+}
+
+// ListAllEIPs lists the EIPs page by page until the last page. A truncated page without a new marker is an error,
+// since returning part of the EIPs may make EIPs in use be treated as not exist.
+func ListAllEIPs(ctx context.Context, c Interface, option *bce.SignOption) ([]*eip.EIP, error) {
+	result := []*eip.EIP{}
+	marker := ""
+	for {
+		page, err := c.ListEIPs(ctx, &ListEIPsArgs{
+			Marker:  marker,
+			MaxKeys: DefaultMaxKeys,
+		}, option)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, page.EIPList...)
+		if !page.IsTruncated {
+			return result, nil
+		}
+		if page.NextMarker == "" || page.NextMarker == marker {
+			return nil, fmt.Errorf("ListEIPs is truncated at marker %q without next marker", marker)
+		}
+		marker = page.NextMarker
+	}
+}
@@ -0,0 +1,35 @@
+package temp_eip
+
+import (
+	"context"
+
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/bce"
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/eip"
+)
+
+// DefaultMaxKeys is the max EIPs in a page of ListEIPs
+const DefaultMaxKeys = 1000
+
+// Interface defines the interface of EIP Client
+type Interface interface {
+	eip.Interface
+
+	ListEIPs(ctx context.Context, args *ListEIPsArgs, option *bce.SignOption) (*ListEIPsResponse, error)
+}
+
+// ListEIPsArgs is the args of ListEIPs
+type ListEIPsArgs struct {
+	// Marker is where the page starts, empty means the first page
+	Marker string `json:"-"`
+	// MaxKeys is the max EIPs in a page, 0 means the default of EIP
+	MaxKeys int `json:"-"`
+}
+
+// ListEIPsResponse is the response of ListEIPs
+type ListEIPsResponse struct {
+	Marker      string     `json:"marker"`
+	IsTruncated bool       `json:"isTruncated"`
+	NextMarker  string     `json:"nextMarker"`
+	MaxKeys     int        `json:"maxKeys"`
+	EIPList     []*eip.EIP `json:"eipList"`
+}